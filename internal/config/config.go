@@ -25,6 +25,21 @@ type Config struct {
 	// Theme
 	Theme string
 
+	// GitOps state file (empty disables watching)
+	StateFile          string
+	StateWatchInterval int // seconds
+
+	// Git sync of the managed files (repository lives in ConfigDir)
+	GitSync     bool
+	GitRemote   string
+	GitBranch   string
+	GitUsername string
+	GitToken    string
+	GitAutoPush bool
+
+	// Certificate renewal: seconds to wait for Caddy to obtain a new certificate
+	RenewTimeout int
+
 	// App info
 	Version   string
 	BuildDate string
@@ -40,10 +55,20 @@ func Load(version, buildDate string) (*Config, error) {
 		ContainerName: getEnv("CONTAINER_NAME", "caddy"),
 		DefaultIP:     getEnv("DEFAULT_IP", "192.168.1.1"),
 		Theme:         getEnv("THEME", "classic"),
+		StateFile:     getEnv("STATE_FILE", ""),
 		Version:       version,
 		BuildDate:     buildDate,
 	}
 
+	cfg.StateWatchInterval = getEnvInt("STATE_WATCH_INTERVAL", 30)
+	cfg.GitSync = getEnvBool("GIT_SYNC", false)
+	cfg.GitRemote = getEnv("GIT_REMOTE", "")
+	cfg.GitBranch = getEnv("GIT_BRANCH", "main")
+	cfg.GitUsername = getEnv("GIT_USERNAME", "")
+	cfg.GitToken = getEnv("GIT_TOKEN", "")
+	cfg.GitAutoPush = getEnvBool("GIT_AUTO_PUSH", false)
+	cfg.RenewTimeout = getEnvInt("RENEW_TIMEOUT", 180)
+
 	// Derived paths
 	cfg.SitesDir = cfg.ConfigDir + "/sites"
 
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/network"
+	"github.com/gofiber/fiber/v2"
+)
+
+// AccessListSave creates an access list or updates the networks of an existing one
+func (h *Handler) AccessListSave(c *fiber.Ctx) error {
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	list := models.AccessList{Name: strings.TrimSpace(c.FormValue("name"))}
+	if list.Allow, err = network.Normalize(formLines(c.FormValue("allow"))); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Allowed networks: " + err.Error())
+	}
+	if list.Deny, err = network.Normalize(formLines(c.FormValue("deny"))); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString("Denied networks: " + err.Error())
+	}
+
+	if id := c.FormValue("id"); id != "" {
+		// The ID is used by the sites' imports, so existing lists keep their name
+		existing := cfg.AccessList(id)
+		if existing == nil {
+			return c.Status(fiber.StatusNotFound).SendString("Access list not found")
+		}
+		list.ID, list.Name = existing.ID, existing.Name
+		if err := list.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		*existing = list
+	} else {
+		list.ID = models.AccessListID(list.Name)
+		if err := list.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if cfg.AccessList(list.ID) != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("An access list named " + list.Name + " already exists")
+		}
+		cfg.AccessLists = append(cfg.AccessLists, list)
+	}
+
+	if err := h.snippetsService.SaveConfig(cfg); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	result := h.caddyService.ReloadWithValidation()
+	if !result.Success {
+		setFlash(c, "warning", "Access list saved but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Access list '"+list.Name+"' saved")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/snippets")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/snippets")
+}
+
+// AccessListDelete removes an access list that no site uses
+func (h *Handler) AccessListDelete(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	if domains := h.accessListUsage()[id]; len(domains) > 0 {
+		setFlash(c, "error", "Access list is used by "+strings.Join(domains, ", "))
+	} else if cfg.AccessList(id) == nil {
+		setFlash(c, "error", "Access list not found")
+	} else {
+		// An empty list is saved as [], so the LAN list isn't recreated
+		lists := []models.AccessList{}
+		for _, list := range cfg.AccessLists {
+			if list.ID != id {
+				lists = append(lists, list)
+			}
+		}
+		cfg.AccessLists = lists
+
+		if err := h.snippetsService.SaveConfig(cfg); err != nil {
+			setFlash(c, "error", err.Error())
+		} else {
+			setFlash(c, "success", "Access list deleted")
+		}
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/snippets")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/snippets")
+}
+
+// accessListUsage maps access list IDs to the sites using them
+func (h *Handler) accessListUsage() map[string][]string {
+	usage := make(map[string][]string)
+	sites, _ := h.caddyService.GetAllSites()
+	for _, site := range sites {
+		for _, id := range site.AccessLists {
+			usage[id] = append(usage[id], site.PrimaryDomain())
+		}
+	}
+	return usage
+}
+
+// checkAccessLists verifies that the site's access lists exist
+func (h *Handler) checkAccessLists(site *models.Site) error {
+	if len(site.AccessLists) == 0 {
+		return nil
+	}
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return err
+	}
+	for _, id := range site.AccessLists {
+		if cfg.AccessList(id) == nil {
+			return fmt.Errorf("unknown access list %q", id)
+		}
+	}
+	return nil
+}
+
+// accessLists returns the access lists offered in the site form
+func (h *Handler) accessLists() []models.AccessList {
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg.AccessLists
+}
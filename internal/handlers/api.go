@@ -21,6 +21,45 @@ func (h *Handler) APISites(c *fiber.Ctx) error {
 	})
 }
 
+// APISiteEnable enables a site and reloads Caddy
+func (h *Handler) APISiteEnable(c *fiber.Ctx) error {
+	return h.apiSetSiteEnabled(c, true)
+}
+
+// APISiteDisable disables a site and reloads Caddy; its file is kept
+func (h *Handler) APISiteDisable(c *fiber.Ctx) error {
+	return h.apiSetSiteEnabled(c, false)
+}
+
+// apiSetSiteEnabled enables or disables the site of the id parameter
+func (h *Handler) apiSetSiteEnabled(c *fiber.Ctx, enabled bool) error {
+	if _, err := h.caddyService.GetSite(c.Params("id")); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	site, err := h.caddyService.SetSiteEnabled(c.Params("id"), enabled)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if result := h.caddyService.ReloadWithValidation(); !result.Success {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"site":    site,
+			"error":   result.Error,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"site":    site,
+	})
+}
+
 // APIStatus returns system status as JSON
 func (h *Handler) APIStatus(c *fiber.Ctx) error {
 	stats := h.caddyService.GetStats()
@@ -154,3 +193,64 @@ func escapeHTML(s string) string {
 	s = strings.ReplaceAll(s, "'", "&#39;")
 	return s
 }
+
+// APIStateExport returns the current configuration as a YAML state file
+func (h *Handler) APIStateExport(c *fiber.Ctx) error {
+	data, err := h.stateService.ExportState()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	c.Set("Content-Type", "application/yaml")
+	return c.Send(data)
+}
+
+// APIStatePlan compares the posted YAML state with the current configuration
+func (h *Handler) APIStatePlan(c *fiber.Ctx) error {
+	desired, err := h.stateService.ParseState(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	plan, err := h.stateService.Plan(desired)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(plan)
+}
+
+// APIStateApply applies the posted YAML state and reloads Caddy
+func (h *Handler) APIStateApply(c *fiber.Ctx) error {
+	desired, err := h.stateService.ParseState(c.Body())
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	plan, err := h.stateService.Apply(desired)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"success": false,
+			"error":   err.Error(),
+			"plan":    plan,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"success": true,
+		"plan":    plan,
+	})
+}
+
+// APIStateStatus returns the status of the watched state file
+func (h *Handler) APIStateStatus(c *fiber.Ctx) error {
+	return c.JSON(h.stateService.Status())
+}
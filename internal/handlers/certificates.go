@@ -1,6 +1,11 @@
 package handlers
 
 import (
+	"io"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -14,9 +19,18 @@ func (h *Handler) CertificatesList(c *fiber.Ctx) error {
 	stats := h.certService.GetStats()
 	flashType, flashMsg := getFlash(c)
 
+	customCerts, _ := h.certService.GetCustomCertificates()
+	sites, _ := h.caddyService.GetAllSites()
+
 	data := h.baseData(c, "SSL Certificates")
 	data["Certificates"] = certs
+	data["Coverage"] = services.CertificateCoverage(certs, sites)
 	data["Stats"] = stats
+	data["CustomCertificates"] = customCerts
+	data["CustomCertificateUsage"] = h.customCertificateUsage()
+	data["ClientCAs"], _ = h.mtlsService.GetCAs()
+	data["ClientCAUsage"] = h.clientCAUsage()
+	data["RenewalJobs"] = h.renewalService.Jobs()
 	data["FlashType"] = flashType
 	data["FlashMessage"] = flashMsg
 	data["Active"] = "certificates"
@@ -24,6 +38,65 @@ func (h *Handler) CertificatesList(c *fiber.Ctx) error {
 	return c.Render("pages/certificates", data, "layouts/base")
 }
 
+// CertificateInspect renders the details of a single certificate
+func (h *Handler) CertificateInspect(c *fiber.Ctx) error {
+	detail, err := h.inspectCertificate(c.Params("fingerprint"))
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, err.Error())
+	}
+
+	data := h.baseData(c, "Certificate: "+detail.Domain)
+	data["Cert"] = detail
+	data["Active"] = "certificates"
+
+	return c.Render("pages/certificate_detail", data, "layouts/base")
+}
+
+// APICertificates returns all certificates with the sites they serve as JSON
+func (h *Handler) APICertificates(c *fiber.Ctx) error {
+	certs, err := h.certService.GetAllCertificates()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	sites, _ := h.caddyService.GetAllSites()
+
+	return c.JSON(fiber.Map{
+		"certificates": certs,
+		"count":        len(certs),
+		"coverage":     services.CertificateCoverage(certs, sites),
+	})
+}
+
+// APICertificate returns the details of a single certificate as JSON
+func (h *Handler) APICertificate(c *fiber.Ctx) error {
+	detail, err := h.inspectCertificate(c.Params("fingerprint"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(detail)
+}
+
+// inspectCertificate loads a certificate's details and the managed sites it serves
+func (h *Handler) inspectCertificate(fingerprint string) (*models.CertificateDetail, error) {
+	detail, err := h.certService.InspectCertificate(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	sites, _ := h.caddyService.GetAllSites()
+	for _, site := range sites {
+		if detail.Serves(site) {
+			detail.Sites = append(detail.Sites, site.PrimaryDomain())
+		}
+	}
+	return detail, nil
+}
+
 // CertificateDelete deletes a certificate (no renewal)
 func (h *Handler) CertificateDelete(c *fiber.Ctx) error {
 	domain := c.Params("domain")
@@ -42,20 +115,15 @@ func (h *Handler) CertificateDelete(c *fiber.Ctx) error {
 	return c.Redirect("/certificates")
 }
 
-// CertificateRenew deletes a certificate and reloads Caddy to trigger renewal
+// CertificateRenew backs up a certificate and reloads Caddy to obtain a new one.
+// The previous files are restored if no new certificate appears in time.
 func (h *Handler) CertificateRenew(c *fiber.Ctx) error {
 	domain := c.Params("domain")
 
-	if err := h.certService.DeleteCertificate(domain); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
-	}
-
-	// Reload Caddy to trigger renewal
-	result := h.caddyService.Reload()
-	if !result.Success {
-		setFlash(c, "warning", "Certificate deleted but reload failed: "+result.Error)
+	if _, err := h.renewalService.Start(domain); err != nil {
+		setFlash(c, "error", "Renewal not started: "+err.Error())
 	} else {
-		setFlash(c, "success", "Certificate for '"+domain+"' deleted. Renewal will be triggered.")
+		setFlash(c, "success", "Renewal of '"+domain+"' started. The current certificate is restored if Caddy doesn't obtain a new one.")
 	}
 
 	if c.Get("HX-Request") == "true" {
@@ -66,6 +134,16 @@ func (h *Handler) CertificateRenew(c *fiber.Ctx) error {
 	return c.Redirect("/certificates")
 }
 
+// APICertificateRenewals returns the recent certificate renewals as JSON
+func (h *Handler) APICertificateRenewals(c *fiber.Ctx) error {
+	jobs := h.renewalService.Jobs()
+
+	return c.JSON(fiber.Map{
+		"renewals": jobs,
+		"count":    len(jobs),
+	})
+}
+
 // HTMXCertificatesList returns certificates list as HTML partial
 func (h *Handler) HTMXCertificatesList(c *fiber.Ctx) error {
 	certs, _ := h.certService.GetAllCertificates()
@@ -74,3 +152,78 @@ func (h *Handler) HTMXCertificatesList(c *fiber.Ctx) error {
 		"Certificates": certs,
 	})
 }
+
+// CustomCertificateUpload validates and stores an uploaded certificate
+func (h *Handler) CustomCertificateUpload(c *fiber.Ctx) error {
+	certPEM, err := pemFormValue(c, "certificate")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	chainPEM, err := pemFormValue(c, "chain")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	keyPEM, err := pemFormValue(c, "key")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if len(certPEM) == 0 || len(keyPEM) == 0 {
+		setFlash(c, "error", "Certificate and private key are required")
+		return c.Redirect("/certificates")
+	}
+
+	cert, err := h.certService.ImportCustomCertificate(strings.TrimSpace(c.FormValue("name")), certPEM, chainPEM, keyPEM)
+	if err != nil {
+		setFlash(c, "error", "Certificate rejected: "+err.Error())
+		return c.Redirect("/certificates")
+	}
+
+	setFlash(c, "success", "Certificate '"+cert.Name+"' uploaded, use it with TLS mode custom:"+cert.ID)
+	return c.Redirect("/certificates")
+}
+
+// CustomCertificateDelete removes an uploaded certificate that no site uses
+func (h *Handler) CustomCertificateDelete(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if domains := h.customCertificateUsage()[id]; len(domains) > 0 {
+		setFlash(c, "error", "Certificate is used by "+strings.Join(domains, ", "))
+	} else if err := h.certService.DeleteCustomCertificate(id); err != nil {
+		setFlash(c, "error", err.Error())
+	} else {
+		setFlash(c, "success", "Certificate deleted")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/certificates")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/certificates")
+}
+
+// customCertificateUsage maps uploaded certificate IDs to the sites using them
+func (h *Handler) customCertificateUsage() map[string][]string {
+	usage := make(map[string][]string)
+	sites, _ := h.caddyService.GetAllSites()
+	for _, site := range sites {
+		if id := site.CustomCertificateID(); id != "" {
+			usage[id] = append(usage[id], site.PrimaryDomain())
+		}
+	}
+	return usage
+}
+
+// pemFormValue reads PEM data from an uploaded <key>_file or the pasted <key> field
+func pemFormValue(c *fiber.Ctx, key string) ([]byte, error) {
+	if file, err := c.FormFile(key + "_file"); err == nil && file.Size > 0 {
+		f, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, 1<<20))
+	}
+	return []byte(strings.TrimSpace(c.FormValue(key))), nil
+}
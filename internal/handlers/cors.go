@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// corsFromForm reads the CORS policy of the site form; without origins the
+// site has none
+func corsFromForm(c *fiber.Ctx) *models.CORS {
+	origins := formLines(strings.ToLower(c.FormValue("cors_origins")))
+	if len(origins) == 0 {
+		return nil
+	}
+
+	return &models.CORS{
+		Origins:          origins,
+		Methods:          strings.Fields(strings.ToUpper(strings.ReplaceAll(c.FormValue("cors_methods"), ",", " "))),
+		Headers:          strings.Fields(strings.ReplaceAll(c.FormValue("cors_headers"), ",", " ")),
+		AllowCredentials: c.FormValue("cors_credentials") == "on",
+		MaxAge:           formInt(c, "cors_max_age", 0),
+	}
+}
+
+// checkCORS validates the site's CORS policy
+func checkCORS(site *models.Site) error {
+	if site.CORS == nil {
+		return nil
+	}
+	return site.CORS.Validate()
+}
@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// CustomSnippetSave creates a custom snippet or updates the body of an existing one
+func (h *Handler) CustomSnippetSave(c *fiber.Ctx) error {
+	previous, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	snippet := models.CustomSnippet{
+		Name:        strings.TrimSpace(c.FormValue("name")),
+		Description: strings.Join(strings.Fields(c.FormValue("description")), " "),
+		Body:        strings.ReplaceAll(c.FormValue("body"), "\r\n", "\n"),
+	}
+	if snippet.Params, err = snippetParamsFromForm(c.FormValue("params")); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	if original := c.FormValue("original"); original != "" {
+		// Sites import the snippet by name, so existing snippets keep it
+		existing := cfg.CustomSnippet(original)
+		if existing == nil {
+			return c.Status(fiber.StatusNotFound).SendString("Snippet not found")
+		}
+		snippet.Name = existing.Name
+		if err := snippet.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		// Sites passing arguments rely on the parameters
+		if len(snippet.Params) != len(existing.Params) {
+			if domains := h.snippetArgsUsage(existing.Name); len(domains) > 0 {
+				return c.Status(fiber.StatusBadRequest).SendString("The number of parameters can't change while sites pass arguments: " + strings.Join(domains, ", "))
+			}
+		}
+		*existing = snippet
+	} else {
+		if err := snippet.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if cfg.CustomSnippet(snippet.Name) != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("A snippet named " + snippet.Name + " already exists")
+		}
+		cfg.Custom = append(cfg.Custom, snippet)
+	}
+
+	if err := h.saveValidatedSnippets(previous, cfg); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+
+	result := h.caddyService.ReloadWithValidation()
+	if !result.Success {
+		setFlash(c, "warning", "Snippet saved but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Snippet '"+snippet.Name+"' saved")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/snippets")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/snippets")
+}
+
+// CustomSnippetDelete removes a custom snippet that no site imports
+func (h *Handler) CustomSnippetDelete(c *fiber.Ctx) error {
+	name := c.Params("name")
+
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	if domains := h.snippetUsage()[name]; len(domains) > 0 {
+		setFlash(c, "error", "Snippet is used by "+strings.Join(domains, ", "))
+	} else if cfg.CustomSnippet(name) == nil {
+		setFlash(c, "error", "Snippet not found")
+	} else {
+		var custom []models.CustomSnippet
+		for _, snippet := range cfg.Custom {
+			if snippet.Name != name {
+				custom = append(custom, snippet)
+			}
+		}
+		cfg.Custom = custom
+
+		if err := h.snippetsService.SaveConfig(cfg); err != nil {
+			setFlash(c, "error", err.Error())
+		} else {
+			setFlash(c, "success", "Snippet deleted")
+		}
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/snippets")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/snippets")
+}
+
+// snippetParamsFromForm parses the parameters of a custom snippet, one per
+// line as "name type default"
+func snippetParamsFromForm(value string) ([]models.SnippetParam, error) {
+	var params []models.SnippetParam
+	for _, line := range formLines(value) {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("parameter %q: use name, type and default value", line)
+		}
+		params = append(params, models.SnippetParam{
+			Name:    fields[0],
+			Type:    fields[1],
+			Default: strings.Join(fields[2:], " "),
+		})
+	}
+	return params, nil
+}
+
+// saveValidatedSnippets saves the snippet configuration and has Caddy validate
+// the result. If Caddy rejects it but accepts the previous configuration, the
+// previous one is restored and the validation error returned; other failures,
+// like Caddy not running, are left to the reload.
+func (h *Handler) saveValidatedSnippets(previous, cfg *models.SnippetConfig) error {
+	if err := h.snippetsService.SaveConfig(cfg); err != nil {
+		return err
+	}
+
+	result := h.caddyService.Validate()
+	if result.Success {
+		return nil
+	}
+
+	if err := h.snippetsService.SaveConfig(previous); err != nil {
+		return err
+	}
+	if !h.caddyService.Validate().Success {
+		return h.snippetsService.SaveConfig(cfg)
+	}
+
+	message := result.Error
+	if log := strings.TrimSpace(result.ValidationLog); log != "" {
+		message += "\n" + log
+	}
+	return fmt.Errorf("Caddy rejected the snippet: %s", message)
+}
+
+// snippetUsage maps snippet names to the sites importing them, including
+// the snippets of path routes
+func (h *Handler) snippetUsage() map[string][]string {
+	usage := make(map[string][]string)
+	sites, _ := h.caddyService.GetAllSites()
+	for _, site := range sites {
+		names := append([]string{}, site.Snippets...)
+		for _, route := range site.Routes {
+			names = append(names, route.Snippets...)
+		}
+		for _, name := range names {
+			if !contains(usage[name], site.PrimaryDomain()) {
+				usage[name] = append(usage[name], site.PrimaryDomain())
+			}
+		}
+	}
+	return usage
+}
+
+// snippetArgsUsage returns the sites passing arguments to a snippet
+func (h *Handler) snippetArgsUsage(name string) []string {
+	var domains []string
+	sites, _ := h.caddyService.GetAllSites()
+	for _, site := range sites {
+		if len(site.SnippetArgs[name]) > 0 {
+			domains = append(domains, site.PrimaryDomain())
+		}
+	}
+	return domains
+}
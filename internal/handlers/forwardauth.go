@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// forwardAuthFromForm reads the forward auth settings of the site form
+func forwardAuthFromForm(c *fiber.Ctx) *models.ForwardAuth {
+	provider := c.FormValue("forward_auth_provider")
+	if provider == "" {
+		return nil
+	}
+
+	auth := &models.ForwardAuth{
+		Provider: provider,
+		Bypass:   formLines(c.FormValue("forward_auth_bypass")),
+	}
+	if provider != models.ForwardAuthDefault {
+		auth.Upstream = strings.TrimSpace(c.FormValue("forward_auth_upstream"))
+		auth.URI = strings.TrimSpace(c.FormValue("forward_auth_uri"))
+		auth.CopyHeaders = strings.Fields(strings.ReplaceAll(c.FormValue("forward_auth_copy_headers"), ",", " "))
+	}
+	return auth
+}
+
+// checkForwardAuth validates the site's forward auth; the default provider
+// needs the forward_auth snippet
+func (h *Handler) checkForwardAuth(site *models.Site) error {
+	if site.ForwardAuth == nil {
+		return nil
+	}
+	if err := site.ForwardAuth.Validate(); err != nil {
+		return err
+	}
+	if site.ForwardAuth.Provider == models.ForwardAuthDefault {
+		cfg, err := h.snippetsService.GetConfig()
+		if err != nil {
+			return err
+		}
+		if !cfg.ForwardAuth.Enabled {
+			return fmt.Errorf("the global forward auth provider is disabled, enable it under Snippets")
+		}
+	}
+	return nil
+}
+
+// globalForwardAuth returns the global provider shown in the site form
+func (h *Handler) globalForwardAuth() models.ForwardAuthConfig {
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return models.ForwardAuthConfig{}
+	}
+	return cfg.ForwardAuth
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// GitAutoCommit returns a middleware that commits managed files after every successful change.
+// Form handlers answer failures with a redirect too, so an error flash also skips the commit.
+func (h *Handler) GitAutoCommit() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		if !h.gitService.Enabled() || c.Method() == fiber.MethodGet || c.Method() == fiber.MethodHead {
+			return err
+		}
+		if err != nil || c.Response().StatusCode() >= fiber.StatusBadRequest || c.Locals("flash_type") == "error" {
+			return err
+		}
+
+		if _, commitErr := h.gitService.Commit(c.Method()+" "+c.Path(), h.gitAuthor(c)); commitErr != nil {
+			log.Printf("Error committing configuration: %v", commitErr)
+		}
+
+		return nil
+	}
+}
+
+// SettingsGit renders the git sync settings tab
+func (h *Handler) SettingsGit(c *fiber.Ctx) error {
+	return h.renderSettingsTab(c, "git")
+}
+
+// GitCommitNow commits pending changes to the managed files
+func (h *Handler) GitCommitNow(c *fiber.Ctx) error {
+	committed, err := h.gitService.Commit(c.FormValue("message", "Manual commit"), h.gitAuthor(c))
+	switch {
+	case err != nil:
+		setFlash(c, "error", err.Error())
+	case committed:
+		setFlash(c, "success", "Changes committed")
+	default:
+		setFlash(c, "info", "Nothing to commit")
+	}
+
+	return h.redirectToGit(c)
+}
+
+// GitPush pushes the configuration repository to the remote
+func (h *Handler) GitPush(c *fiber.Ctx) error {
+	if err := h.gitService.Push(); err != nil {
+		setFlash(c, "error", err.Error())
+	} else {
+		setFlash(c, "success", "Pushed to "+h.config.GitRemote)
+	}
+
+	return h.redirectToGit(c)
+}
+
+// GitPull pulls the remote configuration, validates it and reloads Caddy
+func (h *Handler) GitPull(c *fiber.Ctx) error {
+	msg, err := h.gitService.Pull()
+	switch {
+	case errors.Is(err, services.ErrGitConflict):
+		setFlash(c, "warning", "Local and remote changes conflict, choose which side to keep")
+	case err != nil:
+		setFlash(c, "error", err.Error())
+	default:
+		setFlash(c, "success", msg)
+	}
+
+	return h.redirectToGit(c)
+}
+
+// GitResolve resolves a pull conflict by keeping the local or the remote configuration
+func (h *Handler) GitResolve(c *fiber.Ctx) error {
+	use := c.FormValue("use")
+	if err := h.gitService.ResolveConflict(use); err != nil {
+		setFlash(c, "error", err.Error())
+	} else {
+		setFlash(c, "success", "Conflict resolved using the "+use+" configuration")
+	}
+
+	return h.redirectToGit(c)
+}
+
+// gitAuthor returns the username recorded as commit author
+func (h *Handler) gitAuthor(c *fiber.Ctx) string {
+	if user, ok := h.getCurrentUser(c).(*models.User); ok && user.Username != "" {
+		return user.Username
+	}
+	return "cpm"
+}
+
+// redirectToGit redirects back to the git settings tab
+func (h *Handler) redirectToGit(c *fiber.Ctx) error {
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/settings/git")
+		return c.SendStatus(fiber.StatusOK)
+	}
+	return c.Redirect("/settings/git")
+}
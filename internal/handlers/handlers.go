@@ -1,23 +1,33 @@
 package handlers
 
 import (
+	"log"
+	"net/url"
 	"strings"
 
 	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
 	"github.com/TomasZmek/cpm/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
 // Handler contains all HTTP handlers
 type Handler struct {
-	config          *config.Config
-	caddyService    *services.CaddyService
-	certService     *services.CertificateService
-	snippetsService *services.SnippetsService
-	authService     *services.AuthService
-	backupService   *services.BackupService
-	dockerService   *services.DockerService
-	wildcardService *services.WildcardService
+	config             *config.Config
+	caddyService       *services.CaddyService
+	certService        *services.CertificateService
+	snippetsService    *services.SnippetsService
+	authService        *services.AuthService
+	backupService      *services.BackupService
+	dockerService      *services.DockerService
+	wildcardService    *services.WildcardService
+	stateService       *services.StateService
+	gitService         *services.GitService
+	mtlsService        *services.MTLSService
+	renewalService     *services.RenewalService
+	onDemandService    *services.OnDemandService
+	globalsService     *services.GlobalOptionsService
+	maintenanceService *services.MaintenanceService
 }
 
 // New creates a new Handler instance
@@ -30,17 +40,58 @@ func New(
 	backupService *services.BackupService,
 	dockerService *services.DockerService,
 	wildcardService *services.WildcardService,
+	stateService *services.StateService,
+	gitService *services.GitService,
+	mtlsService *services.MTLSService,
+	renewalService *services.RenewalService,
+	onDemandService *services.OnDemandService,
+	globalsService *services.GlobalOptionsService,
+	maintenanceService *services.MaintenanceService,
 ) *Handler {
-	return &Handler{
-		config:          cfg,
-		caddyService:    caddyService,
-		certService:     certService,
-		snippetsService: snippetsService,
-		authService:     authService,
-		backupService:   backupService,
-		dockerService:   dockerService,
-		wildcardService: wildcardService,
+	h := &Handler{
+		config:             cfg,
+		caddyService:       caddyService,
+		certService:        certService,
+		snippetsService:    snippetsService,
+		authService:        authService,
+		backupService:      backupService,
+		dockerService:      dockerService,
+		wildcardService:    wildcardService,
+		stateService:       stateService,
+		gitService:         gitService,
+		mtlsService:        mtlsService,
+		renewalService:     renewalService,
+		onDemandService:    onDemandService,
+		globalsService:     globalsService,
+		maintenanceService: maintenanceService,
 	}
+
+	// Revocation snippets of the client CAs are generated into snippets.caddy
+	snippetsService.SetMTLSService(h.mtlsService)
+
+	// Git sync: track the managed files in a repository inside the config directory
+	if cfg.GitSync {
+		if err := h.gitService.Init(); err != nil {
+			log.Printf("Error initializing git repository: %v", err)
+		}
+	}
+
+	return h
+}
+
+// Start runs the background pollers until stop is closed. The application
+// entry point calls it after New and closes stop on shutdown.
+func (h *Handler) Start(stop <-chan struct{}) {
+	// GitOps mode: keep the configuration in sync with the watched state file
+	if h.config.StateFile != "" {
+		go h.stateService.Watch(stop)
+	}
+
+	// Maintenance windows start and end on schedule
+	go h.maintenanceService.Watch(stop)
+
+	// Certificate renewals restore the previous certificate on shutdown
+	h.renewalService.SetStop(stop)
 }
 
 // ErrorHandler handles errors globally
@@ -114,6 +165,8 @@ func (h *Handler) baseData(c *fiber.Ctx, title string) fiber.Map {
 
 // Flash messages helper
 func setFlash(c *fiber.Ctx, msgType, message string) {
+	// Kept for this request too, so GitAutoCommit can tell failed changes apart
+	c.Locals("flash_type", msgType)
 	c.Cookie(&fiber.Cookie{
 		Name:  "flash_type",
 		Value: msgType,
@@ -135,6 +188,24 @@ func getFlash(c *fiber.Ctx) (string, string) {
 	return msgType, message
 }
 
+// localRedirect returns target if it is a path on this server, otherwise fallback.
+// Browsers treat //host and /\host as other sites.
+func localRedirect(target, fallback string) string {
+	if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") && !strings.HasPrefix(target, "/\\") {
+		return target
+	}
+	return fallback
+}
+
+// refererPath returns the path and query of the Referer if it points to this host
+func refererPath(c *fiber.Ctx) string {
+	u, err := url.Parse(c.Get("Referer"))
+	if err != nil || u.Host != c.Hostname() {
+		return ""
+	}
+	return u.RequestURI()
+}
+
 // contains checks if a slice contains an item
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -144,3 +215,33 @@ func contains(slice []string, item string) bool {
 	}
 	return false
 }
+
+// formValues returns all values posted for a form key (e.g. multiple checkboxes)
+func formValues(c *fiber.Ctx, key string) []string {
+	var values []string
+	if form, err := c.MultipartForm(); err == nil && form != nil {
+		values = append(values, form.Value[key]...)
+		return values
+	}
+	for _, v := range c.Request().PostArgs().PeekMulti(key) {
+		values = append(values, string(v))
+	}
+	return values
+}
+
+// acmeSettingsFromForm reads the issuer settings shared by the site and wildcard forms
+func acmeSettingsFromForm(c *fiber.Ctx, issuer string) models.ACMESettings {
+	if !models.IsIssuer(issuer) || issuer == models.IssuerInternal {
+		return models.ACMESettings{}
+	}
+
+	settings := models.ACMESettings{
+		Email:     strings.TrimSpace(c.FormValue("acme_email")),
+		EABKeyID:  strings.TrimSpace(c.FormValue("eab_key_id")),
+		EABMACKey: strings.TrimSpace(c.FormValue("eab_mac_key")),
+	}
+	if issuer == models.IssuerACME {
+		settings.Directory = strings.TrimSpace(c.FormValue("acme_directory"))
+	}
+	return settings
+}
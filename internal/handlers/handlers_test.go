@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestLocalRedirectFromReferer(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		return c.SendString(localRedirect(refererPath(c), "/sites"))
+	})
+
+	tests := []struct {
+		name    string
+		referer string
+		want    string
+	}{
+		{name: "same host", referer: "http://cpm.local/sites?tag=prod", want: "/sites?tag=prod"},
+		{name: "dashboard", referer: "http://cpm.local/", want: "/"},
+		{name: "other host", referer: "https://evil.example/phish", want: "/sites"},
+		{name: "protocol relative", referer: "//evil.example/phish", want: "/sites"},
+		{name: "backslash", referer: "/\\evil.example", want: "/sites"},
+		{name: "missing", want: "/sites"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "http://cpm.local/", nil)
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if got := string(body); got != tt.want {
+				t.Errorf("redirect = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGitAutoCommitSkipsFailedChanges(t *testing.T) {
+	dir := t.TempDir()
+	h := &Handler{gitService: services.NewGitService(&config.Config{ConfigDir: dir, GitSync: true, GitBranch: "main"}, nil)}
+	if err := h.gitService.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+
+	app := fiber.New()
+	app.Use(h.GitAutoCommit())
+	app.Post("/change", func(c *fiber.Ctx) error {
+		if err := os.WriteFile(filepath.Join(dir, "Caddyfile"), []byte(c.FormValue("content")), 0644); err != nil {
+			return err
+		}
+		if c.FormValue("fail") != "" {
+			setFlash(c, "error", "Validation failed")
+		} else {
+			setFlash(c, "success", "Saved")
+		}
+		return c.Redirect("/")
+	})
+
+	tests := []struct {
+		name  string
+		body  string
+		dirty bool
+	}{
+		{name: "failed change", body: "content=a&fail=1", dirty: true},
+		{name: "successful change", body: "content=b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/change", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != fiber.StatusFound {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, fiber.StatusFound)
+			}
+			if dirty := h.gitService.Status().Dirty; dirty != tt.dirty {
+				t.Errorf("working tree dirty = %v, want %v", dirty, tt.dirty)
+			}
+		})
+	}
+}
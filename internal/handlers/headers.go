@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// headerRulesFromForm reads the header editor of the site form. Every rule
+// row posts the same set of fields, so the values line up by index.
+func headerRulesFromForm(c *fiber.Ctx) []models.HeaderRule {
+	targets := formValues(c, "header_target")
+	operations := formValues(c, "header_operation")
+	names := formValues(c, "header_name")
+	values := formValues(c, "header_value")
+	replacements := formValues(c, "header_replace")
+
+	value := func(values []string, i int) string {
+		if i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var rules []models.HeaderRule
+	for i := range names {
+		name := value(names, i)
+		if name == "" {
+			continue
+		}
+		rule := models.HeaderRule{
+			Target:    value(targets, i),
+			Operation: value(operations, i),
+			Name:      name,
+		}
+		switch rule.Operation {
+		case models.HeaderReplace:
+			rule.Value, rule.Replace = value(values, i), value(replacements, i)
+		case models.HeaderSet, models.HeaderAdd:
+			rule.Value = value(values, i)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/TomasZmek/cpm/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// caddyfileImporter returns an importer bound to the handler's services
+func (h *Handler) caddyfileImporter() *services.CaddyfileImporter {
+	return services.NewCaddyfileImporter(h.config, h.caddyService, h.wildcardService, h.snippetsService, h.globalsService)
+}
+
+// CaddyfileImportPage renders the Caddyfile import upload form
+func (h *Handler) CaddyfileImportPage(c *fiber.Ctx) error {
+	flashType, flashMsg := getFlash(c)
+
+	data := h.baseData(c, "Import Caddyfile")
+	data["FlashType"] = flashType
+	data["FlashMessage"] = flashMsg
+	data["Active"] = "settings"
+
+	return c.Render("pages/caddyfile_import", data, "layouts/base")
+}
+
+// CaddyfileImportPreview analyses an uploaded Caddyfile and shows the review screen
+func (h *Handler) CaddyfileImportPreview(c *fiber.Ctx) error {
+	importer := h.caddyfileImporter()
+
+	var content string
+	switch {
+	case c.FormValue("source") == "config":
+		var err error
+		content, err = importer.ReadConfigCaddyfile()
+		if err != nil {
+			setFlash(c, "error", err.Error())
+			return c.Redirect("/settings/import/caddyfile")
+		}
+	default:
+		if file, err := c.FormFile("caddyfile"); err == nil {
+			f, err := file.Open()
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).SendString("Failed to open file")
+			}
+			defer f.Close()
+
+			raw, err := io.ReadAll(f)
+			if err != nil {
+				return c.Status(fiber.StatusInternalServerError).SendString("Failed to read file")
+			}
+			content = string(raw)
+		} else {
+			content = c.FormValue("content")
+		}
+	}
+
+	if content == "" {
+		setFlash(c, "error", "No Caddyfile content provided")
+		return c.Redirect("/settings/import/caddyfile")
+	}
+
+	plan, err := importer.Analyze(content)
+	if err != nil {
+		setFlash(c, "error", "Failed to parse Caddyfile: "+err.Error())
+		return c.Redirect("/settings/import/caddyfile")
+	}
+
+	data := h.baseData(c, "Import Caddyfile")
+	data["Plan"] = plan
+	data["Content"] = content
+	data["Active"] = "settings"
+
+	return c.Render("pages/caddyfile_import", data, "layouts/base")
+}
+
+// CaddyfileImportApply writes the entries selected on the review screen and
+// reloads Caddy; the import is rolled back if the reload fails
+func (h *Handler) CaddyfileImportApply(c *fiber.Ctx) error {
+	importer := h.caddyfileImporter()
+
+	plan, err := importer.Analyze(c.FormValue("content"))
+	if err != nil {
+		setFlash(c, "error", "Failed to parse Caddyfile: "+err.Error())
+		return c.Redirect("/settings/import/caddyfile")
+	}
+
+	selected := formValues(c, "entries")
+	globals := c.FormValue("globals") == "on"
+	if len(selected) == 0 && !globals {
+		setFlash(c, "warning", "No sites selected for import")
+		return c.Redirect("/settings/import/caddyfile")
+	}
+
+	result := importer.Apply(plan, selected, c.FormValue("overwrite") == "on", globals)
+
+	msg := fmt.Sprintf("Imported %d sites", result.Imported)
+	if result.Snippets > 0 {
+		msg += fmt.Sprintf(", %d snippets", result.Snippets)
+	}
+	if result.Globals {
+		msg += " and the global options"
+	}
+	if result.Skipped > 0 {
+		msg += fmt.Sprintf(", skipped %d existing", result.Skipped)
+	}
+
+	switch {
+	case result.ReloadError != "":
+		setFlash(c, "error", "Import rolled back, reload failed: "+result.ReloadError)
+	case len(result.Errors) > 0:
+		setFlash(c, "warning", fmt.Sprintf("%s, %d failed: %s", msg, len(result.Errors), result.Errors[0]))
+	case result.Imported > 0 || result.Globals:
+		setFlash(c, "success", msg)
+	default:
+		setFlash(c, "info", msg)
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/sites")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/sites")
+}
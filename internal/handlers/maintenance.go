@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/network"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maintenanceTimeLayout is the format of datetime-local inputs
+const maintenanceTimeLayout = "2006-01-02T15:04"
+
+// MaintenanceSettingsSave saves who may bypass the maintenance page
+func (h *Handler) MaintenanceSettingsSave(c *fiber.Ctx) error {
+	bypassIPs, err := network.Normalize(formLines(c.FormValue("bypass_ips")))
+	if err != nil {
+		setFlash(c, "error", err.Error())
+		return c.Redirect("/settings/caddy")
+	}
+
+	bypassCookie := c.FormValue("bypass_cookie") == "on"
+	retryAfter := formInt(c, "retry_after", models.DefaultRetryAfter)
+
+	if err := h.maintenanceService.SaveSettings(bypassIPs, bypassCookie, retryAfter); err != nil {
+		setFlash(c, "error", "Failed to save maintenance settings: "+err.Error())
+	} else if result := h.caddyService.ReloadWithValidation(); !result.Success {
+		setFlash(c, "warning", "Settings saved but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Maintenance settings saved")
+	}
+
+	return c.Redirect("/settings/caddy")
+}
+
+// MaintenanceWindowSave adds a maintenance window and applies it right away
+func (h *Handler) MaintenanceWindowSave(c *fiber.Ctx) error {
+	window, err := maintenanceWindowFromForm(c)
+	if err == nil {
+		err = h.maintenanceService.AddWindow(*window)
+	}
+
+	if err != nil {
+		setFlash(c, "error", "Failed to add maintenance window: "+err.Error())
+	} else if err := h.maintenanceService.Apply(); err != nil {
+		setFlash(c, "warning", "Maintenance window saved but not applied: "+err.Error())
+	} else {
+		setFlash(c, "success", "Maintenance window saved")
+	}
+
+	return c.Redirect(maintenanceRedirect(c))
+}
+
+// MaintenanceWindowDelete removes a maintenance window; sites it covered leave
+// maintenance mode right away
+func (h *Handler) MaintenanceWindowDelete(c *fiber.Ctx) error {
+	if err := h.maintenanceService.RemoveWindow(c.Params("id")); err != nil {
+		setFlash(c, "error", err.Error())
+	} else if err := h.maintenanceService.Apply(); err != nil {
+		setFlash(c, "warning", "Maintenance window removed but not applied: "+err.Error())
+	} else {
+		setFlash(c, "success", "Maintenance window removed")
+	}
+
+	redirect := maintenanceRedirect(c)
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", redirect)
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect(redirect)
+}
+
+// ErrorPageSave saves one of the error pages, including the maintenance page
+func (h *Handler) ErrorPageSave(c *fiber.Ctx) error {
+	code, err := c.ParamsInt("code")
+	if err != nil || (code != 403 && code != 404 && code != models.MaintenancePageCode) {
+		return c.Status(fiber.StatusBadRequest).SendString("unsupported error page")
+	}
+
+	if err := h.caddyService.SaveErrorPage(code, c.FormValue("content")); err != nil {
+		setFlash(c, "error", "Failed to save error page: "+err.Error())
+	} else {
+		setFlash(c, "success", fmt.Sprintf("Error page %d.html saved", code))
+	}
+
+	return c.Redirect("/settings/caddy")
+}
+
+// maintenanceWindowFromForm reads a maintenance window; the target is
+// site:<domain> or tag:<tag> and empty times mean now and open-ended
+func maintenanceWindowFromForm(c *fiber.Ctx) (*models.MaintenanceWindow, error) {
+	window := &models.MaintenanceWindow{}
+
+	kind, value, _ := strings.Cut(c.FormValue("target"), ":")
+	switch kind {
+	case "site":
+		window.Site = value
+	case "tag":
+		window.Tag = value
+	default:
+		return nil, fmt.Errorf("choose a site or a tag")
+	}
+
+	var err error
+	if window.Start, err = formTime(c, "start"); err != nil {
+		return nil, err
+	}
+	if window.End, err = formTime(c, "end"); err != nil {
+		return nil, err
+	}
+	return window, nil
+}
+
+// formTime parses a datetime-local input in the server's time zone
+func formTime(c *fiber.Ctx, key string) (time.Time, error) {
+	value := strings.TrimSpace(c.FormValue(key))
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation(maintenanceTimeLayout, value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s time %q", key, value)
+	}
+	return t, nil
+}
+
+// maintenanceRedirect returns the local page the window forms came from
+func maintenanceRedirect(c *fiber.Ctx) string {
+	return localRedirect(c.FormValue("redirect"), "/settings/caddy")
+}
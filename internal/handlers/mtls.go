@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientCACreate creates a CA that issues client certificates
+func (h *Handler) ClientCACreate(c *fiber.Ctx) error {
+	ca, err := h.mtlsService.CreateCA(strings.TrimSpace(c.FormValue("name")), formInt(c, "validity_days", 3650))
+	if err != nil {
+		setFlash(c, "error", "Failed to create CA: "+err.Error())
+		return c.Redirect("/certificates")
+	}
+
+	h.regenerateClientCASnippets()
+	setFlash(c, "success", "Client CA '"+ca.Name+"' created")
+	return c.Redirect("/certificates")
+}
+
+// ClientCAUpload stores an uploaded CA bundle to verify client certificates against
+func (h *Handler) ClientCAUpload(c *fiber.Ctx) error {
+	bundle, err := pemFormValue(c, "bundle")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if len(bundle) == 0 {
+		setFlash(c, "error", "CA certificate is required")
+		return c.Redirect("/certificates")
+	}
+
+	ca, err := h.mtlsService.ImportCA(strings.TrimSpace(c.FormValue("name")), bundle)
+	if err != nil {
+		setFlash(c, "error", "CA rejected: "+err.Error())
+		return c.Redirect("/certificates")
+	}
+
+	h.regenerateClientCASnippets()
+	setFlash(c, "success", "Client CA '"+ca.Name+"' uploaded")
+	return c.Redirect("/certificates")
+}
+
+// ClientCADelete removes a client CA that no site uses
+func (h *Handler) ClientCADelete(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	if domains := h.clientCAUsage()[id]; len(domains) > 0 {
+		setFlash(c, "error", "CA is used by "+strings.Join(domains, ", "))
+	} else if err := h.mtlsService.DeleteCA(id); err != nil {
+		setFlash(c, "error", err.Error())
+	} else {
+		h.regenerateClientCASnippets()
+		setFlash(c, "success", "Client CA deleted")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/certificates")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/certificates")
+}
+
+// ClientCertificateIssue issues a client certificate and downloads it as PKCS#12
+func (h *Handler) ClientCertificateIssue(c *fiber.Ctx) error {
+	commonName := strings.TrimSpace(c.FormValue("common_name"))
+
+	pfx, cert, err := h.mtlsService.IssueClientCertificate(
+		c.Params("id"),
+		commonName,
+		strings.TrimSpace(c.FormValue("email")),
+		formInt(c, "validity_days", 365),
+		c.FormValue("password"),
+	)
+	if err != nil {
+		setFlash(c, "error", "Failed to issue certificate: "+err.Error())
+		return c.Redirect("/certificates")
+	}
+
+	log.Printf("Issued client certificate %s (serial %s)", cert.Subject, cert.SerialNumber)
+
+	filename := regexp.MustCompile(`[^A-Za-z0-9._-]+`).ReplaceAllString(commonName, "_")
+	c.Set("Content-Type", "application/x-pkcs12")
+	c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".p12"))
+	return c.Send(pfx)
+}
+
+// ClientCertificateRevoke revokes an issued client certificate and reloads Caddy
+func (h *Handler) ClientCertificateRevoke(c *fiber.Ctx) error {
+	cert, err := h.mtlsService.RevokeClientCertificate(c.Params("id"), c.Params("serial"))
+	if err != nil {
+		setFlash(c, "error", err.Error())
+	} else if err := h.regenerateWildcardConfig(); err != nil {
+		setFlash(c, "warning", "Certificate revoked but failed to update Caddy config: "+err.Error())
+	} else if result := h.caddyService.ReloadWithValidation(); !result.Success {
+		setFlash(c, "warning", "Certificate revoked but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Certificate '"+cert.CommonName+"' revoked")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/certificates")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/certificates")
+}
+
+// regenerateClientCASnippets updates snippets.caddy after the CA store changed,
+// so sites can import the revocation snippet of every CA
+func (h *Handler) regenerateClientCASnippets() {
+	if err := h.regenerateWildcardConfig(); err != nil {
+		log.Printf("Error regenerating snippets after client CA change: %v", err)
+	}
+}
+
+// clientCAUsage maps client CA IDs to the sites trusting them
+func (h *Handler) clientCAUsage() map[string][]string {
+	usage := make(map[string][]string)
+	sites, _ := h.caddyService.GetAllSites()
+	for _, site := range sites {
+		if site.HasClientAuth() {
+			usage[site.ClientAuth.CAID] = append(usage[site.ClientAuth.CAID], site.PrimaryDomain())
+		}
+	}
+	return usage
+}
+
+// checkClientAuth validates the mutual TLS settings of a site
+func (h *Handler) checkClientAuth(site *models.Site) error {
+	if !site.HasClientAuth() {
+		return nil
+	}
+	if site.IsWildcard() {
+		return fmt.Errorf("client certificates can't be required per host on a wildcard certificate")
+	}
+	if err := site.ClientAuth.Validate(); err != nil {
+		return err
+	}
+	_, err := h.mtlsService.GetCA(site.ClientAuth.CAID)
+	return err
+}
+
+// clientAuthFromForm reads the mutual TLS settings of the site form
+func clientAuthFromForm(c *fiber.Ctx) *models.ClientAuth {
+	mode := c.FormValue("client_auth_mode")
+	if mode == "" {
+		return nil
+	}
+
+	return &models.ClientAuth{
+		Mode:            mode,
+		CAID:            c.FormValue("client_auth_ca"),
+		AllowedSubjects: formLines(c.FormValue("client_auth_subjects")),
+		AllowedSANs:     formLines(c.FormValue("client_auth_sans")),
+	}
+}
+
+// formLines splits a textarea into trimmed, non-empty lines
+func formLines(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
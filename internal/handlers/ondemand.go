@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// OnDemandAsk answers Caddy's on-demand TLS ask request. It must be reachable
+// from the Caddy container without authentication.
+func (h *Handler) OnDemandAsk(c *fiber.Ctx) error {
+	domain := strings.ToLower(strings.TrimSpace(c.Query("domain")))
+	if domain == "" {
+		return c.Status(fiber.StatusBadRequest).SendString("domain is required")
+	}
+
+	sites, _ := h.caddyService.GetAllSites()
+	if !h.onDemandService.Allows(domain, sites) {
+		log.Printf("On-demand TLS: denied certificate for %s", domain)
+		return c.Status(fiber.StatusForbidden).SendString("not allowed")
+	}
+
+	log.Printf("On-demand TLS: allowed certificate for %s", domain)
+	return c.SendString("ok")
+}
+
+// OnDemandSettingsSave saves the on-demand TLS settings and regenerates the Caddyfile
+func (h *Handler) OnDemandSettingsSave(c *fiber.Ctx) error {
+	cfg := &models.OnDemandTLSConfig{
+		Enabled:        c.FormValue("enabled") == "on",
+		AskURL:         strings.TrimSpace(c.FormValue("ask_url")),
+		AllowedDomains: formLines(strings.ToLower(c.FormValue("allowed_domains"))),
+	}
+
+	sites, err := h.caddyService.GetAllSites()
+	if err != nil {
+		setFlash(c, "error", "Failed to load sites: "+err.Error())
+	} else if err := h.onDemandService.CheckConfig(cfg, sites); err != nil {
+		setFlash(c, "error", "Failed to save on-demand TLS: "+err.Error())
+	} else if err := h.onDemandService.SaveConfig(cfg); err != nil {
+		setFlash(c, "error", "Failed to save on-demand TLS: "+err.Error())
+	} else if err := h.caddyService.RegenerateCaddyfile(); err != nil {
+		setFlash(c, "warning", fmt.Sprintf("Settings saved but failed to regenerate Caddyfile: %v", err))
+	} else if result := h.caddyService.ReloadWithValidation(); !result.Success {
+		setFlash(c, "warning", "Settings saved but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "On-demand TLS settings saved")
+	}
+
+	return c.Redirect("/settings/caddy")
+}
+
+// checkOnDemand validates the on-demand certificate setting of a site
+func (h *Handler) checkOnDemand(site *models.Site) error {
+	if site.OnDemand && (site.IsWildcard() || site.IsCustomCertificate()) {
+		return fmt.Errorf("on-demand certificates need an ACME issuer, not a wildcard or uploaded certificate")
+	}
+	return h.onDemandService.CheckSite(site)
+}
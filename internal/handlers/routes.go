@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/network"
+	"github.com/gofiber/fiber/v2"
+)
+
+// routesFromForm reads the path routes of the site form. Every route row
+// posts the same set of fields, so the values line up by index.
+func routesFromForm(c *fiber.Ctx) []models.Route {
+	paths := formValues(c, "route_path")
+	modes := formValues(c, "route_mode")
+	backends := formValues(c, "route_backends")
+	policies := formValues(c, "route_lb_policy")
+	headers := formValues(c, "route_headers")
+	snippets := formValues(c, "route_snippets")
+
+	value := func(values []string, i int) string {
+		if i < len(values) {
+			return strings.TrimSpace(values[i])
+		}
+		return ""
+	}
+
+	var routes []models.Route
+	for i := range paths {
+		path := value(paths, i)
+		if path == "" {
+			continue
+		}
+		routes = append(routes, models.Route{
+			Path:        path,
+			StripPrefix: value(modes, i) == "handle_path",
+			Backends:    normalizeBackends(strings.Fields(strings.ReplaceAll(value(backends, i), ",", " "))),
+			LBPolicy:    value(policies, i),
+			Headers:     formLines(value(headers, i)),
+			Snippets:    strings.Fields(strings.ReplaceAll(value(snippets, i), ",", " ")),
+		})
+	}
+	return routes
+}
+
+// normalizeBackends writes backend hosts in canonical form; invalid backends
+// are kept for the validation to report
+func normalizeBackends(backends []string) []string {
+	for i, backend := range backends {
+		if normalized, err := network.Backend(backend); err == nil {
+			backends[i] = normalized
+		}
+	}
+	return backends
+}
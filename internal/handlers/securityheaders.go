@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/gofiber/fiber/v2"
+)
+
+// securityHeadersForm is a form of the security headers card: the global
+// headers, an existing profile or a new one
+type securityHeadersForm struct {
+	Action  string
+	Prefix  string                         // Prefix of the element IDs
+	Profile *models.SecurityHeadersProfile // nil for the global headers
+	Headers models.SecurityHeadersConfig
+	IsNew   bool
+}
+
+// securityHeaderForms returns the global headers form, one form per profile
+// and a form for a new profile starting with the global headers
+func securityHeaderForms(cfg *models.SnippetConfig) []securityHeadersForm {
+	forms := []securityHeadersForm{{
+		Action:  "/snippets/security_headers",
+		Prefix:  "sh",
+		Headers: cfg.SecurityHeaders,
+	}}
+	for i := range cfg.SecurityHeaderProfiles {
+		profile := &cfg.SecurityHeaderProfiles[i]
+		forms = append(forms, securityHeadersForm{
+			Action:  "/security-profiles",
+			Prefix:  "sh_" + profile.ID,
+			Profile: profile,
+			Headers: profile.Headers,
+		})
+	}
+	return append(forms, securityHeadersForm{
+		Action:  "/security-profiles",
+		Prefix:  "sh_new",
+		Headers: cfg.SecurityHeaders,
+		IsNew:   true,
+	})
+}
+
+// securityHeadersFromForm reads the security headers, the CSP builder and the
+// Permissions-Policy toggles
+func securityHeadersFromForm(c *fiber.Ctx) (models.SecurityHeadersConfig, error) {
+	headers := models.SecurityHeadersConfig{
+		Enabled:                   c.FormValue("enabled") == "on",
+		HSTSMaxAge:                formInt(c, "hsts_max_age", 31536000),
+		HSTSIncludeSubdomains:     c.FormValue("hsts_include_subdomains") == "on",
+		HSTSPreload:               c.FormValue("hsts_preload") == "on",
+		XContentTypeOptions:       c.FormValue("x_content_type_options") == "on",
+		XFrameOptions:             c.FormValue("x_frame_options"),
+		ReferrerPolicy:            c.FormValue("referrer_policy"),
+		HideServer:                c.FormValue("hide_server") == "on",
+		CrossOriginOpenerPolicy:   c.FormValue("coop"),
+		CrossOriginEmbedderPolicy: c.FormValue("coep"),
+		CrossOriginResourcePolicy: c.FormValue("corp"),
+		CSP: models.ContentSecurityPolicy{
+			Enabled:                 c.FormValue("csp_enabled") == "on",
+			ReportOnly:              c.FormValue("csp_report_only") == "on",
+			UpgradeInsecureRequests: c.FormValue("csp_upgrade_insecure_requests") == "on",
+			ReportURI:               strings.TrimSpace(c.FormValue("csp_report_uri")),
+		},
+	}
+
+	for _, name := range models.CSPDirectives() {
+		sources, err := models.NormalizeCSPSources(strings.Fields(c.FormValue("csp_" + name)))
+		if err != nil {
+			return headers, err
+		}
+		if len(sources) > 0 {
+			headers.CSP.Directives = append(headers.CSP.Directives, models.CSPDirective{Name: name, Sources: sources})
+		}
+	}
+
+	for _, feature := range models.PermissionsPolicyFeatures() {
+		if allow := c.FormValue("pp_" + feature); allow != "" {
+			if headers.PermissionsPolicy == nil {
+				headers.PermissionsPolicy = make(map[string]string)
+			}
+			headers.PermissionsPolicy[feature] = allow
+		}
+	}
+
+	return headers, headers.Validate()
+}
+
+// SecurityProfileSave creates a security headers profile or updates the
+// headers of an existing one
+func (h *Handler) SecurityProfileSave(c *fiber.Ctx) error {
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	headers, err := securityHeadersFromForm(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	headers.Enabled = true // Profiles are generated whenever they exist
+	profile := models.SecurityHeadersProfile{Name: strings.TrimSpace(c.FormValue("name")), Headers: headers}
+
+	if id := c.FormValue("id"); id != "" {
+		// The ID is used by the sites' imports, so existing profiles keep their name
+		existing := cfg.SecurityHeadersProfile(id)
+		if existing == nil {
+			return c.Status(fiber.StatusNotFound).SendString("Profile not found")
+		}
+		profile.ID, profile.Name = existing.ID, existing.Name
+		*existing = profile
+	} else {
+		profile.ID = models.AccessListID(profile.Name)
+		if err := profile.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if cfg.SecurityHeadersProfile(profile.ID) != nil {
+			return c.Status(fiber.StatusBadRequest).SendString("A profile named " + profile.Name + " already exists")
+		}
+		cfg.SecurityHeaderProfiles = append(cfg.SecurityHeaderProfiles, profile)
+	}
+
+	if err := h.snippetsService.SaveConfig(cfg); err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	result := h.caddyService.ReloadWithValidation()
+	if !result.Success {
+		setFlash(c, "warning", "Profile saved but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Security headers profile '"+profile.Name+"' saved")
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/snippets")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/snippets")
+}
+
+// SecurityProfileDelete removes a security headers profile that no site uses
+func (h *Handler) SecurityProfileDelete(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	if domains := h.snippetUsage()[models.SecurityHeadersProfileSnippet(id)]; len(domains) > 0 {
+		setFlash(c, "error", "Profile is used by "+strings.Join(domains, ", "))
+	} else if cfg.SecurityHeadersProfile(id) == nil {
+		setFlash(c, "error", "Profile not found")
+	} else {
+		var profiles []models.SecurityHeadersProfile
+		for _, profile := range cfg.SecurityHeaderProfiles {
+			if profile.ID != id {
+				profiles = append(profiles, profile)
+			}
+		}
+		cfg.SecurityHeaderProfiles = profiles
+
+		if err := h.snippetsService.SaveConfig(cfg); err != nil {
+			setFlash(c, "error", err.Error())
+		} else {
+			setFlash(c, "success", "Profile deleted")
+		}
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/snippets")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/snippets")
+}
@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"strings"
+	"time"
 
 	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/services"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -61,6 +66,17 @@ func (h *Handler) renderSettingsTab(c *fiber.Ctx, tab string) error {
 		data["SitesCount"] = len(sites)
 
 	case "caddy":
+		globals, err := h.globalsService.GetConfig()
+		if err != nil {
+			data["FlashType"], data["FlashMessage"] = "error", err.Error()
+			globals = &models.GlobalOptions{}
+		}
+		data["Globals"] = globals
+		data["ServerProtocols"] = models.ServerProtocols()
+		data["LogOutputs"] = models.LogOutputs()
+		data["LogFormats"] = models.LogFormats()
+		data["LogLevels"] = models.LogLevels()
+
 		fallback, _ := h.caddyService.GetFallback()
 		data["Fallback"] = fallback
 		data["FallbackExists"] = h.caddyService.FallbackExists()
@@ -70,6 +86,33 @@ func (h *Handler) renderSettingsTab(c *fiber.Ctx, tab string) error {
 		page404, _ := h.caddyService.GetErrorPage(404)
 		data["ErrorPage403"] = page403
 		data["ErrorPage404"] = page404
+		page503, _ := h.caddyService.GetErrorPage(models.MaintenancePageCode)
+		data["ErrorPage503"] = page503
+
+		// On-demand TLS
+		onDemand, err := h.onDemandService.GetConfig()
+		if err != nil {
+			data["FlashType"], data["FlashMessage"] = "error", err.Error()
+			onDemand = &models.OnDemandTLSConfig{}
+		}
+		data["OnDemand"] = onDemand
+		certs, _ := h.certService.GetAllCertificates()
+		sites, _ := h.caddyService.GetAllSites()
+		data["OnDemandCertificates"] = h.onDemandService.Certificates(certs, sites)
+
+		// Maintenance mode
+		maintenance, err := h.maintenanceService.GetConfig()
+		if err != nil {
+			data["FlashType"], data["FlashMessage"] = "error", err.Error()
+			maintenance = &models.MaintenanceConfig{}
+		}
+		data["Maintenance"] = maintenance
+		data["MaintenanceSites"] = sites
+		data["Tags"], _ = h.caddyService.GetAllTags()
+		data["Now"] = time.Now()
+
+	case "git":
+		data["Git"] = h.gitService.Status()
 
 	case "users":
 		data["Users"] = h.authService.GetUsers()
@@ -80,6 +123,42 @@ func (h *Handler) renderSettingsTab(c *fiber.Ctx, tab string) error {
 	return c.Render("pages/settings", data, "layouts/base")
 }
 
+// GlobalOptionsSave saves the Caddyfile global options, regenerates the Caddyfile and reloads Caddy
+func (h *Handler) GlobalOptionsSave(c *fiber.Ctx) error {
+	opts := &models.GlobalOptions{
+		Email:          strings.TrimSpace(c.FormValue("email")),
+		DefaultSNI:     strings.TrimSpace(c.FormValue("default_sni")),
+		Admin:          strings.TrimSpace(c.FormValue("admin")),
+		GracePeriod:    formInt(c, "grace_period", 0),
+		TrustedProxies: strings.Fields(strings.ReplaceAll(c.FormValue("trusted_proxies"), ",", " ")),
+		Protocols:      formValues(c, "protocols"),
+		Log: models.GlobalLog{
+			Enabled: c.FormValue("log_enabled") == "on",
+			Output:  c.FormValue("log_output"),
+			File:    strings.TrimSpace(c.FormValue("log_file")),
+			Format:  c.FormValue("log_format"),
+			Level:   c.FormValue("log_level"),
+		},
+	}
+
+	// Invalid entries are reported by SaveConfig
+	if proxies, err := models.NormalizeTrustedProxies(opts.TrustedProxies); err == nil {
+		opts.TrustedProxies = proxies
+	}
+
+	if err := h.globalsService.SaveConfig(opts); err != nil {
+		setFlash(c, "error", "Failed to save global options: "+err.Error())
+	} else if err := h.caddyService.RegenerateCaddyfile(); err != nil {
+		setFlash(c, "warning", fmt.Sprintf("Global options saved but failed to regenerate Caddyfile: %v", err))
+	} else if result := h.caddyService.ReloadWithValidation(); !result.Success {
+		setFlash(c, "warning", "Global options saved but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Global options saved")
+	}
+
+	return c.Redirect("/settings/caddy")
+}
+
 // BackupCreate creates a backup
 func (h *Handler) BackupCreate(c *fiber.Ctx) error {
 	data, filename, err := h.backupService.CreateBackup()
@@ -132,54 +211,101 @@ func (h *Handler) BackupRestore(c *fiber.Ctx) error {
 	return c.Redirect("/settings?tab=backup")
 }
 
-// ImportRules imports rules from JSON
+// ImportRules imports rules from a JSON export. A dry run only shows what would happen.
 func (h *Handler) ImportRules(c *fiber.Ctx) error {
-	file, err := c.FormFile("rules")
-	if err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString("No file uploaded")
-	}
+	var data []byte
+	if file, err := c.FormFile("import_file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open file")
+		}
+		defer f.Close()
 
-	f, err := file.Open()
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to open file")
+		data, err = io.ReadAll(f)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to read file")
+		}
+	} else {
+		// Confirming a dry run posts the file content back
+		data = []byte(c.FormValue("content"))
 	}
-	defer f.Close()
 
-	data := make([]byte, file.Size)
-	if _, err := f.Read(data); err != nil {
-		return c.Status(fiber.StatusInternalServerError).SendString("Failed to read file")
+	if len(bytes.TrimSpace(data)) == 0 {
+		setFlash(c, "error", "No file uploaded")
+		return c.Redirect("/settings?tab=backup")
 	}
 
-	skipExisting := c.FormValue("skip_existing") == "on"
+	opts := services.RuleImportOptions{
+		Mode:            c.FormValue("mode", services.ImportModeSkip),
+		DryRun:          c.FormValue("dry_run") == "on",
+		ImportWildcards: c.FormValue("import_wildcards") == "on",
+		ImportSnippets:  c.FormValue("import_snippets") == "on",
+	}
 
-	imported, skipped, err := h.backupService.ImportRules(data, h.caddyService, skipExisting)
+	result, err := h.backupService.ImportRules(data, h.caddyService, h.wildcardService, h.snippetsService, h.certService, opts)
 	if err != nil {
-		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		setFlash(c, "error", "Import failed: "+err.Error())
+		return c.Redirect("/settings?tab=backup")
 	}
 
-	// Reload Caddy
-	if imported > 0 {
-		h.caddyService.ReloadWithValidation()
-	}
+	var flashType, flashMsg string
+	if !result.DryRun {
+		msg := formatImportResult(result.Imported, result.Skipped)
+		if result.Failed > 0 {
+			msg += fmt.Sprintf(", %d failed", result.Failed)
+		}
+		flashType, flashMsg = "success", msg
+		if result.Failed > 0 {
+			flashType = "warning"
+		}
 
-	setFlash(c, "success", formatImportResult(imported, skipped))
+		if result.WildcardsImported > 0 || result.SnippetsImported {
+			if err := h.regenerateWildcardConfig(); err != nil {
+				flashType, flashMsg = "warning", msg+" but failed to update Caddy config: "+err.Error()
+			}
+		}
 
-	if c.Get("HX-Request") == "true" {
-		c.Set("HX-Redirect", "/settings?tab=backup")
-		return c.SendStatus(fiber.StatusOK)
+		if result.Imported > 0 || result.WildcardsImported > 0 || result.SnippetsImported {
+			if reload := h.caddyService.ReloadWithValidation(); !reload.Success {
+				flashType, flashMsg = "warning", msg+" but reload failed: "+reload.Error
+			}
+		} else if result.Failed == 0 {
+			flashType = "info"
+		}
+
+		// Without per-rule problems there's nothing to review
+		if result.Failed == 0 {
+			setFlash(c, flashType, flashMsg)
+			if c.Get("HX-Request") == "true" {
+				c.Set("HX-Redirect", "/settings?tab=backup")
+				return c.SendStatus(fiber.StatusOK)
+			}
+			return c.Redirect("/settings?tab=backup")
+		}
 	}
 
-	return c.Redirect("/settings?tab=backup")
+	viewData := h.baseData(c, "Import Rules")
+	viewData["FlashType"] = flashType
+	viewData["FlashMessage"] = flashMsg
+	viewData["Active"] = "settings"
+	viewData["Result"] = result
+	viewData["Content"] = string(data)
+	viewData["Options"] = opts
+
+	return c.Render("pages/rules_import", viewData, "layouts/base")
 }
 
-// ExportRules exports rules as JSON
+// ExportRules exports rules, wildcard domains and snippet config as JSON
 func (h *Handler) ExportRules(c *fiber.Ctx) error {
 	sites, err := h.caddyService.GetAllSites()
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
 
-	data, err := h.backupService.ExportRules(sites)
+	wildcards, _ := h.wildcardService.GetDomains()
+	snippetCfg, _ := h.snippetsService.GetConfig()
+
+	data, err := h.backupService.ExportRules(sites, wildcards, snippetCfg)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"strings"
+	"time"
 
 	"github.com/TomasZmek/cpm/internal/models"
 	"github.com/TomasZmek/cpm/internal/services"
@@ -61,7 +62,23 @@ func (h *Handler) SiteNew(c *fiber.Ctx) error {
 	data["Site"] = &models.Site{TLSMode: "auto"}
 	data["DefaultIP"] = h.config.DefaultIP
 	data["AvailableSnippets"] = availableSnippets
+	data["SnippetParams"] = h.snippetParams()
 	data["WildcardDomains"] = wildcardDomains
+	data["Issuers"] = models.Issuers()
+	data["CustomCertificates"], _ = h.certService.GetCustomCertificates()
+	data["ClientCAs"], _ = h.mtlsService.GetCAs()
+	data["ClientAuthModes"] = models.ClientAuthModes()
+	data["OnDemand"], _ = h.onDemandService.GetConfig()
+	data["LBPolicies"] = models.LBPolicies()
+	data["SiteTypes"] = models.SiteTypes()
+	data["RedirectCodes"] = models.RedirectCodes()
+	data["ForwardAuthProviders"] = models.ForwardAuthProviders()
+	data["ForwardAuthPresets"] = models.ForwardAuthPresets()
+	data["GlobalForwardAuth"] = h.globalForwardAuth()
+	data["CORSMethods"] = models.DefaultCORSMethods()
+	data["HeaderTargets"] = models.HeaderTargets()
+	data["HeaderOperations"] = models.HeaderOperations()
+	data["AccessLists"] = h.accessLists()
 	data["Templates"] = templates
 	data["Categories"] = categories
 	data["Active"] = "sites"
@@ -85,6 +102,15 @@ func (h *Handler) SiteCreate(c *fiber.Ctx) error {
 	if site.TLSMode == "" {
 		site.TLSMode = "auto"
 	}
+	site.ACMESettings = acmeSettingsFromForm(c, site.TLSMode)
+	site.ClientAuth = clientAuthFromForm(c)
+	site.OnDemand = c.FormValue("on_demand") == "on"
+	site.Routes = routesFromForm(c)
+	site.ForwardAuth = forwardAuthFromForm(c)
+	site.AccessLists = formValues(c, "access_lists")
+	site.CORS = corsFromForm(c)
+	site.HeaderRules = headerRulesFromForm(c)
+	siteTypeFromForm(c, site)
 
 	// Parse snippets
 	if snippets := c.FormValue("snippets"); snippets != "" {
@@ -103,14 +129,60 @@ func (h *Handler) SiteCreate(c *fiber.Ctx) error {
 	if len(site.Domains) == 0 {
 		return c.Status(fiber.StatusBadRequest).SendString("At least one domain is required")
 	}
-	if site.TargetPort == "" {
+	if site.IsProxy() && site.TargetPort == "" {
 		return c.Status(fiber.StatusBadRequest).SendString("Port is required")
 	}
+	if err := checkTarget(site); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := site.ValidateType(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if site.HasIssuer() {
+		if err := site.ACMESettings.Validate(site.TLSMode); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+	}
+	if site.IsCustomCertificate() {
+		if err := h.certService.CheckCustomCertificate(site.CustomCertificateID(), site.Domains); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+	}
+	if err := h.checkClientAuth(site); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := h.checkOnDemand(site); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := site.ValidateRoutes(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := h.checkForwardAuth(site); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := h.checkAccessLists(site); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := checkCORS(site); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	if err := site.ValidateHeaderRules(); err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	args, err := h.snippetArgsFromForm(c, site.Snippets)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+	}
+	site.SnippetArgs = args
+
+	// A window for one of the site's tags may already be active
+	site.Maintenance = h.maintenanceService.InMaintenance(site)
 
 	// Create site
 	if err := h.caddyService.CreateSite(site); err != nil {
 		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 	}
+	h.ensureStaticRoot(site)
 
 	// Reload Caddy
 	result := h.caddyService.ReloadWithValidation()
@@ -142,6 +214,10 @@ func (h *Handler) SiteDetail(c *fiber.Ctx) error {
 	data := h.baseData(c, site.PrimaryDomain())
 	data["Site"] = site
 	data["Active"] = "sites"
+	if maintenance, err := h.maintenanceService.GetConfig(); err == nil {
+		data["MaintenanceWindows"] = maintenance.SiteWindows(site)
+	}
+	data["Now"] = time.Now()
 
 	return c.Render("pages/site_detail", data, "layouts/base")
 }
@@ -168,7 +244,23 @@ func (h *Handler) SiteEdit(c *fiber.Ctx) error {
 	data["Site"] = site
 	data["DefaultIP"] = h.config.DefaultIP
 	data["AvailableSnippets"] = availableSnippets
+	data["SnippetParams"] = h.snippetParams()
 	data["WildcardDomains"] = wildcardDomains
+	data["Issuers"] = models.Issuers()
+	data["CustomCertificates"], _ = h.certService.GetCustomCertificates()
+	data["ClientCAs"], _ = h.mtlsService.GetCAs()
+	data["ClientAuthModes"] = models.ClientAuthModes()
+	data["OnDemand"], _ = h.onDemandService.GetConfig()
+	data["LBPolicies"] = models.LBPolicies()
+	data["SiteTypes"] = models.SiteTypes()
+	data["RedirectCodes"] = models.RedirectCodes()
+	data["ForwardAuthProviders"] = models.ForwardAuthProviders()
+	data["ForwardAuthPresets"] = models.ForwardAuthPresets()
+	data["GlobalForwardAuth"] = h.globalForwardAuth()
+	data["CORSMethods"] = models.DefaultCORSMethods()
+	data["HeaderTargets"] = models.HeaderTargets()
+	data["HeaderOperations"] = models.HeaderOperations()
+	data["AccessLists"] = h.accessLists()
 	data["Active"] = "sites"
 
 	return c.Render("pages/site_form", data, "layouts/base")
@@ -201,12 +293,63 @@ func (h *Handler) SiteUpdate(c *fiber.Ctx) error {
 		if site.TLSMode == "" {
 			site.TLSMode = "auto"
 		}
+		site.ACMESettings = acmeSettingsFromForm(c, site.TLSMode)
+		site.ClientAuth = clientAuthFromForm(c)
+		site.OnDemand = c.FormValue("on_demand") == "on"
+		site.Routes = routesFromForm(c)
+		site.ForwardAuth = forwardAuthFromForm(c)
+		site.AccessLists = formValues(c, "access_lists")
+		site.CORS = corsFromForm(c)
+		site.HeaderRules = headerRulesFromForm(c)
+		siteTypeFromForm(c, site)
+		if err := checkTarget(site); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := site.ValidateType(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if site.HasIssuer() {
+			if err := site.ACMESettings.Validate(site.TLSMode); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+			}
+		}
+		if site.IsCustomCertificate() {
+			if err := h.certService.CheckCustomCertificate(site.CustomCertificateID(), site.Domains); err != nil {
+				return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+			}
+		}
+		if err := h.checkClientAuth(site); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := h.checkOnDemand(site); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := site.ValidateRoutes(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := h.checkForwardAuth(site); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := h.checkAccessLists(site); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := checkCORS(site); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		if err := site.ValidateHeaderRules(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
 
 		// Parse snippets
 		site.Snippets = []string{}
 		if snippets := c.FormValue("snippets"); snippets != "" {
 			site.Snippets = strings.Split(snippets, ",")
 		}
+		args, err := h.snippetArgsFromForm(c, site.Snippets)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		site.SnippetArgs = args
 		
 		// Derive IsInternal from snippets
 		site.IsInternal = contains(site.Snippets, "internal_only")
@@ -217,9 +360,13 @@ func (h *Handler) SiteUpdate(c *fiber.Ctx) error {
 			site.Tags = []string{}
 		}
 
+		// Tag changes can move the site into or out of a maintenance window
+		site.Maintenance = h.maintenanceService.InMaintenance(site)
+
 		if err := h.caddyService.UpdateSite(site); err != nil {
 			return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
 		}
+		h.ensureStaticRoot(site)
 	}
 
 	// Reload Caddy
@@ -267,6 +414,56 @@ func (h *Handler) SiteDelete(c *fiber.Ctx) error {
 	return c.Redirect("/sites")
 }
 
+// SiteToggle enables a disabled site or disables an enabled one
+func (h *Handler) SiteToggle(c *fiber.Ctx) error {
+	filename := c.Params("id")
+
+	site, err := h.caddyService.GetSite(filename)
+	if err != nil {
+		return fiber.NewError(fiber.StatusNotFound, "Site not found")
+	}
+
+	if site.Disabled {
+		enabled := *site
+		enabled.Disabled = false
+		if err := h.onDemandService.CheckSite(&enabled); err != nil {
+			setFlash(c, "error", err.Error())
+			return h.siteToggleRedirect(c)
+		}
+	}
+
+	site, err = h.caddyService.SetSiteEnabled(filename, site.Disabled)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	state := "enabled"
+	if site.Disabled {
+		state = "disabled"
+	}
+
+	// Reload Caddy
+	result := h.caddyService.ReloadWithValidation()
+	if !result.Success {
+		setFlash(c, "warning", "Rule "+state+" but reload failed: "+result.Error)
+	} else {
+		setFlash(c, "success", "Rule '"+site.PrimaryDomain()+"' "+state)
+	}
+
+	return h.siteToggleRedirect(c)
+}
+
+// siteToggleRedirect returns to the list the rule was toggled from, if it is on this server
+func (h *Handler) siteToggleRedirect(c *fiber.Ctx) error {
+	redirect := localRedirect(refererPath(c), "/sites")
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", redirect)
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect(redirect)
+}
+
 // SiteDuplicate duplicates a site
 func (h *Handler) SiteDuplicate(c *fiber.Ctx) error {
 	filename := c.Params("id")
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/network"
+	"github.com/gofiber/fiber/v2"
+)
+
+// siteTypeFromForm reads the site type and its settings from the site form.
+// Proxy sites keep an empty type, so their files don't change.
+func siteTypeFromForm(c *fiber.Ctx, site *models.Site) {
+	site.Type = c.FormValue("site_type")
+	site.Redirect, site.Static, site.Respond = nil, nil, nil
+
+	switch site.Type {
+	case models.SiteTypeProxy:
+		site.Type = ""
+	case models.SiteTypeRedirect:
+		site.Redirect = &models.RedirectSettings{
+			To:           strings.TrimSpace(c.FormValue("redirect_to")),
+			Code:         c.FormValue("redirect_code"),
+			PreservePath: c.FormValue("redirect_preserve_path") == "on",
+			Canonical:    c.FormValue("redirect_canonical"),
+		}
+		if site.Redirect.Canonical != "" {
+			site.Redirect.To = ""
+		}
+	case models.SiteTypeStatic:
+		site.Static = &models.StaticSettings{
+			Root:   strings.TrimSpace(c.FormValue("static_root")),
+			Browse: c.FormValue("static_browse") == "on",
+			Index:  strings.Fields(strings.ReplaceAll(c.FormValue("static_index"), ",", " ")),
+		}
+	case models.SiteTypeRespond:
+		site.Respond = &models.RespondSettings{
+			Status:      formInt(c, "respond_status", 0),
+			Body:        strings.ReplaceAll(c.FormValue("respond_body"), "\r\n", "\n"),
+			ContentType: strings.TrimSpace(c.FormValue("respond_content_type")),
+		}
+	}
+}
+
+// ensureStaticRoot creates the directory of a static site in the Caddy data volume
+func (h *Handler) ensureStaticRoot(site *models.Site) {
+	if site.SiteType() != models.SiteTypeStatic || site.Static == nil {
+		return
+	}
+	rel, ok := strings.CutPrefix(site.Static.Root, "/data/")
+	if !ok {
+		return
+	}
+	if err := os.MkdirAll(filepath.Join(h.config.DataDir, filepath.FromSlash(rel)), 0755); err != nil {
+		log.Printf("Error creating static root %s: %v", site.Static.Root, err)
+	}
+}
+
+// checkTarget validates the backend of proxy sites and writes IP addresses in
+// canonical form
+func checkTarget(site *models.Site) error {
+	if !site.IsProxy() {
+		return nil
+	}
+	host, err := network.Host(site.TargetIP)
+	if err != nil {
+		return fmt.Errorf("invalid target: %w", err)
+	}
+	site.TargetIP = host
+	return network.Port(site.TargetPort)
+}
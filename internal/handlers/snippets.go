@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/network"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -22,6 +23,11 @@ func (h *Handler) SnippetsList(c *fiber.Ctx) error {
 	data := h.baseData(c, "Snippets Manager")
 	data["Config"] = cfg
 	data["KnownSnippets"] = knownSnippets
+	data["SecurityHeaderForms"] = securityHeaderForms(cfg)
+	data["CSPDirectives"] = models.CSPDirectives()
+	data["PermissionsPolicyFeatures"] = models.PermissionsPolicyFeatures()
+	data["ForwardAuthProviders"] = models.ForwardAuthProviders()[1:] // The global provider can't be "default"
+	data["ForwardAuthPresets"] = models.ForwardAuthPresets()
 	data["FlashType"] = flashType
 	data["FlashMessage"] = flashMsg
 	data["Active"] = "snippets"
@@ -46,17 +52,18 @@ func (h *Handler) SnippetUpdate(c *fiber.Ctx) error {
 
 	case "internal_only":
 		cfg.InternalOnly.Enabled = c.FormValue("enabled") == "on"
-		networks := c.FormValue("allowed_networks")
-		cfg.InternalOnly.AllowedNetworks = parseNetworks(networks)
+		networks, err := network.Normalize(formLines(c.FormValue("allowed_networks")))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		cfg.InternalOnly.AllowedNetworks = networks
 
 	case "security_headers":
-		cfg.SecurityHeaders.Enabled = c.FormValue("enabled") == "on"
-		cfg.SecurityHeaders.HSTSMaxAge = formInt(c, "hsts_max_age", 31536000)
-		cfg.SecurityHeaders.HSTSIncludeSubdomains = c.FormValue("hsts_include_subdomains") == "on"
-		cfg.SecurityHeaders.XContentTypeOptions = c.FormValue("x_content_type_options") == "on"
-		cfg.SecurityHeaders.XFrameOptions = c.FormValue("x_frame_options")
-		cfg.SecurityHeaders.ReferrerPolicy = c.FormValue("referrer_policy")
-		cfg.SecurityHeaders.HideServer = c.FormValue("hide_server") == "on"
+		headers, err := securityHeadersFromForm(c)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+		cfg.SecurityHeaders = headers
 
 	case "compression":
 		cfg.Compression.Enabled = c.FormValue("enabled") == "on"
@@ -72,6 +79,18 @@ func (h *Handler) SnippetUpdate(c *fiber.Ctx) error {
 		cfg.BasicAuth.Enabled = c.FormValue("enabled") == "on"
 		// User management is done separately
 
+	case "forward_auth":
+		cfg.ForwardAuth = models.ForwardAuthConfig{
+			Enabled:     c.FormValue("enabled") == "on",
+			Provider:    c.FormValue("provider"),
+			Upstream:    strings.TrimSpace(c.FormValue("upstream")),
+			URI:         strings.TrimSpace(c.FormValue("uri")),
+			CopyHeaders: strings.Fields(strings.ReplaceAll(c.FormValue("copy_headers"), ",", " ")),
+		}
+		if err := cfg.ForwardAuth.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).SendString(err.Error())
+		}
+
 	default:
 		return c.Status(fiber.StatusBadRequest).SendString("Unknown snippet: " + snippetName)
 	}
@@ -110,16 +129,47 @@ func (h *Handler) HTMXSnippetForm(c *fiber.Ctx) error {
 	})
 }
 
-// parseNetworks parses networks from form input
-func parseNetworks(input string) []string {
-	var networks []string
-	for _, line := range strings.Split(input, "\n") {
-		line = strings.TrimSpace(line)
-		if line != "" {
-			networks = append(networks, line)
+// snippetArgsFromForm reads the site form's arguments of the selected
+// parameterised snippets. Empty fields take the default, as a site passes
+// all arguments or none.
+func (h *Handler) snippetArgsFromForm(c *fiber.Ctx, snippets []string) (map[string][]string, error) {
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var args map[string][]string
+	for name, params := range cfg.SnippetParams() {
+		values := formValues(c, "snippet_arg_"+name)
+		if !contains(snippets, name) || strings.TrimSpace(strings.Join(values, "")) == "" {
+			continue
 		}
+
+		siteArgs := make([]string, len(params))
+		for i, param := range params {
+			siteArgs[i] = param.Default
+			if i < len(values) && strings.TrimSpace(values[i]) != "" {
+				siteArgs[i] = strings.TrimSpace(values[i])
+			}
+		}
+		if err := cfg.CheckSnippetArgs(name, siteArgs); err != nil {
+			return nil, err
+		}
+		if args == nil {
+			args = make(map[string][]string)
+		}
+		args[name] = siteArgs
+	}
+	return args, nil
+}
+
+// snippetParams returns the parameters offered in the site form
+func (h *Handler) snippetParams() map[string][]models.SnippetParam {
+	cfg, err := h.snippetsService.GetConfig()
+	if err != nil {
+		return nil
 	}
-	return networks
+	return cfg.SnippetParams()
 }
 
 // formInt parses form value as int with default
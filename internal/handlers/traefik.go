@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/TomasZmek/cpm/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// traefikConverter returns a converter bound to the handler's services
+func (h *Handler) traefikConverter() *services.TraefikConverter {
+	return services.NewTraefikConverter(h.config, h.snippetsService, h.wildcardService)
+}
+
+// traefikExportOptions reads export options from the query string
+func traefikExportOptions(c *fiber.Ctx) services.TraefikExportOptions {
+	return services.TraefikExportOptions{
+		EntryPoint:   c.Query("entrypoint"),
+		CertResolver: c.Query("certresolver"),
+	}
+}
+
+// TraefikPage renders the Traefik import/export page
+func (h *Handler) TraefikPage(c *fiber.Ctx) error {
+	flashType, flashMsg := getFlash(c)
+
+	data := h.baseData(c, "Traefik")
+	data["FlashType"] = flashType
+	data["FlashMessage"] = flashMsg
+	data["Active"] = "settings"
+	data["Format"] = services.TraefikFormatYAML
+	data["TargetHost"] = h.config.DefaultIP
+
+	return c.Render("pages/traefik", data, "layouts/base")
+}
+
+// TraefikExport converts all sites to Traefik configuration and shows or downloads it
+func (h *Handler) TraefikExport(c *fiber.Ctx) error {
+	sites, err := h.caddyService.GetAllSites()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+	}
+
+	format := c.Query("format", services.TraefikFormatYAML)
+	opts := traefikExportOptions(c)
+
+	output, report, err := h.traefikConverter().Export(sites, format, opts)
+	if err != nil {
+		setFlash(c, "error", err.Error())
+		return c.Redirect("/settings/traefik")
+	}
+
+	if c.Query("download") == "1" {
+		filename := "cpm_traefik.yml"
+		switch format {
+		case services.TraefikFormatTOML:
+			filename = "cpm_traefik.toml"
+		case services.TraefikFormatLabels:
+			filename = "cpm_traefik_labels.yml"
+		}
+		c.Set("Content-Disposition", "attachment; filename="+filename)
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		return c.SendString(output)
+	}
+
+	data := h.baseData(c, "Traefik")
+	data["Active"] = "settings"
+	data["Format"] = format
+	data["EntryPoint"] = opts.EntryPoint
+	data["CertResolver"] = opts.CertResolver
+	data["TargetHost"] = h.config.DefaultIP
+	data["Output"] = output
+	data["Report"] = report
+
+	return c.Render("pages/traefik", data, "layouts/base")
+}
+
+// TraefikImportPreview converts Traefik configuration and shows the resulting sites
+func (h *Handler) TraefikImportPreview(c *fiber.Ctx) error {
+	content := c.FormValue("content")
+	if file, err := c.FormFile("file"); err == nil && file.Size > 0 {
+		f, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to open file")
+		}
+		defer f.Close()
+
+		raw, err := io.ReadAll(f)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).SendString("Failed to read file")
+		}
+		content = string(raw)
+	}
+
+	if content == "" {
+		setFlash(c, "error", "No Traefik configuration provided")
+		return c.Redirect("/settings/traefik")
+	}
+
+	format := c.FormValue("format", "auto")
+	targetHost := c.FormValue("target_host", h.config.DefaultIP)
+
+	sites, report, err := h.traefikConverter().Import(content, format, targetHost)
+	if err != nil {
+		setFlash(c, "error", "Failed to convert Traefik configuration: "+err.Error())
+		return c.Redirect("/settings/traefik")
+	}
+
+	existing := make(map[string]bool)
+	for _, site := range sites {
+		if _, err := h.caddyService.GetSite(site.Filename); err == nil {
+			existing[site.Filename] = true
+		}
+	}
+
+	data := h.baseData(c, "Traefik")
+	data["Active"] = "settings"
+	data["Content"] = content
+	data["ImportFormat"] = format
+	data["TargetHost"] = targetHost
+	data["ImportSites"] = sites
+	data["Existing"] = existing
+	data["Report"] = report
+
+	return c.Render("pages/traefik", data, "layouts/base")
+}
+
+// TraefikImportApply creates the sites selected on the review screen
+func (h *Handler) TraefikImportApply(c *fiber.Ctx) error {
+	sites, _, err := h.traefikConverter().Import(c.FormValue("content"), c.FormValue("format", "auto"), c.FormValue("target_host", h.config.DefaultIP))
+	if err != nil {
+		setFlash(c, "error", "Failed to convert Traefik configuration: "+err.Error())
+		return c.Redirect("/settings/traefik")
+	}
+
+	selected := formValues(c, "sites")
+	if len(selected) == 0 {
+		setFlash(c, "warning", "No sites selected for import")
+		return c.Redirect("/settings/traefik")
+	}
+
+	imported, skipped := 0, 0
+	var errors []string
+	for _, site := range sites {
+		if !contains(selected, site.Filename) {
+			continue
+		}
+		if _, err := h.caddyService.GetSite(site.Filename); err == nil {
+			skipped++
+			continue
+		}
+		if err := h.caddyService.CreateSite(site); err != nil {
+			errors = append(errors, fmt.Sprintf("%s: %v", site.PrimaryDomain(), err))
+			continue
+		}
+		imported++
+	}
+
+	msg := formatImportResult(imported, skipped)
+	switch {
+	case len(errors) > 0:
+		setFlash(c, "warning", fmt.Sprintf("%s, %d failed: %s", msg, len(errors), errors[0]))
+	case imported > 0:
+		reload := h.caddyService.ReloadWithValidation()
+		if !reload.Success {
+			setFlash(c, "warning", msg+" but reload failed: "+reload.Error)
+		} else {
+			setFlash(c, "success", msg)
+		}
+	default:
+		setFlash(c, "info", msg)
+	}
+
+	if c.Get("HX-Request") == "true" {
+		c.Set("HX-Redirect", "/sites")
+		return c.SendStatus(fiber.StatusOK)
+	}
+
+	return c.Redirect("/sites")
+}
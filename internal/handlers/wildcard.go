@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"path/filepath"
+	"strings"
 
 	"github.com/TomasZmek/cpm/internal/models"
 	"github.com/TomasZmek/cpm/internal/services"
@@ -29,6 +30,8 @@ func (h *Handler) WildcardSettings(c *fiber.Ctx) error {
 	data := h.baseData(c, "Settings - Wildcard SSL")
 	data["ActiveTab"] = "wildcard"
 	data["WildcardDomains"] = domains
+	data["DNSProviders"] = models.GetDNSProviders()
+	data["Issuers"] = models.Issuers()
 
 	return c.Render("pages/settings", data, "layouts/base")
 }
@@ -43,7 +46,6 @@ func (h *Handler) WildcardAdd(c *fiber.Ctx) error {
 	domain := c.FormValue("domain")
 	provider := c.FormValue("provider")
 	useEnv := c.FormValue("use_env") == "on"
-	apiToken := c.FormValue("api_token")
 
 	log.Printf("WildcardAdd: domain=%s, provider=%s, useEnv=%v", domain, provider, useEnv)
 
@@ -62,10 +64,34 @@ func (h *Handler) WildcardAdd(c *fiber.Ctx) error {
 	}
 
 	wildcard := models.WildcardDomain{
-		Domain:   domain,
-		Provider: provider,
-		UseEnv:   useEnv,
-		APIToken: apiToken,
+		Domain:      domain,
+		Provider:    provider,
+		UseEnv:      useEnv,
+		Credentials: map[string]string{},
+		EnvVars:     map[string]string{},
+		Issuer:      c.FormValue("issuer"),
+	}
+	wildcard.ACMESettings = acmeSettingsFromForm(c, wildcard.Issuer)
+
+	// Provider specific fields are posted as <provider>_<key> and <provider>_<key>_env
+	if p := models.GetDNSProviderByID(provider); p != nil {
+		for _, field := range p.Fields {
+			name := provider + "_" + field.Key
+			if field.Secret && useEnv {
+				if env := strings.TrimSpace(c.FormValue(name + "_env")); env != "" && env != field.EnvVar {
+					wildcard.EnvVars[field.Key] = env
+				}
+				continue
+			}
+			if value := strings.TrimSpace(c.FormValue(name)); value != "" {
+				wildcard.Credentials[field.Key] = value
+			}
+		}
+	}
+
+	if err := services.ValidateWildcardDomain(wildcard); err != nil {
+		setFlash(c, "error", err.Error())
+		return c.Redirect("/settings/wildcard")
 	}
 
 	if err := h.wildcardService.AddDomain(wildcard); err != nil {
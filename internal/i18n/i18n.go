@@ -198,7 +198,7 @@ var englishTranslations = map[string]string{
 	"snippets_api_token":          "API Token (if not using env)",
 	"snippets_api_token_placeholder": "Enter Cloudflare API token",
 	"snippets_allowed_networks":   "Allowed Networks (CIDR)",
-	"snippets_networks_help":      "One network per line in CIDR notation",
+	"snippets_networks_help":      "One IPv4 or IPv6 address, CIDR range or range (first-last) per line",
 	"snippets_hsts_max_age":       "HSTS Max Age (seconds)",
 	"snippets_include_subdomains": "Include Subdomains",
 	"snippets_x_frame_options":    "X-Frame-Options",
@@ -228,14 +228,14 @@ var englishTranslations = map[string]string{
 	"certs_delete":         "Force Renewal",
 	"certs_force_renew":    "Force Renew",
 	"certs_renew":          "Renew",
-	"certs_confirm_renew":  "Delete certificate and trigger renewal for",
+	"certs_confirm_renew":  "Back up certificate and trigger renewal for",
 	"certs_confirm_delete": "Delete certificate for",
 	"certs_empty":          "No certificates found",
 	"certs_empty_title":    "No Certificates Found",
 	"certs_empty_desc":     "Certificates will appear here once Caddy obtains them.",
 	"certs_about_title":    "About Certificate Management",
 	"certs_about_desc":     "Caddy automatically renews certificates before they expire.",
-	"certs_renew_desc":     "Delete certificate and reload Caddy to trigger automatic renewal (use for active domains)",
+	"certs_renew_desc":     "Back up certificate and reload Caddy to obtain a new one, the backup is restored if renewal fails (checks Let's Encrypt rate limits)",
 	"certs_delete_desc":    "Only delete certificate without renewal (use for unused domains)",
 
 	// Logs
@@ -267,7 +267,7 @@ var englishTranslations = map[string]string{
 	"import_export":           "Import / Export Rules",
 	"import_export_desc":      "Export rules as JSON or import from another CPM instance.",
 	"export_rules":            "Export Rules",
-	"export_rules_desc":       "Download all proxy rules as JSON file.",
+	"export_rules_desc":       "Download all proxy rules, wildcard domains and snippet settings as a JSON file.",
 	"export_json":             "Export JSON",
 	"import_rules":            "Import Rules",
 	"import_rules_desc":       "Import rules from a JSON file.",
@@ -329,8 +329,8 @@ var englishTranslations = map[string]string{
 	"wildcard_domain":             "Domain",
 	"wildcard_domain_help":        "Enter the base domain (without *. prefix)",
 	"wildcard_provider":           "DNS Provider",
-	"wildcard_use_env":            "Use CF_API_TOKEN environment variable",
-	"wildcard_env_help":           "Recommended: Set CF_API_TOKEN in your Docker environment",
+	"wildcard_use_env":            "Read credentials from environment variables",
+	"wildcard_env_help":           "Recommended: set the variables in the Caddy container environment. Leave the name empty to use the default.",
 	"wildcard_api_token":          "API Token",
 	"wildcard_api_token_placeholder": "Enter your Cloudflare API token",
 	"wildcard_api_token_help":     "Token needs Zone:DNS:Edit permissions",
@@ -369,6 +369,386 @@ var englishTranslations = map[string]string{
 	"tls_hint":              "Wildcard certificates are shared across all subdomains - more private and efficient",
 	"wildcard_snippets_hint": "cloudflare_dns and internal_only are handled automatically at wildcard level",
 	"wildcard_tls_active":   "Wildcard TLS active - DNS and internal restrictions handled automatically",
+
+	// Caddyfile Import
+	"caddyfile_import_title":         "Import Caddyfile",
+	"caddyfile_import_link":          "Import an existing Caddyfile",
+	"caddyfile_import_info_title":    "Nothing is written until you confirm",
+	"caddyfile_import_info_desc":     "CPM splits the Caddyfile into site blocks, maps each one onto a proxy rule and shows you the result for review. Unknown directives are kept as extra config.",
+	"caddyfile_import_upload":        "Upload Caddyfile",
+	"caddyfile_import_config":        "Use current Caddyfile",
+	"caddyfile_import_config_desc":   "Analyze the Caddyfile stored in the Caddy config directory.",
+	"caddyfile_import_paste":         "Paste Caddyfile",
+	"caddyfile_import_analyze":       "Analyze",
+	"caddyfile_import_warnings":      "Warnings",
+	"caddyfile_import_sites":         "Sites found",
+	"caddyfile_import_type":          "Import",
+	"caddyfile_import_notes":         "Notes",
+	"caddyfile_import_mapped":        "Mapped",
+	"caddyfile_import_verbatim":      "Verbatim",
+	"caddyfile_import_preview":       "Show file",
+	"caddyfile_import_no_sites":      "No site blocks found.",
+	"caddyfile_import_global":        "Global options",
+	"caddyfile_import_global_desc":   "Options CPM manages can be imported into the global options. The others are listed in the warnings.",
+	"caddyfile_import_global_apply":  "Import global options (replaces the current values of these options)",
+	"caddyfile_import_snippets":      "Snippets",
+	"caddyfile_import_snippets_desc": "Snippets used by the selected sites are created as custom snippets. Sites importing a snippet that can't be created are not imported.",
+	"caddyfile_import_snippet_kept":  "CPM already has this snippet, the imported definition is ignored",
+	"caddyfile_import_overwrite":     "Overwrite existing rules with the same filename",
+	"caddyfile_import_apply":         "Import selected sites",
+
+	// Traefik
+	"traefik_title":            "Traefik Import / Export",
+	"traefik_link":             "Import or export Traefik configuration",
+	"traefik_report":           "Mapping report",
+	"traefik_report_desc":      "These settings were dropped or approximated during the conversion.",
+	"traefik_field":            "Field",
+	"traefik_exists":           "Exists",
+	"traefik_snippets":         "Snippets",
+	"traefik_export":           "Export to Traefik",
+	"traefik_export_desc":      "Convert all proxy rules to Traefik routers, services and middlewares.",
+	"traefik_format":           "Format",
+	"traefik_file_provider":    "file provider",
+	"traefik_entrypoint":       "Entrypoint",
+	"traefik_certresolver":     "Certificate resolver",
+	"traefik_convert":          "Convert",
+	"traefik_download":         "Download",
+	"traefik_import":           "Import from Traefik",
+	"traefik_import_desc":      "Paste or upload a dynamic configuration file or docker-compose labels. Nothing is written until you confirm.",
+	"traefik_auto":             "Detect automatically",
+	"traefik_target_host":      "Target host for labels",
+	"traefik_target_host_desc": "Labels only contain the container port, this address is used as the backend.",
+
+	// Rules Import
+	"import_conflict_mode":    "If a rule already exists",
+	"import_mode_overwrite":   "Overwrite existing rules",
+	"import_mode_rename":      "Import under a new filename",
+	"import_wildcards":        "Import wildcard domains",
+	"import_snippets":         "Import snippet settings",
+	"import_dry_run":          "Dry run (preview only)",
+	"import_dry_run_title":    "Dry run - nothing was written",
+	"import_dry_run_desc":     "This is what the import would do. Review the result and confirm to import.",
+	"import_result_imported":  "Imported",
+	"import_result_skipped":   "Skipped",
+	"import_result_failed":    "Failed",
+	"import_result_version":   "Export format version",
+	"import_result_file":      "File",
+	"import_result_action":    "Action",
+	"import_action_create":    "Create",
+	"import_action_overwrite": "Overwrite",
+	"import_action_rename":    "Rename",
+	"import_action_skip":      "Skip",
+	"import_action_error":     "Error",
+	"import_apply":            "Import",
+
+	// Git Sync
+	"settings_git":           "Git Sync",
+	"git_title":              "Git Repository Sync",
+	"git_description":        "Every change to sites, snippets, the Caddyfile and wildcard domains is committed to a git repository in the config directory.",
+	"git_disabled":           "Git sync is disabled. Set GIT_SYNC=true (and optionally GIT_REMOTE) to enable it.",
+	"git_last_error":         "Last error",
+	"git_secrets_warning":    "The repository tracks .snippets_config.json and wildcard.json, which hold basic auth password hashes and DNS provider API tokens. Every push copies them to the remote, so only push to a private repository you trust and keep DNS tokens in environment variables.",
+	"git_branch":             "Branch",
+	"git_remote":             "Remote",
+	"git_no_remote":          "No remote configured",
+	"git_state":              "Working tree",
+	"git_dirty":              "Uncommitted changes",
+	"git_clean":              "Clean",
+	"git_last_push":          "Last push",
+	"git_last_pull":          "Last pull",
+	"git_commit_btn":         "Commit Now",
+	"git_pull_btn":           "Pull & Apply",
+	"git_push_btn":           "Push",
+	"git_conflict_title":     "Pull Conflict",
+	"git_conflict_desc":      "The local and remote configuration were both changed. These files were modified on both sides:",
+	"git_conflict_unrelated": "The histories have no common commit",
+	"git_local":              "Local",
+	"git_keep_local":         "Keep Local & Push",
+	"git_use_remote":         "Use Remote",
+	"git_confirm_remote":     "Discard local changes and apply the remote configuration?",
+	"git_history":            "Recent Commits",
+	"git_commit":             "Commit",
+	"git_message":            "Message",
+	"git_author":             "Author",
+	"git_date":               "Date",
+
+	// DNS Providers
+	"wildcard_env_var":         "environment variable",
+	"wildcard_provider_module": "Caddy must be built with",
+
+	// Certificate Issuers
+	"tls_issuer_default":             "Caddy default (Let's Encrypt, ZeroSSL fallback)",
+	"tls_issuer_letsencrypt":         "Let's Encrypt",
+	"tls_issuer_letsencrypt_staging": "Let's Encrypt (staging)",
+	"tls_issuer_zerossl":             "ZeroSSL",
+	"tls_issuer_acme":                "Custom ACME directory",
+	"tls_issuer_internal":            "Internal CA (LAN only)",
+	"wildcard_issuer":                "Certificate Issuer",
+	"acme_email":                     "ACME Account Email",
+	"acme_email_hint":                "Used for expiry notices. ZeroSSL uses it to create EAB credentials automatically.",
+	"acme_directory":                 "ACME Directory URL",
+	"eab_key_id":                     "EAB Key ID",
+	"eab_mac_key":                    "EAB MAC Key",
+
+	// Custom Certificates
+	"tls_custom_cert":         "Uploaded certificate",
+	"certs_custom":            "custom",
+	"certs_custom_title":      "Custom Certificates",
+	"certs_custom_desc":       "Certificates from your own PKI. Select them in a rule's certificate mode; Caddy serves them instead of requesting one.",
+	"certs_custom_name":       "Name",
+	"certs_custom_sans":       "Domains (SAN)",
+	"certs_custom_used_by":    "Used by",
+	"certs_custom_upload":     "Upload Certificate",
+	"certs_custom_cert":       "Certificate (PEM)",
+	"certs_custom_chain":      "Chain / intermediates (optional)",
+	"certs_custom_key":        "Private key (PEM)",
+	"certs_custom_help":       "Upload a file or paste the PEM. The key must match the certificate and the chain must be in order. Keys are stored with 0600 permissions in the data volume.",
+	"certs_custom_upload_btn": "Upload",
+
+	// Client Certificates (mTLS)
+	"mtls_mode":                    "Client certificates (mTLS)",
+	"mtls_off":                     "Off",
+	"mtls_mode_require_and_verify": "Required - reject clients without a valid certificate",
+	"mtls_mode_verify_if_given":    "Optional - verify certificates that are sent",
+	"mtls_hint":                    "Only browsers and devices with a certificate from the trusted CA can connect. Not available with wildcard certificates.",
+	"mtls_ca":                      "Trusted CA",
+	"mtls_no_ca":                   "No client CA - create one on the Certificates page",
+	"mtls_allowed_subjects":        "Allowed subjects (one per line)",
+	"mtls_allowed_sans":            "Allowed emails / DNS names (one per line)",
+	"mtls_allow_hint":              "Leave both empty to accept every certificate of the CA. Otherwise the certificate must match a subject (e.g. CN=alice) or its first email or DNS name.",
+	"mtls_title":                   "Client Certificate Authorities",
+	"mtls_desc":                    "CAs used to verify client certificates (mutual TLS). CPM can run a small CA that issues and revokes client certificates, or trust an uploaded CA bundle.",
+	"mtls_managed":                 "issues certificates",
+	"mtls_uploaded":                "uploaded",
+	"mtls_confirm_delete_ca":       "Delete client CA",
+	"mtls_common_name":             "Name (CN)",
+	"mtls_email":                   "Email",
+	"mtls_serial":                  "Serial number",
+	"mtls_revoked":                 "revoked",
+	"mtls_confirm_revoke":          "Revoke the client certificate of",
+	"mtls_revoke":                  "Revoke",
+	"mtls_validity_days":           "Validity (days)",
+	"mtls_p12_password":            "PKCS#12 password",
+	"mtls_issue_help":              "The certificate and its private key are downloaded as a password protected .p12 file for import into a browser or OS keychain. CPM doesn't keep the private key.",
+	"mtls_issue":                   "Issue and download .p12",
+	"mtls_create_ca":               "Create CA",
+	"mtls_upload_ca":               "Upload CA Bundle",
+	"mtls_bundle":                  "CA certificates (PEM)",
+
+	// Certificate Inspector
+	"cert_info":             "Certificate",
+	"cert_subject":          "Subject",
+	"cert_key":              "Key",
+	"cert_signature":        "Signature algorithm",
+	"cert_issuer_key":       "Storage directory",
+	"cert_ocsp_next_update": "next update",
+	"cert_ocsp_none":        "No OCSP staple cached",
+	"cert_sites":            "Serves sites",
+	"cert_unused":           "serves no site",
+	"cert_chain":            "Chain",
+	"cert_metadata":         "Caddy metadata",
+	"cert_uncovered":        "Sites without a valid certificate",
+
+	// Certificate Renewals
+	"renew_title":          "Certificate Renewals",
+	"renew_started":        "Started",
+	"renew_state_running":  "Running",
+	"renew_state_renewed":  "Renewed",
+	"renew_state_restored": "Restored",
+	"renew_state_failed":   "Failed",
+
+	// On-Demand TLS
+	"ondemand_title":         "On-Demand TLS",
+	"ondemand_desc":          "Caddy obtains certificates for customer domains during the first TLS handshake. Before each certificate it asks CPM whether the domain is allowed.",
+	"ondemand_enabled":       "Enable on-demand TLS",
+	"ondemand_ask_url":       "Ask endpoint",
+	"ondemand_ask_url_hint":  "CPM's /tls/ask endpoint as reachable from the Caddy container, without authentication",
+	"ondemand_allowed":       "Allowed domains",
+	"ondemand_allowed_hint":  "One domain or pattern per line (*.customers.example.com matches one label). Hosts of on-demand rules are always allowed.",
+	"ondemand_issued":        "Issued on-demand certificates",
+	"ondemand_via":           "Allowed by",
+	"ondemand_allow_list":    "Allow-list",
+	"ondemand_none_issued":   "No on-demand certificates have been issued yet.",
+	"ondemand_site":          "On-demand certificates",
+	"ondemand_site_hint":     "Obtain certificates during the TLS handshake, e.g. for https:// catch-all rules serving customer domains",
+	"ondemand_disabled_hint": "On-demand TLS is disabled in Settings → Caddy, Caddy won't obtain certificates for this rule",
+
+	// Global Options
+	"globals_title":                "Global Options",
+	"globals_desc":                 "Options written into the global block of the generated Caddyfile.",
+	"globals_default_sni":          "Default SNI",
+	"globals_admin":                "Admin API listener",
+	"globals_admin_hint":           "Empty for localhost:2019. CPM reloads Caddy through the admin API, so it can't be turned off.",
+	"globals_grace_period":         "Grace period (seconds)",
+	"globals_servers":              "Servers",
+	"globals_trusted_proxies":      "Trusted proxies",
+	"globals_trusted_proxies_hint": "CIDR ranges or private_ranges, one per line. Client IPs are read from X-Forwarded-For only behind these proxies.",
+	"globals_protocols":            "Protocols",
+	"globals_protocols_hint":       "None selected uses Caddy's default (h1 h2 h3).",
+	"globals_log":                  "Logging",
+	"globals_log_enabled":          "Configure the default logger",
+	"globals_log_output":           "Output",
+	"globals_log_file":             "Log file",
+	"globals_log_format":           "Format",
+	"globals_log_level":            "Level",
+	"globals_default":              "Default",
+
+	// Path routes
+	"routes_title":      "Path Routes",
+	"routes_hint":       "Send matching paths to their own backends. Other requests go to the backend target above.",
+	"routes_path":       "Path",
+	"routes_mode":       "Mode",
+	"routes_strip":      "strip prefix",
+	"routes_lb_policy":  "Load Balancing",
+	"routes_lb_default": "Default (random)",
+	"routes_backends":   "Backends (space separated)",
+	"routes_headers":    "Upstream Headers (one per line)",
+	"routes_add":        "Add Route",
+
+	// Site types
+	"sitetype":                 "Site Type",
+	"sitetype_proxy":           "Reverse Proxy",
+	"sitetype_redirect":        "Redirect",
+	"sitetype_static":          "Static Files",
+	"sitetype_respond":         "Fixed Response",
+	"redirect_canonical":       "Canonical Name",
+	"redirect_canonical_none":  "None - redirect to the target below",
+	"redirect_to":              "Redirect To",
+	"redirect_to_hint":         "URL, domain or path, e.g. https://new.example.com or /app",
+	"redirect_code":            "Status",
+	"redirect_code_permanent":  "Permanent (301)",
+	"redirect_code_temporary":  "Temporary (302)",
+	"redirect_code_307":        "Temporary, keep method (307)",
+	"redirect_code_308":        "Permanent, keep method (308)",
+	"redirect_preserve_path":   "Preserve path and query",
+	"static_root":              "Root Directory",
+	"static_root_hint":         "Path in the Caddy container, inside /data/www (caddy-data/www on the host) or /usr/share/caddy",
+	"static_index":             "Index Files",
+	"static_browse":            "Directory listing",
+	"respond_status":           "Status Code",
+	"respond_content_type":     "Content Type",
+	"respond_body":             "Body",
+	"respond_body_placeholder": "We'll be back soon.",
+
+	// Forward auth
+	"forward_auth_title":                 "Forward Auth",
+	"forward_auth_hint":                  "Let an identity provider authenticate every request before it reaches the site.",
+	"forward_auth_provider":              "Provider",
+	"forward_auth_none":                  "None",
+	"forward_auth_provider_default":      "Global default",
+	"forward_auth_provider_authelia":     "Authelia",
+	"forward_auth_provider_authentik":    "Authentik",
+	"forward_auth_provider_oauth2-proxy": "oauth2-proxy",
+	"forward_auth_provider_custom":       "Custom",
+	"forward_auth_upstream":              "Provider Address",
+	"forward_auth_uri":                   "Verify URI",
+	"forward_auth_copy_headers":          "Copy Headers",
+	"forward_auth_bypass":                "Bypass Paths (one per line)",
+	"forward_auth_bypass_hint":           "Served without authentication, e.g. /api/webhook/* for webhooks",
+	"forward_auth_enable":                "Enable global forward auth provider",
+	"forward_auth_global_hint":           "Sites use it by selecting the Global default provider in their Forward Auth section.",
+
+	// Access lists
+	"access_lists_title":          "Access Lists",
+	"access_lists_hint":           "Named sets of networks rules can be restricted to. A client passes if any selected list allows it and none denies it. Lists with only denied networks allow everyone else.",
+	"access_lists_site_hint":      "Only clients from the selected lists may connect. On a wildcard domain the selection replaces the wildcard's internal network restriction, so LAN-only and public subdomains can share one certificate.",
+	"access_lists_empty":          "No access lists yet, create them under Snippets",
+	"access_lists_allow":          "Allowed networks",
+	"access_lists_deny":           "Denied networks",
+	"access_lists_new":            "New access list",
+	"access_lists_add":            "Add access list",
+	"access_lists_networks_help":  "One IPv4 or IPv6 address, CIDR range or range (first-last) per line",
+	"access_lists_confirm_delete": "Delete access list",
+
+	// Custom snippets
+	"custom_snippets_title":          "Custom Snippets",
+	"custom_snippets_hint":           "Your own snippets with a raw Caddyfile body. They are written into snippets.caddy, checked by Caddy on save and offered in the site form.",
+	"custom_snippets_new":            "New snippet name",
+	"custom_snippets_description":    "Description",
+	"custom_snippets_body":           "Caddyfile body",
+	"custom_snippets_body_help":      "Directives only, without the (name) { } wrapper. Built-in and generated snippet names are reserved.",
+	"custom_snippets_add":            "Add snippet",
+	"custom_snippets_confirm_delete": "Delete snippet",
+
+	// Snippet parameters
+	"snippet_params_title":            "parameters",
+	"snippet_params_hint":             "Leave empty to use the defaults of the snippet. Once a value is set, the empty fields are saved with the current defaults.",
+	"custom_snippets_params":          "Parameters",
+	"custom_snippets_params_help":     "One per line: name, type (int, duration or string) and default value. The body uses them in order as {args[0]}, {args[1]}, ... and sites can override them.",
+	"snippets_rate_limit_params_help": "These are the defaults; a rule can set its own limit in the snippet parameters of the rule form, e.g. 20 requests per 10s on a login page.",
+
+	// Security headers builder
+	"security_hsts_preload":            "HSTS preload",
+	"security_csp_enable":              "Send Content-Security-Policy",
+	"security_csp_report_only":         "Report only",
+	"security_csp_help":                "Space separated sources per directive, e.g. self https://cdn.example.com data:. Keywords like self, none or unsafe-inline are quoted automatically; empty directives are left out. Report only sends Content-Security-Policy-Report-Only, so violations are reported without being blocked.",
+	"security_permission_none":         "Disabled ()",
+	"security_permission_self":         "Same origin (self)",
+	"security_permission_all":          "Everyone (*)",
+	"security_profiles_title":          "Security Header Profiles",
+	"security_profiles_hint":           "Named sets of security headers, each generated as a security_headers_<id> snippet. Select a profile in the snippets of a rule instead of security_headers to give it its own headers.",
+	"security_profiles_add":            "Add profile",
+	"security_profiles_name":           "Profile name",
+	"security_profiles_confirm_delete": "Delete profile",
+
+	// CORS
+	"cors_title":            "CORS",
+	"cors_hint":             "Lets browser apps on other origins call this site. Preflight requests are answered by Caddy with 204.",
+	"cors_origins":          "Allowed origins",
+	"cors_origins_hint":     "One per line: https://app.example.com, https://*.example.com for subdomains, or * for any origin. Leave empty to disable CORS.",
+	"cors_methods":          "Allowed methods",
+	"cors_headers":          "Allowed headers",
+	"cors_max_age":          "Max-age (s)",
+	"cors_credentials":      "Allow credentials",
+	"cors_credentials_hint": "Cookies and authorization headers; the request's origin is sent instead of *. Preflights don't carry credentials, so forward auth and basic auth need a bypass.",
+
+	// Header rules
+	"headers_title":             "Headers",
+	"headers_hint":              "Set, add, delete or rewrite headers of the response (header), the request to the backend (header_up) or the backend's response (header_down). Replace takes a regular expression and its replacement, e.g. ^http://(.*) → https://$1.",
+	"headers_target":            "Target",
+	"headers_target_response":   "Response (header)",
+	"headers_target_upstream":   "To backend (header_up)",
+	"headers_target_downstream": "From backend (header_down)",
+	"headers_operation":         "Operation",
+	"headers_operation_set":     "Set",
+	"headers_operation_add":     "Add",
+	"headers_operation_delete":  "Delete",
+	"headers_operation_replace": "Replace (regex)",
+	"headers_name":              "Header",
+	"headers_value":             "Value / regex",
+	"headers_replace":           "Replacement",
+	"headers_add":               "Add Header Rule",
+
+	// Maintenance mode
+	"maintenance_title":              "Maintenance",
+	"maintenance_desc":               "Sites in maintenance answer with pages/503.html, status 503 and Retry-After. Windows target a site or every site with a tag and start and end on schedule, checked every 30 seconds.",
+	"maintenance_bypass_ips":         "Bypass IPs",
+	"maintenance_bypass_ips_hint":    "One IP or CIDR per line; these clients see the site as usual.",
+	"maintenance_bypass_cookie":      "Bypass cookie",
+	"maintenance_bypass_cookie_hint": "Generates a secret link that sets a cookie and lets the visitor through for a day.",
+	"maintenance_bypass_link":        "Open a site with this query to bypass maintenance:",
+	"maintenance_retry_after":        "Retry-After (s)",
+	"maintenance_retry_after_hint":   "Empty for one hour.",
+	"maintenance_windows":            "Maintenance windows",
+	"maintenance_no_windows":         "No maintenance windows.",
+	"maintenance_target":             "Target",
+	"maintenance_tags":               "Tags",
+	"maintenance_sites":              "Sites",
+	"maintenance_start":              "Start",
+	"maintenance_start_hint":         "Empty starts now.",
+	"maintenance_end":                "End",
+	"maintenance_end_hint":           "Empty lasts until the window is removed.",
+	"maintenance_until_removed":      "Until removed",
+	"maintenance_active":             "In maintenance",
+	"maintenance_scheduled":          "Scheduled",
+	"maintenance_add_window":         "Add maintenance window",
+	"maintenance_confirm_delete":     "Remove this maintenance window?",
+
+	// Disabled sites
+	"sites_disabled":      "Disabled",
+	"sites_enable":        "Enable",
+	"sites_disable":       "Disable",
+	"sites_disabled_hint": "This rule is disabled: its file is kept in sites/disabled and Caddy doesn't serve it.",
 }
 
 // Czech translations
@@ -505,7 +885,7 @@ var czechTranslations = map[string]string{
 	"snippets_api_token":          "API token (pokud nepoužíváte env)",
 	"snippets_api_token_placeholder": "Zadejte Cloudflare API token",
 	"snippets_allowed_networks":   "Povolené sítě (CIDR)",
-	"snippets_networks_help":      "Jedna síť na řádek v CIDR notaci",
+	"snippets_networks_help":      "Jedna IPv4 nebo IPv6 adresa, CIDR rozsah nebo rozsah (první-poslední) na řádek",
 	"snippets_hsts_max_age":       "HSTS Max Age (sekundy)",
 	"snippets_include_subdomains": "Zahrnout subdomény",
 	"snippets_x_frame_options":    "X-Frame-Options",
@@ -535,14 +915,14 @@ var czechTranslations = map[string]string{
 	"certs_delete":         "Vynutit obnovu",
 	"certs_force_renew":    "Vynutit obnovu",
 	"certs_renew":          "Obnovit",
-	"certs_confirm_renew":  "Smazat certifikát a vynutit obnovu pro",
+	"certs_confirm_renew":  "Zálohovat certifikát a vynutit obnovu pro",
 	"certs_confirm_delete": "Smazat certifikát pro",
 	"certs_empty":          "Žádné certifikáty nenalezeny",
 	"certs_empty_title":    "Žádné certifikáty",
 	"certs_empty_desc":     "Certifikáty se zde zobrazí poté, co je Caddy získá.",
 	"certs_about_title":    "O správě certifikátů",
 	"certs_about_desc":     "Caddy automaticky obnovuje certifikáty před vypršením.",
-	"certs_renew_desc":     "Smaže certifikát a restartuje Caddy pro automatickou obnovu (pro aktivní domény)",
+	"certs_renew_desc":     "Zálohuje certifikát a restartuje Caddy pro získání nového, při neúspěchu obnoví zálohu (hlídá limity Let's Encrypt)",
 	"certs_delete_desc":    "Pouze smaže certifikát bez obnovy (pro nepoužívané domény)",
 
 	// Logs
@@ -574,7 +954,7 @@ var czechTranslations = map[string]string{
 	"import_export":           "Import / Export pravidel",
 	"import_export_desc":      "Exportujte pravidla jako JSON nebo importujte z jiné CPM instance.",
 	"export_rules":            "Exportovat pravidla",
-	"export_rules_desc":       "Stáhněte všechna proxy pravidla jako JSON soubor.",
+	"export_rules_desc":       "Stáhněte všechna proxy pravidla, wildcard domény a nastavení snippetů jako JSON soubor.",
 	"export_json":             "Exportovat JSON",
 	"import_rules":            "Importovat pravidla",
 	"import_rules_desc":       "Importujte pravidla z JSON souboru.",
@@ -636,8 +1016,8 @@ var czechTranslations = map[string]string{
 	"wildcard_domain":             "Doména",
 	"wildcard_domain_help":        "Zadejte základní doménu (bez *. prefixu)",
 	"wildcard_provider":           "DNS provider",
-	"wildcard_use_env":            "Použít proměnnou prostředí CF_API_TOKEN",
-	"wildcard_env_help":           "Doporučeno: Nastavte CF_API_TOKEN v Docker prostředí",
+	"wildcard_use_env":            "Načíst přihlašovací údaje z proměnných prostředí",
+	"wildcard_env_help":           "Doporučeno: nastavte proměnné v prostředí Caddy kontejneru. Prázdný název použije výchozí.",
 	"wildcard_api_token":          "API token",
 	"wildcard_api_token_placeholder": "Zadejte váš Cloudflare API token",
 	"wildcard_api_token_help":     "Token potřebuje oprávnění Zone:DNS:Edit",
@@ -676,4 +1056,384 @@ var czechTranslations = map[string]string{
 	"tls_hint":              "Wildcard certifikáty jsou sdílené pro všechny subdomény - více soukromí a efektivnější",
 	"wildcard_snippets_hint": "cloudflare_dns a internal_only jsou automaticky řešeny na úrovni wildcard",
 	"wildcard_tls_active":   "Wildcard TLS aktivní - DNS a interní omezení jsou řešeny automaticky",
+
+	// Caddyfile Import
+	"caddyfile_import_title":         "Import Caddyfile",
+	"caddyfile_import_link":          "Importovat existující Caddyfile",
+	"caddyfile_import_info_title":    "Nic se nezapíše, dokud nepotvrdíte",
+	"caddyfile_import_info_desc":     "CPM rozdělí Caddyfile na bloky webů, převede každý na proxy pravidlo a zobrazí výsledek ke kontrole. Neznámé direktivy se zachovají jako extra konfigurace.",
+	"caddyfile_import_upload":        "Nahrát Caddyfile",
+	"caddyfile_import_config":        "Použít aktuální Caddyfile",
+	"caddyfile_import_config_desc":   "Analyzovat Caddyfile uložený v konfiguračním adresáři Caddy.",
+	"caddyfile_import_paste":         "Vložit Caddyfile",
+	"caddyfile_import_analyze":       "Analyzovat",
+	"caddyfile_import_warnings":      "Upozornění",
+	"caddyfile_import_sites":         "Nalezené weby",
+	"caddyfile_import_type":          "Import",
+	"caddyfile_import_notes":         "Poznámky",
+	"caddyfile_import_mapped":        "Převedeno",
+	"caddyfile_import_verbatim":      "Beze změny",
+	"caddyfile_import_preview":       "Zobrazit soubor",
+	"caddyfile_import_no_sites":      "Nenalezeny žádné bloky webů.",
+	"caddyfile_import_global":        "Globální nastavení",
+	"caddyfile_import_global_desc":   "Nastavení, která CPM spravuje, lze importovat do globálního nastavení. Ostatní jsou uvedena v upozorněních.",
+	"caddyfile_import_global_apply":  "Importovat globální nastavení (nahradí aktuální hodnoty těchto nastavení)",
+	"caddyfile_import_snippets":      "Snippety",
+	"caddyfile_import_snippets_desc": "Snippety používané vybranými weby se vytvoří jako vlastní snippety. Weby, které importují snippet, jenž nelze vytvořit, se neimportují.",
+	"caddyfile_import_snippet_kept":  "CPM tento snippet už má, importovaná definice se ignoruje",
+	"caddyfile_import_overwrite":     "Přepsat existující pravidla se stejným názvem souboru",
+	"caddyfile_import_apply":         "Importovat vybrané weby",
+
+	// Traefik
+	"traefik_title":            "Import / export Traefik",
+	"traefik_link":             "Importovat nebo exportovat konfiguraci Traefik",
+	"traefik_report":           "Přehled převodu",
+	"traefik_report_desc":      "Tato nastavení byla při převodu vynechána nebo převedena přibližně.",
+	"traefik_field":            "Pole",
+	"traefik_exists":           "Existuje",
+	"traefik_snippets":         "Snippety",
+	"traefik_export":           "Export do Traefik",
+	"traefik_export_desc":      "Převede všechna proxy pravidla na routery, služby a middleware Traefiku.",
+	"traefik_format":           "Formát",
+	"traefik_file_provider":    "file provider",
+	"traefik_entrypoint":       "Entrypoint",
+	"traefik_certresolver":     "Certificate resolver",
+	"traefik_convert":          "Převést",
+	"traefik_download":         "Stáhnout",
+	"traefik_import":           "Import z Traefik",
+	"traefik_import_desc":      "Vložte nebo nahrajte soubor dynamické konfigurace nebo docker-compose labels. Nic se nezapíše, dokud nepotvrdíte.",
+	"traefik_auto":             "Rozpoznat automaticky",
+	"traefik_target_host":      "Cílový host pro labels",
+	"traefik_target_host_desc": "Labels obsahují pouze port kontejneru, jako backend se použije tato adresa.",
+
+	// Rules Import
+	"import_conflict_mode":    "Pokud pravidlo již existuje",
+	"import_mode_overwrite":   "Přepsat existující pravidla",
+	"import_mode_rename":      "Importovat pod novým názvem souboru",
+	"import_wildcards":        "Importovat wildcard domény",
+	"import_snippets":         "Importovat nastavení snippetů",
+	"import_dry_run":          "Zkušební běh (pouze náhled)",
+	"import_dry_run_title":    "Zkušební běh - nic nebylo zapsáno",
+	"import_dry_run_desc":     "Takto by import proběhl. Zkontrolujte výsledek a potvrďte import.",
+	"import_result_imported":  "Importováno",
+	"import_result_skipped":   "Přeskočeno",
+	"import_result_failed":    "Chyby",
+	"import_result_version":   "Verze formátu exportu",
+	"import_result_file":      "Soubor",
+	"import_result_action":    "Akce",
+	"import_action_create":    "Vytvořit",
+	"import_action_overwrite": "Přepsat",
+	"import_action_rename":    "Přejmenovat",
+	"import_action_skip":      "Přeskočit",
+	"import_action_error":     "Chyba",
+	"import_apply":            "Importovat",
+
+	// Git Sync
+	"settings_git":           "Git synchronizace",
+	"git_title":              "Synchronizace s Git repozitářem",
+	"git_description":        "Každá změna webů, snippetů, Caddyfile a wildcard domén se commituje do git repozitáře v konfiguračním adresáři.",
+	"git_disabled":           "Git synchronizace je vypnutá. Zapněte ji nastavením GIT_SYNC=true (volitelně GIT_REMOTE).",
+	"git_last_error":         "Poslední chyba",
+	"git_secrets_warning":    "Repozitář obsahuje soubory .snippets_config.json a wildcard.json s hashi hesel pro basic auth a API tokeny DNS poskytovatelů. Každý push je zkopíruje na vzdálený repozitář, proto pushujte jen do soukromého repozitáře, kterému důvěřujete, a DNS tokeny držte v proměnných prostředí.",
+	"git_branch":             "Větev",
+	"git_remote":             "Remote",
+	"git_no_remote":          "Remote není nastaven",
+	"git_state":              "Pracovní strom",
+	"git_dirty":              "Necommitnuté změny",
+	"git_clean":              "Čistý",
+	"git_last_push":          "Poslední push",
+	"git_last_pull":          "Poslední pull",
+	"git_commit_btn":         "Commitnout",
+	"git_pull_btn":           "Stáhnout a použít",
+	"git_push_btn":           "Odeslat",
+	"git_conflict_title":     "Konflikt při stahování",
+	"git_conflict_desc":      "Lokální i vzdálená konfigurace byly změněny. Tyto soubory se změnily na obou stranách:",
+	"git_conflict_unrelated": "Historie nemají žádný společný commit",
+	"git_local":              "Lokální",
+	"git_keep_local":         "Ponechat lokální a odeslat",
+	"git_use_remote":         "Použít vzdálenou",
+	"git_confirm_remote":     "Zahodit lokální změny a použít vzdálenou konfiguraci?",
+	"git_history":            "Poslední commity",
+	"git_commit":             "Commit",
+	"git_message":            "Zpráva",
+	"git_author":             "Autor",
+	"git_date":               "Datum",
+
+	// DNS Providers
+	"wildcard_env_var":         "proměnná prostředí",
+	"wildcard_provider_module": "Caddy musí být sestaven s modulem",
+
+	// Certificate Issuers
+	"tls_issuer_default":             "Výchozí Caddy (Let's Encrypt, záložně ZeroSSL)",
+	"tls_issuer_letsencrypt":         "Let's Encrypt",
+	"tls_issuer_letsencrypt_staging": "Let's Encrypt (staging)",
+	"tls_issuer_zerossl":             "ZeroSSL",
+	"tls_issuer_acme":                "Vlastní ACME adresář",
+	"tls_issuer_internal":            "Interní CA (jen LAN)",
+	"wildcard_issuer":                "Vydavatel certifikátu",
+	"acme_email":                     "E-mail ACME účtu",
+	"acme_email_hint":                "Slouží pro upozornění na expiraci. ZeroSSL z něj automaticky vytvoří EAB údaje.",
+	"acme_directory":                 "URL ACME adresáře",
+	"eab_key_id":                     "EAB Key ID",
+	"eab_mac_key":                    "EAB MAC klíč",
+
+	// Custom Certificates
+	"tls_custom_cert":         "Nahraný certifikát",
+	"certs_custom":            "vlastní",
+	"certs_custom_title":      "Vlastní certifikáty",
+	"certs_custom_desc":       "Certifikáty z vaší vlastní PKI. Vyberte je v režimu certifikátu pravidla; Caddy je použije místo vyžádání nového.",
+	"certs_custom_name":       "Název",
+	"certs_custom_sans":       "Domény (SAN)",
+	"certs_custom_used_by":    "Používá",
+	"certs_custom_upload":     "Nahrát certifikát",
+	"certs_custom_cert":       "Certifikát (PEM)",
+	"certs_custom_chain":      "Řetězec / mezilehlé (volitelné)",
+	"certs_custom_key":        "Privátní klíč (PEM)",
+	"certs_custom_help":       "Nahrajte soubor nebo vložte PEM. Klíč musí odpovídat certifikátu a řetězec musí být ve správném pořadí. Klíče se ukládají s oprávněním 0600 do datového svazku.",
+	"certs_custom_upload_btn": "Nahrát",
+
+	// Client Certificates (mTLS)
+	"mtls_mode":                    "Klientské certifikáty (mTLS)",
+	"mtls_off":                     "Vypnuto",
+	"mtls_mode_require_and_verify": "Vyžadováno - odmítnout klienty bez platného certifikátu",
+	"mtls_mode_verify_if_given":    "Volitelné - ověřit zaslané certifikáty",
+	"mtls_hint":                    "Připojit se mohou jen prohlížeče a zařízení s certifikátem od důvěryhodné CA. Není dostupné s wildcard certifikáty.",
+	"mtls_ca":                      "Důvěryhodná CA",
+	"mtls_no_ca":                   "Žádná klientská CA - vytvořte ji na stránce Certifikáty",
+	"mtls_allowed_subjects":        "Povolené subjekty (jeden na řádek)",
+	"mtls_allowed_sans":            "Povolené e-maily / DNS jména (jedno na řádek)",
+	"mtls_allow_hint":              "Ponechte obě prázdná pro přijetí všech certifikátů CA. Jinak musí certifikát odpovídat subjektu (např. CN=alice) nebo svému prvnímu e-mailu či DNS jménu.",
+	"mtls_title":                   "Certifikační autority klientů",
+	"mtls_desc":                    "CA pro ověřování klientských certifikátů (mutual TLS). CPM může provozovat malou CA, která vydává a odvolává klientské certifikáty, nebo důvěřovat nahranému balíku CA.",
+	"mtls_managed":                 "vydává certifikáty",
+	"mtls_uploaded":                "nahraná",
+	"mtls_confirm_delete_ca":       "Smazat klientskou CA",
+	"mtls_common_name":             "Jméno (CN)",
+	"mtls_email":                   "E-mail",
+	"mtls_serial":                  "Sériové číslo",
+	"mtls_revoked":                 "odvolán",
+	"mtls_confirm_revoke":          "Odvolat klientský certifikát pro",
+	"mtls_revoke":                  "Odvolat",
+	"mtls_validity_days":           "Platnost (dny)",
+	"mtls_p12_password":            "Heslo PKCS#12",
+	"mtls_issue_help":              "Certifikát a jeho privátní klíč se stáhnou jako heslem chráněný soubor .p12 pro import do prohlížeče nebo systémové klíčenky. CPM privátní klíč neuchovává.",
+	"mtls_issue":                   "Vydat a stáhnout .p12",
+	"mtls_create_ca":               "Vytvořit CA",
+	"mtls_upload_ca":               "Nahrát balík CA",
+	"mtls_bundle":                  "Certifikáty CA (PEM)",
+
+	// Certificate Inspector
+	"cert_info":             "Certifikát",
+	"cert_subject":          "Subjekt",
+	"cert_key":              "Klíč",
+	"cert_signature":        "Algoritmus podpisu",
+	"cert_issuer_key":       "Adresář úložiště",
+	"cert_ocsp_next_update": "další aktualizace",
+	"cert_ocsp_none":        "Žádná OCSP odpověď v mezipaměti",
+	"cert_sites":            "Obsluhuje weby",
+	"cert_unused":           "neobsluhuje žádný web",
+	"cert_chain":            "Řetězec",
+	"cert_metadata":         "Metadata Caddy",
+	"cert_uncovered":        "Weby bez platného certifikátu",
+
+	// Certificate Renewals
+	"renew_title":          "Obnovy certifikátů",
+	"renew_started":        "Spuštěno",
+	"renew_state_running":  "Probíhá",
+	"renew_state_renewed":  "Obnoveno",
+	"renew_state_restored": "Obnoveno ze zálohy",
+	"renew_state_failed":   "Selhalo",
+
+	// On-Demand TLS
+	"ondemand_title":         "On-demand TLS",
+	"ondemand_desc":          "Caddy získává certifikáty pro domény zákazníků při prvním TLS handshaku. Před každým certifikátem se zeptá CPM, zda je doména povolena.",
+	"ondemand_enabled":       "Povolit on-demand TLS",
+	"ondemand_ask_url":       "Ask endpoint",
+	"ondemand_ask_url_hint":  "Endpoint /tls/ask v CPM dostupný z kontejneru Caddy, bez přihlášení",
+	"ondemand_allowed":       "Povolené domény",
+	"ondemand_allowed_hint":  "Jedna doména nebo vzor na řádek (*.customers.example.com odpovídá jedné úrovni). Hosty on-demand pravidel jsou povoleny vždy.",
+	"ondemand_issued":        "Vydané on-demand certifikáty",
+	"ondemand_via":           "Povoleno přes",
+	"ondemand_allow_list":    "Seznam povolených",
+	"ondemand_none_issued":   "Zatím nebyly vydány žádné on-demand certifikáty.",
+	"ondemand_site":          "On-demand certifikáty",
+	"ondemand_site_hint":     "Získat certifikáty při TLS handshaku, např. pro pravidla https:// obsluhující domény zákazníků",
+	"ondemand_disabled_hint": "On-demand TLS je vypnuto v Nastavení → Caddy, Caddy pro toto pravidlo nezíská certifikáty",
+
+	// Global Options
+	"globals_title":                "Globální nastavení",
+	"globals_desc":                 "Nastavení zapsaná do globálního bloku generovaného Caddyfile.",
+	"globals_default_sni":          "Výchozí SNI",
+	"globals_admin":                "Adresa admin API",
+	"globals_admin_hint":           "Prázdné pro localhost:2019. CPM restartuje Caddy přes admin API, proto ho nelze vypnout.",
+	"globals_grace_period":         "Ochranná doba (sekundy)",
+	"globals_servers":              "Servery",
+	"globals_trusted_proxies":      "Důvěryhodné proxy",
+	"globals_trusted_proxies_hint": "Rozsahy CIDR nebo private_ranges, jeden na řádek. IP klientů se čtou z X-Forwarded-For jen za těmito proxy.",
+	"globals_protocols":            "Protokoly",
+	"globals_protocols_hint":       "Bez výběru se použije výchozí nastavení Caddy (h1 h2 h3).",
+	"globals_log":                  "Logování",
+	"globals_log_enabled":          "Nastavit výchozí logger",
+	"globals_log_output":           "Výstup",
+	"globals_log_file":             "Soubor logu",
+	"globals_log_format":           "Formát",
+	"globals_log_level":            "Úroveň",
+	"globals_default":              "Výchozí",
+
+	// Path routes
+	"routes_title":      "Cesty",
+	"routes_hint":       "Odesílá odpovídající cesty na vlastní backendy. Ostatní požadavky jdou na cíl backendu výše.",
+	"routes_path":       "Cesta",
+	"routes_mode":       "Režim",
+	"routes_strip":      "odebrat prefix",
+	"routes_lb_policy":  "Vyvažování zátěže",
+	"routes_lb_default": "Výchozí (náhodně)",
+	"routes_backends":   "Backendy (oddělené mezerou)",
+	"routes_headers":    "Hlavičky pro upstream (jedna na řádek)",
+	"routes_add":        "Přidat cestu",
+
+	// Site types
+	"sitetype":                 "Typ pravidla",
+	"sitetype_proxy":           "Reverzní proxy",
+	"sitetype_redirect":        "Přesměrování",
+	"sitetype_static":          "Statické soubory",
+	"sitetype_respond":         "Pevná odpověď",
+	"redirect_canonical":       "Kanonický název",
+	"redirect_canonical_none":  "Žádný - přesměrovat na cíl níže",
+	"redirect_to":              "Přesměrovat na",
+	"redirect_to_hint":         "URL, doména nebo cesta, např. https://new.example.com nebo /app",
+	"redirect_code":            "Stav",
+	"redirect_code_permanent":  "Trvalé (301)",
+	"redirect_code_temporary":  "Dočasné (302)",
+	"redirect_code_307":        "Dočasné, zachovat metodu (307)",
+	"redirect_code_308":        "Trvalé, zachovat metodu (308)",
+	"redirect_preserve_path":   "Zachovat cestu a query",
+	"static_root":              "Kořenový adresář",
+	"static_root_hint":         "Cesta v kontejneru Caddy, uvnitř /data/www (caddy-data/www na hostiteli) nebo /usr/share/caddy",
+	"static_index":             "Indexové soubory",
+	"static_browse":            "Výpis adresáře",
+	"respond_status":           "Stavový kód",
+	"respond_content_type":     "Typ obsahu",
+	"respond_body":             "Tělo",
+	"respond_body_placeholder": "Brzy jsme zpět.",
+
+	// Forward auth
+	"forward_auth_title":                 "Forward Auth",
+	"forward_auth_hint":                  "Každý požadavek nejprve ověří poskytovatel identity.",
+	"forward_auth_provider":              "Poskytovatel",
+	"forward_auth_none":                  "Žádný",
+	"forward_auth_provider_default":      "Globální výchozí",
+	"forward_auth_provider_authelia":     "Authelia",
+	"forward_auth_provider_authentik":    "Authentik",
+	"forward_auth_provider_oauth2-proxy": "oauth2-proxy",
+	"forward_auth_provider_custom":       "Vlastní",
+	"forward_auth_upstream":              "Adresa poskytovatele",
+	"forward_auth_uri":                   "Ověřovací URI",
+	"forward_auth_copy_headers":          "Kopírované hlavičky",
+	"forward_auth_bypass":                "Cesty bez ověření (jedna na řádek)",
+	"forward_auth_bypass_hint":           "Obslouženy bez přihlášení, např. /api/webhook/* pro webhooky",
+	"forward_auth_enable":                "Zapnout globálního poskytovatele forward auth",
+	"forward_auth_global_hint":           "Pravidla ho použijí volbou poskytovatele Globální výchozí v sekci Forward Auth.",
+
+	// Access lists
+	"access_lists_title":          "Přístupové seznamy",
+	"access_lists_hint":           "Pojmenované skupiny sítí, na které lze pravidla omezit. Klient projde, pokud ho povoluje některý vybraný seznam a žádný ho nezakazuje. Seznamy jen se zakázanými sítěmi povolují všechny ostatní.",
+	"access_lists_site_hint":      "Připojit se mohou jen klienti z vybraných seznamů. U wildcard domény výběr nahrazuje omezení na interní síť na úrovni wildcard, takže interní a veřejné subdomény mohou sdílet jeden certifikát.",
+	"access_lists_empty":          "Zatím žádné přístupové seznamy, vytvořte je v sekci Snippety",
+	"access_lists_allow":          "Povolené sítě",
+	"access_lists_deny":           "Zakázané sítě",
+	"access_lists_new":            "Nový přístupový seznam",
+	"access_lists_add":            "Přidat přístupový seznam",
+	"access_lists_networks_help":  "Jedna IPv4 nebo IPv6 adresa, CIDR rozsah nebo rozsah (první-poslední) na řádek",
+	"access_lists_confirm_delete": "Smazat přístupový seznam",
+
+	// Custom snippets
+	"custom_snippets_title":          "Vlastní snippety",
+	"custom_snippets_hint":           "Vlastní snippety s obsahem v syntaxi Caddyfile. Zapisují se do snippets.caddy, Caddy je při uložení ověří a nabízejí se ve formuláři webu.",
+	"custom_snippets_new":            "Název nového snippetu",
+	"custom_snippets_description":    "Popis",
+	"custom_snippets_body":           "Obsah (Caddyfile)",
+	"custom_snippets_body_help":      "Pouze direktivy, bez obalu (název) { }. Názvy vestavěných a generovaných snippetů jsou vyhrazené.",
+	"custom_snippets_add":            "Přidat snippet",
+	"custom_snippets_confirm_delete": "Smazat snippet",
+
+	// Snippet parameters
+	"snippet_params_title":            "parametry",
+	"snippet_params_hint":             "Ponechte prázdné pro výchozí hodnoty snippetu. Jakmile je zadána hodnota, prázdná pole se uloží s aktuálními výchozími hodnotami.",
+	"custom_snippets_params":          "Parametry",
+	"custom_snippets_params_help":     "Jeden na řádek: název, typ (int, duration nebo string) a výchozí hodnota. Obsah je používá v pořadí jako {args[0]}, {args[1]}, ... a weby je mohou přepsat.",
+	"snippets_rate_limit_params_help": "Toto jsou výchozí hodnoty; pravidlo může nastavit vlastní limit v parametrech snippetů ve formuláři pravidla, např. 20 požadavků za 10s na přihlašovací stránce.",
+
+	// Security headers builder
+	"security_hsts_preload":            "HSTS preload",
+	"security_csp_enable":              "Odesílat Content-Security-Policy",
+	"security_csp_report_only":         "Pouze hlásit",
+	"security_csp_help":                "Zdroje každé direktivy oddělené mezerou, např. self https://cdn.example.com data:. Klíčová slova jako self, none nebo unsafe-inline se automaticky uzavřou do uvozovek; prázdné direktivy se vynechají. Pouze hlásit odesílá Content-Security-Policy-Report-Only, porušení se tedy hlásí, ale neblokují.",
+	"security_permission_none":         "Zakázáno ()",
+	"security_permission_self":         "Stejný původ (self)",
+	"security_permission_all":          "Všichni (*)",
+	"security_profiles_title":          "Profily bezpečnostních hlaviček",
+	"security_profiles_hint":           "Pojmenované sady bezpečnostních hlaviček, každá generovaná jako snippet security_headers_<id>. Vyberte profil ve snippetech pravidla místo security_headers, aby mělo vlastní hlavičky.",
+	"security_profiles_add":            "Přidat profil",
+	"security_profiles_name":           "Název profilu",
+	"security_profiles_confirm_delete": "Smazat profil",
+
+	// CORS
+	"cors_title":            "CORS",
+	"cors_hint":             "Umožní prohlížečovým aplikacím z jiných originů volat tento web. Na preflight požadavky odpovídá Caddy kódem 204.",
+	"cors_origins":          "Povolené originy",
+	"cors_origins_hint":     "Jeden na řádek: https://app.example.com, https://*.example.com pro subdomény, nebo * pro libovolný origin. Prázdné pole CORS vypne.",
+	"cors_methods":          "Povolené metody",
+	"cors_headers":          "Povolené hlavičky",
+	"cors_max_age":          "Max-age (s)",
+	"cors_credentials":      "Povolit credentials",
+	"cors_credentials_hint": "Cookies a autorizační hlavičky; místo * se posílá origin požadavku. Preflight požadavky credentials nenesou, forward auth a basic auth proto potřebují výjimku.",
+
+	// Header rules
+	"headers_title":             "Hlavičky",
+	"headers_hint":              "Nastavení, přidání, smazání nebo přepis hlaviček odpovědi (header), požadavku na backend (header_up) nebo odpovědi backendu (header_down). Nahrazení bere regulární výraz a náhradu, např. ^http://(.*) → https://$1.",
+	"headers_target":            "Cíl",
+	"headers_target_response":   "Odpověď (header)",
+	"headers_target_upstream":   "Na backend (header_up)",
+	"headers_target_downstream": "Z backendu (header_down)",
+	"headers_operation":         "Operace",
+	"headers_operation_set":     "Nastavit",
+	"headers_operation_add":     "Přidat",
+	"headers_operation_delete":  "Smazat",
+	"headers_operation_replace": "Nahradit (regex)",
+	"headers_name":              "Hlavička",
+	"headers_value":             "Hodnota / regex",
+	"headers_replace":           "Náhrada",
+	"headers_add":               "Přidat pravidlo hlavičky",
+
+	// Maintenance mode
+	"maintenance_title":              "Údržba",
+	"maintenance_desc":               "Weby v údržbě odpovídají stránkou pages/503.html se stavem 503 a Retry-After. Okna míří na web nebo na všechny weby se štítkem a začínají i končí podle plánu, kontrolovaného každých 30 sekund.",
+	"maintenance_bypass_ips":         "IP s přístupem",
+	"maintenance_bypass_ips_hint":    "Jedna IP nebo CIDR na řádek; tito klienti vidí web jako obvykle.",
+	"maintenance_bypass_cookie":      "Cookie pro obejití",
+	"maintenance_bypass_cookie_hint": "Vygeneruje tajný odkaz, který nastaví cookie a návštěvníka na den pustí dál.",
+	"maintenance_bypass_link":        "Pro obejití údržby otevřete web s tímto parametrem:",
+	"maintenance_retry_after":        "Retry-After (s)",
+	"maintenance_retry_after_hint":   "Prázdné pro jednu hodinu.",
+	"maintenance_windows":            "Okna údržby",
+	"maintenance_no_windows":         "Žádná okna údržby.",
+	"maintenance_target":             "Cíl",
+	"maintenance_tags":               "Štítky",
+	"maintenance_sites":              "Weby",
+	"maintenance_start":              "Začátek",
+	"maintenance_start_hint":         "Prázdné začne hned.",
+	"maintenance_end":                "Konec",
+	"maintenance_end_hint":           "Prázdné trvá, dokud okno neodstraníte.",
+	"maintenance_until_removed":      "Do odstranění",
+	"maintenance_active":             "V údržbě",
+	"maintenance_scheduled":          "Naplánováno",
+	"maintenance_add_window":         "Přidat okno údržby",
+	"maintenance_confirm_delete":     "Odstranit toto okno údržby?",
+
+	// Disabled sites
+	"sites_disabled":      "Vypnuto",
+	"sites_enable":        "Zapnout",
+	"sites_disable":       "Vypnout",
+	"sites_disabled_hint": "Toto pravidlo je vypnuté: jeho soubor zůstává v sites/disabled a Caddy ho neobsluhuje.",
 }
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/network"
+)
+
+// AccessListSnippet denies the clients a site's access lists don't allow; it is
+// imported after the acl_<id> snippets, which only set variables
+const AccessListSnippet = "acl"
+
+// AccessListRouteSnippet repeats the acl check inside route handle blocks,
+// which Caddy tries before the acl snippet's own handle blocks
+const AccessListRouteSnippet = "acl_route"
+
+// AccessListSiteVar marks wildcard sites with their own access lists, which
+// replace the internal network restriction of the wildcard block
+const AccessListSiteVar = "site_acl"
+
+var accessListNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]*$`)
+
+// AccessList is a named set of networks sites can be restricted to, e.g. LAN, VPN or Office
+type AccessList struct {
+	ID    string   `json:"id" yaml:"id"` // Lowercase name, see AccessListID
+	Name  string   `json:"name" yaml:"name"`
+	Allow []string `json:"allow,omitempty" yaml:"allow,omitempty"` // IPs and CIDRs; empty allows every client not denied
+	Deny  []string `json:"deny,omitempty" yaml:"deny,omitempty"`   // IPs and CIDRs denied even if allowed
+}
+
+// AccessListID returns the ID of an access list name, e.g. "Home Office" → "home_office"
+func AccessListID(name string) string {
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// AccessListSnippetName returns the snippet of an access list
+func AccessListSnippetName(id string) string {
+	return AccessListSnippet + "_" + id
+}
+
+// DefaultAccessLists returns the lists of configurations saved before access
+// lists existed: a LAN list with the networks of the internal_only snippet
+func DefaultAccessLists(internalNetworks []string) []AccessList {
+	if len(internalNetworks) == 0 {
+		return []AccessList{}
+	}
+	return []AccessList{{ID: "lan", Name: "LAN", Allow: append([]string{}, internalNetworks...)}}
+}
+
+// Validate checks the name and networks of the access list
+func (a *AccessList) Validate() error {
+	if !accessListNameRe.MatchString(a.Name) {
+		return fmt.Errorf("access list name may only contain letters, digits, spaces, - and _")
+	}
+	if a.ID != AccessListID(a.Name) {
+		return fmt.Errorf("access list ID %q doesn't match its name %q", a.ID, a.Name)
+	}
+	if AccessListSnippetName(a.ID) == AccessListRouteSnippet {
+		return fmt.Errorf("access list name %q is reserved", a.Name)
+	}
+	if len(a.Allow) == 0 && len(a.Deny) == 0 {
+		return fmt.Errorf("access list %s has no networks", a.Name)
+	}
+	if _, err := network.Normalize(a.Allow); err != nil {
+		return fmt.Errorf("access list %s, allowed networks: %w", a.Name, err)
+	}
+	if _, err := network.Normalize(a.Deny); err != nil {
+		return fmt.Errorf("access list %s, denied networks: %w", a.Name, err)
+	}
+	return nil
+}
+
+// SnippetLines renders the acl_<id> snippet. Sites may import several lists,
+// so the snippet only records the result in variables: a client is allowed if
+// any list allows it, and denied if any list denies it.
+func (a *AccessList) SnippetLines() []string {
+	snippet := AccessListSnippetName(a.ID)
+
+	lines := []string{"(" + snippet + ") {"}
+	if len(a.Allow) > 0 {
+		lines = append(lines, "    vars acl_restricted yes")
+		lines = append(lines, fmt.Sprintf("    @%s_allowed client_ip %s", snippet, strings.Join(a.Allow, " ")))
+		lines = append(lines, fmt.Sprintf("    vars @%s_allowed acl_allowed yes", snippet))
+	}
+	if len(a.Deny) > 0 {
+		lines = append(lines, fmt.Sprintf("    @%s_denied client_ip %s", snippet, strings.Join(a.Deny, " ")))
+		lines = append(lines, fmt.Sprintf("    vars @%s_denied acl_denied yes", snippet))
+	}
+	return append(lines, "}")
+}
+
+// AccessListCheckLines renders the acl snippet answering 403 to the clients
+// denied by the access lists imported before it, and its route variant using
+// the matchers defined by the acl snippet
+func AccessListCheckLines() []string {
+	return []string{
+		"(" + AccessListSnippet + ") {",
+		"    @acl_denied vars acl_denied yes",
+		"    handle @acl_denied {",
+		"        error 403",
+		"    }",
+		"    @acl_outside {",
+		"        vars acl_restricted yes",
+		"        not vars acl_allowed yes",
+		"    }",
+		"    handle @acl_outside {",
+		"        error 403",
+		"    }",
+		"}",
+		"(" + AccessListRouteSnippet + ") {",
+		"    handle @acl_denied {",
+		"        error 403",
+		"    }",
+		"    handle @acl_outside {",
+		"        error 403",
+		"    }",
+		"}",
+	}
+}
+
+// accessListLines imports the site's access lists and the check denying the rest
+func (s *Site) accessListLines() []string {
+	if len(s.AccessLists) == 0 {
+		return nil
+	}
+	var lines []string
+	for _, id := range s.AccessLists {
+		lines = append(lines, "    import "+AccessListSnippetName(id))
+	}
+	return append(lines, "    import "+AccessListSnippet)
+}
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"crypto/x509"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -25,6 +30,117 @@ type Certificate struct {
 	FilePath     string            `json:"file_path"`
 	Status       CertificateStatus `json:"status"`
 	DaysLeft     int               `json:"days_left"`
+	CustomID     string            `json:"custom_id,omitempty"` // Set for uploaded certificates
+	Fingerprint  string            `json:"fingerprint"`         // SHA-256 of the leaf certificate
+	DNSNames     []string          `json:"dns_names"`
+}
+
+// CertificateDetail is the full view of a certificate used by the inspector
+type CertificateDetail struct {
+	*Certificate
+	Subject            string             `json:"subject"`
+	IPAddresses        []string           `json:"ip_addresses,omitempty"`
+	KeyAlgorithm       string             `json:"key_algorithm"` // e.g. "ECDSA P-256"
+	KeySize            int                `json:"key_size"`
+	SignatureAlgorithm string             `json:"signature_algorithm"`
+	Chain              []CertificateChain `json:"chain"`            // Leaf first
+	IssuerKey          string             `json:"issuer_key"`       // Storage directory of the issuer (e.g. "acme-v02.api.letsencrypt.org-directory")
+	IssuerDirectory    string             `json:"issuer_directory"` // ACME directory URL, if known
+	OCSPServers        []string           `json:"ocsp_servers,omitempty"`
+	OCSP               *OCSPStaple        `json:"ocsp,omitempty"`     // Staple cached by Caddy
+	Metadata           string             `json:"metadata,omitempty"` // Caddy's .json metadata (indented)
+	Sites              []string           `json:"sites"`              // Managed sites served by the certificate
+}
+
+// CertificateChain describes one certificate of a chain
+type CertificateChain struct {
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"not_after"`
+	Fingerprint string    `json:"fingerprint"`
+	IsCA        bool      `json:"is_ca"`
+}
+
+// OCSPStaple is the OCSP response Caddy staples to the certificate
+type OCSPStaple struct {
+	Status     string    `json:"status"` // "good", "revoked" or "unknown"
+	ProducedAt time.Time `json:"produced_at"`
+	NextUpdate time.Time `json:"next_update"`
+}
+
+// CertificateCoverage maps certificates to the managed sites they serve
+type CertificateCoverage struct {
+	Sites     map[string][]string `json:"sites"`     // Certificate fingerprint -> served sites
+	Unused    []*Certificate      `json:"unused"`    // Certificates serving no site
+	Uncovered []string            `json:"uncovered"` // Site domains without a valid certificate
+}
+
+// CustomCertificatesDir is the store directory inside the data volume
+const CustomCertificatesDir = "cpm-certificates"
+
+// CaddyDataDir is where the data volume is mounted in the Caddy container
+const CaddyDataDir = "/data"
+
+// CustomCertificate is an uploaded certificate (e.g., from a corporate PKI)
+type CustomCertificate struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	DNSNames     []string  `json:"dns_names"`
+	Issuer       string    `json:"issuer"`
+	NotBefore    time.Time `json:"not_before"`
+	NotAfter     time.Time `json:"not_after"`
+	SerialNumber string    `json:"serial_number"`
+	Fingerprint  string    `json:"fingerprint"` // SHA-256 of the leaf certificate
+	ChainLength  int       `json:"chain_length"`
+	UploadedAt   time.Time `json:"uploaded_at"`
+}
+
+// CaddyPaths returns the certificate and key paths as seen by Caddy
+func (c *CustomCertificate) CaddyPaths() (string, string) {
+	return CustomCertificateCaddyPaths(c.ID)
+}
+
+// customCertificateIDRe matches stored certificate IDs, the first 16 hex
+// digits of the SHA-256 fingerprint
+var customCertificateIDRe = regexp.MustCompile(`^[0-9a-f]{16}$`)
+
+// IsCustomCertificateID returns true if id has the format of a stored certificate ID
+func IsCustomCertificateID(id string) bool {
+	return customCertificateIDRe.MatchString(id)
+}
+
+// CustomCertificateCaddyPaths returns the certificate and key paths of a stored certificate as seen by Caddy
+func CustomCertificateCaddyPaths(id string) (string, string) {
+	dir := path.Join(CaddyDataDir, CustomCertificatesDir, id)
+	return path.Join(dir, "fullchain.pem"), path.Join(dir, "key.pem")
+}
+
+// IssuerKeyFor returns the directory name Caddy stores an ACME issuer's certificates under
+func IssuerKeyFor(directory string) string {
+	u, err := url.Parse(directory)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	key := u.Host
+	if p := strings.Trim(u.Path, "/"); p != "" {
+		key += "-" + strings.ReplaceAll(p, "/", "-")
+	}
+	return strings.ToLower(key)
+}
+
+// Serves returns true if the certificate is used for the site
+func (c *Certificate) Serves(site *Site) bool {
+	if site.IsCustomCertificate() || c.CustomID != "" {
+		return c.CustomID != "" && c.CustomID == site.CustomCertificateID()
+	}
+
+	leaf := &x509.Certificate{DNSNames: c.DNSNames}
+	for _, domain := range site.Domains {
+		if leaf.VerifyHostname(domain) == nil {
+			return true
+		}
+	}
+	return false
 }
 
 // UpdateStatus updates the certificate status based on expiration
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// CORS matchers: requests from an allowed origin, and their preflight requests
+const (
+	CORSOriginMatcher    = "@cors_origin"
+	CORSPreflightMatcher = "@cors_preflight"
+)
+
+// CORSPreflightVar is set for preflight requests, which browsers send without
+// credentials. The vars directive runs before basic_auth and forward_auth,
+// whose matchers let these requests through, see CORSPreflightExemption.
+const CORSPreflightVar = "cors_preflight"
+
+// CORSPreflightExemption excludes preflight requests from an authentication matcher
+const CORSPreflightExemption = "not vars " + CORSPreflightVar + " yes"
+
+// BasicAuthMatcher matches the requests basic_auth checks
+const BasicAuthMatcher = "@basic_auth"
+
+// corsSubdomainRe matches one subdomain label of a wildcard origin
+const corsSubdomainRe = `[a-z0-9-]+\.`
+
+var (
+	corsOriginRe = regexp.MustCompile(`^https?://(\*\.)?[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*(:\d{1,5})?$`)
+	corsMethodRe = regexp.MustCompile(`^[A-Z]+$`)
+	corsHeaderRe = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+)
+
+// CORS is the cross-origin resource sharing policy of a site
+type CORS struct {
+	Origins          []string `json:"origins" yaml:"origins"`                                         // Exact origins, wildcard subdomains like https://*.example.com, or * for any
+	Methods          []string `json:"methods,omitempty" yaml:"methods,omitempty"`                     // Access-Control-Allow-Methods
+	Headers          []string `json:"headers,omitempty" yaml:"headers,omitempty"`                     // Access-Control-Allow-Headers
+	AllowCredentials bool     `json:"allow_credentials,omitempty" yaml:"allow_credentials,omitempty"` // Cookies and authorization headers
+	MaxAge           int      `json:"max_age,omitempty" yaml:"max_age,omitempty"`                     // Seconds browsers cache the preflight, 0 for the browser default
+}
+
+// DefaultCORSMethods returns the methods offered for a new policy
+func DefaultCORSMethods() []string {
+	return []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+}
+
+// AnyOrigin returns true if every origin is allowed
+func (c *CORS) AnyOrigin() bool {
+	return len(c.Origins) == 1 && c.Origins[0] == "*"
+}
+
+// Validate checks the origins, methods, headers and max-age
+func (c *CORS) Validate() error {
+	if len(c.Origins) == 0 {
+		return fmt.Errorf("CORS needs at least one allowed origin")
+	}
+	for _, origin := range c.Origins {
+		if origin == "*" {
+			if len(c.Origins) > 1 {
+				return fmt.Errorf("the CORS origin * can't be combined with other origins")
+			}
+			continue
+		}
+		if !corsOriginRe.MatchString(origin) {
+			return fmt.Errorf("invalid CORS origin %q, use e.g. https://app.example.com or https://*.example.com", origin)
+		}
+	}
+	for _, method := range c.Methods {
+		if !corsMethodRe.MatchString(method) {
+			return fmt.Errorf("invalid CORS method %q", method)
+		}
+	}
+	for _, header := range c.Headers {
+		if !corsHeaderRe.MatchString(header) {
+			return fmt.Errorf("invalid CORS header %q", header)
+		}
+	}
+	if c.MaxAge < 0 {
+		return fmt.Errorf("CORS max-age can't be negative")
+	}
+	return nil
+}
+
+// OriginRegexp returns the regular expression matching the allowed origins
+func (c *CORS) OriginRegexp() string {
+	alternatives := make([]string, len(c.Origins))
+	for i, origin := range c.Origins {
+		scheme, host, _ := strings.Cut(origin, "://")
+		if rest, ok := strings.CutPrefix(host, "*."); ok {
+			alternatives[i] = scheme + "://" + corsSubdomainRe + regexp.QuoteMeta(rest)
+		} else {
+			alternatives[i] = scheme + "://" + regexp.QuoteMeta(host)
+		}
+	}
+	return "^(" + strings.Join(alternatives, "|") + ")$"
+}
+
+// CORSOrigins returns the origins of a regular expression built by OriginRegexp
+func CORSOrigins(expr string) []string {
+	expr = strings.TrimSuffix(strings.TrimPrefix(expr, "^("), ")$")
+	var origins []string
+	for _, alternative := range strings.Split(expr, "|") {
+		alternative = strings.Replace(alternative, corsSubdomainRe, "*.", 1)
+		origins = append(origins, strings.ReplaceAll(alternative, `\.`, "."))
+	}
+	return origins
+}
+
+// originMatcher returns the matcher of the Origin header, without the matcher name
+func (c *CORS) originMatcher() string {
+	if c.AnyOrigin() {
+		return "header Origin *"
+	}
+	return "header_regexp Origin " + c.OriginRegexp()
+}
+
+// PreflightLines renders the response to preflight requests, indented for
+// the block it goes into. Inside handle blocks respond runs before the proxy.
+func (c *CORS) PreflightLines(indent string) []string {
+	return []string{indent + "respond " + CORSPreflightMatcher + " 204"}
+}
+
+// Lines renders the matchers and headers of the policy. Any origin is
+// answered with *, unless credentials are allowed, which browsers only accept
+// with the request's own origin.
+func (c *CORS) Lines() []string {
+	lines := []string{
+		"    " + CORSOriginMatcher + " " + c.originMatcher(),
+		"    " + CORSPreflightMatcher + " {",
+		"        method OPTIONS",
+		"        " + c.originMatcher(),
+		"        header Access-Control-Request-Method *",
+		"    }",
+		"    vars " + CORSPreflightMatcher + " " + CORSPreflightVar + " yes",
+	}
+
+	// Deferred, so the policy replaces CORS headers set by the backend
+	lines = append(lines, "    header "+CORSOriginMatcher+" {")
+	if c.AnyOrigin() && !c.AllowCredentials {
+		lines = append(lines, `        Access-Control-Allow-Origin "*"`)
+	} else {
+		lines = append(lines, `        Access-Control-Allow-Origin "{http.request.header.Origin}"`)
+		lines = append(lines, "        +Vary Origin")
+	}
+	if c.AllowCredentials {
+		lines = append(lines, `        Access-Control-Allow-Credentials "true"`)
+	}
+	lines = append(lines, "        defer", "    }")
+
+	var preflight []string
+	if len(c.Methods) > 0 {
+		preflight = append(preflight, fmt.Sprintf("        Access-Control-Allow-Methods %q", strings.Join(c.Methods, ", ")))
+	}
+	if len(c.Headers) > 0 {
+		preflight = append(preflight, fmt.Sprintf("        Access-Control-Allow-Headers %q", strings.Join(c.Headers, ", ")))
+	}
+	if c.MaxAge > 0 {
+		preflight = append(preflight, fmt.Sprintf("        Access-Control-Max-Age %q", strconv.Itoa(c.MaxAge)))
+	}
+	if len(preflight) > 0 {
+		lines = append(lines, "    header "+CORSPreflightMatcher+" {")
+		lines = append(lines, preflight...)
+		lines = append(lines, "    }")
+	}
+
+	return lines
+}
@@ -0,0 +1,110 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var customSnippetNameRe = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// CustomSnippet is a user-defined snippet with a raw Caddyfile body, imported
+// by sites like the built-in snippets
+type CustomSnippet struct {
+	Name        string         `json:"name" yaml:"name"`
+	Description string         `json:"description,omitempty" yaml:"description,omitempty"`
+	Body        string         `json:"body" yaml:"body"`
+	Params      []SnippetParam `json:"params,omitempty" yaml:"params,omitempty"` // Passed as {args[0]}, {args[1]}, ...
+}
+
+// IsReservedSnippetName returns true for the names of the built-in and
+// generated snippets
+func IsReservedSnippetName(name string) bool {
+	for _, snippet := range KnownSnippets() {
+		if snippet.ID == name {
+			return true
+		}
+	}
+	switch name {
+	case ForwardAuthSnippet, ForwardAuthBypassSnippet, AccessListSnippet, MaintenanceSnippet, MaintenanceRouteSnippet, CloudflareDNSChallengeSnippet, InternalOnlyRouteSnippet:
+		return true
+	}
+	return strings.HasPrefix(name, AccessListSnippetName("")) ||
+		strings.HasPrefix(name, ClientCARevokedSnippet("")) ||
+		strings.HasPrefix(name, "wildcard-tls-") ||
+		strings.HasPrefix(name, SecurityHeadersProfileSnippet("")) ||
+		strings.HasSuffix(name, SnippetArgsSuffix)
+}
+
+// Validate checks the name, the parameters and that the braces of the body
+// are balanced
+func (s *CustomSnippet) Validate() error {
+	if !customSnippetNameRe.MatchString(s.Name) {
+		return fmt.Errorf("invalid snippet name %q: use lowercase letters, digits, - and _", s.Name)
+	}
+	if IsReservedSnippetName(s.Name) {
+		return fmt.Errorf("snippet name %s is reserved", s.Name)
+	}
+	if strings.TrimSpace(s.Body) == "" {
+		return fmt.Errorf("snippet %s has no body", s.Name)
+	}
+
+	depth := 0
+	for i, line := range strings.Split(s.Body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if depth == 0 && strings.HasPrefix(trimmed, "(") && strings.HasSuffix(trimmed, "{") {
+			return fmt.Errorf("snippet %s, line %d: snippets can't be defined inside a snippet", s.Name, i+1)
+		}
+		depth += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+		if depth < 0 {
+			return fmt.Errorf("snippet %s, line %d: unexpected }", s.Name, i+1)
+		}
+	}
+	if depth > 0 {
+		return fmt.Errorf("snippet %s: missing }", s.Name)
+	}
+
+	names := make(map[string]bool)
+	for _, param := range s.Params {
+		if err := param.Validate(); err != nil {
+			return fmt.Errorf("snippet %s: %w", s.Name, err)
+		}
+		if names[param.Name] {
+			return fmt.Errorf("snippet %s: duplicate parameter %s", s.Name, param.Name)
+		}
+		names[param.Name] = true
+	}
+	if max := maxArgsIndex(s.Body); max >= len(s.Params) {
+		return fmt.Errorf("snippet %s uses {args[%d]} but has %d parameters", s.Name, max, len(s.Params))
+	}
+	return nil
+}
+
+// SnippetLines renders the snippet definition for snippets.caddy
+func (s *CustomSnippet) SnippetLines() []string {
+	var lines []string
+	if s.Description != "" {
+		lines = append(lines, "# "+s.Description)
+	}
+
+	var body []string
+	for _, line := range strings.Split(strings.TrimRight(s.Body, "\n"), "\n") {
+		body = append(body, strings.TrimRight(line, " \t\r"))
+	}
+	if len(s.Params) > 0 {
+		return append(lines, ParamSnippetLines(s.Name, s.Params, body)...)
+	}
+
+	lines = append(lines, "("+s.Name+") {")
+	for _, line := range body {
+		if line == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, "    "+line)
+	}
+	return append(lines, "}")
+}
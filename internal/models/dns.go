@@ -0,0 +1,113 @@
+package models
+
+// DNSProviderField describes one credential or setting of a DNS provider
+type DNSProviderField struct {
+	Key         string `json:"key"`         // Caddy subdirective (e.g., "api_token")
+	Label       string `json:"label"`       // Form label
+	EnvVar      string `json:"env_var"`     // Default environment variable for secrets
+	Secret      bool   `json:"secret"`      // Can be read from the environment
+	Required    bool   `json:"required"`    // Must be set
+	Placeholder string `json:"placeholder"` // Form placeholder
+}
+
+// DNSProvider describes a caddy-dns module used for the DNS challenge
+type DNSProvider struct {
+	ID     string             `json:"id"`     // Module name used in "dns <id>"
+	Name   string             `json:"name"`   // Display name
+	Module string             `json:"module"` // Go module to build Caddy with
+	Inline bool               `json:"inline"` // First field can be passed as the only argument
+	Fields []DNSProviderField `json:"fields"`
+}
+
+// GetDNSProviders returns all supported DNS providers
+func GetDNSProviders() []DNSProvider {
+	return []DNSProvider{
+		{
+			ID:     "cloudflare",
+			Name:   "Cloudflare",
+			Module: "github.com/caddy-dns/cloudflare",
+			Inline: true,
+			Fields: []DNSProviderField{
+				{Key: "api_token", Label: "API Token", EnvVar: "CF_API_TOKEN", Secret: true, Required: true},
+				{Key: "zone_token", Label: "Zone Token (optional)", EnvVar: "CF_ZONE_TOKEN", Secret: true},
+			},
+		},
+		{
+			ID:     "route53",
+			Name:   "AWS Route53",
+			Module: "github.com/caddy-dns/route53",
+			Fields: []DNSProviderField{
+				{Key: "access_key_id", Label: "Access Key ID", EnvVar: "AWS_ACCESS_KEY_ID", Secret: true, Required: true},
+				{Key: "secret_access_key", Label: "Secret Access Key", EnvVar: "AWS_SECRET_ACCESS_KEY", Secret: true, Required: true},
+				{Key: "region", Label: "Region", Placeholder: "us-east-1"},
+				{Key: "hosted_zone_id", Label: "Hosted Zone ID (optional)", Placeholder: "Z1D633PJN98FT9"},
+			},
+		},
+		{
+			ID:     "digitalocean",
+			Name:   "DigitalOcean",
+			Module: "github.com/caddy-dns/digitalocean",
+			Inline: true,
+			Fields: []DNSProviderField{
+				{Key: "auth_token", Label: "API Token", EnvVar: "DO_AUTH_TOKEN", Secret: true, Required: true},
+			},
+		},
+		{
+			ID:     "hetzner",
+			Name:   "Hetzner",
+			Module: "github.com/caddy-dns/hetzner",
+			Inline: true,
+			Fields: []DNSProviderField{
+				{Key: "api_token", Label: "API Token", EnvVar: "HETZNER_API_TOKEN", Secret: true, Required: true},
+			},
+		},
+		{
+			ID:     "porkbun",
+			Name:   "Porkbun",
+			Module: "github.com/caddy-dns/porkbun",
+			Fields: []DNSProviderField{
+				{Key: "api_key", Label: "API Key", EnvVar: "PORKBUN_API_KEY", Secret: true, Required: true},
+				{Key: "api_secret_key", Label: "Secret API Key", EnvVar: "PORKBUN_API_SECRET_KEY", Secret: true, Required: true},
+			},
+		},
+		{
+			ID:     "duckdns",
+			Name:   "DuckDNS",
+			Module: "github.com/caddy-dns/duckdns",
+			Inline: true,
+			Fields: []DNSProviderField{
+				{Key: "api_token", Label: "Token", EnvVar: "DUCKDNS_API_TOKEN", Secret: true, Required: true},
+				{Key: "override_domain", Label: "Override Domain (optional)", Placeholder: "example.duckdns.org"},
+			},
+		},
+		{
+			ID:     "desec",
+			Name:   "deSEC",
+			Module: "github.com/caddy-dns/desec",
+			Fields: []DNSProviderField{
+				{Key: "token", Label: "Token", EnvVar: "DESEC_TOKEN", Secret: true, Required: true},
+			},
+		},
+		{
+			ID:     "rfc2136",
+			Name:   "RFC2136 (BIND, Knot, PowerDNS)",
+			Module: "github.com/caddy-dns/rfc2136",
+			Fields: []DNSProviderField{
+				{Key: "key_name", Label: "TSIG Key Name", Required: true, Placeholder: "caddy."},
+				{Key: "key_alg", Label: "TSIG Algorithm", Required: true, Placeholder: "hmac-sha256"},
+				{Key: "key", Label: "TSIG Secret", EnvVar: "RFC2136_KEY", Secret: true, Required: true},
+				{Key: "server", Label: "DNS Server", Required: true, Placeholder: "ns1.example.com:53"},
+			},
+		},
+	}
+}
+
+// GetDNSProviderByID returns a DNS provider by ID
+func GetDNSProviderByID(id string) *DNSProvider {
+	for _, p := range GetDNSProviders() {
+		if p.ID == id {
+			return &p
+		}
+	}
+	return nil
+}
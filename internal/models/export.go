@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// RulesExportVersion is the current version of the JSON rules export.
+// Version 1 was a bare array of rules without wildcard domains or snippet config.
+const RulesExportVersion = 2
+
+// RulesExport is the JSON document produced by the rules export
+type RulesExport struct {
+	Version         int              `json:"version"`
+	ExportedAt      time.Time        `json:"exported_at"`
+	Sites           []Site           `json:"sites"`
+	WildcardDomains []WildcardDomain `json:"wildcard_domains"`
+	SnippetConfig   *SnippetConfig   `json:"snippet_config,omitempty"`
+}
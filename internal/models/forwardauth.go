@@ -0,0 +1,195 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Forward auth providers
+const (
+	ForwardAuthDefault   = "default" // The global provider from the forward_auth snippet
+	ForwardAuthAuthelia  = "authelia"
+	ForwardAuthAuthentik = "authentik"
+	ForwardAuthOAuth2    = "oauth2-proxy"
+	ForwardAuthCustom    = "custom"
+)
+
+// Snippets of the global provider; the bypass variant takes the excluded paths as arguments
+const (
+	ForwardAuthSnippet       = "forward_auth"
+	ForwardAuthBypassSnippet = "forward_auth_bypass"
+)
+
+// ForwardAuthMatcher matches the requests that need authentication
+const ForwardAuthMatcher = "@forward_auth"
+
+// ForwardAuthPreset holds the defaults of an authentication provider
+type ForwardAuthPreset struct {
+	Upstream    string   `json:"upstream"`
+	URI         string   `json:"uri"`
+	CopyHeaders []string `json:"copy_headers"`
+	ProxyPaths  []string `json:"proxy_paths,omitempty"` // Provider endpoints on the site itself, proxied without authentication
+	SignIn      string   `json:"sign_in,omitempty"`     // Redirect for 401 responses
+}
+
+// ForwardAuthPresets returns the presets of the supported providers
+func ForwardAuthPresets() map[string]ForwardAuthPreset {
+	return map[string]ForwardAuthPreset{
+		ForwardAuthAuthelia: {
+			Upstream:    "http://authelia:9091",
+			URI:         "/api/authz/forward-auth",
+			CopyHeaders: []string{"Remote-User", "Remote-Groups", "Remote-Email", "Remote-Name"},
+		},
+		ForwardAuthAuthentik: {
+			Upstream: "http://authentik-server:9000",
+			URI:      "/outpost.goauthentik.io/auth/caddy",
+			CopyHeaders: []string{"X-Authentik-Username", "X-Authentik-Groups", "X-Authentik-Email", "X-Authentik-Name",
+				"X-Authentik-Uid", "X-Authentik-Jwt", "X-Authentik-Meta-Jwks", "X-Authentik-Meta-Outpost",
+				"X-Authentik-Meta-Provider", "X-Authentik-Meta-App", "X-Authentik-Meta-Version"},
+			ProxyPaths: []string{"/outpost.goauthentik.io/*"},
+		},
+		ForwardAuthOAuth2: {
+			Upstream:    "http://oauth2-proxy:4180",
+			URI:         "/oauth2/auth",
+			CopyHeaders: []string{"X-Auth-Request-User", "X-Auth-Request-Email", "Authorization"},
+			ProxyPaths:  []string{"/oauth2/*"},
+			SignIn:      "/oauth2/sign_in?rd={scheme}://{host}{uri}",
+		},
+	}
+}
+
+// ForwardAuthProviders returns the providers offered for sites
+func ForwardAuthProviders() []string {
+	return []string{ForwardAuthDefault, ForwardAuthAuthelia, ForwardAuthAuthentik, ForwardAuthOAuth2, ForwardAuthCustom}
+}
+
+// ForwardAuth delegates authentication of a site to an external provider
+type ForwardAuth struct {
+	Provider    string   `json:"provider" yaml:"provider"`                             // See ForwardAuthProviders
+	Upstream    string   `json:"upstream,omitempty" yaml:"upstream,omitempty"`         // Provider address, unused for the default provider
+	URI         string   `json:"uri,omitempty" yaml:"uri,omitempty"`                   // Verify endpoint
+	CopyHeaders []string `json:"copy_headers,omitempty" yaml:"copy_headers,omitempty"` // Identity headers passed to the backend
+	Bypass      []string `json:"bypass,omitempty" yaml:"bypass,omitempty"`             // Paths served without authentication, e.g. /api/webhook
+}
+
+// ForwardAuthConfig is the global default provider, imported by sites as the forward_auth snippet
+type ForwardAuthConfig struct {
+	Enabled     bool     `json:"enabled" yaml:"enabled"`
+	Provider    string   `json:"provider" yaml:"provider"`
+	Upstream    string   `json:"upstream" yaml:"upstream"`
+	URI         string   `json:"uri" yaml:"uri"`
+	CopyHeaders []string `json:"copy_headers" yaml:"copy_headers"`
+}
+
+// Settings returns the global provider as site settings
+func (c *ForwardAuthConfig) Settings() *ForwardAuth {
+	return &ForwardAuth{Provider: c.Provider, Upstream: c.Upstream, URI: c.URI, CopyHeaders: c.CopyHeaders}
+}
+
+// Validate checks the global provider
+func (c *ForwardAuthConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	if c.Provider == ForwardAuthDefault {
+		return fmt.Errorf("the global provider must be a preset or custom")
+	}
+	return c.Settings().Validate()
+}
+
+// ForwardAuthProvider returns the preset provider using the verify URI, or custom
+func ForwardAuthProvider(uri string) string {
+	for name, preset := range ForwardAuthPresets() {
+		if preset.URI == uri {
+			return name
+		}
+	}
+	return ForwardAuthCustom
+}
+
+// Preset returns the provider's preset; custom providers have none
+func (f *ForwardAuth) Preset() ForwardAuthPreset {
+	return ForwardAuthPresets()[f.Provider]
+}
+
+// Validate checks the forward auth settings
+func (f *ForwardAuth) Validate() error {
+	if !contains(ForwardAuthProviders(), f.Provider) {
+		return fmt.Errorf("unknown forward auth provider %q", f.Provider)
+	}
+	for _, path := range f.Bypass {
+		if !strings.HasPrefix(path, "/") || strings.ContainsAny(path, " \t\n{}") {
+			return fmt.Errorf("bypass path %q must start with / and contain no spaces", path)
+		}
+	}
+	if f.Provider == ForwardAuthDefault {
+		return nil
+	}
+
+	if f.Upstream == "" || strings.ContainsAny(f.Upstream, " \t\n{}") {
+		return fmt.Errorf("invalid forward auth upstream %q", f.Upstream)
+	}
+	if !strings.HasPrefix(f.URI, "/") || strings.ContainsAny(f.URI, " \t\n{}") {
+		return fmt.Errorf("forward auth URI must be a path, e.g. %s", ForwardAuthPresets()[ForwardAuthAuthelia].URI)
+	}
+	for _, header := range f.CopyHeaders {
+		if header == "" || strings.ContainsAny(header, " \t\n{}:") {
+			return fmt.Errorf("invalid header name %q", header)
+		}
+	}
+	return nil
+}
+
+// Lines renders the forward_auth directive. Paths excluded from authentication
+// get a not-path matcher; the provider's own endpoints are proxied to it.
+func (f *ForwardAuth) Lines() []string {
+	if f.Provider == ForwardAuthDefault {
+		if len(f.Bypass) == 0 {
+			return []string{"    import " + ForwardAuthSnippet}
+		}
+		return []string{"    import " + ForwardAuthBypassSnippet + " " + strings.Join(f.Bypass, " ")}
+	}
+
+	var lines []string
+	preset := f.Preset()
+
+	lines = append(lines, "    "+ForwardAuthMatcher+" {")
+	if excluded := append(append([]string{}, preset.ProxyPaths...), f.Bypass...); len(excluded) > 0 {
+		lines = append(lines, "        not path "+strings.Join(excluded, " "))
+	}
+	lines = append(lines, "        "+CORSPreflightExemption, "    }")
+
+	lines = append(lines, fmt.Sprintf("    forward_auth %s %s {", ForwardAuthMatcher, f.Upstream))
+	lines = append(lines, "        uri "+f.URI)
+	if len(f.CopyHeaders) > 0 {
+		lines = append(lines, "        copy_headers "+strings.Join(f.CopyHeaders, " "))
+	}
+	if preset.SignIn != "" {
+		lines = append(lines, "        @forward_auth_denied status 401")
+		lines = append(lines, "        handle_response @forward_auth_denied {")
+		lines = append(lines, "            redir * "+preset.SignIn)
+		lines = append(lines, "        }")
+	}
+	lines = append(lines, "    }")
+
+	for _, path := range preset.ProxyPaths {
+		lines = append(lines, "    handle "+path+" {")
+		lines = append(lines, "        reverse_proxy "+f.Upstream)
+		lines = append(lines, "    }")
+	}
+
+	return lines
+}
+
+// SnippetLines renders the forward_auth and forward_auth_bypass snippets of the
+// global provider
+func (c *ForwardAuthConfig) SnippetLines() []string {
+	settings := c.Settings()
+
+	lines := []string{"(" + ForwardAuthSnippet + ") {"}
+	lines = append(lines, settings.Lines()...)
+	lines = append(lines, "}", "", "("+ForwardAuthBypassSnippet+") {")
+	settings.Bypass = []string{"{args[:]}"}
+	lines = append(lines, settings.Lines()...)
+	return append(lines, "}")
+}
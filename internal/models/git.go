@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// GitCommit is a commit in the configuration repository
+type GitCommit struct {
+	Hash    string    `json:"hash"`
+	Message string    `json:"message"`
+	Author  string    `json:"author"`
+	When    time.Time `json:"when"`
+}
+
+// ShortHash returns the abbreviated commit hash
+func (c GitCommit) ShortHash() string {
+	if len(c.Hash) > 7 {
+		return c.Hash[:7]
+	}
+	return c.Hash
+}
+
+// GitConflict describes local and remote histories that have diverged
+type GitConflict struct {
+	Local      string    `json:"local"`
+	Remote     string    `json:"remote"`
+	Files      []string  `json:"files"` // files changed on both sides
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// GitStatus describes the state of the configuration repository
+type GitStatus struct {
+	Enabled   bool         `json:"enabled"`
+	Branch    string       `json:"branch"`
+	Remote    string       `json:"remote,omitempty"`
+	Head      string       `json:"head,omitempty"`
+	Dirty     bool         `json:"dirty"`
+	Commits   []GitCommit  `json:"commits"`
+	Conflict  *GitConflict `json:"conflict,omitempty"`
+	LastPush  time.Time    `json:"last_push,omitempty"`
+	LastPull  time.Time    `json:"last_pull,omitempty"`
+	LastError string       `json:"last_error,omitempty"`
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"path"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/network"
+)
+
+// DefaultAdminAddress is Caddy's admin API listener when the admin option is not set
+const DefaultAdminAddress = "localhost:2019"
+
+// GlobalOptions holds the global options block of the generated Caddyfile
+type GlobalOptions struct {
+	Email          string    `json:"email,omitempty" yaml:"email,omitempty"`                     // ACME account email
+	DefaultSNI     string    `json:"default_sni,omitempty" yaml:"default_sni,omitempty"`         // Server name for clients without SNI
+	Admin          string    `json:"admin,omitempty" yaml:"admin,omitempty"`                     // Admin API listener, empty for localhost:2019
+	GracePeriod    int       `json:"grace_period,omitempty" yaml:"grace_period,omitempty"`       // Seconds to finish requests on reload
+	TrustedProxies []string  `json:"trusted_proxies,omitempty" yaml:"trusted_proxies,omitempty"` // CIDR ranges or "private_ranges"
+	Protocols      []string  `json:"protocols,omitempty" yaml:"protocols,omitempty"`             // Empty for Caddy's default (h1 h2 h3)
+	Log            GlobalLog `json:"log" yaml:"log"`
+}
+
+// GlobalLog configures Caddy's default logger
+type GlobalLog struct {
+	Enabled bool   `json:"enabled" yaml:"enabled"`
+	Output  string `json:"output,omitempty" yaml:"output,omitempty"` // stdout, stderr or file
+	File    string `json:"file,omitempty" yaml:"file,omitempty"`     // Path inside the Caddy container when Output is file
+	Format  string `json:"format,omitempty" yaml:"format,omitempty"` // console or json
+	Level   string `json:"level,omitempty" yaml:"level,omitempty"`
+}
+
+// ServerProtocols returns the protocols the servers option accepts
+func ServerProtocols() []string {
+	return []string{"h1", "h2", "h2c", "h3"}
+}
+
+// LogOutputs returns the supported log outputs
+func LogOutputs() []string {
+	return []string{"stdout", "stderr", "file"}
+}
+
+// LogFormats returns the supported log encoders
+func LogFormats() []string {
+	return []string{"console", "json"}
+}
+
+// LogLevels returns the supported log levels
+func LogLevels() []string {
+	return []string{"DEBUG", "INFO", "WARN", "ERROR"}
+}
+
+// Validate checks the global options
+func (g *GlobalOptions) Validate() error {
+	if g.Email != "" {
+		if addr, err := mail.ParseAddress(g.Email); err != nil || addr.Address != g.Email {
+			return fmt.Errorf("invalid email %q", g.Email)
+		}
+	}
+	if g.DefaultSNI != "" && (strings.ContainsAny(g.DefaultSNI, " \t{}/:") || strings.Contains(g.DefaultSNI, "*")) {
+		return fmt.Errorf("invalid default SNI %q", g.DefaultSNI)
+	}
+	if g.Admin != "" {
+		// CPM reloads Caddy through the admin API
+		if g.Admin == "off" {
+			return fmt.Errorf("the admin API can't be turned off, CPM reloads Caddy through it")
+		}
+		if !strings.HasPrefix(g.Admin, "unix/") {
+			if _, port, err := net.SplitHostPort(g.Admin); err != nil || port == "" {
+				return fmt.Errorf("admin listener must be host:port or unix/<path>")
+			}
+		}
+	}
+	if g.GracePeriod < 0 {
+		return fmt.Errorf("invalid grace period %d", g.GracePeriod)
+	}
+	if _, err := NormalizeTrustedProxies(g.TrustedProxies); err != nil {
+		return err
+	}
+	for _, protocol := range g.Protocols {
+		if !contains(ServerProtocols(), protocol) {
+			return fmt.Errorf("unknown protocol %q", protocol)
+		}
+	}
+	return g.Log.Validate()
+}
+
+// NormalizeTrustedProxies validates the trusted proxies and returns the ranges
+// in canonical form; private_ranges is kept first
+func NormalizeTrustedProxies(proxies []string) ([]string, error) {
+	var ranges []string
+	private := false
+	for _, proxy := range proxies {
+		if proxy == "private_ranges" {
+			private = true
+			continue
+		}
+		ranges = append(ranges, proxy)
+	}
+
+	normalized, err := network.Normalize(ranges)
+	if err != nil {
+		return nil, fmt.Errorf("trusted proxies: %w", err)
+	}
+	if private {
+		normalized = append([]string{"private_ranges"}, normalized...)
+	}
+	return normalized, nil
+}
+
+// Validate checks the logger settings
+func (l *GlobalLog) Validate() error {
+	if !l.Enabled {
+		return nil
+	}
+	if l.Output != "" && !contains(LogOutputs(), l.Output) {
+		return fmt.Errorf("unknown log output %q", l.Output)
+	}
+	if l.Output == "file" && (!path.IsAbs(l.File) || strings.ContainsAny(l.File, " \t{}")) {
+		return fmt.Errorf("log file must be an absolute path inside the Caddy container")
+	}
+	if l.Format != "" && !contains(LogFormats(), l.Format) {
+		return fmt.Errorf("unknown log format %q", l.Format)
+	}
+	if l.Level != "" && !contains(LogLevels(), l.Level) {
+		return fmt.Errorf("unknown log level %q", l.Level)
+	}
+	return nil
+}
+
+// AdminAddress returns the admin listener set in the global options block of
+// a Caddyfile, or DefaultAdminAddress
+func AdminAddress(caddyfile string) string {
+	depth := 0
+	for _, line := range strings.Split(caddyfile, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if depth == 0 {
+			// The global options block must be the first one in the file
+			if len(fields) != 1 || fields[0] != "{" {
+				break
+			}
+			depth = 1
+			continue
+		}
+		if depth == 1 && fields[0] == "admin" && len(fields) > 1 && fields[1] != "off" && fields[1] != "{" {
+			return fields[1]
+		}
+		depth += strings.Count(line, "{") - strings.Count(line, "}")
+		if depth <= 0 {
+			break
+		}
+	}
+	return DefaultAdminAddress
+}
+
+// Lines renders the options inside the global block
+func (g *GlobalOptions) Lines() []string {
+	var lines []string
+
+	if g.Email != "" {
+		lines = append(lines, "email "+g.Email)
+	}
+	if g.DefaultSNI != "" {
+		lines = append(lines, "default_sni "+g.DefaultSNI)
+	}
+	if g.Admin != "" {
+		lines = append(lines, "admin "+g.Admin)
+	}
+	if g.GracePeriod > 0 {
+		lines = append(lines, fmt.Sprintf("grace_period %ds", g.GracePeriod))
+	}
+
+	if len(g.TrustedProxies) > 0 || len(g.Protocols) > 0 {
+		lines = append(lines, "servers {")
+		if len(g.TrustedProxies) > 0 {
+			lines = append(lines, "    trusted_proxies static "+strings.Join(g.TrustedProxies, " "))
+		}
+		if len(g.Protocols) > 0 {
+			lines = append(lines, "    protocols "+strings.Join(g.Protocols, " "))
+		}
+		lines = append(lines, "}")
+	}
+
+	if g.Log.Enabled {
+		var logLines []string
+		switch g.Log.Output {
+		case "file":
+			logLines = append(logLines, "    output file "+g.Log.File)
+		case "stdout", "stderr":
+			logLines = append(logLines, "    output "+g.Log.Output)
+		}
+		if g.Log.Format != "" {
+			logLines = append(logLines, "    format "+g.Log.Format)
+		}
+		if g.Log.Level != "" {
+			logLines = append(logLines, "    level "+g.Log.Level)
+		}
+		if len(logLines) == 0 {
+			lines = append(lines, "log")
+		} else {
+			lines = append(lines, "log {")
+			lines = append(lines, logLines...)
+			lines = append(lines, "}")
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,145 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Header rule targets: the response to the client, the request to the
+// backend and the backend's response
+const (
+	HeaderTargetResponse   = "response"   // header
+	HeaderTargetUpstream   = "upstream"   // header_up
+	HeaderTargetDownstream = "downstream" // header_down
+)
+
+// Header rule operations
+const (
+	HeaderSet     = "set"
+	HeaderAdd     = "add"
+	HeaderDelete  = "delete"
+	HeaderReplace = "replace" // Regular expression replacement in the values
+)
+
+var headerNameRe = regexp.MustCompile(`^[A-Za-z0-9!#$%&'*+.^_|~-]+$`)
+
+// HeaderRule sets, adds, deletes or rewrites a header
+type HeaderRule struct {
+	Target    string `json:"target" yaml:"target"`                       // See HeaderTargets
+	Operation string `json:"operation" yaml:"operation"`                 // See HeaderOperations
+	Name      string `json:"name" yaml:"name"`                           // Header field
+	Value     string `json:"value,omitempty" yaml:"value,omitempty"`     // New value, or the regular expression to replace
+	Replace   string `json:"replace,omitempty" yaml:"replace,omitempty"` // Replacement of the matches, may use $1
+}
+
+// HeaderTargets returns the targets offered in the header editor
+func HeaderTargets() []string {
+	return []string{HeaderTargetResponse, HeaderTargetUpstream, HeaderTargetDownstream}
+}
+
+// HeaderOperations returns the operations offered in the header editor
+func HeaderOperations() []string {
+	return []string{HeaderSet, HeaderAdd, HeaderDelete, HeaderReplace}
+}
+
+// Directive returns the Caddy directive of the rule's target
+func (r *HeaderRule) Directive() string {
+	switch r.Target {
+	case HeaderTargetUpstream:
+		return "header_up"
+	case HeaderTargetDownstream:
+		return "header_down"
+	}
+	return "header"
+}
+
+// Validate checks the target, operation, header name and values
+func (r *HeaderRule) Validate() error {
+	if !contains(HeaderTargets(), r.Target) {
+		return fmt.Errorf("unknown header target %q", r.Target)
+	}
+	if !contains(HeaderOperations(), r.Operation) {
+		return fmt.Errorf("unknown header operation %q", r.Operation)
+	}
+	if !headerNameRe.MatchString(r.Name) {
+		return fmt.Errorf("invalid header name %q", r.Name)
+	}
+	if strings.ContainsAny(r.Value+r.Replace, "\n\r") {
+		return fmt.Errorf("header %s: values must be a single line", r.Name)
+	}
+	switch r.Operation {
+	case HeaderSet, HeaderAdd:
+		if r.Value == "" {
+			return fmt.Errorf("header %s needs a value", r.Name)
+		}
+	case HeaderReplace:
+		if r.Value == "" {
+			return fmt.Errorf("header %s needs a regular expression to replace", r.Name)
+		}
+		if _, err := regexp.Compile(r.Value); err != nil {
+			return fmt.Errorf("header %s: invalid regular expression: %w", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// Line renders the arguments of the rule's directive, e.g. +X-Served-By "cpm"
+func (r *HeaderRule) Line() string {
+	switch r.Operation {
+	case HeaderDelete:
+		return "-" + r.Name
+	case HeaderAdd:
+		return "+" + r.Name + " " + quoteHeaderValue(r.Value)
+	case HeaderReplace:
+		return r.Name + " " + quoteHeaderValue(r.Value) + " " + quoteHeaderValue(r.Replace)
+	}
+	return r.Name + " " + quoteHeaderValue(r.Value)
+}
+
+// quoteHeaderValue quotes a value for the Caddyfile; only quotes are escaped,
+// so backslashes of regular expressions stay as they are
+func quoteHeaderValue(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// ValidateHeaderRules checks the site's header rules; header_up and
+// header_down need a reverse proxy
+func (s *Site) ValidateHeaderRules() error {
+	for i := range s.HeaderRules {
+		rule := &s.HeaderRules[i]
+		if err := rule.Validate(); err != nil {
+			return err
+		}
+		if rule.Target != HeaderTargetResponse && !s.IsProxy() {
+			return fmt.Errorf("%s needs a proxy site", rule.Directive())
+		}
+	}
+	return nil
+}
+
+// responseHeaderLines renders the response header rules as a header block
+func (s *Site) responseHeaderLines() []string {
+	var lines []string
+	for i := range s.HeaderRules {
+		if s.HeaderRules[i].Target == HeaderTargetResponse {
+			lines = append(lines, "        "+s.HeaderRules[i].Line())
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return append(append([]string{"    header {"}, lines...), "    }")
+}
+
+// proxyHeaderLines renders the header_up and header_down rules for the
+// reverse_proxy block
+func (s *Site) proxyHeaderLines() []string {
+	var lines []string
+	for i := range s.HeaderRules {
+		if rule := &s.HeaderRules[i]; rule.Target != HeaderTargetResponse {
+			lines = append(lines, "        "+rule.Directive()+" "+rule.Line())
+		}
+	}
+	return lines
+}
@@ -0,0 +1,138 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// Certificate issuers that can be selected for a site (TLSMode) or wildcard domain (Issuer)
+const (
+	IssuerDefault            = ""                    // Caddy default (Let's Encrypt with ZeroSSL fallback)
+	IssuerLetsEncrypt        = "letsencrypt"         // Let's Encrypt production
+	IssuerLetsEncryptStaging = "letsencrypt_staging" // Let's Encrypt staging (untrusted test certificates)
+	IssuerZeroSSL            = "zerossl"             // ZeroSSL ACME
+	IssuerACME               = "acme"                // Custom ACME directory (e.g., step-ca)
+	IssuerInternal           = "internal"            // Caddy's local CA, for LAN-only hosts
+)
+
+// ACME directory URLs of the built-in issuers
+const (
+	LetsEncryptDirectory        = "https://acme-v02.api.letsencrypt.org/directory"
+	LetsEncryptStagingDirectory = "https://acme-staging-v02.api.letsencrypt.org/directory"
+	ZeroSSLDirectory            = "https://acme.zerossl.com/v2/DV90"
+)
+
+// ACMESettings holds the ACME account settings used by an issuer
+type ACMESettings struct {
+	Email     string `json:"acme_email,omitempty" yaml:"acme_email,omitempty"`         // ACME account email
+	Directory string `json:"acme_directory,omitempty" yaml:"acme_directory,omitempty"` // Directory URL for the custom ACME issuer
+	EABKeyID  string `json:"eab_key_id,omitempty" yaml:"eab_key_id,omitempty"`         // External Account Binding key ID
+	EABMACKey string `json:"eab_mac_key,omitempty" yaml:"eab_mac_key,omitempty"`       // External Account Binding HMAC key
+}
+
+// Issuers returns the selectable issuers in display order
+func Issuers() []string {
+	return []string{
+		IssuerLetsEncrypt,
+		IssuerLetsEncryptStaging,
+		IssuerZeroSSL,
+		IssuerACME,
+		IssuerInternal,
+	}
+}
+
+// IsIssuer returns true if mode names an explicit certificate issuer
+func IsIssuer(mode string) bool {
+	for _, issuer := range Issuers() {
+		if mode == issuer {
+			return true
+		}
+	}
+	return false
+}
+
+// DirectoryFor returns the ACME directory URL used with an issuer
+func (a ACMESettings) DirectoryFor(issuer string) string {
+	switch issuer {
+	case IssuerLetsEncrypt:
+		return LetsEncryptDirectory
+	case IssuerLetsEncryptStaging:
+		return LetsEncryptStagingDirectory
+	case IssuerZeroSSL:
+		return ZeroSSLDirectory
+	case IssuerACME:
+		return a.Directory
+	}
+	return ""
+}
+
+// Validate checks that the settings required by an issuer are present
+func (a ACMESettings) Validate(issuer string) error {
+	if issuer != IssuerDefault && !IsIssuer(issuer) {
+		return fmt.Errorf("unknown certificate issuer %q", issuer)
+	}
+
+	if (a.EABKeyID == "") != (a.EABMACKey == "") {
+		return fmt.Errorf("external account binding needs both a key ID and a MAC key")
+	}
+
+	switch issuer {
+	case IssuerACME:
+		u, err := url.Parse(a.Directory)
+		if a.Directory == "" || err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("custom ACME issuer needs an https directory URL")
+		}
+	case IssuerZeroSSL:
+		// Caddy fetches EAB credentials for ZeroSSL using the account email
+		if a.Email == "" && a.EABKeyID == "" {
+			return fmt.Errorf("ZeroSSL needs an account email or EAB credentials")
+		}
+	}
+
+	return nil
+}
+
+// TLSBlock renders a tls directive for the issuer. dns holds optional DNS challenge
+// lines; they are dropped for the internal issuer, which doesn't use ACME.
+func (a ACMESettings) TLSBlock(issuer string, dns []string) []string {
+	return TLSDirectiveLines(a.TLSDirective(issuer, dns))
+}
+
+// TLSDirective returns the tls directive line and subdirectives for the issuer
+func (a ACMESettings) TLSDirective(issuer string, dns []string) (string, []string) {
+	if issuer == IssuerInternal {
+		return "tls internal", nil
+	}
+
+	var body []string
+	if dir := a.DirectoryFor(issuer); dir != "" {
+		body = append(body, "ca "+dir)
+	}
+	if a.EABKeyID != "" && a.EABMACKey != "" {
+		body = append(body, "eab "+a.EABKeyID+" "+a.EABMACKey)
+	}
+	body = append(body, dns...)
+
+	header := "tls"
+	if a.Email != "" {
+		header += " " + a.Email
+	}
+	return header, body
+}
+
+// TLSDirectiveLines renders a tls directive with optional subdirectives.
+// A bare "tls" without subdirectives renders nothing.
+func TLSDirectiveLines(header string, body []string) []string {
+	if len(body) == 0 {
+		if header == "tls" {
+			return nil
+		}
+		return []string{header}
+	}
+
+	lines := []string{header + " {"}
+	for _, line := range body {
+		lines = append(lines, "    "+line)
+	}
+	return append(lines, "}")
+}
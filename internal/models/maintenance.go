@@ -0,0 +1,183 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/network"
+)
+
+// Snippets of the maintenance mode: the site-level one defines the matcher,
+// the route one answers inside path route handle blocks, which Caddy tries
+// before other handle blocks
+const (
+	MaintenanceSnippet      = "maintenance"
+	MaintenanceRouteSnippet = "maintenance_route"
+)
+
+// MaintenanceMatcher matches the requests answered with the maintenance page
+const MaintenanceMatcher = "@maintenance"
+
+// MaintenanceCookie holds the bypass token; visiting a site with
+// ?cpm_maintenance=<token> sets it
+const MaintenanceCookie = "cpm_maintenance"
+
+// MaintenancePageCode is the status of the maintenance page, saved as pages/503.html
+const MaintenancePageCode = 503
+
+// DefaultRetryAfter is the Retry-After of the maintenance page in seconds
+const DefaultRetryAfter = 3600
+
+// maintenancePagesDir is the error pages directory as seen by Caddy
+const maintenancePagesDir = "/etc/caddy/pages"
+
+var maintenanceTokenRe = regexp.MustCompile(`^[A-Za-z0-9_-]{8,}$`)
+
+// MaintenanceWindow puts a site, or every site with a tag, into maintenance
+// mode. Without a start it begins immediately, without an end it lasts until
+// it is removed.
+type MaintenanceWindow struct {
+	ID    string    `json:"id" yaml:"id"`
+	Site  string    `json:"site,omitempty" yaml:"site,omitempty"` // A domain of the site
+	Tag   string    `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Start time.Time `json:"start,omitempty" yaml:"start,omitempty"`
+	End   time.Time `json:"end,omitempty" yaml:"end,omitempty"`
+}
+
+// MaintenanceConfig holds the maintenance windows and who may bypass them
+type MaintenanceConfig struct {
+	BypassIPs   []string            `json:"bypass_ips,omitempty" yaml:"bypass_ips,omitempty"`     // Client IPs and networks served normally
+	BypassToken string              `json:"bypass_token,omitempty" yaml:"bypass_token,omitempty"` // Value of the bypass cookie, empty disables it
+	RetryAfter  int                 `json:"retry_after,omitempty" yaml:"retry_after,omitempty"`   // Retry-After seconds, 0 for DefaultRetryAfter
+	Windows     []MaintenanceWindow `json:"windows,omitempty" yaml:"windows,omitempty"`
+}
+
+// Active returns true if the window is in effect at the given time
+func (w MaintenanceWindow) Active(now time.Time) bool {
+	return (w.Start.IsZero() || !now.Before(w.Start)) && (w.End.IsZero() || now.Before(w.End))
+}
+
+// Ended returns true if the window is over
+func (w MaintenanceWindow) Ended(now time.Time) bool {
+	return !w.End.IsZero() && !now.Before(w.End)
+}
+
+// Matches returns true if the window covers the site
+func (w MaintenanceWindow) Matches(site *Site) bool {
+	if w.Tag != "" {
+		return contains(site.Tags, w.Tag)
+	}
+	return contains(site.Domains, w.Site)
+}
+
+// Validate checks that the window has one target and ends after it starts
+func (w MaintenanceWindow) Validate() error {
+	if (w.Site == "") == (w.Tag == "") {
+		return fmt.Errorf("a maintenance window needs either a site or a tag")
+	}
+	if !w.Start.IsZero() && !w.End.IsZero() && !w.End.After(w.Start) {
+		return fmt.Errorf("a maintenance window must end after it starts")
+	}
+	return nil
+}
+
+// Validate checks the bypass settings and the windows
+func (c *MaintenanceConfig) Validate() error {
+	if _, err := network.Normalize(c.BypassIPs); err != nil {
+		return fmt.Errorf("maintenance bypass: %w", err)
+	}
+	if c.BypassToken != "" && !maintenanceTokenRe.MatchString(c.BypassToken) {
+		return fmt.Errorf("the bypass token needs at least 8 letters, digits, - or _")
+	}
+	if c.RetryAfter < 0 {
+		return fmt.Errorf("Retry-After can't be negative")
+	}
+	ids := make(map[string]bool)
+	for i := range c.Windows {
+		if err := c.Windows[i].Validate(); err != nil {
+			return err
+		}
+		if ids[c.Windows[i].ID] {
+			return fmt.Errorf("duplicate maintenance window %s", c.Windows[i].ID)
+		}
+		ids[c.Windows[i].ID] = true
+	}
+	return nil
+}
+
+// InMaintenance returns true if an active window covers the site
+func (c *MaintenanceConfig) InMaintenance(site *Site, now time.Time) bool {
+	for i := range c.Windows {
+		if c.Windows[i].Active(now) && c.Windows[i].Matches(site) {
+			return true
+		}
+	}
+	return false
+}
+
+// SiteWindows returns the windows covering the site, including scheduled ones
+func (c *MaintenanceConfig) SiteWindows(site *Site) []MaintenanceWindow {
+	var windows []MaintenanceWindow
+	for _, window := range c.Windows {
+		if window.Matches(site) {
+			windows = append(windows, window)
+		}
+	}
+	return windows
+}
+
+// pageLines renders the handle block answering with the maintenance page
+func (c *MaintenanceConfig) pageLines() []string {
+	retryAfter := c.RetryAfter
+	if retryAfter == 0 {
+		retryAfter = DefaultRetryAfter
+	}
+	return []string{
+		"    handle " + MaintenanceMatcher + " {",
+		fmt.Sprintf(`        header Retry-After "%d"`, retryAfter),
+		`        header Cache-Control "no-store"`,
+		"        root * " + maintenancePagesDir,
+		fmt.Sprintf("        rewrite * /%d.html", MaintenancePageCode),
+		"        file_server {",
+		fmt.Sprintf("            status %d", MaintenancePageCode),
+		"        }",
+		"    }",
+	}
+}
+
+// SnippetLines renders the maintenance snippets. Requests from the bypass
+// networks, with the bypass cookie or the bypass query aren't matched; the
+// query also sets the cookie.
+func (c *MaintenanceConfig) SnippetLines() []string {
+	var conditions []string
+	if len(c.BypassIPs) > 0 {
+		conditions = append(conditions, "not client_ip "+strings.Join(c.BypassIPs, " "))
+	}
+	if c.BypassToken != "" {
+		conditions = append(conditions,
+			fmt.Sprintf("not header Cookie *%s=%s*", MaintenanceCookie, c.BypassToken),
+			fmt.Sprintf("not query %s=%s", MaintenanceCookie, c.BypassToken))
+	}
+
+	lines := []string{"(" + MaintenanceSnippet + ") {"}
+	if len(conditions) == 0 {
+		lines = append(lines, "    "+MaintenanceMatcher+" path *")
+	} else {
+		lines = append(lines, "    "+MaintenanceMatcher+" {")
+		for _, condition := range conditions {
+			lines = append(lines, "        "+condition)
+		}
+		lines = append(lines, "    }")
+	}
+	if c.BypassToken != "" {
+		lines = append(lines,
+			fmt.Sprintf("    @maintenance_bypass query %s=%s", MaintenanceCookie, c.BypassToken),
+			fmt.Sprintf(`    header @maintenance_bypass Set-Cookie "%s=%s; Path=/; Max-Age=86400; HttpOnly; Secure; SameSite=Lax"`, MaintenanceCookie, c.BypassToken))
+	}
+	lines = append(lines, c.pageLines()...)
+	lines = append(lines, "}", "", "("+MaintenanceRouteSnippet+") {")
+	lines = append(lines, c.pageLines()...)
+	return append(lines, "}")
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client certificate verification modes (Caddy client_auth mode)
+const (
+	ClientAuthRequireAndVerify = "require_and_verify" // Reject connections without a valid client certificate
+	ClientAuthVerifyIfGiven    = "verify_if_given"    // Allow connections without a certificate, verify those that send one
+)
+
+// ClientCAsDir is the client CA store directory inside the data volume
+const ClientCAsDir = "cpm-mtls"
+
+// Caddy placeholders used by the allow-list matchers
+const (
+	clientSubjectPlaceholder = "{http.request.tls.client.subject}"
+	clientEmailPlaceholder   = "{http.request.tls.client.san.emails.0}"
+	clientDNSPlaceholder     = "{http.request.tls.client.san.dns_names.0}"
+	clientSerialPlaceholder  = "{http.request.tls.client.serial}"
+)
+
+// ClientAuth holds the mutual TLS settings of a site
+type ClientAuth struct {
+	Mode            string   `json:"mode" yaml:"mode"`                                             // See ClientAuthRequireAndVerify
+	CAID            string   `json:"ca_id" yaml:"ca_id"`                                           // Trusted CA bundle from the client CA store
+	AllowedSubjects []string `json:"allowed_subjects,omitempty" yaml:"allowed_subjects,omitempty"` // Full subjects, e.g. "CN=alice,O=Example"
+	AllowedSANs     []string `json:"allowed_sans,omitempty" yaml:"allowed_sans,omitempty"`         // Matched against the first email or DNS SAN
+}
+
+// ClientAuthModes returns the selectable verification modes
+func ClientAuthModes() []string {
+	return []string{ClientAuthRequireAndVerify, ClientAuthVerifyIfGiven}
+}
+
+// Validate checks the mutual TLS settings
+func (a *ClientAuth) Validate() error {
+	if a.Mode != ClientAuthRequireAndVerify && a.Mode != ClientAuthVerifyIfGiven {
+		return fmt.Errorf("unknown client certificate mode %q", a.Mode)
+	}
+	if a.CAID == "" {
+		return fmt.Errorf("client certificate authentication needs a trusted CA")
+	}
+	for _, value := range append(append([]string{}, a.AllowedSubjects...), a.AllowedSANs...) {
+		if strings.ContainsAny(value, "\"\n{}") {
+			return fmt.Errorf("invalid client certificate allow-list entry %q", value)
+		}
+	}
+	return nil
+}
+
+// TLSLines renders the client_auth subdirective of the tls block
+func (a *ClientAuth) TLSLines() []string {
+	return []string{
+		"client_auth {",
+		"    mode " + a.Mode,
+		"    trust_pool file " + ClientCACaddyPath(a.CAID),
+		"}",
+	}
+}
+
+// MatcherLines renders the allow-list check. A request is denied when its certificate
+// matches neither an allowed subject nor an allowed SAN.
+func (a *ClientAuth) MatcherLines() []string {
+	if len(a.AllowedSubjects) == 0 && len(a.AllowedSANs) == 0 {
+		return nil
+	}
+
+	lines := []string{"@mtls_denied {"}
+	if len(a.AllowedSubjects) > 0 {
+		lines = append(lines, "    not vars "+clientSubjectPlaceholder+" "+quoteAll(a.AllowedSubjects))
+	}
+	if len(a.AllowedSANs) > 0 {
+		lines = append(lines, "    not vars "+clientEmailPlaceholder+" "+quoteAll(a.AllowedSANs))
+		lines = append(lines, "    not vars "+clientDNSPlaceholder+" "+quoteAll(a.AllowedSANs))
+	}
+	lines = append(lines, "}", "error @mtls_denied 403")
+	return lines
+}
+
+// ClientCACaddyPath returns the trusted CA bundle path of a stored client CA as seen by Caddy
+func ClientCACaddyPath(id string) string {
+	return path.Join(CaddyDataDir, ClientCAsDir, id, "ca.pem")
+}
+
+// ClientCARevokedSnippet returns the name of the snippet rejecting revoked certificates of a CA
+func ClientCARevokedSnippet(id string) string {
+	return "mtls_revoked_" + id
+}
+
+// ClientCARevokedRouteSnippet returns the name of the revocation check imported
+// inside route handle blocks, which Caddy tries before the site's error directives
+func ClientCARevokedRouteSnippet(id string) string {
+	return ClientCARevokedSnippet(id) + "_route"
+}
+
+// ClientCA is a trusted client CA bundle. Managed CAs were created by CPM and
+// can issue client certificates; uploaded bundles are only trusted.
+type ClientCA struct {
+	ID        string              `json:"id"`
+	Name      string              `json:"name"`
+	Subject   string              `json:"subject"`
+	NotAfter  time.Time           `json:"not_after"`
+	Managed   bool                `json:"managed"`
+	CreatedAt time.Time           `json:"created_at"`
+	Issued    []ClientCertificate `json:"issued,omitempty"`
+}
+
+// ClientCertificate is a client certificate issued by a managed CA
+type ClientCertificate struct {
+	SerialNumber string     `json:"serial_number"` // Decimal, as in Caddy's serial placeholder
+	Subject      string     `json:"subject"`
+	CommonName   string     `json:"common_name"`
+	Email        string     `json:"email,omitempty"`
+	NotAfter     time.Time  `json:"not_after"`
+	IssuedAt     time.Time  `json:"issued_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+}
+
+// IsRevoked returns true if the certificate was revoked
+func (c *ClientCertificate) IsRevoked() bool {
+	return c.RevokedAt != nil
+}
+
+// RevokedSerials returns the serial numbers of revoked certificates
+func (ca *ClientCA) RevokedSerials() []string {
+	var serials []string
+	for _, cert := range ca.Issued {
+		if cert.IsRevoked() {
+			serials = append(serials, cert.SerialNumber)
+		}
+	}
+	return serials
+}
+
+// RevokedSnippet renders the snippet rejecting revoked certificates and its
+// route variant, which uses the matcher defined at site level. They are
+// generated for every CA in the store so sites can import them before anything
+// is revoked.
+func (ca *ClientCA) RevokedSnippet() []string {
+	lines := []string{fmt.Sprintf("(%s) {", ClientCARevokedSnippet(ca.ID))}
+	serials := ca.RevokedSerials()
+	if len(serials) > 0 {
+		lines = append(lines, "    @mtls_revoked vars "+clientSerialPlaceholder+" "+strings.Join(serials, " "))
+		lines = append(lines, "    error @mtls_revoked 403")
+	}
+	lines = append(lines, "}", fmt.Sprintf("(%s) {", ClientCARevokedRouteSnippet(ca.ID)))
+	if len(serials) > 0 {
+		lines = append(lines, "    error @mtls_revoked 403")
+	}
+	return append(lines, "}")
+}
+
+// quoteAll quotes values for use as Caddyfile arguments
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// OnDemandAskPath is the CPM endpoint Caddy asks before obtaining an on-demand certificate
+const OnDemandAskPath = "/tls/ask"
+
+// OnDemandTLSConfig holds the global on-demand TLS settings
+type OnDemandTLSConfig struct {
+	Enabled        bool     `json:"enabled" yaml:"enabled"`
+	AskURL         string   `json:"ask_url" yaml:"ask_url"`                 // CPM's ask endpoint as reachable from the Caddy container
+	AllowedDomains []string `json:"allowed_domains" yaml:"allowed_domains"` // Exact domains or patterns like *.customers.example.com
+}
+
+// Validate checks the on-demand TLS settings
+func (c *OnDemandTLSConfig) Validate() error {
+	if !c.Enabled {
+		return nil
+	}
+	u, err := url.Parse(c.AskURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("ask URL must be an http(s) URL reachable from Caddy")
+	}
+	for _, pattern := range c.AllowedDomains {
+		if strings.ContainsAny(pattern, " \t/{}") || strings.Contains(strings.TrimPrefix(pattern, "*."), "*") {
+			return fmt.Errorf("invalid domain pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+// GlobalLines returns the on_demand_tls global option
+func (c *OnDemandTLSConfig) GlobalLines() []string {
+	if !c.Enabled {
+		return nil
+	}
+	return []string{
+		"on_demand_tls {",
+		"    ask " + c.AskURL,
+		"}",
+	}
+}
+
+// Matches returns true if the domain is on the allow-list
+func (c *OnDemandTLSConfig) Matches(domain string) bool {
+	for _, pattern := range c.AllowedDomains {
+		if MatchDomainPattern(pattern, domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDomainPattern matches a domain against an exact name or a wildcard
+// pattern, where "*" stands for a single label as in certificates
+func MatchDomainPattern(pattern, domain string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if pattern == "" || domain == "" {
+		return false
+	}
+
+	suffix, ok := strings.CutPrefix(pattern, "*")
+	if !ok {
+		return pattern == domain
+	}
+	label, ok := strings.CutSuffix(domain, suffix)
+	return ok && label != "" && !strings.Contains(label, ".")
+}
+
+// SiteHost returns the host name of a site address, or "" for catch-all
+// addresses like "https://" or ":443"
+func SiteHost(address string) string {
+	host := address
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+	host, _, _ = strings.Cut(host, "/")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return host
+}
+
+// OnDemandCertificate is a certificate Caddy obtained on demand
+type OnDemandCertificate struct {
+	*Certificate
+	Site string `json:"site,omitempty"` // On-demand site serving it, empty when only allowed by a pattern
+}
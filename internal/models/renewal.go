@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// RateLimitWindow is the rolling window of the Let's Encrypt issuance limits
+const RateLimitWindow = 7 * 24 * time.Hour
+
+// RateLimit holds the issuance limits of an ACME issuer per RateLimitWindow
+type RateLimit struct {
+	PerDomain  int // Certificates per registered domain
+	Duplicates int // Certificates for the exact same set of names
+}
+
+// RateLimitFor returns the known limits of the issuer stored under issuerKey
+func RateLimitFor(issuerKey string) (RateLimit, bool) {
+	switch issuerKey {
+	case IssuerKeyFor(LetsEncryptDirectory):
+		return RateLimit{PerDomain: 50, Duplicates: 5}, true
+	case IssuerKeyFor(LetsEncryptStagingDirectory):
+		return RateLimit{PerDomain: 30000, Duplicates: 30000}, true
+	}
+	return RateLimit{}, false
+}
+
+// Issuance is a certificate issuance seen by CPM
+type Issuance struct {
+	Names            []string  `json:"names"`
+	RegisteredDomain string    `json:"registered_domain"`
+	IssuerKey        string    `json:"issuer_key"`
+	Fingerprint      string    `json:"fingerprint"`
+	IssuedAt         time.Time `json:"issued_at"`
+}
+
+// RenewalBudget is the remaining rate-limit budget for renewing a certificate
+type RenewalBudget struct {
+	RegisteredDomain string    `json:"registered_domain"`
+	Limited          bool      `json:"limited"` // The issuer has known limits
+	Issued           int       `json:"issued"`  // Certificates for the registered domain in the window
+	Limit            int       `json:"limit"`
+	Duplicates       int       `json:"duplicates"` // Certificates for the same names in the window
+	DuplicateLimit   int       `json:"duplicate_limit"`
+	ResetAt          time.Time `json:"reset_at,omitempty"` // When the oldest counted issuance leaves the window
+}
+
+// Allows returns true if another certificate can be requested
+func (b *RenewalBudget) Allows() bool {
+	return !b.Limited || (b.Issued < b.Limit && b.Duplicates < b.DuplicateLimit)
+}
+
+// Renewal job states
+const (
+	RenewalRunning  = "running"
+	RenewalRenewed  = "renewed"  // Caddy obtained a new certificate
+	RenewalRestored = "restored" // No new certificate in time, the previous files were restored
+	RenewalFailed   = "failed"
+)
+
+// RenewalJob tracks a certificate renewal
+type RenewalJob struct {
+	Domain      string     `json:"domain"`
+	Fingerprint string     `json:"fingerprint"` // Certificate being renewed
+	State       string     `json:"state"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	Log         []string   `json:"log"`
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/network"
+)
+
+// Route sends requests matching a path to its own backends. Routes are
+// rendered as handle blocks before the site's default backend; Caddy tries
+// more specific paths first, the list order only decides between equal ones.
+type Route struct {
+	Path        string   `json:"path" yaml:"path"`                                     // Path matcher, e.g. "/api/*"
+	StripPrefix bool     `json:"strip_prefix,omitempty" yaml:"strip_prefix,omitempty"` // handle_path: remove the matched prefix
+	Backends    []string `json:"backends" yaml:"backends"`                             // host:port or http(s)://host:port
+	LBPolicy    string   `json:"lb_policy,omitempty" yaml:"lb_policy,omitempty"`
+	Headers     []string `json:"headers,omitempty" yaml:"headers,omitempty"`   // header_up values, e.g. "X-Service api"
+	Snippets    []string `json:"snippets,omitempty" yaml:"snippets,omitempty"` // Snippets imported inside the route
+}
+
+// LBPolicies returns the load balancing policies offered for routes
+func LBPolicies() []string {
+	return []string{"random", "round_robin", "least_conn", "first", "ip_hash", "client_ip_hash", "uri_hash"}
+}
+
+// Directive returns "handle_path" when the prefix is stripped, otherwise "handle"
+func (r *Route) Directive() string {
+	if r.StripPrefix {
+		return "handle_path"
+	}
+	return "handle"
+}
+
+// Validate checks the route
+func (r *Route) Validate() error {
+	if !strings.HasPrefix(r.Path, "/") || strings.ContainsAny(r.Path, " \t\n{}") {
+		return fmt.Errorf("route path %q must start with / and contain no spaces", r.Path)
+	}
+	if len(r.Backends) == 0 {
+		return fmt.Errorf("route %s needs at least one backend", r.Path)
+	}
+	for _, backend := range r.Backends {
+		if _, err := network.Backend(backend); err != nil {
+			return fmt.Errorf("route %s: %w", r.Path, err)
+		}
+	}
+	if strings.ContainsAny(r.LBPolicy, " \t\n{}") {
+		return fmt.Errorf("route %s: invalid load balancing policy %q", r.Path, r.LBPolicy)
+	}
+	for _, header := range r.Headers {
+		// Placeholders like {remote_host} are allowed, unbalanced braces would break the block
+		if name, _, _ := strings.Cut(header, " "); name == "" || strings.Contains(header, "\n") ||
+			strings.Count(header, "{") != strings.Count(header, "}") {
+			return fmt.Errorf("route %s: invalid header %q", r.Path, header)
+		}
+	}
+	for _, snippet := range r.Snippets {
+		// The Cloudflare snippet sets the tls directive, which isn't allowed in a handle block
+		if snippet == "" || snippet == "cloudflare_dns" || strings.ContainsAny(snippet, " \t\n{}") {
+			return fmt.Errorf("route %s: snippet %q can't be used in a route", r.Path, snippet)
+		}
+	}
+	return nil
+}
+
+// Lines renders the route as a handle block, indented for the site block. The
+// site's header_up and header_down rules apply to the route's backends too.
+func (r *Route) Lines(headerRules []HeaderRule) []string {
+	lines := []string{fmt.Sprintf("    %s %s {", r.Directive(), r.Path)}
+
+	for _, snippet := range r.Snippets {
+		lines = append(lines, "        import "+snippet)
+	}
+
+	var proxyHeaders []string
+	for i := range headerRules {
+		if rule := &headerRules[i]; rule.Target != HeaderTargetResponse {
+			proxyHeaders = append(proxyHeaders, "            "+rule.Directive()+" "+rule.Line())
+		}
+	}
+
+	backends := strings.Join(r.Backends, " ")
+	if r.LBPolicy == "" && len(r.Headers) == 0 && len(proxyHeaders) == 0 {
+		lines = append(lines, "        reverse_proxy "+backends)
+	} else {
+		lines = append(lines, "        reverse_proxy "+backends+" {")
+		if r.LBPolicy != "" {
+			lines = append(lines, "            lb_policy "+r.LBPolicy)
+		}
+		for _, header := range r.Headers {
+			lines = append(lines, "            header_up "+header)
+		}
+		lines = append(lines, proxyHeaders...)
+		lines = append(lines, "        }")
+	}
+
+	return append(lines, "    }")
+}
+
+// ValidateRoutes checks the site's path routes
+func (s *Site) ValidateRoutes() error {
+	seen := make(map[string]bool)
+	for i := range s.Routes {
+		if err := s.Routes[i].Validate(); err != nil {
+			return err
+		}
+		if seen[s.Routes[i].Path] {
+			return fmt.Errorf("duplicate route %s", s.Routes[i].Path)
+		}
+		seen[s.Routes[i].Path] = true
+	}
+	return nil
+}
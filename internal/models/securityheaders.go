@@ -0,0 +1,263 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SecurityHeadersSnippet is the snippet of the global security headers;
+// profiles are generated as security_headers_<id>
+const SecurityHeadersSnippet = "security_headers"
+
+// Permissions-Policy allowlists
+const (
+	PermissionNone = "none" // feature=()
+	PermissionSelf = "self" // feature=(self)
+	PermissionAll  = "all"  // feature=*
+)
+
+var (
+	cspKeywords      = []string{"self", "none", "unsafe-inline", "unsafe-eval", "unsafe-hashes", "strict-dynamic", "wasm-unsafe-eval", "report-sample"}
+	cspSchemeRe      = regexp.MustCompile(`^[a-z][a-z0-9+.-]*:$`)
+	cspHostRe        = regexp.MustCompile(`^([a-z][a-z0-9+.-]*://)?(\*|(\*\.)?[A-Za-z0-9.-]+)(:(\d+|\*))?(/[^\s;,'"]*)?$`)
+	cspHashOrNonceRe = regexp.MustCompile(`^(nonce-[A-Za-z0-9+/_=-]+|sha(256|384|512)-[A-Za-z0-9+/_=-]+)$`)
+	profileNameRe    = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _-]*$`)
+)
+
+// ContentSecurityPolicy is a Content-Security-Policy built directive by directive
+type ContentSecurityPolicy struct {
+	Enabled                 bool           `json:"enabled" yaml:"enabled"`
+	ReportOnly              bool           `json:"report_only,omitempty" yaml:"report_only,omitempty"` // Sent as Content-Security-Policy-Report-Only
+	Directives              []CSPDirective `json:"directives,omitempty" yaml:"directives,omitempty"`
+	UpgradeInsecureRequests bool           `json:"upgrade_insecure_requests,omitempty" yaml:"upgrade_insecure_requests,omitempty"`
+	ReportURI               string         `json:"report_uri,omitempty" yaml:"report_uri,omitempty"`
+}
+
+// CSPDirective is a fetch or navigation directive with its source list
+type CSPDirective struct {
+	Name    string   `json:"name" yaml:"name"`
+	Sources []string `json:"sources" yaml:"sources"`
+}
+
+// SecurityHeadersProfile is a named set of security headers, generated as
+// its own snippet so sites can use other headers than the global ones
+type SecurityHeadersProfile struct {
+	ID      string                `json:"id" yaml:"id"` // Lowercase name, see AccessListID
+	Name    string                `json:"name" yaml:"name"`
+	Headers SecurityHeadersConfig `json:"headers" yaml:"headers"`
+}
+
+// CSPDirectives returns the directives offered by the CSP builder
+func CSPDirectives() []string {
+	return []string{
+		"default-src", "script-src", "style-src", "img-src", "font-src", "connect-src",
+		"media-src", "object-src", "frame-src", "worker-src", "manifest-src",
+		"frame-ancestors", "form-action", "base-uri",
+	}
+}
+
+// PermissionsPolicyFeatures returns the features offered as Permissions-Policy toggles
+func PermissionsPolicyFeatures() []string {
+	return []string{
+		"camera", "microphone", "geolocation", "payment", "usb", "fullscreen",
+		"autoplay", "display-capture", "clipboard-read", "clipboard-write",
+		"accelerometer", "gyroscope", "magnetometer", "browsing-topics",
+	}
+}
+
+// NormalizeCSPSources validates a source list and quotes keywords, hashes and
+// nonces, e.g. self → 'self'
+func NormalizeCSPSources(sources []string) ([]string, error) {
+	var normalized []string
+	for _, source := range sources {
+		bare := strings.Trim(source, "'")
+		switch {
+		case contains(cspKeywords, bare), cspHashOrNonceRe.MatchString(bare):
+			source = "'" + bare + "'"
+		case strings.Contains(source, "'"):
+			return nil, fmt.Errorf("unknown CSP keyword %s", source)
+		case !cspSchemeRe.MatchString(source) && !cspHostRe.MatchString(source):
+			return nil, fmt.Errorf("invalid CSP source %q", source)
+		}
+		if !contains(normalized, source) {
+			normalized = append(normalized, source)
+		}
+	}
+	if contains(normalized, "'none'") && len(normalized) > 1 {
+		return nil, fmt.Errorf("'none' can't be combined with other sources")
+	}
+	return normalized, nil
+}
+
+// Sources returns the source list of a directive
+func (p ContentSecurityPolicy) Sources(name string) []string {
+	for _, directive := range p.Directives {
+		if directive.Name == name {
+			return directive.Sources
+		}
+	}
+	return nil
+}
+
+// HeaderName returns the enforcing or report-only header name
+func (p ContentSecurityPolicy) HeaderName() string {
+	if p.ReportOnly {
+		return "Content-Security-Policy-Report-Only"
+	}
+	return "Content-Security-Policy"
+}
+
+// Value returns the policy, directives separated by semicolons
+func (p ContentSecurityPolicy) Value() string {
+	var parts []string
+	for _, directive := range p.Directives {
+		parts = append(parts, directive.Name+" "+strings.Join(directive.Sources, " "))
+	}
+	if p.UpgradeInsecureRequests {
+		parts = append(parts, "upgrade-insecure-requests")
+	}
+	if p.ReportURI != "" {
+		parts = append(parts, "report-uri "+p.ReportURI)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Validate checks the directives and their source lists
+func (p *ContentSecurityPolicy) Validate() error {
+	if !p.Enabled {
+		return nil
+	}
+	for _, directive := range p.Directives {
+		if !contains(CSPDirectives(), directive.Name) {
+			return fmt.Errorf("unknown CSP directive %s", directive.Name)
+		}
+		if len(directive.Sources) == 0 {
+			return fmt.Errorf("CSP directive %s has no sources", directive.Name)
+		}
+		if _, err := NormalizeCSPSources(directive.Sources); err != nil {
+			return fmt.Errorf("CSP %s: %w", directive.Name, err)
+		}
+	}
+	if p.ReportURI != "" && strings.ContainsAny(p.ReportURI, " ;,\"'") {
+		return fmt.Errorf("invalid CSP report URI %q", p.ReportURI)
+	}
+	if p.Value() == "" {
+		return fmt.Errorf("the Content-Security-Policy has no directives")
+	}
+	return nil
+}
+
+// PermissionsPolicyValue returns the Permissions-Policy header value, empty
+// when no feature is set
+func (h SecurityHeadersConfig) PermissionsPolicyValue() string {
+	var parts []string
+	for _, feature := range PermissionsPolicyFeatures() {
+		switch h.PermissionsPolicy[feature] {
+		case PermissionNone:
+			parts = append(parts, feature+"=()")
+		case PermissionSelf:
+			parts = append(parts, feature+"=(self)")
+		case PermissionAll:
+			parts = append(parts, feature+"=*")
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Validate checks HSTS preload, the CSP, the Permissions-Policy and the
+// cross-origin policies
+func (h *SecurityHeadersConfig) Validate() error {
+	if h.HSTSPreload && (h.HSTSMaxAge < 31536000 || !h.HSTSIncludeSubdomains) {
+		return fmt.Errorf("HSTS preload requires includeSubDomains and a max-age of at least 31536000")
+	}
+	if err := h.CSP.Validate(); err != nil {
+		return err
+	}
+	for feature, allow := range h.PermissionsPolicy {
+		if !contains(PermissionsPolicyFeatures(), feature) {
+			return fmt.Errorf("unknown Permissions-Policy feature %s", feature)
+		}
+		if allow != PermissionNone && allow != PermissionSelf && allow != PermissionAll {
+			return fmt.Errorf("invalid Permissions-Policy allowlist %q of %s", allow, feature)
+		}
+	}
+	policies := map[string][]string{
+		"Cross-Origin-Opener-Policy":   {"same-origin", "same-origin-allow-popups", "unsafe-none"},
+		"Cross-Origin-Embedder-Policy": {"require-corp", "credentialless", "unsafe-none"},
+		"Cross-Origin-Resource-Policy": {"same-origin", "same-site", "cross-origin"},
+	}
+	values := map[string]string{
+		"Cross-Origin-Opener-Policy":   h.CrossOriginOpenerPolicy,
+		"Cross-Origin-Embedder-Policy": h.CrossOriginEmbedderPolicy,
+		"Cross-Origin-Resource-Policy": h.CrossOriginResourcePolicy,
+	}
+	for header, value := range values {
+		if value != "" && !contains(policies[header], value) {
+			return fmt.Errorf("invalid %s %q", header, value)
+		}
+	}
+	return nil
+}
+
+// SnippetLines renders the headers as a snippet for snippets.caddy
+func (h *SecurityHeadersConfig) SnippetLines(name string) []string {
+	lines := []string{"(" + name + ") {", "    header {"}
+	add := func(header, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf(`        %s "%s"`, header, value))
+		}
+	}
+
+	if h.HSTSMaxAge > 0 {
+		hsts := fmt.Sprintf("max-age=%d", h.HSTSMaxAge)
+		if h.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if h.HSTSPreload {
+			hsts += "; preload"
+		}
+		add("Strict-Transport-Security", hsts)
+	}
+	if h.XContentTypeOptions {
+		add("X-Content-Type-Options", "nosniff")
+	}
+	add("X-Frame-Options", h.XFrameOptions)
+	add("Referrer-Policy", h.ReferrerPolicy)
+	if h.CSP.Enabled {
+		add(h.CSP.HeaderName(), h.CSP.Value())
+	}
+	add("Permissions-Policy", h.PermissionsPolicyValue())
+	add("Cross-Origin-Opener-Policy", h.CrossOriginOpenerPolicy)
+	add("Cross-Origin-Embedder-Policy", h.CrossOriginEmbedderPolicy)
+	add("Cross-Origin-Resource-Policy", h.CrossOriginResourcePolicy)
+	if h.HideServer {
+		lines = append(lines, "        -Server")
+	}
+
+	return append(lines, "    }", "}")
+}
+
+// SecurityHeadersProfileSnippet returns the snippet of a security headers profile
+func SecurityHeadersProfileSnippet(id string) string {
+	return SecurityHeadersSnippet + "_" + id
+}
+
+// Snippet returns the snippet sites import to use the profile
+func (p *SecurityHeadersProfile) Snippet() string {
+	return SecurityHeadersProfileSnippet(p.ID)
+}
+
+// Validate checks the name and headers of the profile
+func (p *SecurityHeadersProfile) Validate() error {
+	if !profileNameRe.MatchString(p.Name) {
+		return fmt.Errorf("profile name may only contain letters, digits, spaces, - and _")
+	}
+	if p.ID != AccessListID(p.Name) {
+		return fmt.Errorf("profile ID %q doesn't match its name %q", p.ID, p.Name)
+	}
+	if err := p.Headers.Validate(); err != nil {
+		return fmt.Errorf("profile %s: %w", p.Name, err)
+	}
+	return nil
+}
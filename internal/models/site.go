@@ -2,32 +2,53 @@ package models
 
 import (
 	"fmt"
+	"net"
 	"strings"
 	"time"
 )
 
 // Site represents a proxy rule
 type Site struct {
-	Filename           string    `json:"filename"`
-	Filepath           string    `json:"filepath"`
-	Domains            []string  `json:"domains"`
-	TargetIP           string    `json:"target_ip"`
-	TargetPort         string    `json:"target_port"`
-	IsHTTPSBackend     bool      `json:"is_https_backend"`
-	IsInternal         bool      `json:"is_internal"`
-	TLSMode            string    `json:"tls_mode"` // "auto" or "wildcard:domain.com"
-	Snippets           []string  `json:"snippets"`
-	Tags               []string  `json:"tags"`
-	AdditionalBackends []string  `json:"additional_backends"`
-	LBPolicy           string    `json:"lb_policy"`
-	EnableWebSocket    bool      `json:"enable_websocket"`
-	HealthCheckPath    string    `json:"health_check_path"`
-	TimeoutSeconds     int       `json:"timeout_seconds"`
-	BasicAuthEnabled   bool      `json:"basic_auth_enabled"`
-	BasicAuthUsers     []string  `json:"basic_auth_users"`
-	ExtraConfig        string    `json:"extra_config"`
-	RawContent         string    `json:"raw_content"`
-	ModifiedAt         time.Time `json:"modified_at"`
+	Filename           string    `json:"filename" yaml:"filename,omitempty"`
+	Filepath           string    `json:"filepath" yaml:"-"`
+	Domains            []string  `json:"domains" yaml:"domains"`
+	TargetIP           string    `json:"target_ip" yaml:"target_ip"`
+	TargetPort         string    `json:"target_port" yaml:"target_port"`
+	IsHTTPSBackend     bool      `json:"is_https_backend" yaml:"is_https_backend,omitempty"`
+	IsInternal         bool      `json:"is_internal" yaml:"is_internal,omitempty"`
+	TLSMode            string    `json:"tls_mode" yaml:"tls_mode,omitempty"` // "auto", "wildcard:domain.com", "custom:<id>" or an issuer (see Issuers)
+	Snippets           []string  `json:"snippets" yaml:"snippets,omitempty"`
+	Tags               []string  `json:"tags" yaml:"tags,omitempty"`
+	AdditionalBackends []string  `json:"additional_backends" yaml:"additional_backends,omitempty"`
+	LBPolicy           string    `json:"lb_policy" yaml:"lb_policy,omitempty"`
+	EnableWebSocket    bool      `json:"enable_websocket" yaml:"enable_websocket,omitempty"`
+	HealthCheckPath    string    `json:"health_check_path" yaml:"health_check_path,omitempty"`
+	TimeoutSeconds     int       `json:"timeout_seconds" yaml:"timeout_seconds,omitempty"`
+	BasicAuthEnabled   bool      `json:"basic_auth_enabled" yaml:"basic_auth_enabled,omitempty"`
+	BasicAuthUsers     []string  `json:"basic_auth_users" yaml:"basic_auth_users,omitempty"`
+	ExtraConfig        string    `json:"extra_config" yaml:"extra_config,omitempty"`
+	RawContent         string    `json:"raw_content" yaml:"-"`
+	ModifiedAt         time.Time `json:"modified_at" yaml:"-"`
+
+	SnippetArgs map[string][]string `json:"snippet_args,omitempty" yaml:"snippet_args,omitempty"` // Import arguments of parameterised snippets
+
+	ClientAuth *ClientAuth `json:"client_auth,omitempty" yaml:"client_auth,omitempty"` // Mutual TLS, standard sites only
+	OnDemand   bool        `json:"on_demand,omitempty" yaml:"on_demand,omitempty"`     // Obtain certificates during the TLS handshake
+	Routes     []Route     `json:"routes,omitempty" yaml:"routes,omitempty"`           // Path routes before the default backend
+
+	ForwardAuth *ForwardAuth `json:"forward_auth,omitempty" yaml:"forward_auth,omitempty"` // Authentication by Authelia, Authentik, oauth2-proxy, ...
+	AccessLists []string     `json:"access_lists,omitempty" yaml:"access_lists,omitempty"` // IDs of the access lists clients must pass
+	CORS        *CORS        `json:"cors,omitempty" yaml:"cors,omitempty"`                 // Cross-origin policy for APIs
+	HeaderRules []HeaderRule `json:"header_rules,omitempty" yaml:"header_rules,omitempty"` // Response, header_up and header_down changes
+	Maintenance bool         `json:"maintenance,omitempty" yaml:"-"`                       // Set by the maintenance windows
+	Disabled    bool         `json:"disabled,omitempty" yaml:"disabled,omitempty"`         // Kept in sites/disabled, which the Caddyfile doesn't import
+
+	Type     string            `json:"type,omitempty" yaml:"type,omitempty"` // proxy (default), redirect, static or respond
+	Redirect *RedirectSettings `json:"redirect,omitempty" yaml:"redirect,omitempty"`
+	Static   *StaticSettings   `json:"static,omitempty" yaml:"static,omitempty"`
+	Respond  *RespondSettings  `json:"respond,omitempty" yaml:"respond,omitempty"`
+
+	ACMESettings `yaml:",inline"` // Issuer settings when TLSMode is an issuer
 }
 
 // PrimaryDomain returns the first domain
@@ -38,6 +59,24 @@ func (s *Site) PrimaryDomain() string {
 	return s.Domains[0]
 }
 
+// SnippetArg returns the site's argument i of a parameterised snippet, empty
+// when the site uses the defaults
+func (s *Site) SnippetArg(snippet string, i int) string {
+	if args := s.SnippetArgs[snippet]; i < len(args) {
+		return args[i]
+	}
+	return ""
+}
+
+// snippetImport returns the import of a snippet with the site's arguments
+func (s *Site) snippetImport(snippet string) string {
+	line := "import " + snippet
+	for _, arg := range s.SnippetArgs[snippet] {
+		line += " " + caddyArg(arg)
+	}
+	return line
+}
+
 // DomainsString returns domains as comma-separated string
 func (s *Site) DomainsString() string {
 	return strings.Join(s.Domains, ", ")
@@ -48,19 +87,31 @@ func (s *Site) AccessIcon() string {
 	if s.BasicAuthEnabled {
 		return "🔐"
 	}
-	if s.IsInternal {
+	if s.IsInternal || len(s.AccessLists) > 0 {
 		return "🔒"
 	}
 	return "🌍"
 }
 
-// TargetURL returns the full target URL
+// TargetURL returns the full target URL, for other site types what the site serves
 func (s *Site) TargetURL() string {
+	switch {
+	case s.SiteType() == SiteTypeRedirect && s.Redirect != nil:
+		return "→ " + s.Redirect.Target(s.PrimaryDomain())
+	case s.SiteType() == SiteTypeStatic && s.Static != nil:
+		return s.Static.Root
+	case s.SiteType() == SiteTypeRespond && s.Respond != nil:
+		if s.Respond.Status != 0 {
+			return fmt.Sprintf("respond %d", s.Respond.Status)
+		}
+		return "respond 200"
+	}
+
 	protocol := "http"
 	if s.IsHTTPSBackend {
 		protocol = "https"
 	}
-	return fmt.Sprintf("%s://%s:%s", protocol, s.TargetIP, s.TargetPort)
+	return fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(s.TargetIP, s.TargetPort))
 }
 
 // AllBackends returns all backend URLs including main
@@ -70,7 +121,7 @@ func (s *Site) AllBackends() []string {
 		protocol = "https"
 	}
 
-	main := fmt.Sprintf("%s://%s:%s", protocol, s.TargetIP, s.TargetPort)
+	main := fmt.Sprintf("%s://%s", protocol, net.JoinHostPort(s.TargetIP, s.TargetPort))
 	backends := []string{main}
 
 	for _, backend := range s.AdditionalBackends {
@@ -98,6 +149,34 @@ func (s *Site) WildcardDomain() string {
 	return strings.TrimPrefix(s.TLSMode, "wildcard:")
 }
 
+// HasIssuer returns true if the site selects an explicit certificate issuer
+func (s *Site) HasIssuer() bool {
+	return IsIssuer(s.TLSMode)
+}
+
+// IsCustomCertificate returns true if site uses an uploaded certificate
+func (s *Site) IsCustomCertificate() bool {
+	return strings.HasPrefix(s.TLSMode, "custom:")
+}
+
+// CustomCertificateID returns the uploaded certificate ID (e.g., "3f2a..." from "custom:3f2a...")
+func (s *Site) CustomCertificateID() string {
+	if !s.IsCustomCertificate() {
+		return ""
+	}
+	return strings.TrimPrefix(s.TLSMode, "custom:")
+}
+
+// HasClientAuth returns true if the site requires or verifies client certificates
+func (s *Site) HasClientAuth() bool {
+	return s.ClientAuth != nil
+}
+
+// ManagesTLS returns true if the site file contains a generated tls directive
+func (s *Site) ManagesTLS() bool {
+	return s.HasIssuer() || s.IsCustomCertificate() || s.HasClientAuth() || s.OnDemand
+}
+
 // MatcherName returns a safe matcher name for the primary domain
 // e.g., "home.perteus.cz" -> "home_perteus_cz"
 func (s *Site) MatcherName() string {
@@ -125,29 +204,57 @@ func (s *Site) ToCaddyfileWildcard() string {
 		lines = append(lines, fmt.Sprintf("# @tags: %s", strings.Join(s.Tags, ", ")))
 	}
 	lines = append(lines, fmt.Sprintf("# @tls: %s", s.TLSMode))
+	if !s.IsProxy() {
+		lines = append(lines, fmt.Sprintf("# @type: %s", s.Type))
+	}
 	
 	// Matcher for this specific host
 	matcherName := s.MatcherName()
 	lines = append(lines, fmt.Sprintf("@%s host %s", matcherName, strings.Join(s.Domains, " ")))
 	
+	// Own access lists replace the internal network restriction of the wildcard block
+	if len(s.AccessLists) > 0 {
+		lines = append(lines, fmt.Sprintf("vars @%s %s yes", matcherName, AccessListSiteVar))
+	}
+	
 	// Handle block
 	lines = append(lines, fmt.Sprintf("handle @%s {", matcherName))
 	
+	// Maintenance mode
+	if s.Maintenance {
+		lines = append(lines, "    import "+MaintenanceSnippet)
+	}
+	
 	// Import snippets (except internal_only which is handled at wildcard block level)
 	for _, snippet := range s.Snippets {
 		if snippet != "" && snippet != "internal_only" && snippet != "cloudflare_dns" {
-			lines = append(lines, fmt.Sprintf("    import %s", snippet))
+			lines = append(lines, "    "+s.snippetImport(snippet))
 		}
 	}
 	
+	// Access lists
+	lines = append(lines, s.accessListLines()...)
+	
 	// Basic Auth
 	if s.BasicAuthEnabled && len(s.BasicAuthUsers) > 0 {
-		lines = append(lines, "    basic_auth {")
+		lines = append(lines, "    "+BasicAuthMatcher+" "+CORSPreflightExemption)
+		lines = append(lines, "    basic_auth "+BasicAuthMatcher+" {")
 		for _, userHash := range s.BasicAuthUsers {
 			lines = append(lines, fmt.Sprintf("        %s", userHash))
 		}
 		lines = append(lines, "    }")
 	}
+
+	// Forward auth
+	if s.ForwardAuth != nil {
+		lines = append(lines, s.ForwardAuth.Lines()...)
+	}
+	
+	// CORS
+	lines = append(lines, s.corsLines()...)
+	
+	// Response headers
+	lines = append(lines, s.responseHeaderLines()...)
 	
 	// Extra config
 	if extra := strings.TrimSpace(s.ExtraConfig); extra != "" {
@@ -156,8 +263,8 @@ func (s *Site) ToCaddyfileWildcard() string {
 		}
 	}
 	
-	// Reverse proxy (inline, not nested)
-	lines = append(lines, s.generateReverseProxyWildcard()...)
+	// Path routes and reverse proxy (inline, not nested), or the site type's handler
+	lines = append(lines, s.handlerLines(s.generateReverseProxy())...)
 	
 	lines = append(lines, "}")
 	
@@ -178,13 +285,31 @@ func (s *Site) ToCaddyfileStandard() string {
 		lines = append(lines, fmt.Sprintf("# @tls: %s", s.TLSMode))
 	}
 
+	// Site type as comment for parsing
+	if !s.IsProxy() {
+		lines = append(lines, fmt.Sprintf("# @type: %s", s.Type))
+	}
+
 	// Domain header
 	lines = append(lines, fmt.Sprintf("%s {", strings.Join(s.Domains, ", ")))
 
+	// Generated tls directive (issuer, uploaded certificate, on-demand, client certificates)
+	for _, line := range s.tlsLines() {
+		lines = append(lines, "    "+line)
+	}
+
+	// Maintenance mode
+	if s.Maintenance {
+		lines = append(lines, "    import "+MaintenanceSnippet)
+	}
+
 	// Import snippets
 	for _, snippet := range s.Snippets {
+		if snippet == "cloudflare_dns" && s.ManagesTLS() {
+			continue
+		}
 		if snippet != "" {
-			lines = append(lines, fmt.Sprintf("    import %s", snippet))
+			lines = append(lines, "    "+s.snippetImport(snippet))
 		}
 	}
 
@@ -193,15 +318,38 @@ func (s *Site) ToCaddyfileStandard() string {
 		lines = append(lines, "    import internal_only")
 	}
 
+	// Access lists
+	lines = append(lines, s.accessListLines()...)
+
+	// Client certificate revocation list and allow-list
+	if s.HasClientAuth() {
+		lines = append(lines, fmt.Sprintf("    import %s", ClientCARevokedSnippet(s.ClientAuth.CAID)))
+		for _, line := range s.ClientAuth.MatcherLines() {
+			lines = append(lines, "    "+line)
+		}
+	}
+
 	// Basic Auth
 	if s.BasicAuthEnabled && len(s.BasicAuthUsers) > 0 {
-		lines = append(lines, "    basic_auth {")
+		lines = append(lines, "    "+BasicAuthMatcher+" "+CORSPreflightExemption)
+		lines = append(lines, "    basic_auth "+BasicAuthMatcher+" {")
 		for _, userHash := range s.BasicAuthUsers {
 			lines = append(lines, fmt.Sprintf("        %s", userHash))
 		}
 		lines = append(lines, "    }")
 	}
 
+	// Forward auth
+	if s.ForwardAuth != nil {
+		lines = append(lines, s.ForwardAuth.Lines()...)
+	}
+
+	// CORS
+	lines = append(lines, s.corsLines()...)
+
+	// Response headers
+	lines = append(lines, s.responseHeaderLines()...)
+
 	// Extra config
 	if extra := strings.TrimSpace(s.ExtraConfig); extra != "" {
 		for _, line := range strings.Split(extra, "\n") {
@@ -209,74 +357,123 @@ func (s *Site) ToCaddyfileStandard() string {
 		}
 	}
 
-	// Reverse proxy
-	lines = append(lines, s.generateReverseProxy()...)
+	// Path routes and reverse proxy, or the site type's handler
+	lines = append(lines, s.handlerLines(s.generateReverseProxy())...)
 
 	lines = append(lines, "}")
 
 	return strings.Join(lines, "\n") + "\n"
 }
 
-func (s *Site) generateReverseProxy() []string {
-	var lines []string
-	backends := s.AllBackends()
-
-	// Simple proxy without extra settings
-	simpleProxy := len(backends) == 1 &&
-		!s.IsHTTPSBackend &&
-		!s.EnableWebSocket &&
-		s.HealthCheckPath == "" &&
-		s.TimeoutSeconds == 0
-
-	if simpleProxy {
-		lines = append(lines, fmt.Sprintf("    reverse_proxy %s:%s", s.TargetIP, s.TargetPort))
-		return lines
+// tlsLines renders the site's tls directive. The Cloudflare DNS challenge moves into
+// it through the challenge snippet, since a second tls directive from the
+// cloudflare_dns snippet would conflict.
+func (s *Site) tlsLines() []string {
+	var dns []string
+	if contains(s.Snippets, "cloudflare_dns") && !s.IsCustomCertificate() {
+		dns = append(dns, "import "+CloudflareDNSChallengeSnippet)
 	}
 
-	// Complex proxy
-	backendStr := strings.Join(backends, " ")
-	lines = append(lines, fmt.Sprintf("    reverse_proxy %s {", backendStr))
+	header, body := "tls", dns
+	switch {
+	case s.HasIssuer():
+		header, body = s.ACMESettings.TLSDirective(s.TLSMode, dns)
+	case s.IsCustomCertificate():
+		certPath, keyPath := CustomCertificateCaddyPaths(s.CustomCertificateID())
+		header = fmt.Sprintf("tls %s %s", certPath, keyPath)
+	case !s.HasClientAuth() && !s.OnDemand:
+		return nil
+	}
 
-	// Load balancing policy
-	if len(backends) > 1 && s.LBPolicy != "" {
-		lines = append(lines, fmt.Sprintf("        lb_policy %s", s.LBPolicy))
+	if s.OnDemand {
+		body = append(body, "on_demand")
 	}
+	if s.HasClientAuth() {
+		body = append(body, s.ClientAuth.TLSLines()...)
+	}
+	return TLSDirectiveLines(header, body)
+}
 
-	// Health check
-	if s.HealthCheckPath != "" {
-		lines = append(lines, fmt.Sprintf("        health_uri %s", s.HealthCheckPath))
-		lines = append(lines, "        health_interval 30s")
+// routedProxy places the path routes before the default backend, which moves
+// into a catch-all handle block when the site has routes. Every block repeats
+// the site's checks, see routeCheckLines.
+func (s *Site) routedProxy(proxy []string) []string {
+	if len(s.Routes) == 0 {
+		return proxy
 	}
 
-	// WebSocket headers
-	if s.EnableWebSocket {
-		lines = append(lines, "        header_up Host {host}")
-		lines = append(lines, "        header_up X-Real-IP {remote_host}")
-		lines = append(lines, "        header_up X-Forwarded-For {remote_host}")
-		lines = append(lines, "        header_up X-Forwarded-Proto {scheme}")
+	checks := s.routeCheckLines()
+	var lines []string
+	for i := range s.Routes {
+		route := s.Routes[i].Lines(s.HeaderRules)
+		lines = append(lines, route[0])
+		lines = append(lines, checks...)
+		lines = append(lines, s.corsPreflightLines()...)
+		lines = append(lines, route[1:]...)
 	}
+	lines = append(lines, "    handle {")
+	lines = append(lines, checks...)
+	lines = append(lines, s.corsPreflightLines()...)
+	for _, line := range proxy {
+		lines = append(lines, "    "+line)
+	}
+	return append(lines, "    }")
+}
 
-	// Transport settings
-	if s.IsHTTPSBackend || s.TimeoutSeconds > 0 {
-		lines = append(lines, "        transport http {")
-		if s.IsHTTPSBackend {
-			lines = append(lines, "            tls_insecure_skip_verify")
-		}
-		if s.TimeoutSeconds > 0 {
-			lines = append(lines, fmt.Sprintf("            dial_timeout %ds", s.TimeoutSeconds))
-			lines = append(lines, fmt.Sprintf("            response_header_timeout %ds", s.TimeoutSeconds))
+// routeCheckLines renders the checks repeated inside the route handle blocks.
+// Caddy tries path handle blocks before the site's other handle blocks and
+// error directives, so the maintenance page, the access lists, the internal
+// network restriction and the client certificate checks at site level never
+// run for routes. The route snippets reuse the matchers defined at site level.
+func (s *Site) routeCheckLines() []string {
+	var lines []string
+	if s.Maintenance {
+		lines = append(lines, "        import "+MaintenanceRouteSnippet)
+	}
+	if len(s.AccessLists) > 0 {
+		lines = append(lines, "        import "+AccessListRouteSnippet)
+	}
+	if s.IsWildcard() {
+		// The wildcard block restricts internal networks before the site's handle block
+		return lines
+	}
+	if s.IsInternal || contains(s.Snippets, "internal_only") {
+		lines = append(lines, "        import "+InternalOnlyRouteSnippet)
+	}
+	if s.HasClientAuth() {
+		lines = append(lines, "        import "+ClientCARevokedRouteSnippet(s.ClientAuth.CAID))
+		if len(s.ClientAuth.MatcherLines()) > 0 {
+			lines = append(lines, "        error @mtls_denied 403")
 		}
-		lines = append(lines, "        }")
 	}
+	return lines
+}
 
-	lines = append(lines, "    }")
-
+// corsLines renders the site's CORS policy. The preflight response goes into
+// the route handle blocks when there are routes, since respond is ordered
+// after handle.
+func (s *Site) corsLines() []string {
+	if s.CORS == nil {
+		return nil
+	}
+	lines := s.CORS.Lines()
+	if !s.IsProxy() || len(s.Routes) == 0 {
+		lines = append(lines, s.CORS.PreflightLines("    ")...)
+	}
 	return lines
 }
 
-// generateReverseProxyWildcard generates reverse proxy config for wildcard handle blocks
-// Uses different indentation since it's inside a handle block
-func (s *Site) generateReverseProxyWildcard() []string {
+// corsPreflightLines renders the preflight response inside a route handle block
+func (s *Site) corsPreflightLines() []string {
+	if s.CORS == nil {
+		return nil
+	}
+	return s.CORS.PreflightLines("        ")
+}
+
+// generateReverseProxy renders the default backend, the same for standard
+// sites and the handle blocks of wildcard sites
+func (s *Site) generateReverseProxy() []string {
 	var lines []string
 	backends := s.AllBackends()
 
@@ -285,10 +482,11 @@ func (s *Site) generateReverseProxyWildcard() []string {
 		!s.IsHTTPSBackend &&
 		!s.EnableWebSocket &&
 		s.HealthCheckPath == "" &&
-		s.TimeoutSeconds == 0
+		s.TimeoutSeconds == 0 &&
+		len(s.proxyHeaderLines()) == 0
 
 	if simpleProxy {
-		lines = append(lines, fmt.Sprintf("    reverse_proxy %s:%s", s.TargetIP, s.TargetPort))
+		lines = append(lines, "    reverse_proxy "+net.JoinHostPort(s.TargetIP, s.TargetPort))
 		return lines
 	}
 
@@ -315,6 +513,9 @@ func (s *Site) generateReverseProxyWildcard() []string {
 		lines = append(lines, "        header_up X-Forwarded-Proto {scheme}")
 	}
 
+	// Header rules
+	lines = append(lines, s.proxyHeaderLines()...)
+
 	// Transport settings
 	if s.IsHTTPSBackend || s.TimeoutSeconds > 0 {
 		lines = append(lines, "        transport http {")
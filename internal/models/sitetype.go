@@ -0,0 +1,277 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Site types
+const (
+	SiteTypeProxy    = "proxy"
+	SiteTypeRedirect = "redirect"
+	SiteTypeStatic   = "static"
+	SiteTypeRespond  = "respond"
+)
+
+// SiteTypes returns the supported site types
+func SiteTypes() []string {
+	return []string{SiteTypeProxy, SiteTypeRedirect, SiteTypeStatic, SiteTypeRespond}
+}
+
+// RedirectCodes returns the status codes offered for redirects
+func RedirectCodes() []string {
+	return []string{"permanent", "temporary", "307", "308"}
+}
+
+// StaticRoots are the Caddy container directories static sites may serve from.
+// The rest of /data holds certificates and keys, so only /data/www is allowed.
+var StaticRoots = []string{"/data/www/", "/usr/share/caddy/"}
+
+// respondMarker closes multi-line respond bodies (Caddyfile heredoc)
+const respondMarker = "BODY"
+
+// RedirectSettings configures a redirect site
+type RedirectSettings struct {
+	To           string `json:"to,omitempty" yaml:"to,omitempty"`                       // Target URL or domain, unused with Canonical
+	Code         string `json:"code,omitempty" yaml:"code,omitempty"`                   // permanent, temporary, 307 or 308; empty for Caddy's default (302)
+	PreservePath bool   `json:"preserve_path,omitempty" yaml:"preserve_path,omitempty"` // Append the request path and query
+	Canonical    string `json:"canonical,omitempty" yaml:"canonical,omitempty"`         // "apex" (www.example.com → example.com) or "www" (the reverse)
+}
+
+// StaticSettings configures a static file server site
+type StaticSettings struct {
+	Root   string   `json:"root" yaml:"root"` // Directory inside the Caddy container, see StaticRoots
+	Browse bool     `json:"browse,omitempty" yaml:"browse,omitempty"`
+	Index  []string `json:"index,omitempty" yaml:"index,omitempty"` // Empty for Caddy's default (index.html, index.txt)
+}
+
+// RespondSettings configures a site answering with a fixed response
+type RespondSettings struct {
+	Status      int    `json:"status,omitempty" yaml:"status,omitempty"` // Empty for 200
+	Body        string `json:"body,omitempty" yaml:"body,omitempty"`
+	ContentType string `json:"content_type,omitempty" yaml:"content_type,omitempty"`
+}
+
+// SiteType returns the site type, proxy when not set
+func (s *Site) SiteType() string {
+	if s.Type == "" {
+		return SiteTypeProxy
+	}
+	return s.Type
+}
+
+// IsProxy returns true for reverse proxy sites
+func (s *Site) IsProxy() bool {
+	return s.SiteType() == SiteTypeProxy
+}
+
+// ValidateType checks the settings of the site's type
+func (s *Site) ValidateType() error {
+	switch s.SiteType() {
+	case SiteTypeProxy:
+		return nil
+	case SiteTypeRedirect:
+		if s.Redirect == nil {
+			return fmt.Errorf("redirect settings are missing")
+		}
+		return s.Redirect.Validate(s.Domains)
+	case SiteTypeStatic:
+		if s.Static == nil {
+			return fmt.Errorf("static file settings are missing")
+		}
+		return s.Static.Validate()
+	case SiteTypeRespond:
+		if s.Respond == nil {
+			return fmt.Errorf("response settings are missing")
+		}
+		return s.Respond.Validate()
+	}
+	return fmt.Errorf("unknown site type %q", s.Type)
+}
+
+// handlerLines renders what the site serves: the reverse proxy with its path
+// routes, or the redirect, static files or fixed response of the other types
+func (s *Site) handlerLines(proxy []string) []string {
+	switch {
+	case s.SiteType() == SiteTypeRedirect && s.Redirect != nil:
+		return s.Redirect.Lines(s.PrimaryDomain())
+	case s.SiteType() == SiteTypeStatic && s.Static != nil:
+		return s.Static.Lines()
+	case s.SiteType() == SiteTypeRespond && s.Respond != nil:
+		return s.Respond.Lines()
+	}
+	return s.routedProxy(proxy)
+}
+
+// CanonicalHost returns the host the domain redirects to in a canonical
+// redirect, or "" if the domain already is canonical
+func CanonicalHost(domain, canonical string) string {
+	switch canonical {
+	case "apex":
+		if host, ok := strings.CutPrefix(domain, "www."); ok {
+			return host
+		}
+	case "www":
+		if !strings.HasPrefix(domain, "www.") {
+			return "www." + domain
+		}
+	}
+	return ""
+}
+
+// Target returns the redirect target for the site's primary domain
+func (r *RedirectSettings) Target(domain string) string {
+	target := r.To
+	if r.Canonical != "" {
+		target = "https://" + CanonicalHost(domain, r.Canonical)
+	} else if !strings.Contains(target, "://") && !strings.HasPrefix(target, "/") {
+		target = "https://" + target
+	}
+	if r.PreservePath {
+		target = strings.TrimSuffix(target, "/") + "{uri}"
+	}
+	return target
+}
+
+// Validate checks the redirect against the site's domains
+func (r *RedirectSettings) Validate(domains []string) error {
+	if r.Code != "" && !contains(RedirectCodes(), r.Code) {
+		return fmt.Errorf("unknown redirect code %q", r.Code)
+	}
+
+	if r.Canonical != "" {
+		if r.Canonical != "apex" && r.Canonical != "www" {
+			return fmt.Errorf("unknown canonical redirect %q", r.Canonical)
+		}
+		// Every domain must go to the same host, which must not be served by this rule
+		var host string
+		for _, domain := range domains {
+			target := CanonicalHost(domain, r.Canonical)
+			if target == "" {
+				return fmt.Errorf("%s already is the canonical name, remove it from this rule", domain)
+			}
+			if host != "" && target != host {
+				return fmt.Errorf("%s and %s redirect to different hosts, use one rule per domain", host, target)
+			}
+			host = target
+		}
+		return nil
+	}
+
+	if r.To == "" || strings.ContainsAny(r.To, " \t\n{}\"") {
+		return fmt.Errorf("invalid redirect target %q", r.To)
+	}
+	if strings.Contains(r.To, "://") {
+		if u, err := url.Parse(r.To); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("redirect target must be an http(s) URL, a domain or a path")
+		}
+	}
+	return nil
+}
+
+// Lines renders the redir directive
+func (r *RedirectSettings) Lines(domain string) []string {
+	line := "    redir " + r.Target(domain)
+	if r.Code != "" {
+		line += " " + r.Code
+	}
+	return []string{line}
+}
+
+// Validate checks the static file settings
+func (st *StaticSettings) Validate() error {
+	if !path.IsAbs(st.Root) || path.Clean(st.Root) != strings.TrimSuffix(st.Root, "/") || strings.ContainsAny(st.Root, " \t\n{}") {
+		return fmt.Errorf("root must be an absolute path without spaces")
+	}
+	inRoot := false
+	for _, root := range StaticRoots {
+		if strings.HasPrefix(path.Clean(st.Root)+"/", root) {
+			inRoot = true
+		}
+	}
+	if !inRoot {
+		return fmt.Errorf("root must be inside %s in the Caddy container", strings.Join(StaticRoots, " or "))
+	}
+	for _, index := range st.Index {
+		if index == "" || strings.ContainsAny(index, " \t\n{}/") {
+			return fmt.Errorf("invalid index file %q", index)
+		}
+	}
+	return nil
+}
+
+// Lines renders the root and file_server directives
+func (st *StaticSettings) Lines() []string {
+	lines := []string{"    root * " + st.Root}
+
+	switch {
+	case len(st.Index) > 0:
+		lines = append(lines, "    file_server {")
+		if st.Browse {
+			lines = append(lines, "        browse")
+		}
+		lines = append(lines, "        index "+strings.Join(st.Index, " "))
+		lines = append(lines, "    }")
+	case st.Browse:
+		lines = append(lines, "    file_server browse")
+	default:
+		lines = append(lines, "    file_server")
+	}
+
+	return lines
+}
+
+// Validate checks the fixed response
+func (r *RespondSettings) Validate() error {
+	if r.Status != 0 && (r.Status < 100 || r.Status > 599) {
+		return fmt.Errorf("invalid status code %d", r.Status)
+	}
+	if strings.ContainsAny(r.ContentType, "\"\n{}") {
+		return fmt.Errorf("invalid content type %q", r.ContentType)
+	}
+	for _, line := range strings.Split(r.Body, "\n") {
+		if strings.TrimSpace(line) == respondMarker || strings.HasPrefix(strings.TrimSpace(line), respondMarker+" ") {
+			return fmt.Errorf("the body can't contain a line starting with %s", respondMarker)
+		}
+	}
+	// The site file is parsed by counting braces
+	if strings.Count(r.Body, "{") != strings.Count(r.Body, "}") {
+		return fmt.Errorf("the body has unbalanced braces")
+	}
+	return nil
+}
+
+// Lines renders the respond directive; multi-line bodies use a heredoc
+func (r *RespondSettings) Lines() []string {
+	var lines []string
+	if r.ContentType != "" {
+		lines = append(lines, fmt.Sprintf("    header Content-Type \"%s\"", r.ContentType))
+	}
+
+	status := ""
+	if r.Status != 0 && r.Status != 200 {
+		status = " " + strconv.Itoa(r.Status)
+	}
+
+	body := strings.TrimRight(strings.ReplaceAll(r.Body, "\r\n", "\n"), "\n")
+	switch {
+	case body == "" && status == "":
+		lines = append(lines, "    respond 200")
+	case body == "":
+		lines = append(lines, "    respond"+status)
+	case !strings.Contains(body, "\n"):
+		lines = append(lines, "    respond \""+strings.ReplaceAll(body, "\"", "\\\"")+"\""+status)
+	default:
+		lines = append(lines, "    respond <<"+respondMarker)
+		for _, line := range strings.Split(body, "\n") {
+			// Caddy strips the closing marker's indentation from every line
+			lines = append(lines, "        "+line)
+		}
+		lines = append(lines, "        "+respondMarker+status)
+	}
+
+	return lines
+}
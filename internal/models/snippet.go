@@ -1,62 +1,102 @@
 package models
 
+import (
+	"fmt"
+
+	"github.com/TomasZmek/cpm/internal/network"
+)
+
 // SnippetConfig represents the configuration for all snippets
 type SnippetConfig struct {
-	CloudflareDNS   CloudflareDNSConfig   `json:"cloudflare_dns"`
-	InternalOnly    InternalOnlyConfig    `json:"internal_only"`
-	SecurityHeaders SecurityHeadersConfig `json:"security_headers"`
-	Compression     CompressionConfig     `json:"compression"`
-	RateLimit       RateLimitConfig       `json:"rate_limit"`
-	BasicAuth       BasicAuthConfig       `json:"basic_auth"`
+	CloudflareDNS   CloudflareDNSConfig   `json:"cloudflare_dns" yaml:"cloudflare_dns"`
+	InternalOnly    InternalOnlyConfig    `json:"internal_only" yaml:"internal_only"`
+	SecurityHeaders SecurityHeadersConfig `json:"security_headers" yaml:"security_headers"`
+	Compression     CompressionConfig     `json:"compression" yaml:"compression"`
+	RateLimit       RateLimitConfig       `json:"rate_limit" yaml:"rate_limit"`
+	BasicAuth       BasicAuthConfig       `json:"basic_auth" yaml:"basic_auth"`
+	ForwardAuth     ForwardAuthConfig     `json:"forward_auth" yaml:"forward_auth"`
+	AccessLists     []AccessList          `json:"access_lists" yaml:"access_lists"`
+	Custom          []CustomSnippet       `json:"custom,omitempty" yaml:"custom,omitempty"`
+
+	SecurityHeaderProfiles []SecurityHeadersProfile `json:"security_header_profiles,omitempty" yaml:"security_header_profiles,omitempty"`
+	Maintenance            MaintenanceConfig        `json:"maintenance" yaml:"maintenance"`
 }
 
+// InternalOnlyRouteSnippet answers 403 to the clients the internal_only snippet
+// denies; it is imported inside route handle blocks, which Caddy tries before
+// the snippet's own handle block
+const InternalOnlyRouteSnippet = "internal_only_route"
+
+// CloudflareDNSChallengeSnippet holds only the dns line of the Cloudflare DNS
+// challenge, imported inside the tls blocks of sites that manage TLS themselves
+const CloudflareDNSChallengeSnippet = "cloudflare_dns_challenge"
+
 // CloudflareDNSConfig holds Cloudflare DNS challenge settings
 type CloudflareDNSConfig struct {
-	Enabled  bool   `json:"enabled"`
-	UseEnv   bool   `json:"use_env"`   // Use CF_API_TOKEN env var
-	APIToken string `json:"api_token"` // Direct token (if not using env)
+	Enabled  bool   `json:"enabled" yaml:"enabled"`
+	UseEnv   bool   `json:"use_env" yaml:"use_env"`     // Use CF_API_TOKEN env var
+	APIToken string `json:"api_token" yaml:"api_token"` // Direct token (if not using env)
+}
+
+// DNSLine returns the dns directive for the configured token, or an empty
+// string if no token is set
+func (c CloudflareDNSConfig) DNSLine() string {
+	if c.UseEnv {
+		return "dns cloudflare {env.CF_API_TOKEN}"
+	}
+	if c.APIToken != "" {
+		return "dns cloudflare " + c.APIToken
+	}
+	return ""
 }
 
 // InternalOnlyConfig holds internal network restriction settings
 type InternalOnlyConfig struct {
-	Enabled         bool     `json:"enabled"`
-	AllowedNetworks []string `json:"allowed_networks"` // CIDR ranges
+	Enabled         bool     `json:"enabled" yaml:"enabled"`
+	AllowedNetworks []string `json:"allowed_networks" yaml:"allowed_networks"` // CIDR ranges
 }
 
 // SecurityHeadersConfig holds security headers settings
 type SecurityHeadersConfig struct {
-	Enabled               bool   `json:"enabled"`
-	HSTSMaxAge            int    `json:"hsts_max_age"`
-	HSTSIncludeSubdomains bool   `json:"hsts_include_subdomains"`
-	XContentTypeOptions   bool   `json:"x_content_type_options"`
-	XFrameOptions         string `json:"x_frame_options"` // DENY, SAMEORIGIN
-	ReferrerPolicy        string `json:"referrer_policy"`
-	HideServer            bool   `json:"hide_server"`
+	Enabled               bool   `json:"enabled" yaml:"enabled"`
+	HSTSMaxAge            int    `json:"hsts_max_age" yaml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool   `json:"hsts_include_subdomains" yaml:"hsts_include_subdomains"`
+	XContentTypeOptions   bool   `json:"x_content_type_options" yaml:"x_content_type_options"`
+	XFrameOptions         string `json:"x_frame_options" yaml:"x_frame_options"` // DENY, SAMEORIGIN
+	ReferrerPolicy        string `json:"referrer_policy" yaml:"referrer_policy"`
+	HideServer            bool   `json:"hide_server" yaml:"hide_server"`
+
+	HSTSPreload               bool                  `json:"hsts_preload,omitempty" yaml:"hsts_preload,omitempty"`
+	CSP                       ContentSecurityPolicy `json:"csp" yaml:"csp,omitempty"`
+	PermissionsPolicy         map[string]string     `json:"permissions_policy,omitempty" yaml:"permissions_policy,omitempty"` // Feature -> none, self or all
+	CrossOriginOpenerPolicy   string                `json:"cross_origin_opener_policy,omitempty" yaml:"cross_origin_opener_policy,omitempty"`
+	CrossOriginEmbedderPolicy string                `json:"cross_origin_embedder_policy,omitempty" yaml:"cross_origin_embedder_policy,omitempty"`
+	CrossOriginResourcePolicy string                `json:"cross_origin_resource_policy,omitempty" yaml:"cross_origin_resource_policy,omitempty"`
 }
 
 // CompressionConfig holds compression settings
 type CompressionConfig struct {
-	Enabled bool `json:"enabled"`
-	Zstd    bool `json:"zstd"`
-	Gzip    bool `json:"gzip"`
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	Zstd    bool `json:"zstd" yaml:"zstd"`
+	Gzip    bool `json:"gzip" yaml:"gzip"`
 }
 
 // RateLimitConfig holds rate limiting settings
 type RateLimitConfig struct {
-	Enabled    bool `json:"enabled"`
-	Requests   int  `json:"requests"`    // Requests per window
-	WindowSecs int  `json:"window_secs"` // Window in seconds
+	Enabled    bool `json:"enabled" yaml:"enabled"`
+	Requests   int  `json:"requests" yaml:"requests"`       // Requests per window
+	WindowSecs int  `json:"window_secs" yaml:"window_secs"` // Window in seconds
 }
 
 // BasicAuthConfig holds basic auth settings
 type BasicAuthConfig struct {
-	Enabled bool              `json:"enabled"`
-	Users   map[string]string `json:"users"` // username -> bcrypt hash
+	Enabled bool              `json:"enabled" yaml:"enabled"`
+	Users   map[string]string `json:"users" yaml:"users"` // username -> bcrypt hash
 }
 
 // DefaultSnippetConfig returns default configuration
 func DefaultSnippetConfig() *SnippetConfig {
-	return &SnippetConfig{
+	cfg := &SnippetConfig{
 		CloudflareDNS: CloudflareDNSConfig{
 			Enabled: true,
 			UseEnv:  true,
@@ -92,16 +132,25 @@ func DefaultSnippetConfig() *SnippetConfig {
 			Enabled: false,
 			Users:   make(map[string]string),
 		},
+		ForwardAuth: ForwardAuthConfig{
+			Enabled:     false,
+			Provider:    ForwardAuthAuthelia,
+			Upstream:    ForwardAuthPresets()[ForwardAuthAuthelia].Upstream,
+			URI:         ForwardAuthPresets()[ForwardAuthAuthelia].URI,
+			CopyHeaders: ForwardAuthPresets()[ForwardAuthAuthelia].CopyHeaders,
+		},
 	}
+	cfg.AccessLists = DefaultAccessLists(cfg.InternalOnly.AllowedNetworks)
+	return cfg
 }
 
 // Snippet represents a known snippet
 type Snippet struct {
-	ID          string `json:"id"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Icon        string `json:"icon"`
-	Enabled     bool   `json:"enabled"`
+	ID          string `json:"id" yaml:"id"`
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+	Icon        string `json:"icon" yaml:"icon"`
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
 }
 
 // KnownSnippets returns all known snippets
@@ -145,3 +194,81 @@ func KnownSnippets() []Snippet {
 		},
 	}
 }
+
+// AccessList returns the access list with the given ID
+func (c *SnippetConfig) AccessList(id string) *AccessList {
+	for i := range c.AccessLists {
+		if c.AccessLists[i].ID == id {
+			return &c.AccessLists[i]
+		}
+	}
+	return nil
+}
+
+// CustomSnippet returns the custom snippet with the given name
+func (c *SnippetConfig) CustomSnippet(name string) *CustomSnippet {
+	for i := range c.Custom {
+		if c.Custom[i].Name == name {
+			return &c.Custom[i]
+		}
+	}
+	return nil
+}
+
+// SecurityHeadersProfile returns the security headers profile with the given ID
+func (c *SnippetConfig) SecurityHeadersProfile(id string) *SecurityHeadersProfile {
+	for i := range c.SecurityHeaderProfiles {
+		if c.SecurityHeaderProfiles[i].ID == id {
+			return &c.SecurityHeaderProfiles[i]
+		}
+	}
+	return nil
+}
+
+// Validate checks the networks, access lists, security headers, forward auth
+// provider, maintenance settings and custom snippets
+func (c *SnippetConfig) Validate() error {
+	if _, err := network.Normalize(c.InternalOnly.AllowedNetworks); err != nil {
+		return fmt.Errorf("internal_only networks: %w", err)
+	}
+	ids := make(map[string]bool)
+	for _, list := range c.AccessLists {
+		if err := list.Validate(); err != nil {
+			return err
+		}
+		if ids[list.ID] {
+			return fmt.Errorf("duplicate access list %s", list.Name)
+		}
+		ids[list.ID] = true
+	}
+	if err := c.SecurityHeaders.Validate(); err != nil {
+		return err
+	}
+	profiles := make(map[string]bool)
+	for _, profile := range c.SecurityHeaderProfiles {
+		if err := profile.Validate(); err != nil {
+			return err
+		}
+		if profiles[profile.ID] {
+			return fmt.Errorf("duplicate security headers profile %s", profile.Name)
+		}
+		profiles[profile.ID] = true
+	}
+	if err := c.ForwardAuth.Validate(); err != nil {
+		return err
+	}
+	if err := c.Maintenance.Validate(); err != nil {
+		return err
+	}
+	names := make(map[string]bool)
+	for _, snippet := range c.Custom {
+		if err := snippet.Validate(); err != nil {
+			return err
+		}
+		if names[snippet.Name] {
+			return fmt.Errorf("duplicate snippet %s", snippet.Name)
+		}
+		names[snippet.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Snippet parameter types
+const (
+	SnippetParamInt      = "int"
+	SnippetParamDuration = "duration"
+	SnippetParamString   = "string"
+)
+
+// SnippetArgsSuffix names the snippet holding the body of a parameterised
+// snippet; the snippet itself imports it with the site's arguments followed
+// by the defaults
+const SnippetArgsSuffix = "_args"
+
+var (
+	snippetParamNameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	durationRe         = regexp.MustCompile(`^(\d+(\.\d+)?(ns|us|µs|ms|s|m|h|d))+$`)
+	argsPlaceholderRe  = regexp.MustCompile(`\{args\[(\d+)\]\}`)
+)
+
+// SnippetParam is a typed parameter of a snippet, passed as an import
+// argument and used in the snippet body as {args[N]}
+type SnippetParam struct {
+	Name        string `json:"name" yaml:"name"`
+	Type        string `json:"type" yaml:"type"` // int, duration or string
+	Default     string `json:"default" yaml:"default"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+}
+
+// SnippetParamTypes returns the supported parameter types
+func SnippetParamTypes() []string {
+	return []string{SnippetParamInt, SnippetParamDuration, SnippetParamString}
+}
+
+// Check validates a value of the parameter
+func (p *SnippetParam) Check(value string) error {
+	switch p.Type {
+	case SnippetParamInt:
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return fmt.Errorf("%s must be a whole number, got %q", p.Name, value)
+		}
+	case SnippetParamDuration:
+		if !durationRe.MatchString(value) {
+			return fmt.Errorf("%s must be a duration like 30s, 5m or 1h, got %q", p.Name, value)
+		}
+	case SnippetParamString:
+		if value == "" || strings.ContainsAny(value, "\n\r") {
+			return fmt.Errorf("%s must be a single line of text", p.Name)
+		}
+	default:
+		return fmt.Errorf("unknown type %q of parameter %s", p.Type, p.Name)
+	}
+	return nil
+}
+
+// Validate checks the name, type and default value
+func (p *SnippetParam) Validate() error {
+	if !snippetParamNameRe.MatchString(p.Name) {
+		return fmt.Errorf("invalid parameter name %q", p.Name)
+	}
+	return p.Check(p.Default)
+}
+
+// ParamSnippetLines renders a parameterised snippet. The defaults are passed
+// after the site's arguments, so a plain import uses the defaults and sites
+// overriding them pass all arguments.
+func ParamSnippetLines(name string, params []SnippetParam, body []string) []string {
+	lines := []string{"(" + name + SnippetArgsSuffix + ") {"}
+	for _, line := range body {
+		if line == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, "    "+line)
+	}
+	lines = append(lines, "}", "("+name+") {")
+
+	defaults := make([]string, len(params))
+	for i, param := range params {
+		defaults[i] = caddyArg(param.Default)
+	}
+	lines = append(lines, fmt.Sprintf("    import %s%s {args[:]} %s", name, SnippetArgsSuffix, strings.Join(defaults, " ")))
+	return append(lines, "}")
+}
+
+// caddyArg quotes a Caddyfile argument if needed
+func caddyArg(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\"") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+// maxArgsIndex returns the highest {args[N]} index used in a snippet body, -1
+// for none
+func maxArgsIndex(body string) int {
+	max := -1
+	for _, match := range argsPlaceholderRe.FindAllStringSubmatch(body, -1) {
+		if n, err := strconv.Atoi(match[1]); err == nil && n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// Params returns the parameters of the rate_limit snippet, with the
+// global settings as defaults
+func (c *RateLimitConfig) Params() []SnippetParam {
+	return []SnippetParam{
+		{Name: "requests", Type: SnippetParamInt, Default: strconv.Itoa(c.Requests), Description: "Requests per window"},
+		{Name: "window", Type: SnippetParamDuration, Default: fmt.Sprintf("%ds", c.WindowSecs), Description: "Window"},
+	}
+}
+
+// SnippetParams maps the names of parameterised snippets to their parameters
+func (c *SnippetConfig) SnippetParams() map[string][]SnippetParam {
+	params := map[string][]SnippetParam{
+		"rate_limit": c.RateLimit.Params(),
+	}
+	for _, snippet := range c.Custom {
+		if len(snippet.Params) > 0 {
+			params[snippet.Name] = snippet.Params
+		}
+	}
+	return params
+}
+
+// CheckSnippetArgs validates the import arguments of a site's snippet: none,
+// or one valid value per parameter
+func (c *SnippetConfig) CheckSnippetArgs(name string, args []string) error {
+	if len(args) == 0 {
+		return nil
+	}
+	params := c.SnippetParams()[name]
+	if len(params) == 0 {
+		return fmt.Errorf("snippet %s takes no arguments", name)
+	}
+	if len(args) != len(params) {
+		return fmt.Errorf("snippet %s takes %d arguments, got %d", name, len(params), len(args))
+	}
+	for i, param := range params {
+		if err := param.Check(args[i]); err != nil {
+			return fmt.Errorf("snippet %s: %w", name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// StateVersion is the current version of the declarative state file
+const StateVersion = 1
+
+// DesiredState is the declarative YAML state file used in GitOps mode.
+// Wildcard domains and snippets are only managed when present in the file.
+type DesiredState struct {
+	Version         int              `json:"version" yaml:"version"`
+	Sites           []Site           `json:"sites" yaml:"sites"`
+	WildcardDomains []WildcardDomain `json:"wildcard_domains,omitempty" yaml:"wildcard_domains,omitempty"`
+	Snippets        *SnippetConfig   `json:"snippets,omitempty" yaml:"snippets,omitempty"`
+}
+
+// StateAction is a single change needed to reach the desired state
+type StateAction struct {
+	Kind   string `json:"kind"`   // site, wildcard, snippets
+	Name   string `json:"name"`   // filename, wildcard domain or "snippets"
+	Action string `json:"action"` // create, update, delete
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+}
+
+// StatePlan lists the actions needed to reach the desired state
+type StatePlan struct {
+	Actions []StateAction `json:"actions"`
+	Create  int           `json:"create"`
+	Update  int           `json:"update"`
+	Delete  int           `json:"delete"`
+}
+
+// HasChanges returns true if the plan contains any action
+func (p *StatePlan) HasChanges() bool {
+	return len(p.Actions) > 0
+}
+
+// StateSyncStatus describes the last reconciliation of the watched state file
+type StateSyncStatus struct {
+	Enabled   bool      `json:"enabled"`
+	Path      string    `json:"path,omitempty"`
+	LastCheck time.Time `json:"last_check,omitempty"`
+	LastApply time.Time `json:"last_apply,omitempty"`
+	Applied   int       `json:"applied"`
+	Error     string    `json:"error,omitempty"`
+}
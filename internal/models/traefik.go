@@ -0,0 +1,141 @@
+package models
+
+// TraefikDynamicConfig is the subset of Traefik's dynamic configuration CPM converts
+type TraefikDynamicConfig struct {
+	HTTP *TraefikHTTPConfig `json:"http,omitempty" yaml:"http,omitempty" toml:"http,omitempty"`
+}
+
+// TraefikHTTPConfig holds HTTP routers, services and middlewares
+type TraefikHTTPConfig struct {
+	Routers           map[string]*TraefikRouter           `json:"routers,omitempty" yaml:"routers,omitempty" toml:"routers,omitempty"`
+	Services          map[string]*TraefikService          `json:"services,omitempty" yaml:"services,omitempty" toml:"services,omitempty"`
+	Middlewares       map[string]*TraefikMiddleware       `json:"middlewares,omitempty" yaml:"middlewares,omitempty" toml:"middlewares,omitempty"`
+	ServersTransports map[string]*TraefikServersTransport `json:"serversTransports,omitempty" yaml:"serversTransports,omitempty" toml:"serversTransports,omitempty"`
+}
+
+// TraefikRouter is an HTTP router
+type TraefikRouter struct {
+	Rule        string            `json:"rule" yaml:"rule" toml:"rule"`
+	EntryPoints []string          `json:"entryPoints,omitempty" yaml:"entryPoints,omitempty" toml:"entryPoints,omitempty"`
+	Service     string            `json:"service" yaml:"service" toml:"service"`
+	Middlewares []string          `json:"middlewares,omitempty" yaml:"middlewares,omitempty" toml:"middlewares,omitempty"`
+	Priority    int               `json:"priority,omitempty" yaml:"priority,omitempty" toml:"priority,omitempty,omitzero"`
+	TLS         *TraefikRouterTLS `json:"tls,omitempty" yaml:"tls,omitempty" toml:"tls,omitempty"`
+}
+
+// TraefikRouterTLS holds router TLS settings
+type TraefikRouterTLS struct {
+	CertResolver string             `json:"certResolver,omitempty" yaml:"certResolver,omitempty" toml:"certResolver,omitempty"`
+	Domains      []TraefikTLSDomain `json:"domains,omitempty" yaml:"domains,omitempty" toml:"domains,omitempty"`
+}
+
+// TraefikTLSDomain is a main domain with its SANs for certificate requests
+type TraefikTLSDomain struct {
+	Main string   `json:"main" yaml:"main" toml:"main"`
+	SANs []string `json:"sans,omitempty" yaml:"sans,omitempty" toml:"sans,omitempty"`
+}
+
+// TraefikService is an HTTP service
+type TraefikService struct {
+	LoadBalancer *TraefikLoadBalancer `json:"loadBalancer,omitempty" yaml:"loadBalancer,omitempty" toml:"loadBalancer,omitempty"`
+}
+
+// TraefikLoadBalancer holds load balancer settings
+type TraefikLoadBalancer struct {
+	Servers          []TraefikServer     `json:"servers" yaml:"servers" toml:"servers"`
+	HealthCheck      *TraefikHealthCheck `json:"healthCheck,omitempty" yaml:"healthCheck,omitempty" toml:"healthCheck,omitempty"`
+	Sticky           *TraefikSticky      `json:"sticky,omitempty" yaml:"sticky,omitempty" toml:"sticky,omitempty"`
+	ServersTransport string              `json:"serversTransport,omitempty" yaml:"serversTransport,omitempty" toml:"serversTransport,omitempty"`
+}
+
+// TraefikServer is a load balancer backend
+type TraefikServer struct {
+	URL string `json:"url" yaml:"url" toml:"url"`
+}
+
+// TraefikHealthCheck holds active health check settings
+type TraefikHealthCheck struct {
+	Path     string `json:"path" yaml:"path" toml:"path"`
+	Interval string `json:"interval,omitempty" yaml:"interval,omitempty" toml:"interval,omitempty"`
+}
+
+// TraefikSticky enables sticky sessions
+type TraefikSticky struct {
+	Cookie *TraefikStickyCookie `json:"cookie,omitempty" yaml:"cookie,omitempty" toml:"cookie,omitempty"`
+}
+
+// TraefikStickyCookie holds sticky cookie settings
+type TraefikStickyCookie struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty" toml:"name,omitempty"`
+}
+
+// TraefikServersTransport holds backend connection settings
+type TraefikServersTransport struct {
+	InsecureSkipVerify bool                       `json:"insecureSkipVerify,omitempty" yaml:"insecureSkipVerify,omitempty" toml:"insecureSkipVerify,omitempty"`
+	ForwardingTimeouts *TraefikForwardingTimeouts `json:"forwardingTimeouts,omitempty" yaml:"forwardingTimeouts,omitempty" toml:"forwardingTimeouts,omitempty"`
+}
+
+// TraefikForwardingTimeouts holds backend timeouts
+type TraefikForwardingTimeouts struct {
+	DialTimeout           string `json:"dialTimeout,omitempty" yaml:"dialTimeout,omitempty" toml:"dialTimeout,omitempty"`
+	ResponseHeaderTimeout string `json:"responseHeaderTimeout,omitempty" yaml:"responseHeaderTimeout,omitempty" toml:"responseHeaderTimeout,omitempty"`
+}
+
+// TraefikMiddleware is an HTTP middleware. Only the types CPM can map are modelled.
+type TraefikMiddleware struct {
+	BasicAuth   *TraefikBasicAuth   `json:"basicAuth,omitempty" yaml:"basicAuth,omitempty" toml:"basicAuth,omitempty"`
+	IPAllowList *TraefikIPAllowList `json:"ipAllowList,omitempty" yaml:"ipAllowList,omitempty" toml:"ipAllowList,omitempty"`
+	IPWhiteList *TraefikIPAllowList `json:"ipWhiteList,omitempty" yaml:"ipWhiteList,omitempty" toml:"ipWhiteList,omitempty"` // Traefik v2 name
+	Compress    *struct{}           `json:"compress,omitempty" yaml:"compress,omitempty" toml:"compress,omitempty"`
+	Headers     *TraefikHeaders     `json:"headers,omitempty" yaml:"headers,omitempty" toml:"headers,omitempty"`
+	RateLimit   *TraefikRateLimit   `json:"rateLimit,omitempty" yaml:"rateLimit,omitempty" toml:"rateLimit,omitempty"`
+}
+
+// TraefikBasicAuth holds htpasswd-style users ("user:hash")
+type TraefikBasicAuth struct {
+	Users []string `json:"users" yaml:"users" toml:"users"`
+}
+
+// TraefikIPAllowList holds allowed source ranges
+type TraefikIPAllowList struct {
+	SourceRange []string `json:"sourceRange" yaml:"sourceRange" toml:"sourceRange"`
+}
+
+// TraefikHeaders holds the security header options CPM maps
+type TraefikHeaders struct {
+	STSSeconds                      int               `json:"stsSeconds,omitempty" yaml:"stsSeconds,omitempty" toml:"stsSeconds,omitempty,omitzero"`
+	STSIncludeSubdomains            bool              `json:"stsIncludeSubdomains,omitempty" yaml:"stsIncludeSubdomains,omitempty" toml:"stsIncludeSubdomains,omitempty"`
+	ContentTypeNosniff              bool              `json:"contentTypeNosniff,omitempty" yaml:"contentTypeNosniff,omitempty" toml:"contentTypeNosniff,omitempty"`
+	FrameDeny                       bool              `json:"frameDeny,omitempty" yaml:"frameDeny,omitempty" toml:"frameDeny,omitempty"`
+	CustomFrameOptionsValue         string            `json:"customFrameOptionsValue,omitempty" yaml:"customFrameOptionsValue,omitempty" toml:"customFrameOptionsValue,omitempty"`
+	ReferrerPolicy                  string            `json:"referrerPolicy,omitempty" yaml:"referrerPolicy,omitempty" toml:"referrerPolicy,omitempty"`
+	STSPreload                      bool              `json:"stsPreload,omitempty" yaml:"stsPreload,omitempty" toml:"stsPreload,omitempty"`
+	ContentSecurityPolicy           string            `json:"contentSecurityPolicy,omitempty" yaml:"contentSecurityPolicy,omitempty" toml:"contentSecurityPolicy,omitempty"`
+	ContentSecurityPolicyReportOnly string            `json:"contentSecurityPolicyReportOnly,omitempty" yaml:"contentSecurityPolicyReportOnly,omitempty" toml:"contentSecurityPolicyReportOnly,omitempty"`
+	PermissionsPolicy               string            `json:"permissionsPolicy,omitempty" yaml:"permissionsPolicy,omitempty" toml:"permissionsPolicy,omitempty"`
+	CustomResponseHeaders           map[string]string `json:"customResponseHeaders,omitempty" yaml:"customResponseHeaders,omitempty" toml:"customResponseHeaders,omitempty"`
+}
+
+// TraefikRateLimit holds rate limit settings
+type TraefikRateLimit struct {
+	Average int    `json:"average" yaml:"average" toml:"average"`
+	Period  string `json:"period,omitempty" yaml:"period,omitempty" toml:"period,omitempty"`
+	Burst   int    `json:"burst,omitempty" yaml:"burst,omitempty" toml:"burst,omitempty,omitzero"`
+}
+
+// TraefikMappingIssue describes a field that could not be converted
+type TraefikMappingIssue struct {
+	Site    string `json:"site"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// TraefikMappingReport lists everything that was lost or approximated in a conversion
+type TraefikMappingReport struct {
+	Issues []TraefikMappingIssue `json:"issues"`
+}
+
+// Add records a mapping issue
+func (r *TraefikMappingReport) Add(site, field, message string) {
+	r.Issues = append(r.Issues, TraefikMappingIssue{Site: site, Field: field, Message: message})
+}
@@ -2,13 +2,46 @@ package models
 
 // WildcardDomain represents a wildcard SSL certificate configuration
 type WildcardDomain struct {
-	Domain   string `json:"domain"`   // Base domain (e.g., "example.com")
-	Provider string `json:"provider"` // DNS provider (e.g., "cloudflare")
-	UseEnv   bool   `json:"use_env"`  // Use environment variable for API token
-	APIToken string `json:"api_token,omitempty"` // API token (if not using env)
+	Domain      string            `json:"domain" yaml:"domain"`                               // Base domain (e.g., "example.com")
+	Provider    string            `json:"provider" yaml:"provider"`                           // DNS provider (e.g., "cloudflare")
+	UseEnv      bool              `json:"use_env" yaml:"use_env"`                             // Read secrets from environment variables
+	APIToken    string            `json:"api_token,omitempty" yaml:"api_token,omitempty"`     // API token (legacy, Cloudflare only)
+	Credentials map[string]string `json:"credentials,omitempty" yaml:"credentials,omitempty"` // Provider field values (if not using env)
+	EnvVars     map[string]string `json:"env_vars,omitempty" yaml:"env_vars,omitempty"`       // Per-domain environment variable names
+	Issuer      string            `json:"issuer,omitempty" yaml:"issuer,omitempty"`           // Certificate issuer (see Issuers), empty for Caddy's default
+
+	ACMESettings `yaml:",inline"` // Issuer settings
+}
+
+// ProviderID returns the DNS provider, defaulting to Cloudflare for older configs
+func (w *WildcardDomain) ProviderID() string {
+	if w.Provider == "" {
+		return "cloudflare"
+	}
+	return w.Provider
+}
+
+// Credential returns the configured value of a provider field
+func (w *WildcardDomain) Credential(key string) string {
+	if v := w.Credentials[key]; v != "" {
+		return v
+	}
+	// Older configs stored the Cloudflare token in APIToken
+	if key == "api_token" && w.ProviderID() == "cloudflare" {
+		return w.APIToken
+	}
+	return ""
+}
+
+// EnvVar returns the environment variable name used for a provider field
+func (w *WildcardDomain) EnvVar(field DNSProviderField) string {
+	if v := w.EnvVars[field.Key]; v != "" {
+		return v
+	}
+	return field.EnvVar
 }
 
 // WildcardConfig holds all wildcard domain configurations
 type WildcardConfig struct {
-	Domains []WildcardDomain `json:"domains"`
+	Domains []WildcardDomain `json:"domains" yaml:"domains"`
 }
@@ -0,0 +1,264 @@
+// Package network validates and normalises the IP addresses, CIDR ranges and
+// backend hosts entered in CPM
+package network
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var hostnameRe = regexp.MustCompile(`^[A-Za-z0-9_]([A-Za-z0-9_.-]*[A-Za-z0-9_])?$`)
+
+// EntryError is the error of a single entry of a network list
+type EntryError struct {
+	Entry string
+	Err   error
+}
+
+func (e *EntryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Entry, e.Err)
+}
+
+func (e *EntryError) Unwrap() error {
+	return e.Err
+}
+
+// Errors lists the errors of all invalid entries of a network list
+type Errors []*EntryError
+
+func (e Errors) Error() string {
+	var parts []string
+	for _, err := range e {
+		parts = append(parts, err.Error())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ParseEntry parses an IP address, a CIDR range or an address range
+// (first-last) into the CIDR ranges it covers. Host bits of CIDR ranges are
+// cleared and IPv4-mapped IPv6 addresses are converted to IPv4.
+func ParseEntry(entry string) ([]netip.Prefix, error) {
+	entry = strings.TrimSpace(entry)
+
+	switch {
+	case entry == "":
+		return nil, fmt.Errorf("empty entry")
+
+	case strings.Contains(entry, "/"):
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid CIDR range")
+		}
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return []netip.Prefix{prefix.Masked()}, nil
+
+	case strings.Contains(entry, "-"):
+		first, last, _ := strings.Cut(entry, "-")
+		from, err := parseAddr(first)
+		if err != nil {
+			return nil, err
+		}
+		to, err := parseAddr(last)
+		if err != nil {
+			return nil, err
+		}
+		if from.Is4() != to.Is4() {
+			return nil, fmt.Errorf("range mixes IPv4 and IPv6")
+		}
+		if from.Compare(to) > 0 {
+			return nil, fmt.Errorf("range starts after it ends")
+		}
+		return rangePrefixes(from, to), nil
+	}
+
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return nil, fmt.Errorf("not an IP address, CIDR range or address range")
+	}
+	if addr.Zone() != "" {
+		return nil, fmt.Errorf("IPv6 zones are not supported")
+	}
+	addr = addr.Unmap()
+	return []netip.Prefix{netip.PrefixFrom(addr, addr.BitLen())}, nil
+}
+
+// parseAddr parses an address of a range
+func parseAddr(value string) (netip.Addr, error) {
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%q is not a valid IP address", strings.TrimSpace(value))
+	}
+	if addr.Zone() != "" {
+		return netip.Addr{}, fmt.Errorf("IPv6 zones are not supported")
+	}
+	return addr.Unmap(), nil
+}
+
+// rangePrefixes returns the smallest set of CIDR ranges covering from-to
+func rangePrefixes(from, to netip.Addr) []netip.Prefix {
+	var prefixes []netip.Prefix
+	for {
+		// Grow the prefix while it starts at from and ends before to
+		bits := from.BitLen()
+		for bits > 0 {
+			wider := netip.PrefixFrom(from, bits-1).Masked()
+			if wider.Addr() != from || lastAddr(wider).Compare(to) > 0 {
+				break
+			}
+			bits--
+		}
+
+		prefix := netip.PrefixFrom(from, bits)
+		prefixes = append(prefixes, prefix)
+		if lastAddr(prefix).Compare(to) >= 0 {
+			return prefixes
+		}
+		from = lastAddr(prefix).Next()
+	}
+}
+
+// lastAddr returns the last address of a CIDR range
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for i := prefix.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 1 << (7 - i%8)
+	}
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// Format returns the canonical form of a CIDR range, single addresses without
+// the prefix length
+func Format(prefix netip.Prefix) string {
+	if prefix.IsSingleIP() {
+		return prefix.Addr().String()
+	}
+	return prefix.String()
+}
+
+// Normalize validates a network list and returns it in canonical form; address
+// ranges become the CIDR ranges covering them. Invalid entries and entries
+// overlapping an earlier one are reported per entry as Errors.
+func Normalize(entries []string) ([]string, error) {
+	var errs Errors
+	var prefixes []netip.Prefix
+	var owners []int // Index of the entry each prefix comes from
+
+	for i, entry := range entries {
+		parsed, err := ParseEntry(entry)
+		if err != nil {
+			errs = append(errs, &EntryError{Entry: strings.TrimSpace(entry), Err: err})
+			continue
+		}
+		for _, prefix := range parsed {
+			prefixes = append(prefixes, prefix)
+			owners = append(owners, i)
+		}
+	}
+
+	// CIDR ranges either nest or are disjoint, so any overlap is containment
+	reported := make(map[int]bool)
+	for i, prefix := range prefixes {
+		for j, other := range prefixes {
+			// Report the later entry
+			later, earlier := owners[i], owners[j]
+			if later < earlier {
+				later, earlier = earlier, later
+			}
+			if later == earlier || reported[later] || !prefix.Overlaps(other) {
+				continue
+			}
+			reported[later] = true
+			errs = append(errs, &EntryError{
+				Entry: strings.TrimSpace(entries[later]),
+				Err:   fmt.Errorf("overlaps %s", strings.TrimSpace(entries[earlier])),
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	normalized := make([]string, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		normalized = append(normalized, Format(prefix))
+	}
+	return normalized, nil
+}
+
+// Host validates a backend host, an IP address or a host name, and returns IP
+// addresses in canonical form. Caddy placeholders like {env.BACKEND} are kept.
+func Host(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if isPlaceholder(value) {
+		return value, nil
+	}
+
+	host := strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return addr.Unmap().String(), nil
+	}
+	if strings.Trim(host, "0123456789.") == "" {
+		return "", fmt.Errorf("%q is not a valid IPv4 address", value)
+	}
+	if !hostnameRe.MatchString(host) {
+		return "", fmt.Errorf("%q is not an IP address or host name", value)
+	}
+	return host, nil
+}
+
+// Backend validates a backend address (host:port, optionally with an http://
+// or https:// scheme) and returns it with the host in canonical form
+func Backend(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	scheme := ""
+	if i := strings.Index(value, "://"); i >= 0 {
+		scheme, value = value[:i+3], value[i+3:]
+		if scheme != "http://" && scheme != "https://" {
+			return "", fmt.Errorf("backend %q must use http:// or https://", scheme+value)
+		}
+	}
+	if strings.HasPrefix(value, "unix/") {
+		return scheme + value, nil
+	}
+
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		// A host without a port uses the scheme's default
+		host, port = value, ""
+	}
+	if host, err = Host(host); err != nil {
+		return "", err
+	}
+	if port == "" {
+		if strings.Contains(host, ":") {
+			return scheme + "[" + host + "]", nil
+		}
+		return scheme + host, nil
+	}
+	if err := Port(port); err != nil && !isPlaceholder(port) {
+		return "", err
+	}
+	return scheme + net.JoinHostPort(host, port), nil
+}
+
+// isPlaceholder returns true for a single Caddy placeholder
+func isPlaceholder(value string) bool {
+	return strings.HasPrefix(value, "{") && strings.HasSuffix(value, "}") && strings.Count(value, "{") == 1
+}
+
+// Port validates a TCP port number
+func Port(value string) error {
+	port, err := strconv.Atoi(value)
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("invalid port %q", value)
+	}
+	return nil
+}
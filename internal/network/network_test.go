@@ -0,0 +1,117 @@
+package network
+
+import (
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestRangePrefixes(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{name: "single address", from: "10.0.0.5", to: "10.0.0.5", want: []string{"10.0.0.5/32"}},
+		{name: "aligned block", from: "10.0.0.0", to: "10.0.0.255", want: []string{"10.0.0.0/24"}},
+		{name: "unaligned", from: "10.0.0.1", to: "10.0.0.6", want: []string{"10.0.0.1/32", "10.0.0.2/31", "10.0.0.4/31", "10.0.0.6/32"}},
+		{name: "across octets", from: "10.0.0.128", to: "10.0.1.127", want: []string{"10.0.0.128/25", "10.0.1.0/25"}},
+		{name: "all IPv4", from: "0.0.0.0", to: "255.255.255.255", want: []string{"0.0.0.0/0"}},
+		{name: "IPv6 block", from: "2001:db8::", to: "2001:db8::ffff", want: []string{"2001:db8::/112"}},
+		{name: "IPv6 unaligned", from: "2001:db8::1", to: "2001:db8::2", want: []string{"2001:db8::1/128", "2001:db8::2/128"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, prefix := range rangePrefixes(netip.MustParseAddr(tt.from), netip.MustParseAddr(tt.to)) {
+				got = append(got, prefix.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rangePrefixes(%s, %s) = %q, want %q", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []string
+		errs    []string // Expected per-entry errors
+	}{
+		{
+			name:    "IPv4",
+			entries: []string{" 10.0.0.1 ", "192.168.1.5/24", "172.16.0.1-172.16.0.6"},
+			want:    []string{"10.0.0.1", "192.168.1.0/24", "172.16.0.1", "172.16.0.2/31", "172.16.0.4/31", "172.16.0.6"},
+		},
+		{
+			name:    "IPv6",
+			entries: []string{"2001:DB8::1/64", "fd00::1", "2001:db9::-2001:db9::ff"},
+			want:    []string{"2001:db8::/64", "fd00::1", "2001:db9::/120"},
+		},
+		{
+			name:    "4-in-6 unmapped",
+			entries: []string{"::ffff:192.168.1.10", "::ffff:10.0.0.0/104", "::ffff:172.16.0.1-172.16.0.2"},
+			want:    []string{"192.168.1.10", "10.0.0.0/8", "172.16.0.1", "172.16.0.2"},
+		},
+		{
+			name:    "IPv4 and IPv6 do not overlap",
+			entries: []string{"0.0.0.0/0", "::/0"},
+			want:    []string{"0.0.0.0/0", "::/0"},
+		},
+		{
+			name:    "invalid entries",
+			entries: []string{"999.1.1.1/77", "10.0.0.0/33", "999.1.1.1", "fe80::1%eth0", "", "10.0.0.5-10.0.0.1", "10.0.0.1-::1", "10.0.0.1-nope"},
+			errs: []string{
+				"999.1.1.1/77: not a valid CIDR range",
+				"10.0.0.0/33: not a valid CIDR range",
+				"999.1.1.1: not an IP address, CIDR range or address range",
+				"fe80::1%eth0: IPv6 zones are not supported",
+				": empty entry",
+				"10.0.0.5-10.0.0.1: range starts after it ends",
+				"10.0.0.1-::1: range mixes IPv4 and IPv6",
+				`10.0.0.1-nope: "nope" is not a valid IP address`,
+			},
+		},
+		{
+			name:    "overlap reports the later entry",
+			entries: []string{"10.1.0.0/16", "10.0.0.0/8", "192.168.1.1", "::ffff:192.168.1.1", "10.2.0.0-10.2.0.3"},
+			errs: []string{
+				"10.0.0.0/8: overlaps 10.1.0.0/16",
+				"10.2.0.0-10.2.0.3: overlaps 10.0.0.0/8",
+				"::ffff:192.168.1.1: overlaps 192.168.1.1",
+			},
+		},
+		{
+			name:    "invalid and overlapping entries",
+			entries: []string{"bad", "10.0.0.0/8", "10.0.0.1"},
+			errs:    []string{"bad: not an IP address, CIDR range or address range", "10.0.0.1: overlaps 10.0.0.0/8"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Normalize(tt.entries)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Normalize() = %q, want %q", got, tt.want)
+			}
+
+			var gotErrs []string
+			if err != nil {
+				var errs Errors
+				if !errors.As(err, &errs) {
+					t.Fatalf("error %v is not Errors", err)
+				}
+				for _, entryErr := range errs {
+					gotErrs = append(gotErrs, entryErr.Error())
+				}
+			}
+			if !reflect.DeepEqual(gotErrs, tt.errs) {
+				t.Errorf("errors = %q, want %q", gotErrs, tt.errs)
+			}
+		})
+	}
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/TomasZmek/cpm/internal/config"
 	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/TomasZmek/cpm/internal/network"
 )
 
 // BackupInfo contains information about a backup
@@ -52,6 +53,8 @@ func (b *BackupService) CreateBackup() ([]byte, string, error) {
 		"Caddyfile",
 		"snippets.caddy",
 		".snippets_config.json",
+		"on_demand_tls.json",
+		"global_options.json",
 	}
 
 	// Add individual files
@@ -175,128 +178,408 @@ func (b *BackupService) RestoreBackup(data []byte) *RestoreResult {
 	return result
 }
 
-// ExportRules exports all rules as JSON
-func (b *BackupService) ExportRules(sites []*models.Site) ([]byte, error) {
-	type exportSite struct {
-		Filename           string   `json:"filename"`
-		Domains            []string `json:"domains"`
-		TargetIP           string   `json:"target_ip"`
-		TargetPort         string   `json:"target_port"`
-		IsHTTPSBackend     bool     `json:"is_https_backend"`
-		IsInternal         bool     `json:"is_internal"`
-		Snippets           []string `json:"snippets"`
-		Tags               []string `json:"tags"`
-		AdditionalBackends []string `json:"additional_backends"`
-		LBPolicy           string   `json:"lb_policy"`
-		EnableWebSocket    bool     `json:"enable_websocket"`
-		HealthCheckPath    string   `json:"health_check_path"`
-		TimeoutSeconds     int      `json:"timeout_seconds"`
-		ExtraConfig        string   `json:"extra_config"`
-		RawContent         string   `json:"raw_content"`
-	}
-
-	var export []exportSite
+// Rule import conflict modes
+const (
+	ImportModeSkip      = "skip"
+	ImportModeOverwrite = "overwrite"
+	ImportModeRename    = "rename"
+)
+
+// RuleImportOptions controls how rules are imported
+type RuleImportOptions struct {
+	Mode            string // skip, overwrite or rename
+	DryRun          bool
+	ImportWildcards bool
+	ImportSnippets  bool
+}
+
+// RuleImportEntry is the outcome for a single rule
+type RuleImportEntry struct {
+	Filename string
+	Domain   string
+	Action   string // create, overwrite, rename, skip, error
+	Message  string
+}
+
+// RuleImportResult contains the result of a rules import
+type RuleImportResult struct {
+	Version           int
+	DryRun            bool
+	Entries           []RuleImportEntry
+	Imported          int
+	Skipped           int
+	Failed            int
+	WildcardsImported int
+	SnippetsImported  bool
+}
+
+// ExportRules exports all rules, wildcard domains and snippet config as versioned JSON
+func (b *BackupService) ExportRules(sites []*models.Site, wildcards []models.WildcardDomain, snippetCfg *models.SnippetConfig) ([]byte, error) {
+	export := models.RulesExport{
+		Version:         models.RulesExportVersion,
+		ExportedAt:      time.Now(),
+		Sites:           []models.Site{},
+		WildcardDomains: wildcards,
+		SnippetConfig:   snippetCfg,
+	}
+	if export.WildcardDomains == nil {
+		export.WildcardDomains = []models.WildcardDomain{}
+	}
+
 	for _, site := range sites {
-		export = append(export, exportSite{
-			Filename:           site.Filename,
-			Domains:            site.Domains,
-			TargetIP:           site.TargetIP,
-			TargetPort:         site.TargetPort,
-			IsHTTPSBackend:     site.IsHTTPSBackend,
-			IsInternal:         site.IsInternal,
-			Snippets:           site.Snippets,
-			Tags:               site.Tags,
-			AdditionalBackends: site.AdditionalBackends,
-			LBPolicy:           site.LBPolicy,
-			EnableWebSocket:    site.EnableWebSocket,
-			HealthCheckPath:    site.HealthCheckPath,
-			TimeoutSeconds:     site.TimeoutSeconds,
-			ExtraConfig:        site.ExtraConfig,
-			RawContent:         site.RawContent,
-		})
+		s := *site
+		s.Filepath = ""
+		export.Sites = append(export.Sites, s)
 	}
 
 	return json.MarshalIndent(export, "", "  ")
 }
 
-// ImportRules imports rules from JSON
-func (b *BackupService) ImportRules(data []byte, caddyService *CaddyService, skipExisting bool) (int, int, error) {
-	var rules []map[string]interface{}
-	if err := json.Unmarshal(data, &rules); err != nil {
-		return 0, 0, fmt.Errorf("invalid JSON: %w", err)
+// ParseRulesExport parses a rules export of any supported version
+func ParseRulesExport(data []byte) (*models.RulesExport, error) {
+	trimmed := bytes.TrimSpace(data)
+
+	// Version 1: bare array of rules
+	if bytes.HasPrefix(trimmed, []byte("[")) {
+		var sites []models.Site
+		if err := json.Unmarshal(trimmed, &sites); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return &models.RulesExport{Version: 1, Sites: sites}, nil
 	}
 
-	existingSites, _ := caddyService.GetAllSites()
-	existingNames := make(map[string]bool)
-	for _, s := range existingSites {
-		existingNames[s.Filename] = true
+	var export models.RulesExport
+	if err := json.Unmarshal(trimmed, &export); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+	if export.Version < 1 || export.Version > models.RulesExportVersion {
+		return nil, fmt.Errorf("unsupported export version: %d", export.Version)
 	}
 
-	imported := 0
-	skipped := 0
+	return &export, nil
+}
 
-	for _, rule := range rules {
-		filename, _ := rule["filename"].(string)
+// ImportRules imports rules from JSON. Every rule is validated on its own, so one
+// invalid rule doesn't stop the rest. With DryRun nothing is written.
+func (b *BackupService) ImportRules(data []byte, caddyService *CaddyService, wildcardService *WildcardService, snippetsService *SnippetsService, certService *CertificateService, opts RuleImportOptions) (*RuleImportResult, error) {
+	export, err := ParseRulesExport(data)
+	if err != nil {
+		return nil, err
+	}
 
-		if skipExisting && existingNames[filename] {
-			skipped++
-			continue
-		}
+	switch opts.Mode {
+	case ImportModeSkip, ImportModeOverwrite, ImportModeRename:
+	case "":
+		opts.Mode = ImportModeSkip
+	default:
+		return nil, fmt.Errorf("unknown conflict mode: %s", opts.Mode)
+	}
+
+	result := &RuleImportResult{
+		Version: export.Version,
+		DryRun:  opts.DryRun,
+		Entries: []RuleImportEntry{},
+	}
 
-		// Create site from rule
-		site := &models.Site{
-			Filename: filename,
+	// Wildcard domains
+	wildcards := make(map[string]bool)
+	if wildcardService != nil {
+		existing, _ := wildcardService.GetDomains()
+		for _, wd := range existing {
+			wildcards[wd.Domain] = true
 		}
 
-		if domains, ok := rule["domains"].([]interface{}); ok {
-			for _, d := range domains {
-				if ds, ok := d.(string); ok {
-					site.Domains = append(site.Domains, ds)
+		if opts.ImportWildcards {
+			for _, wd := range export.WildcardDomains {
+				if wd.Domain == "" {
+					continue
+				}
+				exists := wildcards[wd.Domain]
+				if exists && opts.Mode != ImportModeOverwrite {
+					continue
 				}
+				if !opts.DryRun {
+					if exists {
+						err = wildcardService.UpdateDomain(wd)
+					} else {
+						err = wildcardService.AddDomain(wd)
+					}
+					if err != nil {
+						return nil, fmt.Errorf("failed to import wildcard domain %s: %w", wd.Domain, err)
+					}
+				}
+				wildcards[wd.Domain] = true
+				result.WildcardsImported++
 			}
 		}
+	}
 
-		if v, ok := rule["target_ip"].(string); ok {
-			site.TargetIP = v
+	// Snippet configuration
+	if opts.ImportSnippets && export.SnippetConfig != nil && snippetsService != nil {
+		if err := export.SnippetConfig.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid snippet config: %w", err)
 		}
-		if v, ok := rule["target_port"].(string); ok {
-			site.TargetPort = v
+		if !opts.DryRun {
+			if err := snippetsService.SaveConfig(export.SnippetConfig); err != nil {
+				return nil, fmt.Errorf("failed to import snippet config: %w", err)
+			}
 		}
-		if v, ok := rule["is_https_backend"].(bool); ok {
-			site.IsHTTPSBackend = v
+		result.SnippetsImported = true
+	}
+
+	// Existing rules by filename and domain
+	existingSites, err := caddyService.GetAllSites()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load existing rules: %w", err)
+	}
+	byName := make(map[string]*models.Site)
+	domainOwner := make(map[string]string)
+	for _, s := range existingSites {
+		byName[s.Filename] = s
+		for _, d := range s.Domains {
+			domainOwner[strings.ToLower(d)] = s.Filename
 		}
-		if v, ok := rule["is_internal"].(bool); ok {
-			site.IsInternal = v
+	}
+
+	for i := range export.Sites {
+		site := export.Sites[i]
+		site.Filepath = ""
+		if site.Filename == "" {
+			site.Filename = sanitizeFilename(site.PrimaryDomain())
+		}
+		if site.TLSMode == "" {
+			site.TLSMode = "auto"
 		}
 
-		if snippets, ok := rule["snippets"].([]interface{}); ok {
-			for _, s := range snippets {
-				if ss, ok := s.(string); ok {
-					site.Snippets = append(site.Snippets, ss)
-				}
+		entry := RuleImportEntry{Filename: site.Filename, Domain: site.PrimaryDomain()}
+
+		action, err := b.planRule(&site, byName, domainOwner, wildcards, certService, opts.Mode)
+		entry.Action = action
+		if err != nil {
+			entry.Message = err.Error()
+		}
+
+		switch action {
+		case "error":
+			result.Failed++
+			result.Entries = append(result.Entries, entry)
+			continue
+		case "skip":
+			result.Skipped++
+			result.Entries = append(result.Entries, entry)
+			continue
+		case "rename":
+			entry.Message = "Imported as " + site.Filename
+		}
+
+		if !opts.DryRun {
+			if err := b.writeRule(&site, byName[entry.Filename], action, caddyService); err != nil {
+				entry.Action = "error"
+				entry.Message = err.Error()
+				result.Failed++
+				result.Entries = append(result.Entries, entry)
+				continue
 			}
 		}
 
-		if tags, ok := rule["tags"].([]interface{}); ok {
-			for _, t := range tags {
-				if ts, ok := t.(string); ok {
-					site.Tags = append(site.Tags, ts)
-				}
+		// Claim filename and domains so duplicates within the file are caught too
+		byName[site.Filename] = &site
+		for _, d := range site.Domains {
+			domainOwner[strings.ToLower(d)] = site.Filename
+		}
+
+		result.Imported++
+		result.Entries = append(result.Entries, entry)
+	}
+
+	return result, nil
+}
+
+// planRule validates a rule and decides what to do with it. For renames the
+// site's filename is changed to a free one.
+func (b *BackupService) planRule(site *models.Site, byName map[string]*models.Site, domainOwner map[string]string, wildcards map[string]bool, certService *CertificateService, mode string) (string, error) {
+	if err := validateRule(site, wildcards, certService); err != nil {
+		return "error", err
+	}
+
+	// Domains served by a different rule can't be resolved by any mode
+	for _, d := range site.Domains {
+		owner, ok := domainOwner[strings.ToLower(d)]
+		if !ok || owner == site.Filename {
+			continue
+		}
+		if mode == ImportModeSkip {
+			return "skip", fmt.Errorf("domain %s is already used by %s", d, owner)
+		}
+		return "error", fmt.Errorf("domain %s is already used by %s", d, owner)
+	}
+
+	if _, exists := byName[site.Filename]; !exists {
+		return "create", nil
+	}
+
+	switch mode {
+	case ImportModeOverwrite:
+		return "overwrite", nil
+	case ImportModeRename:
+		for _, d := range site.Domains {
+			if domainOwner[strings.ToLower(d)] == site.Filename {
+				return "error", fmt.Errorf("domain %s is already used by %s, a renamed copy would conflict", d, site.Filename)
+			}
+		}
+		base := site.Filename
+		for n := 2; ; n++ {
+			candidate := fmt.Sprintf("%s-%d", base, n)
+			if _, taken := byName[candidate]; !taken {
+				site.Filename = candidate
+				return "rename", nil
 			}
 		}
+	default:
+		return "skip", fmt.Errorf("rule already exists")
+	}
+}
+
+// writeRule writes an imported rule. The file is generated from the validated
+// fields; the exported raw content is not trusted, since it could contain
+// anything the validation rejects.
+func (b *BackupService) writeRule(site *models.Site, existing *models.Site, action string, caddyService *CaddyService) error {
+	dir := caddyService.SiteDirectory(site)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create sites directory: %w", err)
+	}
+
+	path := filepath.Join(dir, site.Filename+".caddy")
+
+	content := site.ToCaddyfile()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write site file: %w", err)
+	}
+
+	// Remove the old file if the overwritten rule lived in another directory
+	if action == "overwrite" && existing != nil && existing.Filepath != "" && existing.Filepath != path {
+		os.Remove(existing.Filepath)
+	}
+
+	site.Filepath = path
+	site.RawContent = content
+	return nil
+}
 
-		// Use raw content if available
-		if raw, ok := rule["raw_content"].(string); ok && raw != "" {
-			site.RawContent = raw
-			caddyService.UpdateSiteRaw(filename, raw)
-		} else {
-			caddyService.CreateSite(site)
+// validateRule checks an imported rule before it is written. Custom
+// certificates must exist in the certificate store.
+func validateRule(site *models.Site, wildcards map[string]bool, certService *CertificateService) error {
+	if site.Filename == "" || strings.ContainsAny(site.Filename, `/\`) || strings.HasPrefix(site.Filename, ".") {
+		return fmt.Errorf("invalid filename %q", site.Filename)
+	}
+
+	if len(site.Domains) == 0 {
+		return fmt.Errorf("no domains")
+	}
+	for _, d := range site.Domains {
+		if d == "" || strings.ContainsAny(d, " \t\n{}") {
+			return fmt.Errorf("invalid domain %q", d)
+		}
+	}
+
+	if site.IsProxy() {
+		if site.TargetIP == "" {
+			return fmt.Errorf("target IP is required")
+		}
+		if _, err := network.Host(site.TargetIP); err != nil {
+			return fmt.Errorf("invalid target: %w", err)
 		}
+		if err := network.Port(site.TargetPort); err != nil {
+			return fmt.Errorf("invalid target port %q", site.TargetPort)
+		}
+	}
 
-		imported++
+	switch {
+	case site.TLSMode == "auto":
+	case site.HasIssuer():
+		if err := site.ACMESettings.Validate(site.TLSMode); err != nil {
+			return err
+		}
+	case site.IsCustomCertificate():
+		id := site.CustomCertificateID()
+		if id == "" {
+			return fmt.Errorf("custom TLS mode without a certificate ID")
+		}
+		if !models.IsCustomCertificateID(id) {
+			return fmt.Errorf("invalid certificate ID %q", id)
+		}
+		if certService == nil {
+			return fmt.Errorf("certificate store not available for %s", id)
+		}
+		if _, err := certService.GetCustomCertificate(id); err != nil {
+			return err
+		}
+	default:
+		domain, ok := strings.CutPrefix(site.TLSMode, "wildcard:")
+		if !ok {
+			return fmt.Errorf("unknown TLS mode %q", site.TLSMode)
+		}
+		if !wildcards[domain] {
+			return fmt.Errorf("wildcard domain %s is not configured", domain)
+		}
+	}
+
+	if err := site.ValidateType(); err != nil {
+		return err
+	}
+	if site.ForwardAuth != nil {
+		if err := site.ForwardAuth.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := site.ValidateRoutes(); err != nil {
+		return err
+	}
+	if site.CORS != nil {
+		if err := site.CORS.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := site.ValidateHeaderRules(); err != nil {
+		return err
+	}
+	for _, id := range site.AccessLists {
+		if id == "" || models.AccessListID(id) != id || strings.ContainsAny(id, "{}") {
+			return fmt.Errorf("invalid access list %q", id)
+		}
+	}
+
+	if site.OnDemand && (site.IsWildcard() || site.IsCustomCertificate()) {
+		return fmt.Errorf("on-demand certificates need an ACME issuer, not a wildcard or uploaded certificate")
+	}
+
+	if site.HasClientAuth() {
+		if site.IsWildcard() {
+			return fmt.Errorf("client certificates can't be required per host on a wildcard certificate")
+		}
+		if err := site.ClientAuth.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if site.TimeoutSeconds < 0 {
+		return fmt.Errorf("invalid timeout %d", site.TimeoutSeconds)
+	}
+	if strings.ContainsAny(site.LBPolicy, " \t\n{}") {
+		return fmt.Errorf("invalid load balancing policy %q", site.LBPolicy)
+	}
+	for _, backend := range site.AdditionalBackends {
+		if _, err := network.Backend(backend); err != nil {
+			return err
+		}
+	}
+	for _, user := range site.BasicAuthUsers {
+		if len(strings.Fields(user)) != 2 {
+			return fmt.Errorf("invalid basic auth user entry")
+		}
 	}
 
-	return imported, skipped, nil
+	return nil
 }
 
 // addFileToZip adds a file to the zip archive
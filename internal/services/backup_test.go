@@ -0,0 +1,173 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestValidateRuleCustomCertificate(t *testing.T) {
+	certService := NewCertificateService(t.TempDir())
+	dir := filepath.Join(certService.customCertsDir(), "0123456789abcdef")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta.json"), []byte(`{"id":"0123456789abcdef"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		tlsMode string
+		wantErr bool
+	}{
+		{name: "stored certificate", tlsMode: "custom:0123456789abcdef"},
+		{name: "missing certificate", tlsMode: "custom:fedcba9876543210", wantErr: true},
+		{name: "empty ID", tlsMode: "custom:", wantErr: true},
+		{name: "path traversal", tlsMode: "custom:../../etc", wantErr: true},
+		{name: "space", tlsMode: "custom:0123456789abcdef extra", wantErr: true},
+		{name: "brace", tlsMode: "custom:0123456789abcde{", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &models.Site{
+				Filename:   "app.example.com.caddy",
+				Domains:    []string{"app.example.com"},
+				TargetIP:   "10.0.0.5",
+				TargetPort: "8080",
+				TLSMode:    tt.tlsMode,
+			}
+			err := validateRule(site, nil, certService)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// newTestRulesImport returns a backup and Caddy service on empty directories
+// with app.example.com already configured
+func newTestRulesImport(t *testing.T) (*BackupService, *CaddyService) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{ConfigDir: dir, SitesDir: filepath.Join(dir, "sites")}
+	caddyService := NewCaddyService(cfg, nil)
+	existing := &models.Site{Filename: "app.example.com", Domains: []string{"app.example.com"}, TargetIP: "10.0.0.5", TargetPort: "8080", TLSMode: "auto"}
+	if err := os.MkdirAll(cfg.SitesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfg.SitesDir, "app.example.com.caddy"), []byte(existing.ToCaddyfile()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewBackupService(cfg), caddyService
+}
+
+func TestImportRulesModes(t *testing.T) {
+	rules := `[
+  {"filename": "app.example.com", "domains": ["app.example.com"], "target_ip": "10.0.0.9", "target_port": "9090", "tls_mode": "auto",
+   "raw_content": "app.example.com {\n    respond \"raw\"\n}\n"},
+  {"filename": "new.example.com", "domains": ["new.example.com"], "target_ip": "10.0.0.7", "target_port": "80", "tls_mode": "auto"},
+  {"filename": "bad.example.com", "domains": ["bad.example.com"], "target_ip": "10.0.0.8", "target_port": "99999", "tls_mode": "auto"}
+]`
+
+	tests := []struct {
+		mode    string
+		dryRun  bool
+		actions []string
+		files   map[string]string // Expected target of each file, "" if it must not exist
+	}{
+		{
+			mode:    ImportModeSkip,
+			actions: []string{"skip", "create", "error"},
+			files:   map[string]string{"app.example.com": "10.0.0.5:8080", "new.example.com": "10.0.0.7:80", "bad.example.com": ""},
+		},
+		{
+			mode:    ImportModeOverwrite,
+			actions: []string{"overwrite", "create", "error"},
+			files:   map[string]string{"app.example.com": "10.0.0.9:9090", "new.example.com": "10.0.0.7:80"},
+		},
+		{
+			mode:    ImportModeRename,
+			actions: []string{"error", "create", "error"},
+			files:   map[string]string{"app.example.com": "10.0.0.5:8080", "app.example.com-2": ""},
+		},
+		{
+			mode:    ImportModeOverwrite,
+			dryRun:  true,
+			actions: []string{"overwrite", "create", "error"},
+			files:   map[string]string{"app.example.com": "10.0.0.5:8080", "new.example.com": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		name := tt.mode
+		if tt.dryRun {
+			name += " dry run"
+		}
+		t.Run(name, func(t *testing.T) {
+			backupService, caddyService := newTestRulesImport(t)
+			result, err := backupService.ImportRules([]byte(rules), caddyService, nil, nil, nil, RuleImportOptions{Mode: tt.mode, DryRun: tt.dryRun})
+			if err != nil {
+				t.Fatalf("ImportRules: %v", err)
+			}
+
+			var actions []string
+			for _, entry := range result.Entries {
+				actions = append(actions, entry.Action)
+			}
+			if !reflect.DeepEqual(actions, tt.actions) {
+				t.Errorf("actions = %q, want %q", actions, tt.actions)
+			}
+
+			for filename, target := range tt.files {
+				site, err := caddyService.GetSite(filename)
+				if target == "" {
+					if err == nil {
+						t.Errorf("%s was written", filename)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("%s: %v", filename, err)
+					continue
+				}
+				if got := site.TargetIP + ":" + site.TargetPort; got != target {
+					t.Errorf("%s target = %s, want %s", filename, got, target)
+				}
+				if strings.Contains(site.RawContent, `respond "raw"`) {
+					t.Errorf("%s was written from the exported raw content:\n%s", filename, site.RawContent)
+				}
+			}
+		})
+	}
+}
+
+func TestImportRulesRenamesFilename(t *testing.T) {
+	backupService, caddyService := newTestRulesImport(t)
+	rules := `[{"filename": "app.example.com", "domains": ["copy.example.com"], "target_ip": "10.0.0.9", "target_port": "9090", "tls_mode": "auto"}]`
+
+	result, err := backupService.ImportRules([]byte(rules), caddyService, nil, nil, nil, RuleImportOptions{Mode: ImportModeRename})
+	if err != nil {
+		t.Fatalf("ImportRules: %v", err)
+	}
+	if len(result.Entries) != 1 || result.Entries[0].Action != "rename" {
+		t.Fatalf("entries = %+v", result.Entries)
+	}
+
+	renamed, err := caddyService.GetSite("app.example.com-2")
+	if err != nil {
+		t.Fatalf("renamed rule: %v", err)
+	}
+	if renamed.PrimaryDomain() != "copy.example.com" || renamed.TargetPort != "9090" {
+		t.Errorf("renamed rule = %+v", renamed)
+	}
+	if original, err := caddyService.GetSite("app.example.com"); err != nil || original.TargetPort != "8080" {
+		t.Errorf("original rule changed: %+v, %v", original, err)
+	}
+}
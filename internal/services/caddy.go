@@ -31,11 +31,27 @@ type CaddyService struct {
 
 // NewCaddyService creates a new Caddy service
 func NewCaddyService(cfg *config.Config, dockerService *DockerService) *CaddyService {
-	return &CaddyService{
+	c := &CaddyService{
 		config:        cfg,
 		dockerService: dockerService,
 		parser:        NewParserService(),
 	}
+	// Caddy runs with the Caddyfile on disk until the first reload
+	c.recordAdminAddress()
+	return c
+}
+
+// recordAdminAddress tells the Docker service the admin listener of the
+// Caddyfile Caddy has loaded, so the next reload reaches it
+func (c *CaddyService) recordAdminAddress() {
+	if c.dockerService == nil {
+		return
+	}
+	content, err := os.ReadFile(filepath.Join(c.config.ConfigDir, "Caddyfile"))
+	if err != nil {
+		return
+	}
+	c.dockerService.SetAdminAddress(models.AdminAddress(string(content)))
 }
 
 // SetCaddyfileManager sets the CaddyfileManager (to avoid circular dependency)
@@ -53,6 +69,7 @@ func (c *CaddyService) GetAllSites() ([]*models.Site, error) {
 		filepath.Join(sitesDir, "wildcard"),
 		filepath.Join(sitesDir, "standard"),
 		sitesDir, // Legacy flat structure
+		c.disabledDir(),
 	}
 
 	// Keep track of loaded files to avoid duplicates
@@ -123,6 +140,7 @@ func (c *CaddyService) GetSite(filename string) (*models.Site, error) {
 		filepath.Join(c.config.SitesDir, "wildcard"),
 		filepath.Join(c.config.SitesDir, "standard"),
 		c.config.SitesDir, // Legacy flat structure
+		c.disabledDir(),
 	}
 
 	for _, dir := range searchDirs {
@@ -147,6 +165,7 @@ func (c *CaddyService) loadSite(filepath string) (*models.Site, error) {
 	site := c.parser.Parse(string(content), filename)
 	site.Filepath = filepath
 	site.Filename = filename
+	site.Disabled = strings.HasPrefix(filepath, c.disabledDir()+"/")
 
 	// Get modification time
 	info, err := os.Stat(filepath)
@@ -157,6 +176,27 @@ func (c *CaddyService) loadSite(filepath string) (*models.Site, error) {
 	return site, nil
 }
 
+// SiteDirectory returns the directory a site file belongs in based on its type
+func (c *CaddyService) SiteDirectory(site *models.Site) string {
+	if site.Disabled {
+		return c.disabledDir()
+	}
+	if c.caddyfileManager == nil {
+		// Fallback to legacy flat structure
+		return c.config.SitesDir
+	}
+	if site.IsWildcard() {
+		return filepath.Join(c.config.SitesDir, "wildcard")
+	}
+	return filepath.Join(c.config.SitesDir, "standard")
+}
+
+// disabledDir returns the directory of disabled sites. The Caddyfile imports
+// only the standard and wildcard directories, so Caddy never loads them.
+func (c *CaddyService) disabledDir() string {
+	return filepath.Join(c.config.SitesDir, "disabled")
+}
+
 // CreateSite creates a new proxy rule
 func (c *CaddyService) CreateSite(site *models.Site) error {
 	// Generate filename from primary domain
@@ -165,15 +205,7 @@ func (c *CaddyService) CreateSite(site *models.Site) error {
 	}
 
 	// Determine correct directory based on site type
-	var sitesDir string
-	if site.IsWildcard() && c.caddyfileManager != nil {
-		sitesDir = filepath.Join(c.config.SitesDir, "wildcard")
-	} else if !site.IsWildcard() && c.caddyfileManager != nil {
-		sitesDir = filepath.Join(c.config.SitesDir, "standard")
-	} else {
-		// Fallback to legacy flat structure
-		sitesDir = c.config.SitesDir
-	}
+	sitesDir := c.SiteDirectory(site)
 
 	site.Filepath = filepath.Join(sitesDir, site.Filename+".caddy")
 
@@ -181,6 +213,9 @@ func (c *CaddyService) CreateSite(site *models.Site) error {
 	if _, err := os.Stat(site.Filepath); err == nil {
 		return fmt.Errorf("site already exists: %s", site.Filename)
 	}
+	if _, err := os.Stat(filepath.Join(c.disabledDir(), site.Filename+".caddy")); err == nil {
+		return fmt.Errorf("site already exists and is disabled: %s", site.Filename)
+	}
 
 	// Ensure sites directory exists
 	if err := os.MkdirAll(sitesDir, 0755); err != nil {
@@ -204,15 +239,7 @@ func (c *CaddyService) UpdateSite(site *models.Site) error {
 	oldFilepath := site.Filepath
 	
 	// Determine correct directory based on site type
-	var sitesDir string
-	if site.IsWildcard() && c.caddyfileManager != nil {
-		sitesDir = filepath.Join(c.config.SitesDir, "wildcard")
-	} else if !site.IsWildcard() && c.caddyfileManager != nil {
-		sitesDir = filepath.Join(c.config.SitesDir, "standard")
-	} else {
-		// Fallback to legacy flat structure
-		sitesDir = c.config.SitesDir
-	}
+	sitesDir := c.SiteDirectory(site)
 
 	newFilepath := filepath.Join(sitesDir, site.Filename+".caddy")
 
@@ -240,6 +267,34 @@ func (c *CaddyService) UpdateSite(site *models.Site) error {
 	return nil
 }
 
+// SetSiteEnabled enables or disables a site. The file is moved as it is
+// between sites/disabled and the directory of its type, keeping its content.
+func (c *CaddyService) SetSiteEnabled(filename string, enabled bool) (*models.Site, error) {
+	site, err := c.GetSite(filename)
+	if err != nil {
+		return nil, err
+	}
+	if site.Disabled == !enabled {
+		return site, nil
+	}
+
+	site.Disabled = !enabled
+	sitesDir := c.SiteDirectory(site)
+	newFilepath := filepath.Join(sitesDir, site.Filename+".caddy")
+	if _, err := os.Stat(newFilepath); err == nil {
+		return nil, fmt.Errorf("another site uses the file %s", newFilepath)
+	}
+	if err := os.MkdirAll(sitesDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sites directory: %w", err)
+	}
+	if err := os.Rename(site.Filepath, newFilepath); err != nil {
+		return nil, fmt.Errorf("failed to move site file: %w", err)
+	}
+
+	site.Filepath = newFilepath
+	return site, nil
+}
+
 // UpdateSiteRaw updates a site with raw content
 func (c *CaddyService) UpdateSiteRaw(filename, content string) error {
 	filepath := filepath.Join(c.config.SitesDir, filename)
@@ -247,6 +302,11 @@ func (c *CaddyService) UpdateSiteRaw(filename, content string) error {
 		filepath += ".caddy"
 	}
 
+	// Existing sites are written in place, also in the type and disabled directories
+	if site, err := c.GetSite(filename); err == nil {
+		filepath = site.Filepath
+	}
+
 	if err := os.WriteFile(filepath, []byte(content), 0644); err != nil {
 		return fmt.Errorf("failed to write site file: %w", err)
 	}
@@ -265,6 +325,7 @@ func (c *CaddyService) DeleteSite(filename string) error {
 		filepath.Join(c.config.SitesDir, "wildcard"),
 		filepath.Join(c.config.SitesDir, "standard"),
 		c.config.SitesDir, // Legacy flat structure
+		c.disabledDir(),
 	}
 
 	for _, dir := range searchDirs {
@@ -295,6 +356,7 @@ func (c *CaddyService) DuplicateSite(sourceFilename string, newDomains []string)
 		IsHTTPSBackend:     source.IsHTTPSBackend,
 		IsInternal:         source.IsInternal,
 		Snippets:           source.Snippets,
+		SnippetArgs:        source.SnippetArgs,
 		Tags:               source.Tags,
 		AdditionalBackends: source.AdditionalBackends,
 		LBPolicy:           source.LBPolicy,
@@ -304,6 +366,15 @@ func (c *CaddyService) DuplicateSite(sourceFilename string, newDomains []string)
 		BasicAuthEnabled:   source.BasicAuthEnabled,
 		BasicAuthUsers:     source.BasicAuthUsers,
 		ExtraConfig:        source.ExtraConfig,
+		Routes:             source.Routes,
+		ForwardAuth:        source.ForwardAuth,
+		AccessLists:        source.AccessLists,
+		CORS:               source.CORS,
+		HeaderRules:        source.HeaderRules,
+		Type:               source.Type,
+		Redirect:           source.Redirect,
+		Static:             source.Static,
+		Respond:            source.Respond,
 	}
 
 	if err := c.CreateSite(newSite); err != nil {
@@ -324,6 +395,26 @@ func (c *CaddyService) Reload() *ReloadResult {
 		}
 	}
 
+	c.recordAdminAddress()
+	return &ReloadResult{
+		Success:   true,
+		Message:   "Configuration reloaded successfully",
+		ReloadLog: output,
+	}
+}
+
+// ForceReload reloads Caddy even if the configuration is unchanged
+func (c *CaddyService) ForceReload() *ReloadResult {
+	output, err := c.dockerService.ForceReloadCaddyWithOutput()
+	if err != nil {
+		return &ReloadResult{
+			Success:   false,
+			Error:     err.Error(),
+			ReloadLog: output,
+		}
+	}
+
+	c.recordAdminAddress()
 	return &ReloadResult{
 		Success:   true,
 		Message:   "Configuration reloaded successfully",
@@ -372,6 +463,7 @@ func (c *CaddyService) ReloadWithValidation() *ReloadResult {
 		}
 	}
 
+	c.recordAdminAddress()
 	return &ReloadResult{
 		Success:       true,
 		Message:       "Configuration validated and reloaded successfully",
@@ -473,8 +565,12 @@ func (c *CaddyService) GetStats() map[string]interface{} {
 	internal := 0
 	public := 0
 	withAuth := 0
+	disabled := 0
 
 	for _, site := range sites {
+		if site.Disabled {
+			disabled++
+		}
 		if site.IsInternal {
 			internal++
 		} else {
@@ -490,6 +586,7 @@ func (c *CaddyService) GetStats() map[string]interface{} {
 		"internal":  internal,
 		"public":    public,
 		"with_auth": withAuth,
+		"disabled":  disabled,
 		"tags":      len(tags),
 	}
 }
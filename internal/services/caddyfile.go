@@ -15,14 +15,18 @@ type CaddyfileManager struct {
 	config          *config.Config
 	wildcardService *WildcardService
 	snippetsService *SnippetsService
+	onDemandService *OnDemandService
+	globalsService  *GlobalOptionsService
 }
 
 // NewCaddyfileManager creates a new CaddyfileManager
-func NewCaddyfileManager(cfg *config.Config, ws *WildcardService, ss *SnippetsService) *CaddyfileManager {
+func NewCaddyfileManager(cfg *config.Config, ws *WildcardService, ss *SnippetsService, od *OnDemandService, gs *GlobalOptionsService) *CaddyfileManager {
 	return &CaddyfileManager{
 		config:          cfg,
 		wildcardService: ws,
 		snippetsService: ss,
+		onDemandService: od,
+		globalsService:  gs,
 	}
 }
 
@@ -71,7 +75,21 @@ func (m *CaddyfileManager) GenerateCaddyfile() (string, error) {
 	lines = append(lines, "# Caddyfile - Auto-generated by CPM v3.1.0")
 	lines = append(lines, "# DO NOT EDIT MANUALLY - Use the CPM web interface")
 	lines = append(lines, "{")
-	lines = append(lines, "    # email your@email.com")
+	globals, err := m.globalsService.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	onDemand, err := m.onDemandService.GetConfig()
+	if err != nil {
+		return "", err
+	}
+	options := append(globals.Lines(), onDemand.GlobalLines()...)
+	if len(options) == 0 {
+		lines = append(lines, "    # email your@email.com")
+	}
+	for _, line := range options {
+		lines = append(lines, "    "+line)
+	}
 	lines = append(lines, "}")
 	lines = append(lines, "")
 	lines = append(lines, "import /etc/caddy/snippets.caddy")
@@ -122,12 +140,16 @@ func (m *CaddyfileManager) generateWildcardBlock(wd models.WildcardDomain, inter
 	lines = append(lines, fmt.Sprintf("    import %s", snippetName))
 	
 	// Internal network restriction - MUST be before site imports and outside handle blocks
-	// This replaces per-site internal_only snippet for wildcard sites
+	// This replaces per-site internal_only snippet for wildcard sites; sites with
+	// their own access lists set the site_acl variable and enforce them instead
 	if len(internalNetworks) > 0 {
 		networks := strings.Join(internalNetworks, " ")
 		lines = append(lines, "")
-		lines = append(lines, "    # Internal network restriction (replaces internal_only for wildcard sites)")
-		lines = append(lines, fmt.Sprintf("    @internal_denied not client_ip %s", networks))
+		lines = append(lines, "    # Internal network restriction (replaces internal_only for wildcard sites without access lists)")
+		lines = append(lines, "    @internal_denied {")
+		lines = append(lines, fmt.Sprintf("        not client_ip %s", networks))
+		lines = append(lines, fmt.Sprintf("        not vars %s yes", models.AccessListSiteVar))
+		lines = append(lines, "    }")
 		lines = append(lines, "    handle @internal_denied {")
 		lines = append(lines, "        error 403")
 		lines = append(lines, "    }")
@@ -1,15 +1,26 @@
 package services
 
 import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/TomasZmek/cpm/internal/models"
+	"golang.org/x/crypto/ocsp"
 )
 
 // CertificateService handles SSL certificate operations
@@ -29,7 +40,11 @@ func (c *CertificateService) GetAllCertificates() ([]*models.Certificate, error)
 	certsDir := filepath.Join(c.dataDir, "caddy", "certificates")
 
 	if _, err := os.Stat(certsDir); os.IsNotExist(err) {
-		return []*models.Certificate{}, nil
+		certs := c.customCertificateEntries()
+		if certs == nil {
+			certs = []*models.Certificate{}
+		}
+		return certs, nil
 	}
 
 	var certs []*models.Certificate
@@ -63,6 +78,9 @@ func (c *CertificateService) GetAllCertificates() ([]*models.Certificate, error)
 		return nil, fmt.Errorf("failed to walk certificates directory: %w", err)
 	}
 
+	// Uploaded certificates are tracked alongside the ACME ones
+	certs = append(certs, c.customCertificateEntries()...)
+
 	// Sort by domain
 	sort.Slice(certs, func(i, j int) bool {
 		return certs[i].Domain < certs[j].Domain
@@ -95,7 +113,7 @@ func (c *CertificateService) DeleteCertificate(domain string) error {
 	}
 
 	for _, cert := range certs {
-		if cert.Domain == domain {
+		if cert.Domain == domain && cert.CustomID == "" {
 			// Delete the certificate file
 			if err := os.Remove(cert.FilePath); err != nil {
 				return fmt.Errorf("failed to delete certificate: %w", err)
@@ -147,6 +165,8 @@ func (c *CertificateService) parseCertificate(path string) (*models.Certificate,
 		NotAfter:     cert.NotAfter,
 		SerialNumber: cert.SerialNumber.String(),
 		FilePath:     path,
+		Fingerprint:  certFingerprint(cert),
+		DNSNames:     cert.DNSNames,
 	}
 
 	result.UpdateStatus()
@@ -227,3 +247,412 @@ func (c *CertificateService) GetExpiringCertificates(days int) ([]*models.Certif
 
 	return expiring, nil
 }
+
+// GetCertificateByFingerprint returns a certificate by its SHA-256 fingerprint
+func (c *CertificateService) GetCertificateByFingerprint(fingerprint string) (*models.Certificate, error) {
+	certs, err := c.GetAllCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cert := range certs {
+		if cert.Fingerprint == strings.ToLower(fingerprint) {
+			return cert, nil
+		}
+	}
+
+	return nil, fmt.Errorf("certificate not found: %s", fingerprint)
+}
+
+// InspectCertificate returns the full details of a certificate, including its chain,
+// Caddy's metadata and the cached OCSP staple. Sites are matched by the caller.
+func (c *CertificateService) InspectCertificate(fingerprint string) (*models.CertificateDetail, error) {
+	summary, err := c.GetCertificateByFingerprint(fingerprint)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(summary.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate: %w", err)
+	}
+	chain, err := parsePEMCertificates(content)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("no certificate found in %s", summary.FilePath)
+	}
+	leaf := chain[0]
+
+	detail := &models.CertificateDetail{
+		Certificate:        summary,
+		Subject:            leaf.Subject.String(),
+		SignatureAlgorithm: leaf.SignatureAlgorithm.String(),
+		OCSPServers:        leaf.OCSPServer,
+		Sites:              []string{},
+	}
+	detail.KeyAlgorithm, detail.KeySize = publicKeyInfo(leaf)
+	for _, ip := range leaf.IPAddresses {
+		detail.IPAddresses = append(detail.IPAddresses, ip.String())
+	}
+	for _, cert := range chain {
+		detail.Chain = append(detail.Chain, models.CertificateChain{
+			Subject:     cert.Subject.String(),
+			Issuer:      cert.Issuer.String(),
+			NotAfter:    cert.NotAfter,
+			Fingerprint: certFingerprint(cert),
+			IsCA:        cert.IsCA,
+		})
+	}
+
+	if summary.CustomID != "" {
+		detail.IssuerKey = models.CustomCertificatesDir
+		return detail, nil
+	}
+
+	// Layout: caddy/certificates/<issuer key>/<name>/<name>.crt
+	detail.IssuerKey = filepath.Base(filepath.Dir(filepath.Dir(summary.FilePath)))
+	detail.IssuerDirectory = knownIssuerDirectory(detail.IssuerKey)
+
+	// Caddy's metadata records the directory the certificate was obtained from
+	metaPath := strings.TrimSuffix(summary.FilePath, ".crt") + ".json"
+	if data, err := os.ReadFile(metaPath); err == nil {
+		var indented bytes.Buffer
+		if json.Indent(&indented, data, "", "  ") == nil {
+			detail.Metadata = indented.String()
+		}
+		var meta struct {
+			IssuerData struct {
+				CA string `json:"ca"`
+			} `json:"issuer_data"`
+		}
+		if json.Unmarshal(data, &meta) == nil && meta.IssuerData.CA != "" {
+			detail.IssuerDirectory = meta.IssuerData.CA
+		}
+	}
+
+	if len(chain) > 1 {
+		detail.OCSP = c.ocspStaple(summary, leaf, chain[1])
+	}
+
+	return detail, nil
+}
+
+// ocspStaple reads the OCSP response Caddy cached for a certificate
+func (c *CertificateService) ocspStaple(summary *models.Certificate, leaf, issuer *x509.Certificate) *models.OCSPStaple {
+	name := strings.TrimSuffix(filepath.Base(summary.FilePath), ".crt")
+	matches, _ := filepath.Glob(filepath.Join(c.dataDir, "caddy", "ocsp", name+"-*"))
+
+	for _, path := range matches {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		resp, err := ocsp.ParseResponseForCert(data, leaf, issuer)
+		if err != nil {
+			continue
+		}
+
+		status := "unknown"
+		switch resp.Status {
+		case ocsp.Good:
+			status = "good"
+		case ocsp.Revoked:
+			status = "revoked"
+		}
+		return &models.OCSPStaple{
+			Status:     status,
+			ProducedAt: resp.ProducedAt,
+			NextUpdate: resp.NextUpdate,
+		}
+	}
+	return nil
+}
+
+// CertificateCoverage matches certificates with the managed sites they serve and
+// reports certificates serving no site and domains of enabled sites without a
+// valid certificate
+func CertificateCoverage(certs []*models.Certificate, sites []*models.Site) *models.CertificateCoverage {
+	coverage := &models.CertificateCoverage{
+		Sites:     make(map[string][]string),
+		Unused:    []*models.Certificate{},
+		Uncovered: []string{},
+	}
+
+	for _, cert := range certs {
+		for _, site := range sites {
+			if cert.Serves(site) {
+				coverage.Sites[cert.Fingerprint] = append(coverage.Sites[cert.Fingerprint], site.PrimaryDomain())
+			}
+		}
+		if len(coverage.Sites[cert.Fingerprint]) == 0 {
+			coverage.Unused = append(coverage.Unused, cert)
+		}
+	}
+
+	for _, site := range sites {
+		// Caddy doesn't serve disabled sites, so they need no certificate
+		if site.Disabled {
+			continue
+		}
+		for _, domain := range site.Domains {
+			// Plain HTTP addresses don't need a certificate
+			if strings.HasPrefix(domain, "http://") || strings.HasPrefix(domain, ":") {
+				continue
+			}
+
+			covered := false
+			for _, cert := range certs {
+				if cert.Status != models.CertStatusExpired && cert.Serves(&models.Site{Domains: []string{domain}, TLSMode: site.TLSMode}) {
+					covered = true
+					break
+				}
+			}
+			if !covered {
+				coverage.Uncovered = append(coverage.Uncovered, domain)
+			}
+		}
+	}
+
+	return coverage
+}
+
+// knownIssuerDirectory maps the storage key of a built-in issuer back to its directory URL
+func knownIssuerDirectory(key string) string {
+	for _, dir := range []string{models.LetsEncryptDirectory, models.LetsEncryptStagingDirectory, models.ZeroSSLDirectory} {
+		if models.IssuerKeyFor(dir) == strings.ToLower(key) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// publicKeyInfo returns the key algorithm and size of a certificate
+func publicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch key := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name, key.Curve.Params().BitSize
+	case *rsa.PublicKey:
+		return "RSA", key.N.BitLen()
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	}
+	return cert.PublicKeyAlgorithm.String(), 0
+}
+
+// certFingerprint returns the SHA-256 fingerprint of a certificate
+func certFingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
+
+// customCertsDir returns the custom certificate store directory
+func (c *CertificateService) customCertsDir() string {
+	return filepath.Join(c.dataDir, models.CustomCertificatesDir)
+}
+
+// ImportCustomCertificate validates and stores an uploaded certificate.
+// certPEM holds the leaf certificate (optionally followed by its chain), chainPEM
+// any further intermediates and keyPEM the matching private key.
+func (c *CertificateService) ImportCustomCertificate(name string, certPEM, chainPEM, keyPEM []byte) (*models.CustomCertificate, error) {
+	certs, err := parsePEMCertificates(append(append([]byte{}, certPEM...), append([]byte("\n"), chainPEM...)...))
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in the uploaded PEM")
+	}
+	leaf := certs[0]
+
+	// Re-encode so the stored chain only contains certificates in the right order
+	var fullchain bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&fullchain, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	// X509KeyPair checks that the key matches the leaf certificate
+	if _, err := tls.X509KeyPair(fullchain.Bytes(), keyPEM); err != nil {
+		return nil, fmt.Errorf("certificate and key don't match: %w", err)
+	}
+
+	if time.Now().After(leaf.NotAfter) {
+		return nil, fmt.Errorf("certificate expired on %s", leaf.NotAfter.Format("2006-01-02"))
+	}
+	if len(leaf.DNSNames) == 0 {
+		return nil, fmt.Errorf("certificate has no DNS names (SAN)")
+	}
+
+	// Intermediates must chain up to the leaf
+	for i := 1; i < len(certs); i++ {
+		if err := certs[i-1].CheckSignatureFrom(certs[i]); err != nil {
+			return nil, fmt.Errorf("chain certificate %d (%s) did not sign %s", i, certs[i].Subject.CommonName, certs[i-1].Subject.CommonName)
+		}
+	}
+
+	fingerprint := certFingerprint(leaf)
+
+	if name == "" {
+		name = leaf.DNSNames[0]
+	}
+
+	meta := &models.CustomCertificate{
+		ID:           fingerprint[:16],
+		Name:         name,
+		DNSNames:     leaf.DNSNames,
+		Issuer:       leaf.Issuer.CommonName,
+		NotBefore:    leaf.NotBefore,
+		NotAfter:     leaf.NotAfter,
+		SerialNumber: leaf.SerialNumber.String(),
+		Fingerprint:  fingerprint,
+		ChainLength:  len(certs),
+		UploadedAt:   time.Now(),
+	}
+
+	dir := filepath.Join(c.customCertsDir(), meta.ID)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("failed to create certificate directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "fullchain.pem"), fullchain.Bytes(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to write certificate: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "key.pem"), keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write key: %w", err)
+	}
+	metaJSON, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal certificate info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "meta.json"), metaJSON, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write certificate info: %w", err)
+	}
+
+	return meta, nil
+}
+
+// GetCustomCertificates returns all uploaded certificates
+func (c *CertificateService) GetCustomCertificates() ([]*models.CustomCertificate, error) {
+	entries, err := os.ReadDir(c.customCertsDir())
+	if os.IsNotExist(err) {
+		return []*models.CustomCertificate{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate store: %w", err)
+	}
+
+	certs := []*models.CustomCertificate{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		cert, err := c.GetCustomCertificate(entry.Name())
+		if err != nil {
+			log.Printf("Warning: Could not read custom certificate %s: %v", entry.Name(), err)
+			continue
+		}
+		certs = append(certs, cert)
+	}
+
+	sort.Slice(certs, func(i, j int) bool {
+		return certs[i].Name < certs[j].Name
+	})
+
+	return certs, nil
+}
+
+// GetCustomCertificate returns an uploaded certificate by ID
+func (c *CertificateService) GetCustomCertificate(id string) (*models.CustomCertificate, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("invalid certificate ID: %s", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.customCertsDir(), id, "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("custom certificate not found: %s", id)
+	}
+
+	var cert models.CustomCertificate
+	if err := json.Unmarshal(data, &cert); err != nil {
+		return nil, fmt.Errorf("failed to parse certificate info: %w", err)
+	}
+	return &cert, nil
+}
+
+// DeleteCustomCertificate removes an uploaded certificate from the store
+func (c *CertificateService) DeleteCustomCertificate(id string) error {
+	if _, err := c.GetCustomCertificate(id); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(filepath.Join(c.customCertsDir(), id)); err != nil {
+		return fmt.Errorf("failed to delete certificate: %w", err)
+	}
+	return nil
+}
+
+// CheckCustomCertificate verifies that an uploaded certificate exists and covers all domains
+func (c *CertificateService) CheckCustomCertificate(id string, domains []string) error {
+	cert, err := c.GetCustomCertificate(id)
+	if err != nil {
+		return err
+	}
+
+	leaf := &x509.Certificate{DNSNames: cert.DNSNames}
+	var missing []string
+	for _, domain := range domains {
+		if leaf.VerifyHostname(domain) != nil {
+			missing = append(missing, domain)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("certificate %s doesn't cover %s", cert.Name, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// customCertificateEntries returns uploaded certificates in the common certificate format
+func (c *CertificateService) customCertificateEntries() []*models.Certificate {
+	custom, err := c.GetCustomCertificates()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+
+	var certs []*models.Certificate
+	for _, cc := range custom {
+		cert := &models.Certificate{
+			Domain:       cc.Name,
+			Issuer:       cc.Issuer,
+			NotBefore:    cc.NotBefore,
+			NotAfter:     cc.NotAfter,
+			SerialNumber: cc.SerialNumber,
+			FilePath:     filepath.Join(c.customCertsDir(), cc.ID, "fullchain.pem"),
+			CustomID:     cc.ID,
+			Fingerprint:  cc.Fingerprint,
+			DNSNames:     cc.DNSNames,
+		}
+		cert.UpdateStatus()
+		certs = append(certs, cert)
+	}
+	return certs
+}
+
+// parsePEMCertificates parses every CERTIFICATE block in data
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
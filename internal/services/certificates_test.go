@@ -0,0 +1,186 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// testCertificateChain issues a leaf certificate for names from a new CA and
+// returns the leaf, the CA certificate and the leaf key as PEM
+func testCertificateChain(t *testing.T, names []string) ([]byte, []byte, []byte) {
+	t.Helper()
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, &key.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leafDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func TestInspectCertificate(t *testing.T) {
+	dataDir := t.TempDir()
+	c := NewCertificateService(dataDir)
+
+	leaf, ca, _ := testCertificateChain(t, []string{"app.example.com", "www.example.com"})
+	dir := filepath.Join(dataDir, "caddy", "certificates", models.IssuerKeyFor(models.LetsEncryptDirectory), "app.example.com")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "app.example.com.crt"), append(leaf, ca...), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	certs, err := c.GetAllCertificates()
+	if err != nil || len(certs) != 1 {
+		t.Fatalf("GetAllCertificates() = %v, %v", certs, err)
+	}
+	fingerprint := certs[0].Fingerprint
+
+	detail, err := c.InspectCertificate(strings.ToUpper(fingerprint))
+	if err != nil {
+		t.Fatalf("InspectCertificate: %v", err)
+	}
+	if detail.Subject != "CN=app.example.com" || detail.KeyAlgorithm != "ECDSA P-256" || detail.KeySize != 256 {
+		t.Errorf("detail subject = %q, key = %s %d", detail.Subject, detail.KeyAlgorithm, detail.KeySize)
+	}
+	if !reflect.DeepEqual(detail.DNSNames, []string{"app.example.com", "www.example.com"}) {
+		t.Errorf("DNS names = %q", detail.DNSNames)
+	}
+	if len(detail.Chain) != 2 || detail.Chain[0].Fingerprint != fingerprint || detail.Chain[0].IsCA ||
+		!detail.Chain[1].IsCA || detail.Chain[1].Subject != "CN=Test CA" {
+		t.Errorf("chain = %+v", detail.Chain)
+	}
+	if detail.IssuerKey != models.IssuerKeyFor(models.LetsEncryptDirectory) || detail.IssuerDirectory != models.LetsEncryptDirectory {
+		t.Errorf("issuer = %s (%s)", detail.IssuerKey, detail.IssuerDirectory)
+	}
+	if detail.Metadata != "" || detail.OCSP != nil || detail.Sites == nil {
+		t.Errorf("detail metadata = %q, OCSP = %+v, sites = %v", detail.Metadata, detail.OCSP, detail.Sites)
+	}
+
+	// Caddy's metadata names the directory the certificate came from
+	meta := `{"sans":["app.example.com"],"issuer_data":{"ca":"https://acme.example.net/directory"}}`
+	if err := os.WriteFile(filepath.Join(dir, "app.example.com.json"), []byte(meta), 0644); err != nil {
+		t.Fatal(err)
+	}
+	detail, err = c.InspectCertificate(fingerprint)
+	if err != nil {
+		t.Fatalf("InspectCertificate: %v", err)
+	}
+	if detail.IssuerDirectory != "https://acme.example.net/directory" || !strings.Contains(detail.Metadata, "\n  \"issuer_data\"") {
+		t.Errorf("issuer directory = %s, metadata:\n%s", detail.IssuerDirectory, detail.Metadata)
+	}
+
+	if _, err := c.InspectCertificate("0000"); err == nil {
+		t.Error("unknown certificate inspected")
+	}
+}
+
+func TestInspectCustomCertificate(t *testing.T) {
+	c := NewCertificateService(t.TempDir())
+	leaf, ca, key := testCertificateChain(t, []string{"intranet.example.com"})
+	custom, err := c.ImportCustomCertificate("Intranet", leaf, ca, key)
+	if err != nil {
+		t.Fatalf("ImportCustomCertificate: %v", err)
+	}
+
+	detail, err := c.InspectCertificate(custom.Fingerprint)
+	if err != nil {
+		t.Fatalf("InspectCertificate: %v", err)
+	}
+	if detail.CustomID != custom.ID || detail.IssuerKey != models.CustomCertificatesDir || detail.IssuerDirectory != "" {
+		t.Errorf("detail = custom %q, issuer %s (%s)", detail.CustomID, detail.IssuerKey, detail.IssuerDirectory)
+	}
+	if len(detail.Chain) != 2 {
+		t.Errorf("chain has %d certificates, want 2", len(detail.Chain))
+	}
+}
+
+func TestCertificateCoverage(t *testing.T) {
+	cert := func(fingerprint string, names []string, status models.CertificateStatus, customID string) *models.Certificate {
+		return &models.Certificate{Fingerprint: fingerprint, DNSNames: names, Status: status, CustomID: customID}
+	}
+	wildcard := cert("wildcard", []string{"example.com", "*.example.com"}, models.CertStatusValid, "")
+	expired := cert("expired", []string{"app.example.org"}, models.CertStatusExpired, "")
+	custom := cert("custom", []string{"intranet.example.net"}, models.CertStatusValid, "0123456789abcdef")
+	orphan := cert("orphan", []string{"old.example.net"}, models.CertStatusExpiring, "")
+
+	sites := []*models.Site{
+		{Domains: []string{"example.com", "app.example.com"}},
+		{Domains: []string{"api.example.com"}, TLSMode: "wildcard:example.com"},
+		{Domains: []string{"app.example.org"}},
+		// Served by the uploaded certificate only, not by a matching ACME one
+		{Domains: []string{"intranet.example.net"}, TLSMode: "custom:0123456789abcdef"},
+		{Domains: []string{"deep.app.example.com", "http://plain.example.com", ":8080"}},
+		{Domains: []string{"disabled.example.org"}, Disabled: true},
+	}
+
+	coverage := CertificateCoverage([]*models.Certificate{wildcard, expired, custom, orphan}, sites)
+
+	wantSites := map[string][]string{
+		"wildcard": {"example.com", "api.example.com"},
+		"expired":  {"app.example.org"},
+		"custom":   {"intranet.example.net"},
+	}
+	if !reflect.DeepEqual(coverage.Sites, wantSites) {
+		t.Errorf("sites = %v, want %v", coverage.Sites, wantSites)
+	}
+	if len(coverage.Unused) != 1 || coverage.Unused[0] != orphan {
+		t.Errorf("unused = %v, want the orphan certificate", coverage.Unused)
+	}
+	if want := []string{"app.example.org", "deep.app.example.com"}; !reflect.DeepEqual(coverage.Uncovered, want) {
+		t.Errorf("uncovered = %q, want %q", coverage.Uncovered, want)
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// corsTestPolicy is a policy with every option set
+var corsTestPolicy = models.CORS{
+	Origins:          []string{"https://app.example.com", "https://*.example.org"},
+	Methods:          []string{"GET", "POST"},
+	Headers:          []string{"Authorization", "Content-Type"},
+	AllowCredentials: true,
+	MaxAge:           600,
+}
+
+func TestCORSPreflightSkipsAuthentication(t *testing.T) {
+	users := []string{"alice $2a$14$abcdefghijklmnopqrstuv"}
+	tests := []struct {
+		name       string
+		site       models.Site
+		exemptions int // Authentication matchers letting preflights through
+	}{
+		{
+			name:       "basic auth",
+			site:       models.Site{BasicAuthEnabled: true, BasicAuthUsers: users},
+			exemptions: 1,
+		},
+		{
+			name: "forward auth",
+			site: models.Site{ForwardAuth: &models.ForwardAuth{
+				Provider: models.ForwardAuthAuthelia,
+				Upstream: "http://authelia:9091",
+				URI:      "/api/authz/forward-auth",
+				Bypass:   []string{"/api/webhook"},
+			}},
+			exemptions: 1,
+		},
+		{
+			name: "both with routes",
+			site: models.Site{
+				BasicAuthEnabled: true,
+				BasicAuthUsers:   users,
+				ForwardAuth:      &models.ForwardAuth{Provider: models.ForwardAuthDefault},
+				Routes:           []models.Route{{Path: "/api/*", Backends: []string{"10.0.0.6:9000"}}},
+			},
+			exemptions: 1, // The default provider's matcher is in the snippet
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := tt.site
+			site.Filename = "app.example.com"
+			site.Domains = []string{"app.example.com"}
+			site.TargetIP = "10.0.0.5"
+			site.TargetPort = "8080"
+			policy := corsTestPolicy
+			site.CORS = &policy
+
+			content := site.ToCaddyfile()
+			if !strings.Contains(content, "    vars @cors_preflight cors_preflight yes\n") {
+				t.Errorf("preflight variable is not set:\n%s", content)
+			}
+			if got := strings.Count(content, models.CORSPreflightExemption); got != tt.exemptions {
+				t.Errorf("found %d preflight exemptions, want %d:\n%s", got, tt.exemptions, content)
+			}
+			if site.BasicAuthEnabled && !strings.Contains(content, "    basic_auth "+models.BasicAuthMatcher+" {\n") {
+				t.Errorf("basic_auth doesn't use its matcher:\n%s", content)
+			}
+			if site.ForwardAuth != nil && site.ForwardAuth.Provider != models.ForwardAuthDefault &&
+				!strings.Contains(content, "    forward_auth "+models.ForwardAuthMatcher+" ") {
+				t.Errorf("forward_auth doesn't use its matcher:\n%s", content)
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.CORS, site.CORS) {
+				t.Errorf("parsed CORS = %+v, want %+v", parsed.CORS, site.CORS)
+			}
+			if !reflect.DeepEqual(parsed.BasicAuthUsers, site.BasicAuthUsers) {
+				t.Errorf("parsed basic auth users = %q, want %q", parsed.BasicAuthUsers, site.BasicAuthUsers)
+			}
+			if !reflect.DeepEqual(parsed.ForwardAuth, site.ForwardAuth) {
+				t.Errorf("parsed forward auth = %+v, want %+v", parsed.ForwardAuth, site.ForwardAuth)
+			}
+			if parsed.ExtraConfig != "" {
+				t.Errorf("CORS or authentication leaked into extra config: %q", parsed.ExtraConfig)
+			}
+		})
+	}
+}
+
+func TestAuthenticationSnippetsSkipPreflight(t *testing.T) {
+	cfg := &config.Config{ConfigDir: t.TempDir()}
+	snippets := models.DefaultSnippetConfig()
+	snippets.BasicAuth = models.BasicAuthConfig{Enabled: true, Users: map[string]string{"alice": "$2a$14$abcdefghijklmnopqrstuv"}}
+	snippets.ForwardAuth = models.ForwardAuthConfig{
+		Enabled:  true,
+		Provider: models.ForwardAuthAuthelia,
+		Upstream: "http://authelia:9091",
+		URI:      "/api/authz/forward-auth",
+	}
+	if err := NewSnippetsService(cfg).GenerateSnippetsFile(snippets); err != nil {
+		t.Fatalf("GenerateSnippetsFile: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.ConfigDir, "snippets.caddy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(data)
+
+	for _, name := range []string{"basic_auth", models.ForwardAuthSnippet, models.ForwardAuthBypassSnippet} {
+		start := strings.Index(content, "("+name+") {\n")
+		if start < 0 {
+			t.Errorf("snippet %s is missing:\n%s", name, content)
+			continue
+		}
+		body := content[start:]
+		body = body[:strings.Index(body, "\n}\n")]
+		if !strings.Contains(body, models.CORSPreflightExemption) {
+			t.Errorf("snippet %s doesn't let preflights through:\n%s", name, body)
+		}
+	}
+}
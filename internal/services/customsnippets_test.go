@@ -0,0 +1,96 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestCustomSnippetValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		snippet models.CustomSnippet
+		wantErr bool
+	}{
+		{name: "valid", snippet: models.CustomSnippet{Name: "cache_static", Body: "@static path *.css *.js\nheader @static Cache-Control max-age=3600"}},
+		{name: "nested block", snippet: models.CustomSnippet{Name: "gzip", Body: "encode {\n    gzip 6\n}"}},
+		{name: "uppercase name", snippet: models.CustomSnippet{Name: "Gzip", Body: "encode gzip"}, wantErr: true},
+		{name: "built-in name", snippet: models.CustomSnippet{Name: "security_headers", Body: "encode gzip"}, wantErr: true},
+		{name: "generated name", snippet: models.CustomSnippet{Name: "wildcard-tls-example-com", Body: "encode gzip"}, wantErr: true},
+		{name: "args suffix", snippet: models.CustomSnippet{Name: "gzip_args", Body: "encode gzip"}, wantErr: true},
+		{name: "empty body", snippet: models.CustomSnippet{Name: "gzip", Body: "  \n"}, wantErr: true},
+		{name: "snippet definition", snippet: models.CustomSnippet{Name: "gzip", Body: "(inner) {\n    encode gzip\n}"}, wantErr: true},
+		{name: "missing brace", snippet: models.CustomSnippet{Name: "gzip", Body: "encode {\n    gzip"}, wantErr: true},
+		{name: "unexpected brace", snippet: models.CustomSnippet{Name: "gzip", Body: "encode gzip\n}"}, wantErr: true},
+		{name: "args without params", snippet: models.CustomSnippet{Name: "gzip", Body: "encode gzip {args[0]}"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.snippet.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCustomSnippetsFile(t *testing.T) {
+	cfg := &config.Config{ConfigDir: t.TempDir()}
+	snippets := &models.SnippetConfig{Custom: []models.CustomSnippet{
+		{Name: "cache_static", Description: "Cache static assets", Body: "@static path *.css *.js\nheader @static Cache-Control max-age=3600  \n"},
+		{Name: "gzip", Body: "encode {\n    gzip 6\n}\n\nheader -Server"},
+	}}
+	if err := NewSnippetsService(cfg).GenerateSnippetsFile(snippets); err != nil {
+		t.Fatalf("GenerateSnippetsFile: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.ConfigDir, "snippets.caddy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := string(data)
+	for _, want := range []string{
+		"# Cache static assets\n(cache_static) {\n    @static path *.css *.js\n    header @static Cache-Control max-age=3600\n}\n",
+		"(gzip) {\n    encode {\n        gzip 6\n    }\n\n    header -Server\n}\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("snippets.caddy misses %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestCustomSnippetRoundTrip(t *testing.T) {
+	for _, tlsMode := range []string{"", "wildcard:example.com"} {
+		t.Run("tls "+tlsMode, func(t *testing.T) {
+			site := &models.Site{
+				Filename:   "app.example.com.caddy",
+				Domains:    []string{"app.example.com"},
+				TargetIP:   "10.0.0.5",
+				TargetPort: "8080",
+				TLSMode:    tlsMode,
+				Snippets:   []string{"security_headers", "cache_static", "gzip"},
+			}
+
+			content := site.ToCaddyfile()
+			for _, want := range []string{"    import cache_static\n", "    import gzip\n"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("site file misses %q:\n%s", want, content)
+				}
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.Snippets, site.Snippets) {
+				t.Errorf("parsed snippets = %q, want %q", parsed.Snippets, site.Snippets)
+			}
+			if parsed.SnippetArgs != nil || parsed.ExtraConfig != "" {
+				t.Errorf("custom snippets leaked into arguments %q or extra config %q", parsed.SnippetArgs, parsed.ExtraConfig)
+			}
+		})
+	}
+}
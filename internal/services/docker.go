@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/TomasZmek/cpm/internal/models"
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/client"
 )
@@ -15,6 +17,9 @@ import (
 type DockerService struct {
 	containerName string
 	client        *client.Client
+
+	mu           sync.Mutex
+	adminAddress string // Admin API listener of the running Caddy
 }
 
 // NewDockerService creates a new Docker service
@@ -28,7 +33,30 @@ func NewDockerService(containerName string) *DockerService {
 	return &DockerService{
 		containerName: containerName,
 		client:        cli,
+		adminAddress:  models.DefaultAdminAddress,
+	}
+}
+
+// SetAdminAddress records the admin API listener of the running Caddy
+func (d *DockerService) SetAdminAddress(address string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.adminAddress = address
+}
+
+// reloadCommand returns the caddy reload command. Without --address Caddy posts
+// the new config to the admin listener named in it, which isn't listening yet
+// when the admin option changes.
+func (d *DockerService) reloadCommand(args ...string) []string {
+	d.mu.Lock()
+	address := d.adminAddress
+	d.mu.Unlock()
+
+	cmd := []string{"caddy", "reload", "--config", "/etc/caddy/Caddyfile"}
+	if address != "" {
+		cmd = append(cmd, "--address", address)
 	}
+	return append(cmd, args...)
 }
 
 // IsAvailable checks if Docker is available
@@ -86,7 +114,7 @@ func (d *DockerService) IsContainerRunning() bool {
 
 // ReloadCaddy reloads Caddy configuration
 func (d *DockerService) ReloadCaddy() error {
-	output, err := d.ExecCommandWithOutput("caddy", "reload", "--config", "/etc/caddy/Caddyfile")
+	output, err := d.ExecCommandWithOutput(d.reloadCommand()...)
 	if err != nil {
 		return fmt.Errorf("reload failed: %w\nOutput: %s", err, output)
 	}
@@ -95,7 +123,13 @@ func (d *DockerService) ReloadCaddy() error {
 
 // ReloadCaddyWithOutput reloads Caddy and returns output for debugging
 func (d *DockerService) ReloadCaddyWithOutput() (string, error) {
-	return d.ExecCommandWithOutput("caddy", "reload", "--config", "/etc/caddy/Caddyfile")
+	return d.ExecCommandWithOutput(d.reloadCommand()...)
+}
+
+// ForceReloadCaddyWithOutput reloads Caddy even if the configuration is unchanged,
+// which makes it load certificates from storage again
+func (d *DockerService) ForceReloadCaddyWithOutput() (string, error) {
+	return d.ExecCommandWithOutput(d.reloadCommand("--force")...)
 }
 
 // ValidateConfig validates Caddy configuration
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+)
+
+func TestReloadCommandUsesRunningAdminAddress(t *testing.T) {
+	tests := []struct {
+		name      string
+		caddyfile string
+		want      string
+	}{
+		{name: "no Caddyfile", want: "localhost:2019"},
+		{name: "default", caddyfile: "{\n    email admin@example.com\n}\n", want: "localhost:2019"},
+		{name: "moved", caddyfile: "# Caddyfile\n{\n    servers {\n        protocols h1 h2\n    }\n    admin 0.0.0.0:2020\n}\n", want: "0.0.0.0:2020"},
+		{name: "unix socket", caddyfile: "{\n    admin unix//run/caddy/admin.sock\n}\n", want: "unix//run/caddy/admin.sock"},
+		{name: "site block only", caddyfile: "example.com {\n    admin :9999\n}\n", want: "localhost:2019"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ConfigDir: t.TempDir()}
+			if tt.caddyfile != "" {
+				if err := os.WriteFile(filepath.Join(cfg.ConfigDir, "Caddyfile"), []byte(tt.caddyfile), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			docker := &DockerService{adminAddress: "localhost:2019"}
+			NewCaddyService(cfg, docker)
+
+			want := []string{"caddy", "reload", "--config", "/etc/caddy/Caddyfile", "--address", tt.want, "--force"}
+			if got := docker.reloadCommand("--force"); !reflect.DeepEqual(got, want) {
+				t.Errorf("reloadCommand() = %q, want %q", got, want)
+			}
+		})
+	}
+}
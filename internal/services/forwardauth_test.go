@@ -0,0 +1,185 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestForwardAuthRoundTrip(t *testing.T) {
+	presets := models.ForwardAuthPresets()
+	tests := []struct {
+		name    string
+		auth    models.ForwardAuth
+		want    []string // Generated lines
+		tlsMode string
+	}{
+		{
+			name: "default provider",
+			auth: models.ForwardAuth{Provider: models.ForwardAuthDefault},
+			want: []string{"    import forward_auth"},
+		},
+		{
+			name: "default provider with bypass",
+			auth: models.ForwardAuth{Provider: models.ForwardAuthDefault, Bypass: []string{"/api/webhook", "/health"}},
+			want: []string{"    import forward_auth_bypass /api/webhook /health"},
+		},
+		{
+			name: "authelia",
+			auth: models.ForwardAuth{
+				Provider:    models.ForwardAuthAuthelia,
+				Upstream:    presets[models.ForwardAuthAuthelia].Upstream,
+				URI:         presets[models.ForwardAuthAuthelia].URI,
+				CopyHeaders: presets[models.ForwardAuthAuthelia].CopyHeaders,
+				Bypass:      []string{"/api/webhook"},
+			},
+			want: []string{
+				"    @forward_auth {",
+				"        not path /api/webhook",
+				"        not vars cors_preflight yes",
+				"    }",
+				"    forward_auth @forward_auth http://authelia:9091 {",
+				"        uri /api/authz/forward-auth",
+				"        copy_headers Remote-User Remote-Groups Remote-Email Remote-Name",
+				"    }",
+			},
+		},
+		{
+			name: "authentik proxies its outpost",
+			auth: models.ForwardAuth{
+				Provider: models.ForwardAuthAuthentik,
+				Upstream: presets[models.ForwardAuthAuthentik].Upstream,
+				URI:      presets[models.ForwardAuthAuthentik].URI,
+				Bypass:   []string{"/public/*"},
+			},
+			want: []string{
+				"        not path /outpost.goauthentik.io/* /public/*",
+				"        not vars cors_preflight yes",
+				"    }",
+				"    forward_auth @forward_auth http://authentik-server:9000 {",
+				"        uri /outpost.goauthentik.io/auth/caddy",
+				"    }",
+				"    handle /outpost.goauthentik.io/* {",
+				"        reverse_proxy http://authentik-server:9000",
+				"    }",
+			},
+		},
+		{
+			name: "oauth2-proxy redirects to sign in",
+			auth: models.ForwardAuth{
+				Provider:    models.ForwardAuthOAuth2,
+				Upstream:    presets[models.ForwardAuthOAuth2].Upstream,
+				URI:         presets[models.ForwardAuthOAuth2].URI,
+				CopyHeaders: []string{"X-Auth-Request-User"},
+			},
+			want: []string{
+				"        @forward_auth_denied status 401",
+				"        handle_response @forward_auth_denied {",
+				"            redir * /oauth2/sign_in?rd={scheme}://{host}{uri}",
+				"        }",
+			},
+		},
+		{
+			name: "custom provider",
+			auth: models.ForwardAuth{Provider: models.ForwardAuthCustom, Upstream: "http://auth.internal:8080", URI: "/verify", CopyHeaders: []string{"X-User"}},
+			want: []string{
+				"    @forward_auth {",
+				"        not vars cors_preflight yes",
+				"    }",
+				"    forward_auth @forward_auth http://auth.internal:8080 {",
+			},
+		},
+		{
+			name:    "wildcard site",
+			auth:    models.ForwardAuth{Provider: models.ForwardAuthCustom, Upstream: "http://auth.internal:8080", URI: "/verify"},
+			tlsMode: "wildcard:example.com",
+			want:    []string{"    forward_auth @forward_auth http://auth.internal:8080 {", "        uri /verify", "    }"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auth := tt.auth
+			if err := auth.Validate(); err != nil {
+				t.Fatalf("Validate: %v", err)
+			}
+			site := &models.Site{
+				Filename:    "app.example.com.caddy",
+				Domains:     []string{"app.example.com"},
+				TargetIP:    "10.0.0.5",
+				TargetPort:  "8080",
+				TLSMode:     tt.tlsMode,
+				ForwardAuth: &auth,
+			}
+
+			content := site.ToCaddyfile()
+			if !strings.Contains(content, strings.Join(tt.want, "\n")+"\n") {
+				t.Errorf("site file misses %q:\n%s", tt.want, content)
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.ForwardAuth, site.ForwardAuth) {
+				t.Errorf("parsed forward auth = %+v, want %+v", parsed.ForwardAuth, site.ForwardAuth)
+			}
+			if parsed.Routes != nil || parsed.ExtraConfig != "" {
+				t.Errorf("forward auth leaked into routes %+v or extra config %q", parsed.Routes, parsed.ExtraConfig)
+			}
+		})
+	}
+}
+
+func TestForwardAuthValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		auth models.ForwardAuth
+	}{
+		{name: "unknown provider", auth: models.ForwardAuth{Provider: "keycloak"}},
+		{name: "relative bypass path", auth: models.ForwardAuth{Provider: models.ForwardAuthDefault, Bypass: []string{"api"}}},
+		{name: "bypass path with spaces", auth: models.ForwardAuth{Provider: models.ForwardAuthDefault, Bypass: []string{"/a b"}}},
+		{name: "missing upstream", auth: models.ForwardAuth{Provider: models.ForwardAuthCustom, URI: "/verify"}},
+		{name: "relative URI", auth: models.ForwardAuth{Provider: models.ForwardAuthCustom, Upstream: "http://auth:8080", URI: "verify"}},
+		{name: "header with colon", auth: models.ForwardAuth{Provider: models.ForwardAuthCustom, Upstream: "http://auth:8080", URI: "/verify", CopyHeaders: []string{"X-User:"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.auth.Validate(); err == nil {
+				t.Error("invalid forward auth accepted")
+			}
+		})
+	}
+
+	global := models.ForwardAuthConfig{Enabled: true, Provider: models.ForwardAuthDefault}
+	if err := global.Validate(); err == nil {
+		t.Error("global provider referring to itself accepted")
+	}
+}
+
+func TestForwardAuthSnippetLines(t *testing.T) {
+	global := models.ForwardAuthConfig{Enabled: true, Provider: models.ForwardAuthCustom, Upstream: "http://auth:8080", URI: "/verify"}
+	want := []string{
+		"(forward_auth) {",
+		"    @forward_auth {",
+		"        not vars cors_preflight yes",
+		"    }",
+		"    forward_auth @forward_auth http://auth:8080 {",
+		"        uri /verify",
+		"    }",
+		"}",
+		"",
+		"(forward_auth_bypass) {",
+		"    @forward_auth {",
+		"        not path {args[:]}",
+		"        not vars cors_preflight yes",
+		"    }",
+		"    forward_auth @forward_auth http://auth:8080 {",
+		"        uri /verify",
+		"    }",
+		"}",
+	}
+	if got := global.SnippetLines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SnippetLines() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,586 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// gitIgnoreHeader marks a .gitignore written by CPM, which is kept up to date
+const gitIgnoreHeader = "# Managed by CPM"
+
+// gitIgnore limits the repository to the files CPM manages
+const gitIgnore = gitIgnoreHeader + ` - only configuration files are tracked
+/*
+!/.gitignore
+!/Caddyfile
+!/snippets.caddy
+!/.snippets_config.json
+!/wildcard.json
+!/on_demand_tls.json
+!/global_options.json
+!/sites/
+!/pages/
+`
+
+// ErrGitConflict is returned when local and remote changes touch the same files
+var ErrGitConflict = errors.New("local and remote changes conflict")
+
+// GitService keeps the managed configuration files in a git repository
+type GitService struct {
+	config       *config.Config
+	caddyService *CaddyService
+	reloadCaddy  func() *ReloadResult // Validated reload of pulled configuration
+
+	mu        sync.Mutex
+	conflict  *models.GitConflict
+	lastPush  time.Time
+	lastPull  time.Time
+	lastError string
+}
+
+// NewGitService creates a new git service
+func NewGitService(cfg *config.Config, cs *CaddyService) *GitService {
+	return &GitService{
+		config:       cfg,
+		caddyService: cs,
+		reloadCaddy:  cs.ReloadWithValidation,
+	}
+}
+
+// Enabled returns true if git sync is configured
+func (g *GitService) Enabled() bool {
+	return g.config.GitSync
+}
+
+// Init opens or creates the repository in ConfigDir and commits the current files
+func (g *GitService) Init() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	repo, err := git.PlainOpen(g.config.ConfigDir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInitWithOptions(g.config.ConfigDir, &git.PlainInitOptions{
+			InitOptions: git.InitOptions{DefaultBranch: g.branchRef()},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open repository: %w", err)
+	}
+
+	// A .gitignore written by an older version is replaced so newly managed
+	// files get tracked; one without the header belongs to the user
+	ignorePath := filepath.Join(g.config.ConfigDir, ".gitignore")
+	current, err := os.ReadFile(ignorePath)
+	if os.IsNotExist(err) || err == nil && strings.HasPrefix(string(current), gitIgnoreHeader) && string(current) != gitIgnore {
+		if err := os.WriteFile(ignorePath, []byte(gitIgnore), 0644); err != nil {
+			return fmt.Errorf("failed to write .gitignore: %w", err)
+		}
+	}
+
+	if err := g.configureRemote(repo); err != nil {
+		return err
+	}
+
+	_, err = g.commitLocked(repo, "Initial CPM configuration", "cpm")
+	return err
+}
+
+// Commit commits all changes to the managed files. It returns false if there was nothing to commit.
+func (g *GitService) Commit(message, author string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	repo, err := g.open()
+	if err != nil {
+		return false, err
+	}
+
+	committed, err := g.commitLocked(repo, message, author)
+	if err != nil {
+		g.lastError = err.Error()
+		return false, err
+	}
+
+	if committed && g.config.GitAutoPush && g.config.GitRemote != "" {
+		if err := g.pushLocked(repo); err != nil {
+			g.lastError = err.Error()
+		}
+	}
+
+	return committed, nil
+}
+
+// Push pushes the branch to the remote
+func (g *GitService) Push() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	repo, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	if err := g.pushLocked(repo); err != nil {
+		g.lastError = err.Error()
+		return err
+	}
+	return nil
+}
+
+// Pull fetches the remote branch and applies it through a validated reload.
+// Diverged histories are merged file by file; overlapping changes are
+// recorded as a conflict and ErrGitConflict is returned.
+func (g *GitService) Pull() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	msg, err := g.pullLocked()
+	if err != nil {
+		g.lastError = err.Error()
+	} else {
+		g.lastError = ""
+		g.lastPull = time.Now()
+	}
+	return msg, err
+}
+
+// ResolveConflict resolves a recorded conflict by keeping either the "local" or the "remote" side
+func (g *GitService) ResolveConflict(use string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.conflict == nil {
+		return fmt.Errorf("no conflict to resolve")
+	}
+
+	repo, err := g.open()
+	if err != nil {
+		return err
+	}
+
+	local := plumbing.NewHash(g.conflict.Local)
+	remote := plumbing.NewHash(g.conflict.Remote)
+
+	switch use {
+	case "remote":
+		if err := g.checkout(repo, remote, local); err != nil {
+			g.lastError = err.Error()
+			return err
+		}
+	case "local":
+		wt, err := repo.Worktree()
+		if err != nil {
+			return fmt.Errorf("failed to open worktree: %w", err)
+		}
+		// Record the remote as merged while keeping the local files
+		if _, err := wt.Commit("Merge remote changes, keeping local configuration", &git.CommitOptions{
+			Author:            g.signature("cpm"),
+			Parents:           []plumbing.Hash{local, remote},
+			AllowEmptyCommits: true,
+		}); err != nil {
+			return fmt.Errorf("failed to commit merge: %w", err)
+		}
+		if err := g.pushLocked(repo); err != nil {
+			g.lastError = err.Error()
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown resolution: %s", use)
+	}
+
+	g.conflict = nil
+	g.lastError = ""
+	return nil
+}
+
+// Status returns the repository status and recent commits
+func (g *GitService) Status() *models.GitStatus {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	status := &models.GitStatus{
+		Enabled:   g.Enabled(),
+		Branch:    g.config.GitBranch,
+		Remote:    g.config.GitRemote,
+		Commits:   []models.GitCommit{},
+		Conflict:  g.conflict,
+		LastPush:  g.lastPush,
+		LastPull:  g.lastPull,
+		LastError: g.lastError,
+	}
+	if !status.Enabled {
+		return status
+	}
+
+	repo, err := g.open()
+	if err != nil {
+		status.LastError = err.Error()
+		return status
+	}
+
+	if wt, err := repo.Worktree(); err == nil {
+		if st, err := wt.Status(); err == nil {
+			status.Dirty = !st.IsClean()
+		}
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return status
+	}
+	status.Head = head.Hash().String()
+
+	iter, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return status
+	}
+	defer iter.Close()
+	for len(status.Commits) < 20 {
+		c, err := iter.Next()
+		if err != nil {
+			break
+		}
+		status.Commits = append(status.Commits, models.GitCommit{
+			Hash:    c.Hash.String(),
+			Message: c.Message,
+			Author:  c.Author.Name,
+			When:    c.Author.When,
+		})
+	}
+
+	return status
+}
+
+// pullLocked implements Pull; the caller holds the lock
+func (g *GitService) pullLocked() (string, error) {
+	if g.config.GitRemote == "" {
+		return "", fmt.Errorf("no git remote configured")
+	}
+
+	repo, err := g.open()
+	if err != nil {
+		return "", err
+	}
+
+	// Commit pending changes first so a reset can't lose them
+	if _, err := g.commitLocked(repo, "Uncommitted changes before pull", "cpm"); err != nil {
+		return "", err
+	}
+
+	err = repo.Fetch(&git.FetchOptions{RemoteName: git.DefaultRemoteName, Auth: g.auth()})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("failed to fetch: %w", err)
+	}
+
+	remoteRef, err := repo.Reference(plumbing.NewRemoteReferenceName(git.DefaultRemoteName, g.config.GitBranch), true)
+	if err != nil {
+		return "Remote branch is empty", nil
+	}
+	remote := remoteRef.Hash()
+
+	head, err := repo.Head()
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		// Nothing committed locally yet
+		if err := repo.Storer.SetReference(plumbing.NewHashReference(g.branchRef(), remote)); err != nil {
+			return "", fmt.Errorf("failed to update branch: %w", err)
+		}
+		return "Pulled remote configuration", g.checkout(repo, remote, plumbing.ZeroHash)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read HEAD: %w", err)
+	}
+	local := head.Hash()
+
+	if local == remote {
+		return "Already up to date", nil
+	}
+
+	localCommit, err := repo.CommitObject(local)
+	if err != nil {
+		return "", fmt.Errorf("failed to read local commit: %w", err)
+	}
+	remoteCommit, err := repo.CommitObject(remote)
+	if err != nil {
+		return "", fmt.Errorf("failed to read remote commit: %w", err)
+	}
+
+	if ahead, _ := remoteCommit.IsAncestor(localCommit); ahead {
+		return "Local branch is ahead of the remote", nil
+	}
+	if behind, _ := localCommit.IsAncestor(remoteCommit); behind {
+		return "Pulled remote configuration", g.checkout(repo, remote, local)
+	}
+
+	return g.merge(repo, localCommit, remoteCommit)
+}
+
+// merge combines diverged histories when they changed different files
+func (g *GitService) merge(repo *git.Repository, local, remote *object.Commit) (string, error) {
+	var base *object.Commit
+	if bases, err := local.MergeBase(remote); err == nil && len(bases) > 0 {
+		base = bases[0]
+	}
+
+	localChanges, err := changedFiles(base, local)
+	if err != nil {
+		return "", err
+	}
+	remoteChanges, err := changedFiles(base, remote)
+	if err != nil {
+		return "", err
+	}
+
+	var overlap []string
+	for name := range remoteChanges {
+		if localChanges[name] {
+			overlap = append(overlap, name)
+		}
+	}
+	if base == nil || len(overlap) > 0 {
+		sort.Strings(overlap)
+		g.conflict = &models.GitConflict{
+			Local:      local.Hash.String(),
+			Remote:     remote.Hash.String(),
+			Files:      overlap,
+			DetectedAt: time.Now(),
+		}
+		return "", ErrGitConflict
+	}
+
+	// Take the remote version of every file only the remote changed
+	remoteTree, err := remote.Tree()
+	if err != nil {
+		return "", fmt.Errorf("failed to read remote tree: %w", err)
+	}
+	for name := range remoteChanges {
+		path := filepath.Join(g.config.ConfigDir, filepath.FromSlash(name))
+		file, err := remoteTree.File(name)
+		if errors.Is(err, object.ErrFileNotFound) {
+			os.Remove(path)
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		content, err := file.Contents()
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return "", fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		return "", fmt.Errorf("failed to open worktree: %w", err)
+	}
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return "", fmt.Errorf("failed to stage merge: %w", err)
+	}
+	if _, err := wt.Commit("Merge remote configuration", &git.CommitOptions{
+		Author:            g.signature("cpm"),
+		Parents:           []plumbing.Hash{local.Hash, remote.Hash},
+		AllowEmptyCommits: true,
+	}); err != nil {
+		return "", fmt.Errorf("failed to commit merge: %w", err)
+	}
+
+	if err := g.reload(repo, local.Hash); err != nil {
+		return "", err
+	}
+	return "Merged remote configuration", nil
+}
+
+// checkout moves the branch to target and reloads Caddy, rolling back to previous on failure
+func (g *GitService) checkout(repo *git.Repository, target, previous plumbing.Hash) error {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("failed to open worktree: %w", err)
+	}
+	if err := wt.Reset(&git.ResetOptions{Commit: target, Mode: git.HardReset}); err != nil {
+		return fmt.Errorf("failed to check out %s: %w", target.String()[:7], err)
+	}
+	return g.reload(repo, previous)
+}
+
+// reload validates and reloads Caddy; if that fails the repository is reset to previous
+func (g *GitService) reload(repo *git.Repository, previous plumbing.Hash) error {
+	result := g.reloadCaddy()
+	if result.Success {
+		return nil
+	}
+
+	if !previous.IsZero() {
+		if wt, err := repo.Worktree(); err == nil {
+			wt.Reset(&git.ResetOptions{Commit: previous, Mode: git.HardReset})
+			g.reloadCaddy()
+		}
+	}
+	return fmt.Errorf("pulled configuration failed validation and was rolled back: %s", result.Error)
+}
+
+// commitLocked stages and commits all changes; the caller holds the lock
+func (g *GitService) commitLocked(repo *git.Repository, message, author string) (bool, error) {
+	wt, err := repo.Worktree()
+	if err != nil {
+		return false, fmt.Errorf("failed to open worktree: %w", err)
+	}
+
+	if err := wt.AddWithOptions(&git.AddOptions{All: true}); err != nil {
+		return false, fmt.Errorf("failed to stage changes: %w", err)
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return false, fmt.Errorf("failed to read status: %w", err)
+	}
+	if status.IsClean() {
+		return false, nil
+	}
+
+	if _, err := wt.Commit(message, &git.CommitOptions{Author: g.signature(author)}); err != nil {
+		return false, fmt.Errorf("failed to commit: %w", err)
+	}
+	return true, nil
+}
+
+// pushLocked pushes the branch; the caller holds the lock
+func (g *GitService) pushLocked(repo *git.Repository) error {
+	if g.config.GitRemote == "" {
+		return fmt.Errorf("no git remote configured")
+	}
+
+	ref := g.branchRef()
+	err := repo.Push(&git.PushOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(ref + ":" + ref)},
+		Auth:       g.auth(),
+	})
+	switch {
+	case err == nil, errors.Is(err, git.NoErrAlreadyUpToDate):
+		g.lastPush = time.Now()
+		return nil
+	case errors.Is(err, git.ErrNonFastForwardUpdate):
+		return fmt.Errorf("remote has changes that are not pulled yet")
+	default:
+		return fmt.Errorf("failed to push: %w", err)
+	}
+}
+
+// configureRemote points origin at the configured remote URL
+func (g *GitService) configureRemote(repo *git.Repository) error {
+	remote, err := repo.Remote(git.DefaultRemoteName)
+	if err == nil {
+		urls := remote.Config().URLs
+		if g.config.GitRemote != "" && len(urls) == 1 && urls[0] == g.config.GitRemote {
+			return nil
+		}
+		if err := repo.DeleteRemote(git.DefaultRemoteName); err != nil {
+			return fmt.Errorf("failed to update remote: %w", err)
+		}
+	}
+
+	if g.config.GitRemote == "" {
+		return nil
+	}
+
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{g.config.GitRemote},
+	}); err != nil {
+		return fmt.Errorf("failed to add remote: %w", err)
+	}
+	return nil
+}
+
+// open opens the repository
+func (g *GitService) open() (*git.Repository, error) {
+	if !g.Enabled() {
+		return nil, fmt.Errorf("git sync is not enabled")
+	}
+	repo, err := git.PlainOpen(g.config.ConfigDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository: %w", err)
+	}
+	return repo, nil
+}
+
+// branchRef returns the configured branch reference
+func (g *GitService) branchRef() plumbing.ReferenceName {
+	return plumbing.NewBranchReferenceName(g.config.GitBranch)
+}
+
+// auth returns HTTP credentials if a token is configured
+func (g *GitService) auth() transport.AuthMethod {
+	if g.config.GitToken == "" {
+		return nil
+	}
+	username := g.config.GitUsername
+	if username == "" {
+		username = "cpm"
+	}
+	return &githttp.BasicAuth{Username: username, Password: g.config.GitToken}
+}
+
+// signature returns a commit signature for a CPM user
+func (g *GitService) signature(author string) *object.Signature {
+	if author == "" {
+		author = "cpm"
+	}
+	return &object.Signature{
+		Name:  author,
+		Email: author + "@cpm.local",
+		When:  time.Now(),
+	}
+}
+
+// changedFiles returns the files that differ between base and commit.
+// A nil base means every file in commit counts as changed.
+func changedFiles(base, commit *object.Commit) (map[string]bool, error) {
+	to, err := commit.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree: %w", err)
+	}
+
+	var from *object.Tree
+	if base != nil {
+		if from, err = base.Tree(); err != nil {
+			return nil, fmt.Errorf("failed to read tree: %w", err)
+		}
+	}
+
+	changes, err := object.DiffTree(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff trees: %w", err)
+	}
+
+	files := make(map[string]bool)
+	for _, change := range changes {
+		if change.From.Name != "" {
+			files[change.From.Name] = true
+		}
+		if change.To.Name != "" {
+			files[change.To.Name] = true
+		}
+	}
+	return files, nil
+}
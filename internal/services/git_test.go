@@ -0,0 +1,280 @@
+package services
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// newTestGitService returns a git service for dir that pushes to remote and
+// skips the Caddy reload
+func newTestGitService(t *testing.T, dir, remote string) *GitService {
+	t.Helper()
+	cfg := &config.Config{ConfigDir: dir, GitSync: true, GitRemote: remote, GitBranch: "main"}
+	g := NewGitService(cfg, nil)
+	g.reloadCaddy = func() *ReloadResult { return &ReloadResult{Success: true} }
+	return g
+}
+
+// setupTestGit creates a bare remote and two CPM instances sharing one pushed
+// commit that contains the given files
+func setupTestGit(t *testing.T, files map[string]string) (string, *GitService, *GitService) {
+	t.Helper()
+	remote := t.TempDir()
+	if _, err := git.PlainInit(remote, true); err != nil {
+		t.Fatalf("init remote: %v", err)
+	}
+
+	first := newTestGitService(t, t.TempDir(), remote)
+	for name, content := range files {
+		writeTestFile(t, first, name, content)
+	}
+	if err := first.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	if err := first.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+
+	dir := t.TempDir()
+	if _, err := git.PlainClone(dir, false, &git.CloneOptions{
+		URL:           remote,
+		ReferenceName: plumbing.NewBranchReferenceName("main"),
+	}); err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	return remote, first, newTestGitService(t, dir, remote)
+}
+
+func writeTestFile(t *testing.T, g *GitService, name, content string) {
+	t.Helper()
+	path := filepath.Join(g.config.ConfigDir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestFile(t *testing.T, g *GitService, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(g.config.ConfigDir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func commitTestFile(t *testing.T, g *GitService, name, content string) {
+	t.Helper()
+	writeTestFile(t, g, name, content)
+	committed, err := g.Commit("Update "+name, "test")
+	if err != nil || !committed {
+		t.Fatalf("Commit(%s) = %v, %v", name, committed, err)
+	}
+}
+
+func remoteHead(t *testing.T, remote string) string {
+	t.Helper()
+	repo, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := repo.Reference(plumbing.NewBranchReferenceName("main"), true)
+	if err != nil {
+		t.Fatalf("remote branch: %v", err)
+	}
+	return ref.Hash().String()
+}
+
+func TestGitPushAndFastForwardPull(t *testing.T) {
+	remote, first, second := setupTestGit(t, map[string]string{
+		"Caddyfile": "import sites/*/*.caddy\n",
+	})
+
+	commitTestFile(t, first, "sites/standard/app.caddy", "app.example.com {\n}\n")
+	if err := first.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	if got, want := remoteHead(t, remote), first.Status().Head; got != want {
+		t.Fatalf("remote head = %s, want %s", got, want)
+	}
+
+	msg, err := second.Pull()
+	if err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if msg != "Pulled remote configuration" {
+		t.Errorf("Pull message = %q", msg)
+	}
+	if got := readTestFile(t, second, "sites/standard/app.caddy"); got != "app.example.com {\n}\n" {
+		t.Errorf("pulled file = %q", got)
+	}
+
+	msg, err = second.Pull()
+	if err != nil || msg != "Already up to date" {
+		t.Errorf("second Pull = %q, %v", msg, err)
+	}
+}
+
+func TestGitPullMergesDivergedChanges(t *testing.T) {
+	remote, first, second := setupTestGit(t, map[string]string{
+		"Caddyfile":              "import sites/*/*.caddy\n",
+		"sites/standard/a.caddy": "a.example.com {\n}\n",
+		"sites/standard/b.caddy": "b.example.com {\n}\n",
+	})
+
+	commitTestFile(t, first, "sites/standard/a.caddy", "a.example.com {\n\trespond remote\n}\n")
+	if err := first.Push(); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	commitTestFile(t, second, "sites/standard/b.caddy", "b.example.com {\n\trespond local\n}\n")
+
+	msg, err := second.Pull()
+	if err != nil {
+		t.Fatalf("Pull: %v", err)
+	}
+	if msg != "Merged remote configuration" {
+		t.Errorf("Pull message = %q", msg)
+	}
+	if got := readTestFile(t, second, "sites/standard/a.caddy"); got != "a.example.com {\n\trespond remote\n}\n" {
+		t.Errorf("remote change not merged: %q", got)
+	}
+	if got := readTestFile(t, second, "sites/standard/b.caddy"); got != "b.example.com {\n\trespond local\n}\n" {
+		t.Errorf("local change lost: %q", got)
+	}
+
+	repo, err := git.PlainOpen(second.config.ConfigDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(commit.ParentHashes) != 2 {
+		t.Errorf("merge commit has %d parents, want 2", len(commit.ParentHashes))
+	}
+
+	if err := second.Push(); err != nil {
+		t.Fatalf("Push after merge: %v", err)
+	}
+	if got := remoteHead(t, remote); got != head.Hash().String() {
+		t.Errorf("remote head = %s, want %s", got, head.Hash())
+	}
+}
+
+func TestGitResolveConflict(t *testing.T) {
+	tests := []struct {
+		use  string
+		want string
+	}{
+		{use: "local", want: "app.example.com {\n\trespond local\n}\n"},
+		{use: "remote", want: "app.example.com {\n\trespond remote\n}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.use, func(t *testing.T) {
+			remote, first, second := setupTestGit(t, map[string]string{
+				"sites/standard/app.caddy": "app.example.com {\n}\n",
+			})
+
+			commitTestFile(t, first, "sites/standard/app.caddy", "app.example.com {\n\trespond remote\n}\n")
+			if err := first.Push(); err != nil {
+				t.Fatalf("Push: %v", err)
+			}
+			commitTestFile(t, second, "sites/standard/app.caddy", "app.example.com {\n\trespond local\n}\n")
+
+			if _, err := second.Pull(); !errors.Is(err, ErrGitConflict) {
+				t.Fatalf("Pull error = %v, want ErrGitConflict", err)
+			}
+			conflict := second.Status().Conflict
+			if conflict == nil || len(conflict.Files) != 1 || conflict.Files[0] != "sites/standard/app.caddy" {
+				t.Fatalf("conflict = %+v", conflict)
+			}
+
+			if err := second.ResolveConflict(tt.use); err != nil {
+				t.Fatalf("ResolveConflict(%s): %v", tt.use, err)
+			}
+			if got := readTestFile(t, second, "sites/standard/app.caddy"); got != tt.want {
+				t.Errorf("resolved file = %q, want %q", got, tt.want)
+			}
+			status := second.Status()
+			if status.Conflict != nil {
+				t.Errorf("conflict still recorded after resolving")
+			}
+			if tt.use == "local" && remoteHead(t, remote) != status.Head {
+				t.Errorf("local resolution was not pushed")
+			}
+
+			// The next pull must not report the resolved conflict again
+			if _, err := second.Pull(); err != nil {
+				t.Errorf("Pull after resolving: %v", err)
+			}
+		})
+	}
+}
+
+func TestGitInitUpdatesManagedGitignore(t *testing.T) {
+	tests := []struct {
+		name    string
+		ignore  string
+		want    string
+		tracked bool
+	}{
+		{
+			name:    "older managed file",
+			ignore:  "# Managed by CPM - only configuration files are tracked\n/*\n!/.gitignore\n!/Caddyfile\n",
+			want:    gitIgnore,
+			tracked: true,
+		},
+		{
+			name:   "user file",
+			ignore: "/*\n!/.gitignore\n!/Caddyfile\n",
+			want:   "/*\n!/.gitignore\n!/Caddyfile\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGitService(t, t.TempDir(), "")
+			writeTestFile(t, g, ".gitignore", tt.ignore)
+			writeTestFile(t, g, "Caddyfile", "import sites/*/*.caddy\n")
+			writeTestFile(t, g, "on_demand_tls.json", "{}\n")
+			if err := g.Init(); err != nil {
+				t.Fatalf("Init: %v", err)
+			}
+
+			if got := readTestFile(t, g, ".gitignore"); got != tt.want {
+				t.Errorf(".gitignore = %q, want %q", got, tt.want)
+			}
+
+			repo, err := git.PlainOpen(g.config.ConfigDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			head, err := repo.Head()
+			if err != nil {
+				t.Fatal(err)
+			}
+			commit, err := repo.CommitObject(head.Hash())
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = commit.File("on_demand_tls.json")
+			if tracked := err == nil; tracked != tt.tracked {
+				t.Errorf("on_demand_tls.json tracked = %v, want %v", tracked, tt.tracked)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// GlobalOptionsService manages the global options block of the Caddyfile
+type GlobalOptionsService struct {
+	configPath string
+}
+
+// NewGlobalOptionsService creates a new global options service
+func NewGlobalOptionsService(configDir string) *GlobalOptionsService {
+	return &GlobalOptionsService{
+		configPath: filepath.Join(configDir, "global_options.json"),
+	}
+}
+
+// GetConfig returns the global options
+func (s *GlobalOptionsService) GetConfig() (*models.GlobalOptions, error) {
+	opts := &models.GlobalOptions{}
+
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return opts, nil
+		}
+		return nil, fmt.Errorf("failed to read global options: %w", err)
+	}
+
+	if err := json.Unmarshal(data, opts); err != nil {
+		return nil, fmt.Errorf("failed to parse global options: %w", err)
+	}
+
+	return opts, nil
+}
+
+// SaveConfig validates and saves the global options
+func (s *GlobalOptionsService) SaveConfig(opts *models.GlobalOptions) error {
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(opts, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode global options: %w", err)
+	}
+
+	if err := os.WriteFile(s.configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write global options: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,897 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// CaddyfileBlock is a top-level block (or a nested block) found in a Caddyfile
+type CaddyfileBlock struct {
+	Header   string // e.g. "example.com, www.example.com" or "(snippet)"
+	Body     string // Content between the braces
+	Comments string // Comment lines directly above the header
+	Line     int    // 1-based line of the header
+}
+
+// Raw returns the block as Caddyfile text
+func (b CaddyfileBlock) Raw() string {
+	var sb strings.Builder
+	if b.Comments != "" {
+		sb.WriteString(b.Comments + "\n")
+	}
+	if b.Header != "" {
+		sb.WriteString(b.Header + " {\n")
+	} else {
+		sb.WriteString("{\n")
+	}
+	if body := strings.Trim(b.Body, "\n"); body != "" {
+		sb.WriteString(body + "\n")
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// ImportedSnippet is a (name) { } snippet definition found in an imported Caddyfile
+type ImportedSnippet struct {
+	Name   string
+	Body   string
+	Exists bool   // CPM already has a snippet with this name, which is used instead
+	Error  string // Why the snippet can't be created as a custom snippet
+}
+
+// CustomSnippet returns the custom snippet created for the imported one
+func (s *ImportedSnippet) CustomSnippet() models.CustomSnippet {
+	return models.CustomSnippet{
+		Name:        s.Name,
+		Description: "Imported from Caddyfile",
+		Body:        s.Body,
+	}
+}
+
+// CaddyfileImportEntry is one site that will be created by the import
+type CaddyfileImportEntry struct {
+	ID       string
+	Site     *models.Site
+	Source   string   // Original block text
+	Content  string   // Content that will be written to the site file
+	Mapped   bool     // True if the block was mapped onto the site model, false if kept verbatim
+	Exists   bool     // A site with the same filename already exists
+	Snippets []string // Imported snippets created as custom snippets with the site
+	Blocked  string   // Why the site can't be imported, e.g. a snippet it imports is missing
+	Warnings []string
+}
+
+// Kind returns "wildcard" or "standard"
+func (e *CaddyfileImportEntry) Kind() string {
+	if e.Site.IsWildcard() {
+		return "wildcard"
+	}
+	return "standard"
+}
+
+// CaddyfileImportPlan is the result of analysing a Caddyfile before anything is written
+type CaddyfileImportPlan struct {
+	GlobalOptions string
+	Globals       *models.GlobalOptions // Global options CPM can represent, nil if none
+	Snippets      []ImportedSnippet
+	Entries       []*CaddyfileImportEntry
+	Warnings      []string
+}
+
+// CaddyfileImportResult contains the result of applying an import plan
+type CaddyfileImportResult struct {
+	Imported    int
+	Skipped     int
+	Snippets    int  // Custom snippets created for the imported sites
+	Globals     bool // The global options were imported
+	Errors      []string
+	ReloadError string // Caddy rejected the import, which was rolled back
+}
+
+// CaddyfileImporter converts hand-written Caddyfiles into managed site files
+type CaddyfileImporter struct {
+	config          *config.Config
+	caddyService    *CaddyService
+	wildcardService *WildcardService
+	snippetsService *SnippetsService
+	globalsService  *GlobalOptionsService
+	parser          *ParserService
+}
+
+// NewCaddyfileImporter creates a new Caddyfile importer
+func NewCaddyfileImporter(cfg *config.Config, cs *CaddyService, ws *WildcardService, ss *SnippetsService, gs *GlobalOptionsService) *CaddyfileImporter {
+	return &CaddyfileImporter{
+		config:          cfg,
+		caddyService:    cs,
+		wildcardService: ws,
+		snippetsService: ss,
+		globalsService:  gs,
+		parser:          NewParserService(),
+	}
+}
+
+// ReadConfigCaddyfile returns the Caddyfile currently stored in ConfigDir
+func (i *CaddyfileImporter) ReadConfigCaddyfile() (string, error) {
+	content, err := os.ReadFile(filepath.Join(i.config.ConfigDir, "Caddyfile"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read Caddyfile: %w", err)
+	}
+	return string(content), nil
+}
+
+// Analyze splits a Caddyfile into global options, snippets and site blocks
+// and maps every site block onto a models.Site. Nothing is written to disk.
+func (i *CaddyfileImporter) Analyze(content string) (*CaddyfileImportPlan, error) {
+	blocks, loose, err := SplitCaddyfileBlocks(content)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &CaddyfileImportPlan{}
+
+	for _, line := range loose {
+		plan.Warnings = append(plan.Warnings, fmt.Sprintf("Top-level line ignored: %s", line))
+	}
+
+	existing := make(map[string]bool)
+	if i.caddyService != nil {
+		if sites, err := i.caddyService.GetAllSites(); err == nil {
+			for _, s := range sites {
+				existing[s.Filename] = true
+			}
+		}
+	}
+
+	wildcardDomains := make(map[string]bool)
+	if i.wildcardService != nil {
+		if domains, err := i.wildcardService.GetDomains(); err == nil {
+			for _, wd := range domains {
+				wildcardDomains[wd.Domain] = true
+			}
+		}
+	}
+
+	for idx, block := range blocks {
+		switch {
+		case block.Header == "":
+			if idx == 0 {
+				plan.GlobalOptions = strings.Trim(block.Body, "\n")
+				globals, unsupported := mapGlobalOptions(plan.GlobalOptions)
+				plan.Globals = globals
+				for _, option := range unsupported {
+					plan.Warnings = append(plan.Warnings, fmt.Sprintf("Global option not imported: %s", option))
+				}
+			} else {
+				plan.Warnings = append(plan.Warnings, fmt.Sprintf("Line %d: global options block must be first, ignored", block.Line))
+			}
+
+		case strings.HasPrefix(block.Header, "(") && strings.HasSuffix(block.Header, ")"):
+			plan.Snippets = append(plan.Snippets, ImportedSnippet{
+				Name: strings.TrimSuffix(strings.TrimPrefix(block.Header, "("), ")"),
+				Body: strings.Trim(block.Body, "\n"),
+			})
+
+		case strings.HasPrefix(block.Header, "&("):
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("Line %d: named route %s is not supported, ignored", block.Line, block.Header))
+
+		case strings.HasPrefix(strings.TrimSpace(block.Header), "*."):
+			entries, warnings := i.analyzeWildcardBlock(block, wildcardDomains)
+			plan.Entries = append(plan.Entries, entries...)
+			plan.Warnings = append(plan.Warnings, warnings...)
+
+		default:
+			plan.Entries = append(plan.Entries, i.analyzeSiteBlock(block))
+		}
+	}
+
+	// Assign IDs and detect filename conflicts
+	seen := make(map[string]bool)
+	for n, entry := range plan.Entries {
+		entry.ID = strconv.Itoa(n)
+		if existing[entry.Site.Filename] {
+			entry.Exists = true
+			entry.Warnings = append(entry.Warnings, "A site with this filename already exists")
+		}
+		if seen[entry.Site.Filename] {
+			entry.Warnings = append(entry.Warnings, "Duplicate filename in imported Caddyfile")
+		}
+		seen[entry.Site.Filename] = true
+	}
+
+	i.checkSnippets(plan)
+
+	return plan, nil
+}
+
+// checkSnippets decides which imported snippets can become custom snippets
+// and blocks the entries importing a snippet that is neither in CPM nor
+// importable, since Caddy would reject them
+func (i *CaddyfileImporter) checkSnippets(plan *CaddyfileImportPlan) {
+	available := make(map[string]bool)
+	if i.snippetsService != nil {
+		if names, err := i.snippetsService.GetAvailableSnippets(); err == nil {
+			for _, name := range names {
+				available[name] = true
+			}
+		}
+	}
+
+	defined := make(map[string]*ImportedSnippet)
+	for n := range plan.Snippets {
+		snippet := &plan.Snippets[n]
+		defined[snippet.Name] = snippet
+		if available[snippet.Name] || models.IsReservedSnippetName(snippet.Name) {
+			snippet.Exists = true
+			continue
+		}
+		custom := snippet.CustomSnippet()
+		if err := custom.Validate(); err != nil {
+			snippet.Error = err.Error()
+		}
+	}
+
+	for _, entry := range plan.Entries {
+		created, err := resolveSnippets(entry.Content, defined, available)
+		if err != nil {
+			entry.Blocked = err.Error()
+			entry.Warnings = append(entry.Warnings, "Can't be imported: "+entry.Blocked)
+			continue
+		}
+		entry.Snippets = created
+		for _, name := range created {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("Snippet %s is created as a custom snippet", name))
+		}
+	}
+}
+
+// resolveSnippets returns the imported snippets a site file needs, including
+// the snippets they import themselves, or an error for a snippet that can't be
+// provided. Snippets of CPM are available even when the imported file defines
+// them too.
+func resolveSnippets(content string, defined map[string]*ImportedSnippet, available map[string]bool) ([]string, error) {
+	var created []string
+	seen := make(map[string]bool)
+	queue := snippetImports(content)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] || available[name] || models.IsReservedSnippetName(name) {
+			continue
+		}
+		seen[name] = true
+
+		snippet, ok := defined[name]
+		switch {
+		case !ok:
+			return nil, fmt.Errorf("snippet %s is not defined", name)
+		case snippet.Error != "":
+			return nil, fmt.Errorf("snippet %s can't be imported: %s", name, snippet.Error)
+		}
+		created = append(created, name)
+		queue = append(queue, snippetImports(snippet.Body)...)
+	}
+	return created, nil
+}
+
+// snippetImports returns the snippet names imported by Caddyfile content;
+// file imports like sites/*.caddy are ignored
+func snippetImports(content string) []string {
+	var names []string
+	for _, line := range strings.Split(content, "\n") {
+		tokens := caddyfileTokens(strings.TrimSpace(line))
+		if len(tokens) < 2 || tokens[0] != "import" || strings.ContainsAny(tokens[1], "/*.") {
+			continue
+		}
+		names = append(names, tokens[1])
+	}
+	return names
+}
+
+// mapGlobalOptions maps a global options block onto the options CPM manages.
+// It returns nil if nothing could be mapped, and the options it can't represent.
+func mapGlobalOptions(body string) (*models.GlobalOptions, []string) {
+	opts := &models.GlobalOptions{}
+	mapped := false
+
+	blocks, loose, err := SplitCaddyfileBlocks(body)
+	if err != nil {
+		return nil, []string{err.Error()}
+	}
+
+	var unsupported []string
+	for _, line := range loose {
+		tokens := caddyfileTokens(line)
+		switch {
+		case len(tokens) == 2 && tokens[0] == "email":
+			opts.Email = tokens[1]
+		case len(tokens) == 2 && tokens[0] == "default_sni":
+			opts.DefaultSNI = tokens[1]
+		case len(tokens) == 2 && tokens[0] == "admin" && tokens[1] != "off":
+			opts.Admin = tokens[1]
+		case len(tokens) == 2 && tokens[0] == "grace_period":
+			d, err := time.ParseDuration(tokens[1])
+			if err != nil || d < time.Second || d%time.Second != 0 {
+				unsupported = append(unsupported, line)
+				continue
+			}
+			opts.GracePeriod = int(d / time.Second)
+		case len(tokens) == 1 && tokens[0] == "log":
+			opts.Log.Enabled = true
+		default:
+			unsupported = append(unsupported, line)
+			continue
+		}
+		mapped = true
+	}
+
+	for _, block := range blocks {
+		var rest []string
+		switch block.Header {
+		case "servers":
+			rest = mapServersOption(block.Body, opts)
+		case "log":
+			opts.Log.Enabled = true
+			rest = mapLogOption(block.Body, &opts.Log)
+		default:
+			unsupported = append(unsupported, block.Header+" { … }")
+			continue
+		}
+		for _, line := range rest {
+			unsupported = append(unsupported, block.Header+" { "+line+" }")
+		}
+		mapped = true
+	}
+
+	if !mapped {
+		return nil, unsupported
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, append(unsupported, err.Error())
+	}
+	return opts, unsupported
+}
+
+// mapServersOption maps the servers block and returns the lines it can't represent
+func mapServersOption(body string, opts *models.GlobalOptions) []string {
+	blocks, loose, err := SplitCaddyfileBlocks(body)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var rest []string
+	for _, block := range blocks {
+		rest = append(rest, block.Header+" { … }")
+	}
+	for _, line := range loose {
+		tokens := caddyfileTokens(line)
+		switch {
+		case len(tokens) > 2 && tokens[0] == "trusted_proxies" && tokens[1] == "static":
+			opts.TrustedProxies = tokens[2:]
+		case len(tokens) > 1 && tokens[0] == "protocols":
+			opts.Protocols = tokens[1:]
+		default:
+			rest = append(rest, line)
+		}
+	}
+	return rest
+}
+
+// mapLogOption maps the default logger and returns the lines it can't represent
+func mapLogOption(body string, l *models.GlobalLog) []string {
+	blocks, loose, err := SplitCaddyfileBlocks(body)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	var rest []string
+	for _, block := range blocks {
+		rest = append(rest, block.Header+" { … }")
+	}
+	for _, line := range loose {
+		tokens := caddyfileTokens(line)
+		switch {
+		case len(tokens) == 2 && tokens[0] == "output" && (tokens[1] == "stdout" || tokens[1] == "stderr"):
+			l.Output = tokens[1]
+		case len(tokens) == 3 && tokens[0] == "output" && tokens[1] == "file":
+			l.Output = "file"
+			l.File = tokens[2]
+		case len(tokens) == 2 && tokens[0] == "format":
+			l.Format = tokens[1]
+		case len(tokens) == 2 && tokens[0] == "level":
+			l.Level = strings.ToUpper(tokens[1])
+		default:
+			rest = append(rest, line)
+		}
+	}
+	return rest
+}
+
+// analyzeSiteBlock maps a standard domain { } block onto a site
+func (i *CaddyfileImporter) analyzeSiteBlock(block CaddyfileBlock) *CaddyfileImportEntry {
+	source := block.Raw()
+	domains := CleanDomains(block.Header)
+
+	filename := "unknown"
+	if len(domains) > 0 {
+		filename = sanitizeFilename(domains[0])
+	}
+
+	site := i.parser.Parse(source, filename)
+	site.Filename = filename
+	site.TLSMode = "auto"
+	site.Snippets = importedSnippets(site.Snippets, source)
+
+	entry := &CaddyfileImportEntry{
+		Site:   site,
+		Source: source,
+	}
+
+	generated := site.ToCaddyfile()
+	dropped := droppedLines(source, generated)
+
+	switch {
+	case site.IsProxy() && site.TargetPort == "":
+		// Nothing CPM can model (e.g. file_server, redir) - keep the block as-is
+		entry.Content = source
+		entry.Warnings = append(entry.Warnings, "No reverse_proxy upstream found, block is kept verbatim")
+	case len(dropped) > 0:
+		entry.Content = source
+		entry.Warnings = append(entry.Warnings, "Block is kept verbatim, CPM can't represent: "+strings.Join(dropped, "; "))
+	default:
+		entry.Mapped = true
+		entry.Content = generated
+		if site.ExtraConfig != "" {
+			entry.Warnings = append(entry.Warnings, "Unknown directives moved to extra config")
+		}
+	}
+	site.RawContent = entry.Content
+
+	for _, addr := range domains {
+		if strings.HasPrefix(addr, "http://") || strings.Contains(addr, ":") {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("Address %s includes a scheme or port", addr))
+		}
+	}
+
+	return entry
+}
+
+// analyzeWildcardBlock extracts handle blocks from a *.domain { } block.
+// CPM generates the wildcard block itself, so only the per-host handle blocks are imported.
+func (i *CaddyfileImporter) analyzeWildcardBlock(block CaddyfileBlock, wildcardDomains map[string]bool) ([]*CaddyfileImportEntry, []string) {
+	var entries []*CaddyfileImportEntry
+	var warnings []string
+
+	baseDomain := strings.TrimPrefix(strings.TrimSpace(strings.Split(block.Header, ",")[0]), "*.")
+	if !wildcardDomains[baseDomain] {
+		warnings = append(warnings, fmt.Sprintf("Line %d: wildcard domain *.%s is not configured in CPM, add it under Settings → Wildcard SSL", block.Line, baseDomain))
+	}
+
+	inner, loose, err := SplitCaddyfileBlocks(block.Body)
+	if err != nil {
+		return nil, append(warnings, fmt.Sprintf("Line %d: %v", block.Line, err))
+	}
+
+	// Collect host matchers: @name host a.example.com b.example.com
+	matchers := make(map[string]string)
+	for _, line := range loose {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && strings.HasPrefix(fields[0], "@") && fields[1] == "host" {
+			matchers[strings.TrimPrefix(fields[0], "@")] = strings.Join(fields[2:], " ")
+		}
+	}
+
+	for _, handle := range inner {
+		fields := strings.Fields(handle.Header)
+		if len(fields) != 2 || fields[0] != "handle" || !strings.HasPrefix(fields[1], "@") {
+			continue
+		}
+		name := strings.TrimPrefix(fields[1], "@")
+		hosts, ok := matchers[name]
+		if !ok {
+			continue
+		}
+
+		source := fmt.Sprintf("@%s host %s\n%s", name, hosts, handle.Raw())
+		domains := strings.Fields(hosts)
+		filename := sanitizeFilename(domains[0])
+
+		site := i.parser.Parse(source, filename)
+		site.Filename = filename
+		site.TLSMode = "wildcard:" + baseDomain
+		site.Snippets = importedSnippets(site.Snippets, source)
+
+		entry := &CaddyfileImportEntry{
+			Site:   site,
+			Source: source,
+		}
+		generated := site.ToCaddyfile()
+		dropped := droppedLines(source, generated)
+
+		switch {
+		case site.IsProxy() && site.TargetPort == "":
+			entry.Content = fmt.Sprintf("# @tls: %s\n%s", site.TLSMode, source)
+			entry.Warnings = append(entry.Warnings, "No reverse_proxy upstream found, handle block is kept verbatim")
+		case len(dropped) > 0:
+			entry.Content = fmt.Sprintf("# @tls: %s\n%s", site.TLSMode, source)
+			entry.Warnings = append(entry.Warnings, "Handle block is kept verbatim, CPM can't represent: "+strings.Join(dropped, "; "))
+		default:
+			entry.Mapped = true
+			entry.Content = generated
+			if site.ExtraConfig != "" {
+				entry.Warnings = append(entry.Warnings, "Unknown directives moved to extra config")
+			}
+		}
+		site.RawContent = entry.Content
+
+		if !strings.HasSuffix(domains[0], "."+baseDomain) {
+			entry.Warnings = append(entry.Warnings, fmt.Sprintf("Host %s is not a subdomain of %s", domains[0], baseDomain))
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		warnings = append(warnings, fmt.Sprintf("Line %d: no host handle blocks found in *.%s", block.Line, baseDomain))
+	}
+
+	return entries, warnings
+}
+
+// Apply writes the selected entries of a plan as site files, creates the
+// snippets they need and, if globals is set, imports the global options.
+// Existing sites are skipped unless overwrite is set. Caddy is reloaded when
+// anything was written; if the reload fails, every touched file is restored.
+func (i *CaddyfileImporter) Apply(plan *CaddyfileImportPlan, selected []string, overwrite, globals bool) *CaddyfileImportResult {
+	result := &CaddyfileImportResult{Errors: []string{}}
+
+	var entries []*CaddyfileImportEntry
+	for _, entry := range plan.Entries {
+		if !contains(selected, entry.ID) {
+			continue
+		}
+
+		switch {
+		case entry.Blocked != "":
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %s", entry.Site.PrimaryDomain(), entry.Blocked))
+		case entry.Exists && !overwrite:
+			result.Skipped++
+		default:
+			entries = append(entries, entry)
+		}
+	}
+
+	globals = globals && plan.Globals != nil
+	if len(entries) == 0 && !globals {
+		return result
+	}
+
+	snap, err := snapshotFiles(i.touchedFiles(entries))
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	if err := i.applySnippets(plan, entries, result); err != nil {
+		snap.restore()
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	if globals {
+		if err := i.applyGlobals(plan.Globals); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		} else {
+			result.Globals = true
+		}
+	}
+
+	for _, entry := range entries {
+		dir := i.caddyService.SiteDirectory(entry.Site)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", entry.Site.PrimaryDomain(), err))
+			continue
+		}
+
+		// Remove an existing file in another directory so the site isn't loaded twice
+		if entry.Exists {
+			if old, err := i.caddyService.GetSite(entry.Site.Filename); err == nil && old.Filepath != "" {
+				os.Remove(old.Filepath)
+			}
+		}
+
+		path := filepath.Join(dir, entry.Site.Filename+".caddy")
+		if err := os.WriteFile(path, []byte(entry.Content), 0644); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", entry.Site.PrimaryDomain(), err))
+			continue
+		}
+
+		result.Imported++
+	}
+
+	if result.Imported == 0 && !result.Globals {
+		snap.restore()
+		result.Snippets = 0
+		return result
+	}
+
+	if reload := i.caddyService.ReloadWithValidation(); !reload.Success {
+		snap.restore()
+		if rollback := i.caddyService.ReloadWithValidation(); !rollback.Success {
+			log.Printf("Caddyfile import rollback reload failed: %s", rollback.Error)
+		}
+		result.Imported, result.Snippets, result.Globals = 0, 0, false
+		result.ReloadError = reload.Error
+	}
+
+	return result
+}
+
+// touchedFiles returns the files an import of the entries may write or remove
+func (i *CaddyfileImporter) touchedFiles(entries []*CaddyfileImportEntry) []string {
+	paths := []string{
+		filepath.Join(i.config.ConfigDir, "Caddyfile"),
+		filepath.Join(i.config.ConfigDir, "snippets.caddy"),
+		filepath.Join(i.config.ConfigDir, ".snippets_config.json"),
+		filepath.Join(i.config.ConfigDir, "global_options.json"),
+	}
+	for _, entry := range entries {
+		paths = append(paths, filepath.Join(i.caddyService.SiteDirectory(entry.Site), entry.Site.Filename+".caddy"))
+		if old, err := i.caddyService.GetSite(entry.Site.Filename); err == nil && old.Filepath != "" {
+			paths = append(paths, old.Filepath)
+		}
+	}
+	return paths
+}
+
+// applySnippets creates the custom snippets the entries need
+func (i *CaddyfileImporter) applySnippets(plan *CaddyfileImportPlan, entries []*CaddyfileImportEntry, result *CaddyfileImportResult) error {
+	needed := make(map[string]bool)
+	for _, entry := range entries {
+		for _, name := range entry.Snippets {
+			needed[name] = true
+		}
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+
+	cfg, err := i.snippetsService.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load snippet config: %w", err)
+	}
+	for n := range plan.Snippets {
+		if snippet := &plan.Snippets[n]; needed[snippet.Name] {
+			cfg.Custom = append(cfg.Custom, snippet.CustomSnippet())
+			result.Snippets++
+		}
+	}
+	if err := i.snippetsService.SaveConfig(cfg); err != nil {
+		return fmt.Errorf("failed to save snippets: %w", err)
+	}
+	return nil
+}
+
+// applyGlobals replaces the current values of the imported global options
+// and regenerates the Caddyfile
+func (i *CaddyfileImporter) applyGlobals(imported *models.GlobalOptions) error {
+	opts, err := i.globalsService.GetConfig()
+	if err != nil {
+		return err
+	}
+
+	if imported.Email != "" {
+		opts.Email = imported.Email
+	}
+	if imported.DefaultSNI != "" {
+		opts.DefaultSNI = imported.DefaultSNI
+	}
+	if imported.Admin != "" {
+		opts.Admin = imported.Admin
+	}
+	if imported.GracePeriod > 0 {
+		opts.GracePeriod = imported.GracePeriod
+	}
+	if len(imported.TrustedProxies) > 0 {
+		opts.TrustedProxies = imported.TrustedProxies
+	}
+	if len(imported.Protocols) > 0 {
+		opts.Protocols = imported.Protocols
+	}
+	if imported.Log.Enabled {
+		opts.Log = imported.Log
+	}
+
+	if err := i.globalsService.SaveConfig(opts); err != nil {
+		return fmt.Errorf("failed to save global options: %w", err)
+	}
+	if err := i.caddyService.RegenerateCaddyfile(); err != nil {
+		return fmt.Errorf("failed to regenerate Caddyfile: %w", err)
+	}
+	return nil
+}
+
+// importedSnippets drops the parser's implicit cloudflare_dns default
+// when the block doesn't actually import it
+func importedSnippets(snippets []string, source string) []string {
+	if len(snippets) == 1 && snippets[0] == "cloudflare_dns" && !strings.Contains(source, "import cloudflare_dns") {
+		return []string{}
+	}
+	return snippets
+}
+
+// droppedLines returns directive lines of the source block that are missing
+// from the generated site file. Headers, host matchers and braces are ignored
+// because CPM rewrites them.
+func droppedLines(source, generated string) []string {
+	normalize := func(line string) string {
+		return strings.Join(caddyfileTokens(strings.TrimSpace(line)), " ")
+	}
+
+	have := make(map[string]bool)
+	for _, line := range strings.Split(generated, "\n") {
+		have[normalize(line)] = true
+	}
+
+	var dropped []string
+	headerSeen := false
+	for _, line := range strings.Split(source, "\n") {
+		norm := normalize(line)
+		fields := strings.Fields(norm)
+		switch {
+		case norm == "" || norm == "{" || norm == "}":
+			continue
+		case !headerSeen && fields[len(fields)-1] == "{":
+			// Block header (domain list or handle @matcher)
+			headerSeen = true
+			continue
+		case len(fields) > 1 && strings.HasPrefix(fields[0], "@") && fields[1] == "host":
+			continue
+		}
+		if !have[norm] {
+			dropped = append(dropped, norm)
+		}
+	}
+
+	return dropped
+}
+
+// SplitCaddyfileBlocks splits Caddyfile content into top-level blocks.
+// Lines outside any block (e.g. top-level imports or matchers) are returned separately.
+func SplitCaddyfileBlocks(content string) ([]CaddyfileBlock, []string, error) {
+	var blocks []CaddyfileBlock
+	var loose []string
+
+	var comments []string
+	var header []string
+	var body []string
+	headerLine := 0
+	depth := 0
+
+	for n, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		tokens := caddyfileTokens(trimmed)
+
+		if depth == 0 {
+			if len(tokens) == 0 {
+				if strings.HasPrefix(trimmed, "#") {
+					comments = append(comments, trimmed)
+				} else if len(header) == 0 {
+					comments = nil
+				}
+				continue
+			}
+
+			if len(header) == 0 {
+				headerLine = n + 1
+			}
+
+			opens := tokens[len(tokens)-1] == "{"
+			if !opens {
+				// Address lists can continue on the next line after a trailing comma
+				if strings.HasSuffix(tokens[len(tokens)-1], ",") {
+					header = append(header, strings.Join(tokens, " "))
+					continue
+				}
+				if len(header) > 0 {
+					header = append(header, strings.Join(tokens, " "))
+					loose = append(loose, strings.Join(header, " "))
+					header = nil
+				} else {
+					loose = append(loose, trimmed)
+				}
+				comments = nil
+				continue
+			}
+
+			header = append(header, strings.Join(tokens[:len(tokens)-1], " "))
+			depth = 1
+			continue
+		}
+
+		for _, tok := range tokens {
+			switch tok {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		}
+
+		if depth == 0 {
+			// Closing line of the block; keep anything before the final brace
+			if rest := strings.TrimSpace(strings.TrimSuffix(trimmed, "}")); rest != "" {
+				body = append(body, rest)
+			}
+			blocks = append(blocks, CaddyfileBlock{
+				Header:   strings.TrimSpace(strings.Join(header, " ")),
+				Body:     strings.Join(body, "\n"),
+				Comments: strings.Join(comments, "\n"),
+				Line:     headerLine,
+			})
+			comments, header, body = nil, nil, nil
+			continue
+		}
+
+		if depth < 0 {
+			return nil, nil, fmt.Errorf("line %d: unexpected '}'", n+1)
+		}
+
+		body = append(body, line)
+	}
+
+	if depth > 0 {
+		return nil, nil, fmt.Errorf("line %d: block is not closed", headerLine)
+	}
+
+	return blocks, loose, nil
+}
+
+// caddyfileTokens splits a Caddyfile line into tokens, honouring quotes
+// and dropping comments. Braces only count as tokens when they stand alone.
+func caddyfileTokens(line string) []string {
+	var tokens []string
+	var cur strings.Builder
+	var quote rune
+	inToken := false
+
+	flush := func() {
+		if inToken {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+			inToken = false
+		}
+	}
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			cur.WriteRune(r)
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '`':
+			quote = r
+			inToken = true
+			cur.WriteRune(r)
+		case r == ' ' || r == '\t':
+			flush()
+		case r == '#' && !inToken:
+			flush()
+			return tokens
+		default:
+			inToken = true
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,303 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestSplitCaddyfileBlocks(t *testing.T) {
+	content := `{
+    email admin@example.com
+}
+
+# Shared headers
+(common) {
+    header X-Frame-Options DENY
+}
+
+import sites/*.caddy
+
+a.example.com,
+b.example.com {
+    reverse_proxy 10.0.0.5:8080 {
+        header_up Host {host}
+    }
+}
+c.example.com { respond "ok" }
+`
+
+	blocks, loose, err := SplitCaddyfileBlocks(content)
+	if err != nil {
+		t.Fatalf("SplitCaddyfileBlocks: %v", err)
+	}
+
+	want := []CaddyfileBlock{
+		{Header: "", Body: "    email admin@example.com", Line: 1},
+		{Header: "(common)", Body: "    header X-Frame-Options DENY", Comments: "# Shared headers", Line: 6},
+		{Header: "a.example.com, b.example.com", Body: "    reverse_proxy 10.0.0.5:8080 {\n        header_up Host {host}\n    }", Line: 12},
+	}
+	if len(blocks) != len(want) {
+		t.Fatalf("got %d blocks, want %d: %+v", len(blocks), len(want), blocks)
+	}
+	for n := range want {
+		if !reflect.DeepEqual(blocks[n], want[n]) {
+			t.Errorf("block %d = %+v, want %+v", n, blocks[n], want[n])
+		}
+	}
+	if wantLoose := []string{"import sites/*.caddy", `c.example.com { respond "ok" }`}; !reflect.DeepEqual(loose, wantLoose) {
+		t.Errorf("loose lines = %q, want %q", loose, wantLoose)
+	}
+}
+
+func TestSplitCaddyfileBlocksErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "unclosed", content: "a.example.com {\n    reverse_proxy 10.0.0.5:8080\n"},
+		{name: "extra brace", content: "a.example.com {\n    } }\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := SplitCaddyfileBlocks(tt.content); err == nil {
+				t.Error("invalid Caddyfile accepted")
+			}
+		})
+	}
+}
+
+func TestCaddyfileTokens(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{line: `respond "hello world" 200 # comment`, want: []string{"respond", `"hello world"`, "200"}},
+		{line: "header X-Test a#b", want: []string{"header", "X-Test", "a#b"}},
+		{line: "   ", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := caddyfileTokens(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("caddyfileTokens(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDroppedLines(t *testing.T) {
+	tests := []struct {
+		name      string
+		source    string
+		generated string
+		want      []string
+	}{
+		{
+			name:      "identical up to whitespace",
+			source:    "a.example.com {\n\treverse_proxy   10.0.0.5:8080\n}\n",
+			generated: "a.example.com {\n    reverse_proxy 10.0.0.5:8080\n}\n",
+		},
+		{
+			name:      "comments and host matchers ignored",
+			source:    "@app host a.example.com\nhandle @app {\n    reverse_proxy 10.0.0.5:8080 # backend\n}\n",
+			generated: "handle @app {\n    reverse_proxy 10.0.0.5:8080\n}\n",
+		},
+		{
+			name:      "missing directive",
+			source:    "a.example.com {\n    file_server\n    reverse_proxy 10.0.0.5:8080\n    redir /old /new\n}\n",
+			generated: "a.example.com {\n    reverse_proxy 10.0.0.5:8080\n}\n",
+			want:      []string{"file_server", "redir /old /new"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := droppedLines(tt.source, tt.generated); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("droppedLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCaddyfileImporterMapsSites(t *testing.T) {
+	importer := NewCaddyfileImporter(&config.Config{}, nil, nil, nil, nil)
+	plan, err := importer.Analyze(`app.example.com {
+    reverse_proxy 10.0.0.5:8080
+}
+
+static.example.com {
+    root * /srv
+    file_server
+}
+
+php.example.com {
+    php_fastcgi localhost:9000
+}
+`)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+	if len(plan.Entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(plan.Entries))
+	}
+
+	app := plan.Entries[0]
+	if !app.Mapped || app.Site.Filename != "app.example.com" || app.Site.TargetIP != "10.0.0.5" || app.Site.TargetPort != "8080" {
+		t.Errorf("app entry = mapped %v, site %+v", app.Mapped, app.Site)
+	}
+	if app.Content != app.Site.ToCaddyfile() {
+		t.Errorf("mapped entry is not the generated site file:\n%s", app.Content)
+	}
+
+	static := plan.Entries[1]
+	if !static.Mapped || static.Site.SiteType() != models.SiteTypeStatic {
+		t.Errorf("static entry = mapped %v, type %s", static.Mapped, static.Site.SiteType())
+	}
+
+	php := plan.Entries[2]
+	if php.Mapped || php.Content != php.Source || !strings.Contains(php.Content, "php_fastcgi") {
+		t.Errorf("php entry = mapped %v, content:\n%s", php.Mapped, php.Content)
+	}
+}
+
+func TestCaddyfileImporterSnippets(t *testing.T) {
+	importer := NewCaddyfileImporter(&config.Config{}, nil, nil, nil, nil)
+	plan, err := importer.Analyze(`(headers) {
+    import cache
+}
+(cache) {
+    header Cache-Control "max-age=60"
+}
+(limited) {
+    header X-Limit {args[0]}
+}
+(rate_limit) {
+    header X-Rate none
+}
+
+a.example.com {
+    import headers
+    reverse_proxy 10.0.0.5:8080
+}
+b.example.com {
+    import limited
+    reverse_proxy 10.0.0.6:8080
+}
+c.example.com {
+    import missing
+    reverse_proxy 10.0.0.7:8080
+}
+d.example.com {
+    import rate_limit
+    reverse_proxy 10.0.0.8:8080
+}
+`)
+	if err != nil {
+		t.Fatalf("Analyze: %v", err)
+	}
+
+	snippets := make(map[string]ImportedSnippet)
+	for _, snippet := range plan.Snippets {
+		snippets[snippet.Name] = snippet
+	}
+	if snippets["limited"].Error == "" {
+		t.Error("snippet with arguments but no parameters is importable")
+	}
+	if !snippets["rate_limit"].Exists {
+		t.Error("built-in rate_limit snippet is not reported as existing")
+	}
+
+	tests := []struct {
+		entry    int
+		snippets []string
+		blocked  bool
+	}{
+		{entry: 0, snippets: []string{"headers", "cache"}},
+		{entry: 1, blocked: true},
+		{entry: 2, blocked: true},
+		{entry: 3},
+	}
+	for _, tt := range tests {
+		entry := plan.Entries[tt.entry]
+		if !reflect.DeepEqual(entry.Snippets, tt.snippets) {
+			t.Errorf("%s snippets = %q, want %q", entry.Site.PrimaryDomain(), entry.Snippets, tt.snippets)
+		}
+		if (entry.Blocked != "") != tt.blocked {
+			t.Errorf("%s blocked = %q, want %v", entry.Site.PrimaryDomain(), entry.Blocked, tt.blocked)
+		}
+	}
+}
+
+func TestMapGlobalOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		want        *models.GlobalOptions
+		unsupported []string
+	}{
+		{
+			name: "supported options",
+			body: `email admin@example.com
+admin 0.0.0.0:2019
+grace_period 10s
+servers {
+    trusted_proxies static private_ranges
+    protocols h1 h2
+}
+log {
+    output file /var/log/caddy.log
+    format json
+    level info
+}`,
+			want: &models.GlobalOptions{
+				Email:          "admin@example.com",
+				Admin:          "0.0.0.0:2019",
+				GracePeriod:    10,
+				TrustedProxies: []string{"private_ranges"},
+				Protocols:      []string{"h1", "h2"},
+				Log:            models.GlobalLog{Enabled: true, Output: "file", File: "/var/log/caddy.log", Format: "json", Level: "INFO"},
+			},
+		},
+		{
+			name: "unsupported options",
+			body: `email admin@example.com
+debug
+admin off
+on_demand_tls {
+    ask http://localhost/check
+}
+servers {
+    timeouts {
+        read_body 10s
+    }
+}`,
+			want:        &models.GlobalOptions{Email: "admin@example.com"},
+			unsupported: []string{"debug", "admin off", "on_demand_tls { … }", "servers { timeouts { … } }"},
+		},
+		{
+			name:        "nothing mapped",
+			body:        "auto_https off",
+			unsupported: []string{"auto_https off"},
+		},
+		{
+			name:        "invalid value",
+			body:        "email not-an-address",
+			unsupported: []string{`invalid email "not-an-address"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, unsupported := mapGlobalOptions(tt.body)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("options = %+v, want %+v", got, tt.want)
+			}
+			if !reflect.DeepEqual(unsupported, tt.unsupported) {
+				t.Errorf("unsupported = %q, want %q", unsupported, tt.unsupported)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// maintenanceCheckInterval is how often the maintenance windows are checked
+const maintenanceCheckInterval = 30 * time.Second
+
+// defaultMaintenancePage is written when no maintenance page exists yet
+const defaultMaintenancePage = `<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="utf-8">
+    <meta name="viewport" content="width=device-width, initial-scale=1">
+    <title>Under maintenance</title>
+    <style>
+        body { font-family: system-ui, sans-serif; background: #f5f5f5; color: #333; display: flex; align-items: center; justify-content: center; min-height: 100vh; margin: 0; }
+        main { text-align: center; padding: 2rem; }
+        h1 { font-size: 2rem; margin-bottom: 0.5rem; }
+    </style>
+</head>
+<body>
+    <main>
+        <h1>🚧 Under maintenance</h1>
+        <p>We are working on this site. Please come back in a while.</p>
+    </main>
+</body>
+</html>
+`
+
+// MaintenanceService switches sites into and out of maintenance mode as the
+// maintenance windows start and end
+type MaintenanceService struct {
+	caddyService    *CaddyService
+	snippetsService *SnippetsService
+
+	mu sync.Mutex
+}
+
+// NewMaintenanceService creates a new maintenance service
+func NewMaintenanceService(caddyService *CaddyService, snippetsService *SnippetsService) *MaintenanceService {
+	return &MaintenanceService{
+		caddyService:    caddyService,
+		snippetsService: snippetsService,
+	}
+}
+
+// GetConfig returns the maintenance settings and windows
+func (s *MaintenanceService) GetConfig() (*models.MaintenanceConfig, error) {
+	cfg, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &cfg.Maintenance, nil
+}
+
+// SaveSettings saves the bypass settings and regenerates the snippets. The
+// bypass token is generated when the cookie is enabled and kept until it is
+// disabled.
+func (s *MaintenanceService) SaveSettings(bypassIPs []string, bypassCookie bool, retryAfter int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return err
+	}
+	cfg.Maintenance.BypassIPs = bypassIPs
+	switch {
+	case !bypassCookie:
+		cfg.Maintenance.BypassToken = ""
+	case cfg.Maintenance.BypassToken == "":
+		cfg.Maintenance.BypassToken = generateToken()[:24]
+	}
+	cfg.Maintenance.RetryAfter = retryAfter
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	return s.snippetsService.SaveConfig(cfg)
+}
+
+// AddWindow validates and saves a new maintenance window
+func (s *MaintenanceService) AddWindow(window models.MaintenanceWindow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return err
+	}
+	window.ID = generateToken()[:12]
+	if err := window.Validate(); err != nil {
+		return err
+	}
+	if window.Ended(time.Now()) {
+		return fmt.Errorf("the maintenance window has already ended")
+	}
+	cfg.Maintenance.Windows = append(cfg.Maintenance.Windows, window)
+	return s.snippetsService.SaveConfig(cfg)
+}
+
+// RemoveWindow removes a maintenance window
+func (s *MaintenanceService) RemoveWindow(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cfg, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return err
+	}
+	for i, window := range cfg.Maintenance.Windows {
+		if window.ID == id {
+			cfg.Maintenance.Windows = append(cfg.Maintenance.Windows[:i], cfg.Maintenance.Windows[i+1:]...)
+			return s.snippetsService.SaveConfig(cfg)
+		}
+	}
+	return fmt.Errorf("maintenance window not found: %s", id)
+}
+
+// InMaintenance returns true if an active window covers the site
+func (s *MaintenanceService) InMaintenance(site *models.Site) bool {
+	cfg, err := s.GetConfig()
+	if err != nil {
+		return false
+	}
+	return cfg.InMaintenance(site, time.Now())
+}
+
+// Apply drops ended windows and switches the sites whose maintenance state
+// changed. Caddy is reloaded with validation only if a site changed.
+func (s *MaintenanceService) Apply() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	cfg, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load snippet config: %w", err)
+	}
+
+	var windows []models.MaintenanceWindow
+	for _, window := range cfg.Maintenance.Windows {
+		if !window.Ended(now) {
+			windows = append(windows, window)
+		}
+	}
+	if len(windows) != len(cfg.Maintenance.Windows) {
+		cfg.Maintenance.Windows = windows
+		if err := s.snippetsService.SaveConfig(cfg); err != nil {
+			return fmt.Errorf("failed to save maintenance windows: %w", err)
+		}
+	}
+
+	sites, err := s.caddyService.GetAllSites()
+	if err != nil {
+		return fmt.Errorf("failed to load sites: %w", err)
+	}
+
+	var changed []string
+	inMaintenance := false
+	for _, site := range sites {
+		active := cfg.Maintenance.InMaintenance(site, now)
+		inMaintenance = inMaintenance || active
+		if active == site.Maintenance {
+			continue
+		}
+		site.Maintenance = active
+		if err := s.caddyService.UpdateSite(site); err != nil {
+			return fmt.Errorf("failed to update %s: %w", site.PrimaryDomain(), err)
+		}
+		changed = append(changed, site.PrimaryDomain())
+	}
+
+	// Sites in maintenance need the page, also those switched by the site form
+	if inMaintenance {
+		if _, err := s.caddyService.GetErrorPage(models.MaintenancePageCode); err != nil {
+			if err := s.caddyService.SaveErrorPage(models.MaintenancePageCode, defaultMaintenancePage); err != nil {
+				return fmt.Errorf("failed to write the maintenance page: %w", err)
+			}
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	if result := s.caddyService.ReloadWithValidation(); !result.Success {
+		return fmt.Errorf("reload failed: %s", result.Error)
+	}
+	log.Printf("Maintenance mode switched for %d site(s): %v", len(changed), changed)
+	return nil
+}
+
+// Watch applies the maintenance windows periodically so they start and end
+// on schedule. It returns when stop is closed.
+func (s *MaintenanceService) Watch(stop <-chan struct{}) {
+	ticker := time.NewTicker(maintenanceCheckInterval)
+	defer ticker.Stop()
+
+	s.check()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.check()
+		}
+	}
+}
+
+// check applies the maintenance windows and logs failures
+func (s *MaintenanceService) check() {
+	if err := s.Apply(); err != nil {
+		log.Printf("Maintenance check failed: %v", err)
+	}
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	site := &models.Site{Domains: []string{"app.example.com", "www.example.com"}, Tags: []string{"prod"}}
+	tests := []struct {
+		name       string
+		window     models.MaintenanceWindow
+		wantActive bool
+		wantEnded  bool
+		wantMatch  bool
+	}{
+		{name: "open-ended", window: models.MaintenanceWindow{Site: "app.example.com"}, wantActive: true, wantMatch: true},
+		{name: "started", window: models.MaintenanceWindow{Site: "www.example.com", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}, wantActive: true, wantMatch: true},
+		{name: "starts now", window: models.MaintenanceWindow{Tag: "prod", Start: now}, wantActive: true, wantMatch: true},
+		{name: "scheduled", window: models.MaintenanceWindow{Tag: "prod", Start: now.Add(time.Minute)}, wantMatch: true},
+		{name: "ends now", window: models.MaintenanceWindow{Site: "app.example.com", End: now}, wantEnded: true, wantMatch: true},
+		{name: "other site", window: models.MaintenanceWindow{Site: "api.example.com"}, wantActive: true},
+		{name: "other tag", window: models.MaintenanceWindow{Tag: "staging"}, wantActive: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.window.Active(now); got != tt.wantActive {
+				t.Errorf("Active() = %v, want %v", got, tt.wantActive)
+			}
+			if got := tt.window.Ended(now); got != tt.wantEnded {
+				t.Errorf("Ended() = %v, want %v", got, tt.wantEnded)
+			}
+			if got := tt.window.Matches(site); got != tt.wantMatch {
+				t.Errorf("Matches() = %v, want %v", got, tt.wantMatch)
+			}
+			cfg := models.MaintenanceConfig{Windows: []models.MaintenanceWindow{tt.window}}
+			if got := cfg.InMaintenance(site, now); got != (tt.wantActive && tt.wantMatch) {
+				t.Errorf("InMaintenance() = %v, want %v", got, tt.wantActive && tt.wantMatch)
+			}
+		})
+	}
+}
+
+func TestMaintenanceConfigValidate(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		config  models.MaintenanceConfig
+		wantErr bool
+	}{
+		{name: "valid", config: models.MaintenanceConfig{BypassIPs: []string{"10.0.0.0/8"}, BypassToken: "abcdefgh12", Windows: []models.MaintenanceWindow{{ID: "a", Site: "app.example.com"}, {ID: "b", Tag: "prod"}}}},
+		{name: "site and tag", config: models.MaintenanceConfig{Windows: []models.MaintenanceWindow{{ID: "a", Site: "app.example.com", Tag: "prod"}}}, wantErr: true},
+		{name: "no target", config: models.MaintenanceConfig{Windows: []models.MaintenanceWindow{{ID: "a"}}}, wantErr: true},
+		{name: "ends before start", config: models.MaintenanceConfig{Windows: []models.MaintenanceWindow{{ID: "a", Tag: "prod", Start: now, End: now}}}, wantErr: true},
+		{name: "duplicate window", config: models.MaintenanceConfig{Windows: []models.MaintenanceWindow{{ID: "a", Tag: "prod"}, {ID: "a", Tag: "staging"}}}, wantErr: true},
+		{name: "short token", config: models.MaintenanceConfig{BypassToken: "abc"}, wantErr: true},
+		{name: "invalid bypass network", config: models.MaintenanceConfig{BypassIPs: []string{"10.0.0.0/33"}}, wantErr: true},
+		{name: "negative Retry-After", config: models.MaintenanceConfig{RetryAfter: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMaintenanceSnippetLines(t *testing.T) {
+	cfg := models.MaintenanceConfig{BypassIPs: []string{"10.0.0.0/8"}, BypassToken: "abcdefgh12", RetryAfter: 600}
+	content := strings.Join(cfg.SnippetLines(), "\n")
+	for _, want := range []string{
+		"(maintenance) {\n    @maintenance {\n        not client_ip 10.0.0.0/8\n        not header Cookie *cpm_maintenance=abcdefgh12*\n        not query cpm_maintenance=abcdefgh12\n    }\n",
+		"    @maintenance_bypass query cpm_maintenance=abcdefgh12\n",
+		"    handle @maintenance {\n        header Retry-After \"600\"\n",
+		"(maintenance_route) {\n    handle @maintenance {\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("maintenance snippets miss %q:\n%s", want, content)
+		}
+	}
+
+	cfg = models.MaintenanceConfig{}
+	content = strings.Join(cfg.SnippetLines(), "\n")
+	if !strings.Contains(content, "    @maintenance path *\n") || !strings.Contains(content, `header Retry-After "3600"`) {
+		t.Errorf("maintenance snippet without bypass does not match every request with the default Retry-After:\n%s", content)
+	}
+}
+
+func TestMaintenanceRoundTrip(t *testing.T) {
+	for _, tlsMode := range []string{"", "wildcard:example.com"} {
+		t.Run("tls "+tlsMode, func(t *testing.T) {
+			site := &models.Site{
+				Filename:    "app.example.com.caddy",
+				Domains:     []string{"app.example.com"},
+				TargetIP:    "10.0.0.5",
+				TargetPort:  "8080",
+				TLSMode:     tlsMode,
+				Snippets:    []string{"security_headers"},
+				Maintenance: true,
+			}
+
+			content := site.ToCaddyfile()
+			if !strings.Contains(content, "    import "+models.MaintenanceSnippet+"\n") {
+				t.Errorf("site in maintenance does not import the maintenance snippet:\n%s", content)
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !parsed.Maintenance {
+				t.Errorf("parsed site is not in maintenance")
+			}
+			if !reflect.DeepEqual(parsed.Snippets, site.Snippets) || parsed.ExtraConfig != "" {
+				t.Errorf("maintenance leaked into snippets %q or extra config %q", parsed.Snippets, parsed.ExtraConfig)
+			}
+		})
+	}
+}
+
+// newTestMaintenance returns a maintenance service on empty directories with
+// the given sites and windows. Docker isn't available, so reloads fail.
+func newTestMaintenance(t *testing.T, sites []*models.Site, windows []models.MaintenanceWindow) (*MaintenanceService, *CaddyService) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := &config.Config{ConfigDir: dir, SitesDir: filepath.Join(dir, "sites")}
+	caddyService := NewCaddyService(cfg, &DockerService{})
+	for _, site := range sites {
+		if err := caddyService.CreateSite(site); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snippetsService := NewSnippetsService(cfg)
+	if err := snippetsService.SaveConfig(&models.SnippetConfig{Maintenance: models.MaintenanceConfig{Windows: windows}}); err != nil {
+		t.Fatal(err)
+	}
+	return NewMaintenanceService(caddyService, snippetsService), caddyService
+}
+
+func TestMaintenanceApply(t *testing.T) {
+	now := time.Now()
+	sites := []*models.Site{
+		{Filename: "app.example.com", Domains: []string{"app.example.com"}, TargetIP: "10.0.0.5", TargetPort: "8080", TLSMode: "auto", Tags: []string{"prod"}},
+		{Filename: "api.example.com", Domains: []string{"api.example.com"}, TargetIP: "10.0.0.6", TargetPort: "8080", TLSMode: "auto", Maintenance: true},
+		{Filename: "docs.example.com", Domains: []string{"docs.example.com"}, TargetIP: "10.0.0.7", TargetPort: "8080", TLSMode: "wildcard:example.com", Tags: []string{"prod"}},
+	}
+	windows := []models.MaintenanceWindow{
+		{ID: "prod", Tag: "prod", Start: now.Add(-time.Minute), End: now.Add(time.Hour)},
+		{ID: "ended", Site: "api.example.com", End: now.Add(-time.Minute)},
+		{ID: "scheduled", Site: "api.example.com", Start: now.Add(time.Hour)},
+	}
+	maintenance, caddyService := newTestMaintenance(t, sites, windows)
+
+	err := maintenance.Apply()
+	if err == nil || !strings.Contains(err.Error(), "reload failed") {
+		t.Errorf("Apply() error = %v, want a failed reload after switching sites", err)
+	}
+
+	got, err := caddyService.GetAllSites()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]bool{"app.example.com": true, "api.example.com": false, "docs.example.com": true}
+	for _, site := range got {
+		if site.Maintenance != want[site.PrimaryDomain()] {
+			t.Errorf("%s maintenance = %v, want %v", site.PrimaryDomain(), site.Maintenance, want[site.PrimaryDomain()])
+		}
+	}
+
+	cfg, err := maintenance.GetConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, window := range cfg.Windows {
+		ids = append(ids, window.ID)
+	}
+	if !reflect.DeepEqual(ids, []string{"prod", "scheduled"}) {
+		t.Errorf("windows after Apply = %q, want the ended one dropped", ids)
+	}
+	if _, err := caddyService.GetErrorPage(models.MaintenancePageCode); err != nil {
+		t.Errorf("maintenance page was not written: %v", err)
+	}
+
+	// Nothing changes on the next check, so Caddy isn't reloaded
+	if err := maintenance.Apply(); err != nil {
+		t.Errorf("second Apply() error = %v, want no reload", err)
+	}
+}
+
+func TestMaintenanceApplyWithoutWindows(t *testing.T) {
+	sites := []*models.Site{{Filename: "app.example.com", Domains: []string{"app.example.com"}, TargetIP: "10.0.0.5", TargetPort: "8080", TLSMode: "auto"}}
+	maintenance, caddyService := newTestMaintenance(t, sites, nil)
+
+	if err := maintenance.Apply(); err != nil {
+		t.Errorf("Apply() error = %v, want no reload", err)
+	}
+	if _, err := os.Stat(filepath.Join(caddyService.config.ConfigDir, "pages")); !os.IsNotExist(err) {
+		t.Errorf("maintenance page written without a site in maintenance: %v", err)
+	}
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+// MTLSService manages the client CA store used for mutual TLS
+type MTLSService struct {
+	dataDir string
+	mu      sync.Mutex
+}
+
+// NewMTLSService creates a new mutual TLS service
+func NewMTLSService(dataDir string) *MTLSService {
+	return &MTLSService{
+		dataDir: dataDir,
+	}
+}
+
+// storeDir returns the client CA store directory
+func (m *MTLSService) storeDir() string {
+	return filepath.Join(m.dataDir, models.ClientCAsDir)
+}
+
+// CreateCA creates a CA that can issue client certificates
+func (m *MTLSService) CreateCA(name string, validityDays int) (*models.ClientCA, error) {
+	if name == "" {
+		return nil, fmt.Errorf("CA name is required")
+	}
+	if validityDays <= 0 {
+		validityDays = 3650
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name, Organization: []string{"CPM Client CA"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(0, 0, validityDays),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode CA key: %w", err)
+	}
+
+	ca := newClientCA(name, []*x509.Certificate{cert})
+	ca.Managed = true
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.writeCA(ca, []*x509.Certificate{cert}); err != nil {
+		return nil, err
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	if err := os.WriteFile(filepath.Join(m.storeDir(), ca.ID, "ca-key.pem"), keyPEM, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write CA key: %w", err)
+	}
+
+	return ca, nil
+}
+
+// ImportCA stores an uploaded CA bundle that client certificates are verified against
+func (m *MTLSService) ImportCA(name string, bundle []byte) (*models.ClientCA, error) {
+	certs, err := parsePEMCertificates(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found in the uploaded PEM")
+	}
+	for _, cert := range certs {
+		if !cert.IsCA {
+			return nil, fmt.Errorf("certificate %s is not a CA", cert.Subject.String())
+		}
+	}
+
+	if name == "" {
+		name = certs[0].Subject.CommonName
+	}
+	ca := newClientCA(name, certs)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.writeCA(ca, certs); err != nil {
+		return nil, err
+	}
+	return ca, nil
+}
+
+// GetCAs returns all client CAs
+func (m *MTLSService) GetCAs() ([]*models.ClientCA, error) {
+	entries, err := os.ReadDir(m.storeDir())
+	if os.IsNotExist(err) {
+		return []*models.ClientCA{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA store: %w", err)
+	}
+
+	cas := []*models.ClientCA{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		ca, err := m.GetCA(entry.Name())
+		if err != nil {
+			log.Printf("Warning: Could not read client CA %s: %v", entry.Name(), err)
+			continue
+		}
+		cas = append(cas, ca)
+	}
+
+	sort.Slice(cas, func(i, j int) bool {
+		return cas[i].Name < cas[j].Name
+	})
+
+	return cas, nil
+}
+
+// GetCA returns a client CA by ID
+func (m *MTLSService) GetCA(id string) (*models.ClientCA, error) {
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, fmt.Errorf("invalid CA ID: %s", id)
+	}
+
+	data, err := os.ReadFile(filepath.Join(m.storeDir(), id, "meta.json"))
+	if err != nil {
+		return nil, fmt.Errorf("client CA not found: %s", id)
+	}
+
+	var ca models.ClientCA
+	if err := json.Unmarshal(data, &ca); err != nil {
+		return nil, fmt.Errorf("failed to parse CA info: %w", err)
+	}
+	return &ca, nil
+}
+
+// DeleteCA removes a client CA and its key from the store
+func (m *MTLSService) DeleteCA(id string) error {
+	if _, err := m.GetCA(id); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.RemoveAll(filepath.Join(m.storeDir(), id)); err != nil {
+		return fmt.Errorf("failed to delete CA: %w", err)
+	}
+	return nil
+}
+
+// IssueClientCertificate issues a client certificate from a managed CA and returns it
+// as a password protected PKCS#12 bundle. The private key is not kept by CPM.
+func (m *MTLSService) IssueClientCertificate(caID, commonName, email string, validityDays int, password string) ([]byte, *models.ClientCertificate, error) {
+	if commonName == "" {
+		return nil, nil, fmt.Errorf("common name is required")
+	}
+	if password == "" {
+		return nil, nil, fmt.Errorf("a password for the PKCS#12 file is required")
+	}
+	if validityDays <= 0 {
+		validityDays = 365
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ca, err := m.GetCA(caID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ca.Managed {
+		return nil, nil, fmt.Errorf("CA %s was uploaded and can't issue certificates", ca.Name)
+	}
+	caCert, caKey, err := m.loadCAKeyPair(caID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	notAfter := now.AddDate(0, 0, validityDays)
+	if notAfter.After(caCert.NotAfter) {
+		notAfter = caCert.NotAfter
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if email != "" {
+		template.EmailAddresses = []string{email}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create client certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse client certificate: %w", err)
+	}
+
+	pfx, err := pkcs12.Modern.Encode(key, cert, []*x509.Certificate{caCert}, password)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode PKCS#12: %w", err)
+	}
+
+	issued := models.ClientCertificate{
+		SerialNumber: cert.SerialNumber.String(),
+		Subject:      cert.Subject.String(),
+		CommonName:   commonName,
+		Email:        email,
+		NotAfter:     cert.NotAfter,
+		IssuedAt:     now,
+	}
+	ca.Issued = append(ca.Issued, issued)
+	if err := m.writeMeta(ca); err != nil {
+		return nil, nil, err
+	}
+
+	return pfx, &issued, nil
+}
+
+// RevokeClientCertificate marks an issued certificate as revoked. Caddy rejects it
+// once the snippets are regenerated and reloaded.
+func (m *MTLSService) RevokeClientCertificate(caID, serial string) (*models.ClientCertificate, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ca, err := m.GetCA(caID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range ca.Issued {
+		cert := &ca.Issued[i]
+		if cert.SerialNumber != serial {
+			continue
+		}
+		if !cert.IsRevoked() {
+			now := time.Now()
+			cert.RevokedAt = &now
+			if err := m.writeMeta(ca); err != nil {
+				return nil, err
+			}
+		}
+		return cert, nil
+	}
+
+	return nil, fmt.Errorf("certificate %s not issued by %s", serial, ca.Name)
+}
+
+// RevokedSnippets renders the revocation snippets of all client CAs
+func (m *MTLSService) RevokedSnippets() []string {
+	cas, err := m.GetCAs()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil
+	}
+
+	var lines []string
+	for _, ca := range cas {
+		lines = append(lines, ca.RevokedSnippet()...)
+	}
+	return lines
+}
+
+// writeCA writes the CA bundle and its metadata into the store
+func (m *MTLSService) writeCA(ca *models.ClientCA, certs []*x509.Certificate) error {
+	var bundle bytes.Buffer
+	for _, cert := range certs {
+		pem.Encode(&bundle, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+	}
+
+	dir := filepath.Join(m.storeDir(), ca.ID)
+	if _, err := os.Stat(dir); err == nil {
+		return fmt.Errorf("CA %s is already in the store", ca.Subject)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return fmt.Errorf("failed to create CA directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ca.pem"), bundle.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write CA bundle: %w", err)
+	}
+	return m.writeMeta(ca)
+}
+
+// writeMeta writes the CA metadata including the issued certificates
+func (m *MTLSService) writeMeta(ca *models.ClientCA) error {
+	data, err := json.MarshalIndent(ca, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal CA info: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.storeDir(), ca.ID, "meta.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to write CA info: %w", err)
+	}
+	return nil
+}
+
+// loadCAKeyPair reads the certificate and private key of a managed CA
+func (m *MTLSService) loadCAKeyPair(id string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	dir := filepath.Join(m.storeDir(), id)
+
+	certPEM, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	certs, err := parsePEMCertificates(certPEM)
+	if err != nil || len(certs) == 0 {
+		return nil, nil, fmt.Errorf("failed to parse CA certificate: %v", err)
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(dir, "ca-key.pem"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA key: %w", err)
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("failed to decode CA key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CA key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported CA key type")
+	}
+
+	return certs[0], key, nil
+}
+
+// newClientCA builds the store entry for a CA bundle, identified by the fingerprint of its first certificate
+func newClientCA(name string, certs []*x509.Certificate) *models.ClientCA {
+	sum := sha256.Sum256(certs[0].Raw)
+
+	notAfter := certs[0].NotAfter
+	for _, cert := range certs[1:] {
+		if cert.NotAfter.Before(notAfter) {
+			notAfter = cert.NotAfter
+		}
+	}
+
+	return &models.ClientCA{
+		ID:        hex.EncodeToString(sum[:])[:16],
+		Name:      name,
+		Subject:   certs[0].Subject.String(),
+		NotAfter:  notAfter,
+		CreatedAt: time.Now(),
+	}
+}
+
+// randomSerial returns a random 128-bit certificate serial number
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
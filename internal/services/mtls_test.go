@@ -0,0 +1,262 @@
+package services
+
+import (
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"software.sslmate.com/src/go-pkcs12"
+)
+
+func TestMTLSCreateCA(t *testing.T) {
+	m := NewMTLSService(t.TempDir())
+	ca, err := m.CreateCA("Staff", 30)
+	if err != nil {
+		t.Fatalf("CreateCA: %v", err)
+	}
+	if !ca.Managed || ca.Name != "Staff" || len(ca.ID) != 16 {
+		t.Errorf("CA = %+v", ca)
+	}
+	if until := time.Until(ca.NotAfter); until < 29*24*time.Hour || until > 31*24*time.Hour {
+		t.Errorf("CA valid until %s, want about 30 days", ca.NotAfter)
+	}
+
+	dir := filepath.Join(m.storeDir(), ca.ID)
+	info, err := os.Stat(filepath.Join(dir, "ca-key.pem"))
+	if err != nil {
+		t.Fatalf("CA key not stored: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("CA key permissions = %o, want 600", perm)
+	}
+	bundle, err := os.ReadFile(filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatalf("CA bundle not stored: %v", err)
+	}
+	certs, err := parsePEMCertificates(bundle)
+	if err != nil || len(certs) != 1 || !certs[0].IsCA || certs[0].Subject.CommonName != "Staff" {
+		t.Fatalf("CA bundle = %v, %v", certs, err)
+	}
+
+	cas, err := m.GetCAs()
+	if err != nil || len(cas) != 1 || cas[0].ID != ca.ID {
+		t.Errorf("GetCAs() = %v, %v", cas, err)
+	}
+	if _, err := m.CreateCA("", 30); err == nil {
+		t.Error("CA without a name created")
+	}
+
+	// The same bundle uploaded elsewhere is trusted but can't issue certificates
+	other := NewMTLSService(t.TempDir())
+	imported, err := other.ImportCA("", bundle)
+	if err != nil {
+		t.Fatalf("ImportCA: %v", err)
+	}
+	if imported.Managed || imported.ID != ca.ID || imported.Name != "Staff" {
+		t.Errorf("imported CA = %+v", imported)
+	}
+	if _, _, err := other.IssueClientCertificate(imported.ID, "alice", "", 30, "secret"); err == nil {
+		t.Error("uploaded CA issued a certificate")
+	}
+	if _, err := other.ImportCA("Again", bundle); err == nil {
+		t.Error("CA imported twice")
+	}
+}
+
+func TestMTLSIssueClientCertificate(t *testing.T) {
+	m := NewMTLSService(t.TempDir())
+	ca, err := m.CreateCA("Staff", 10)
+	if err != nil {
+		t.Fatalf("CreateCA: %v", err)
+	}
+
+	pfx, issued, err := m.IssueClientCertificate(ca.ID, "alice", "alice@example.com", 365, "secret")
+	if err != nil {
+		t.Fatalf("IssueClientCertificate: %v", err)
+	}
+
+	key, cert, chain, err := pkcs12.DecodeChain(pfx, "secret")
+	if err != nil {
+		t.Fatalf("decode PKCS#12: %v", err)
+	}
+	if key == nil || len(chain) != 1 || !chain[0].IsCA {
+		t.Errorf("PKCS#12 key = %T, chain = %d certificates", key, len(chain))
+	}
+	if _, _, _, err := pkcs12.DecodeChain(pfx, "wrong"); err == nil {
+		t.Error("PKCS#12 decoded with a wrong password")
+	}
+
+	pool := x509.NewCertPool()
+	pool.AddCert(chain[0])
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool, KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}); err != nil {
+		t.Errorf("client certificate does not verify against the CA: %v", err)
+	}
+	if !reflect.DeepEqual(cert.EmailAddresses, []string{"alice@example.com"}) || cert.Subject.CommonName != "alice" {
+		t.Errorf("certificate subject = %s, emails = %q", cert.Subject, cert.EmailAddresses)
+	}
+	if cert.NotAfter.After(chain[0].NotAfter) {
+		t.Errorf("certificate outlives its CA: %s > %s", cert.NotAfter, chain[0].NotAfter)
+	}
+	if issued.SerialNumber != cert.SerialNumber.String() || issued.CommonName != "alice" {
+		t.Errorf("issued = %+v", issued)
+	}
+
+	stored, err := m.GetCA(ca.ID)
+	if err != nil || len(stored.Issued) != 1 || stored.Issued[0].SerialNumber != issued.SerialNumber {
+		t.Errorf("stored CA = %+v, %v", stored, err)
+	}
+
+	tests := []struct {
+		name       string
+		caID       string
+		commonName string
+		password   string
+	}{
+		{name: "no common name", caID: ca.ID, password: "secret"},
+		{name: "no password", caID: ca.ID, commonName: "bob"},
+		{name: "unknown CA", caID: "0000000000000000", commonName: "bob", password: "secret"},
+		{name: "invalid CA ID", caID: "../" + ca.ID, commonName: "bob", password: "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := m.IssueClientCertificate(tt.caID, tt.commonName, "", 30, tt.password); err == nil {
+				t.Error("certificate issued")
+			}
+		})
+	}
+}
+
+func TestMTLSRevokedSnippets(t *testing.T) {
+	m := NewMTLSService(t.TempDir())
+	if lines := m.RevokedSnippets(); lines != nil {
+		t.Errorf("empty store rendered %q", lines)
+	}
+
+	ca, err := m.CreateCA("Staff", 30)
+	if err != nil {
+		t.Fatalf("CreateCA: %v", err)
+	}
+	want := []string{
+		"(" + models.ClientCARevokedSnippet(ca.ID) + ") {",
+		"}",
+		"(" + models.ClientCARevokedRouteSnippet(ca.ID) + ") {",
+		"}",
+	}
+	if got := m.RevokedSnippets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RevokedSnippets() = %q, want %q", got, want)
+	}
+
+	var serials []string
+	for _, name := range []string{"alice", "bob", "carol"} {
+		_, issued, err := m.IssueClientCertificate(ca.ID, name, "", 30, "secret")
+		if err != nil {
+			t.Fatalf("IssueClientCertificate: %v", err)
+		}
+		serials = append(serials, issued.SerialNumber)
+	}
+
+	revoked, err := m.RevokeClientCertificate(ca.ID, serials[0])
+	if err != nil || !revoked.IsRevoked() {
+		t.Fatalf("RevokeClientCertificate = %+v, %v", revoked, err)
+	}
+	if _, err := m.RevokeClientCertificate(ca.ID, serials[2]); err != nil {
+		t.Fatalf("RevokeClientCertificate: %v", err)
+	}
+	again, err := m.RevokeClientCertificate(ca.ID, serials[0])
+	if err != nil || !again.RevokedAt.Equal(*revoked.RevokedAt) {
+		t.Errorf("revoking twice changed the revocation time: %v, %v", again.RevokedAt, err)
+	}
+	if _, err := m.RevokeClientCertificate(ca.ID, "12345"); err == nil {
+		t.Error("unknown certificate revoked")
+	}
+
+	want = []string{
+		"(" + models.ClientCARevokedSnippet(ca.ID) + ") {",
+		"    @mtls_revoked vars {http.request.tls.client.serial} " + serials[0] + " " + serials[2],
+		"    error @mtls_revoked 403",
+		"}",
+		"(" + models.ClientCARevokedRouteSnippet(ca.ID) + ") {",
+		"    error @mtls_revoked 403",
+		"}",
+	}
+	if got := m.RevokedSnippets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RevokedSnippets() = %q, want %q", got, want)
+	}
+}
+
+func TestClientAuthMatcherLines(t *testing.T) {
+	tests := []struct {
+		name string
+		auth models.ClientAuth
+		want []string
+	}{
+		{name: "no allow-list"},
+		{
+			name: "subjects",
+			auth: models.ClientAuth{AllowedSubjects: []string{"CN=alice,O=Example", "CN=bob"}},
+			want: []string{
+				"@mtls_denied {",
+				`    not vars {http.request.tls.client.subject} "CN=alice,O=Example" "CN=bob"`,
+				"}",
+				"error @mtls_denied 403",
+			},
+		},
+		{
+			name: "SANs",
+			auth: models.ClientAuth{AllowedSANs: []string{"alice@example.com", "host.example.com"}},
+			want: []string{
+				"@mtls_denied {",
+				`    not vars {http.request.tls.client.san.emails.0} "alice@example.com" "host.example.com"`,
+				`    not vars {http.request.tls.client.san.dns_names.0} "alice@example.com" "host.example.com"`,
+				"}",
+				"error @mtls_denied 403",
+			},
+		},
+		{
+			name: "subjects and SANs",
+			auth: models.ClientAuth{AllowedSubjects: []string{"CN=alice"}, AllowedSANs: []string{"alice@example.com"}},
+			want: []string{
+				"@mtls_denied {",
+				`    not vars {http.request.tls.client.subject} "CN=alice"`,
+				`    not vars {http.request.tls.client.san.emails.0} "alice@example.com"`,
+				`    not vars {http.request.tls.client.san.dns_names.0} "alice@example.com"`,
+				"}",
+				"error @mtls_denied 403",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.auth.MatcherLines(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MatcherLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientAuthValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		auth  models.ClientAuth
+		valid bool
+	}{
+		{name: "valid", auth: models.ClientAuth{Mode: models.ClientAuthRequireAndVerify, CAID: "0123456789abcdef", AllowedSANs: []string{"alice@example.com"}}, valid: true},
+		{name: "unknown mode", auth: models.ClientAuth{Mode: "request", CAID: "0123456789abcdef"}},
+		{name: "no CA", auth: models.ClientAuth{Mode: models.ClientAuthVerifyIfGiven}},
+		{name: "placeholder in allow-list", auth: models.ClientAuth{Mode: models.ClientAuthVerifyIfGiven, CAID: "0123456789abcdef", AllowedSubjects: []string{"CN={env.X}"}}},
+		{name: "quote in allow-list", auth: models.ClientAuth{Mode: models.ClientAuthVerifyIfGiven, CAID: "0123456789abcdef", AllowedSANs: []string{`a"b`}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.auth.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Validate() = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// OnDemandService manages the on-demand TLS settings and answers Caddy's ask requests
+type OnDemandService struct {
+	configPath    string
+	defaultAskURL string
+}
+
+// NewOnDemandService creates a new on-demand TLS service
+func NewOnDemandService(cfg *config.Config) *OnDemandService {
+	return &OnDemandService{
+		configPath: filepath.Join(cfg.ConfigDir, "on_demand_tls.json"),
+		// Caddy reaches CPM by its container name (see docker-compose.yml)
+		defaultAskURL: fmt.Sprintf("http://cpm:%d%s", cfg.Port, models.OnDemandAskPath),
+	}
+}
+
+// GetConfig returns the on-demand TLS settings
+func (s *OnDemandService) GetConfig() (*models.OnDemandTLSConfig, error) {
+	cfg := &models.OnDemandTLSConfig{
+		AskURL:         s.defaultAskURL,
+		AllowedDomains: []string{},
+	}
+
+	data, err := os.ReadFile(s.configPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, fmt.Errorf("failed to read on-demand TLS config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse on-demand TLS config: %w", err)
+	}
+	if cfg.AskURL == "" {
+		cfg.AskURL = s.defaultAskURL
+	}
+
+	return cfg, nil
+}
+
+// SaveConfig validates and saves the on-demand TLS settings
+func (s *OnDemandService) SaveConfig(cfg *models.OnDemandTLSConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode on-demand TLS config: %w", err)
+	}
+
+	if err := os.WriteFile(s.configPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write on-demand TLS config: %w", err)
+	}
+
+	return nil
+}
+
+// CheckSite returns an error if an enabled site obtains certificates on
+// demand while on-demand TLS is off, since Caddy would then issue them
+// without asking CPM first
+func (s *OnDemandService) CheckSite(site *models.Site) error {
+	if !site.OnDemand || site.Disabled {
+		return nil
+	}
+	cfg, err := s.GetConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled {
+		return fmt.Errorf("enable on-demand TLS in the Caddy settings before using on-demand certificates")
+	}
+	return nil
+}
+
+// CheckConfig returns an error if the settings turn on-demand TLS off while
+// enabled sites still obtain certificates on demand
+func (s *OnDemandService) CheckConfig(cfg *models.OnDemandTLSConfig, sites []*models.Site) error {
+	if cfg.Enabled {
+		return nil
+	}
+	var domains []string
+	for _, site := range sites {
+		if site.OnDemand && !site.Disabled {
+			domains = append(domains, site.PrimaryDomain())
+		}
+	}
+	if len(domains) > 0 {
+		return fmt.Errorf("on-demand TLS is used by %s", strings.Join(domains, ", "))
+	}
+	return nil
+}
+
+// Allows returns true if Caddy may obtain a certificate for the domain: it is
+// on the allow-list or a host of a site marked on-demand
+func (s *OnDemandService) Allows(domain string, sites []*models.Site) bool {
+	cfg, err := s.GetConfig()
+	if err != nil {
+		log.Printf("Error loading on-demand TLS config: %v", err)
+		return false
+	}
+	if !cfg.Enabled {
+		return false
+	}
+
+	return cfg.Matches(domain) || onDemandSite(domain, sites) != nil
+}
+
+// Certificates returns the stored certificates Caddy obtained on demand
+func (s *OnDemandService) Certificates(certs []*models.Certificate, sites []*models.Site) []*models.OnDemandCertificate {
+	cfg, err := s.GetConfig()
+	if err != nil {
+		return nil
+	}
+
+	var issued []*models.OnDemandCertificate
+	for _, cert := range certs {
+		if cert.CustomID != "" {
+			continue
+		}
+		if site := onDemandSite(cert.Domain, sites); site != nil {
+			issued = append(issued, &models.OnDemandCertificate{Certificate: cert, Site: site.PrimaryDomain()})
+		} else if cfg.Matches(cert.Domain) {
+			issued = append(issued, &models.OnDemandCertificate{Certificate: cert})
+		}
+	}
+
+	return issued
+}
+
+// onDemandSite returns the on-demand site with a host matching the domain
+func onDemandSite(domain string, sites []*models.Site) *models.Site {
+	for _, site := range sites {
+		if !site.OnDemand || site.Disabled {
+			continue
+		}
+		for _, address := range site.Domains {
+			if models.MatchDomainPattern(models.SiteHost(address), domain) {
+				return site
+			}
+		}
+	}
+	return nil
+}
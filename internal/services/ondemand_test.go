@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestOnDemandRequiresGlobalSetting(t *testing.T) {
+	s := NewOnDemandService(&config.Config{ConfigDir: t.TempDir(), Port: 8501})
+	site := &models.Site{Domains: []string{"app.example.com"}, TLSMode: models.IssuerLetsEncrypt, OnDemand: true}
+
+	if err := s.CheckSite(site); err == nil {
+		t.Error("on-demand site accepted while on-demand TLS is off")
+	}
+	disabled := *site
+	disabled.Disabled = true
+	if err := s.CheckSite(&disabled); err != nil {
+		t.Errorf("disabled on-demand site rejected: %v", err)
+	}
+
+	if err := s.SaveConfig(&models.OnDemandTLSConfig{Enabled: true, AskURL: "http://cpm:8501" + models.OnDemandAskPath}); err != nil {
+		t.Fatalf("SaveConfig: %v", err)
+	}
+	if err := s.CheckSite(site); err != nil {
+		t.Errorf("on-demand site rejected while on-demand TLS is on: %v", err)
+	}
+
+	off := &models.OnDemandTLSConfig{}
+	if err := s.CheckConfig(off, []*models.Site{site}); err == nil {
+		t.Error("on-demand TLS turned off while a site uses it")
+	}
+	if err := s.CheckConfig(off, []*models.Site{&disabled}); err != nil {
+		t.Errorf("on-demand TLS can't be turned off for a disabled site: %v", err)
+	}
+}
@@ -54,8 +54,48 @@ func (p *ParserService) Parse(content, filenameDomain string) *models.Site {
 		site.Domains = p.parseDomains(content, filenameDomain)
 	}
 
+	// Checks repeated inside the route blocks, rendered again from the site-level ones
+	content = p.parseRouteChecks(content)
+
+	// Access lists, imported as acl_<id> snippets
+	site.AccessLists, content = p.parseAccessLists(content)
+
+	// Forward auth, parsed first as it contains handle blocks and a reverse proxy of its own
+	site.ForwardAuth, content = p.parseForwardAuth(content)
+
+	// CORS, parsed before the site type and routes as it adds respond directives to them
+	site.CORS, content = p.parseCORS(content)
+
+	// Maintenance mode, imported at site level and in the route blocks
+	site.Maintenance, content = p.parseMaintenance(content)
+
+	// Redirect, static and respond sites; their directives aren't parsed as extra config
+	content = p.parseSiteType(content, site)
+
+	// Path routes are parsed on their own, the rest is parsed as the default backend
+	site.Routes, content = p.parseRoutes(content)
+
+	// Header rules of the site and the default backend
+	site.HeaderRules, content = p.parseHeaderRules(content)
+
 	// Parse snippets
-	site.Snippets = p.parseSnippets(content)
+	site.Snippets, site.SnippetArgs = p.parseSnippets(content)
+
+	// Parse certificate issuer settings
+	if site.HasIssuer() {
+		p.parseIssuer(content, site)
+	}
+
+	// Parse client certificate authentication
+	site.ClientAuth = p.parseClientAuth(content)
+
+	// Detect on-demand certificates
+	site.OnDemand = regexp.MustCompile(`(?m)^\s*on_demand\s*$`).MatchString(content)
+
+	// The Cloudflare DNS challenge is written into the generated tls block instead of the snippet import
+	if site.ManagesTLS() && regexp.MustCompile(`(?m)^\s*(dns\s+cloudflare\b|import\s+`+models.CloudflareDNSChallengeSnippet+`\s*$)`).MatchString(content) && !contains(site.Snippets, "cloudflare_dns") {
+		site.Snippets = append(site.Snippets, "cloudflare_dns")
+	}
 
 	// Detect internal access
 	site.IsInternal = contains(site.Snippets, "internal_only")
@@ -85,7 +125,7 @@ func (p *ParserService) Parse(content, filenameDomain string) *models.Site {
 	site.BasicAuthEnabled, site.BasicAuthUsers = p.parseBasicAuth(content)
 
 	// Parse extra config
-	site.ExtraConfig = p.parseExtraConfig(content)
+	site.ExtraConfig = p.parseExtraConfig(content, site.ManagesTLS())
 
 	return site
 }
@@ -123,7 +163,7 @@ func (p *ParserService) parseDomainsFromHandleBlock(content, defaultDomain strin
 
 // parseTags extracts tags from # @tags: comment
 func (p *ParserService) parseTags(content string) []string {
-	re := regexp.MustCompile(`#\s*@tags:\s*(.+)$`)
+	re := regexp.MustCompile(`(?m)#\s*@tags:\s*(.+)$`)
 	match := re.FindStringSubmatch(content)
 	if len(match) < 2 {
 		return []string{}
@@ -176,37 +216,102 @@ func (p *ParserService) parseDomains(content, defaultDomain string) []string {
 	return domains
 }
 
-// parseSnippets extracts imported snippets
-func (p *ParserService) parseSnippets(content string) []string {
-	re := regexp.MustCompile(`import\s+(\S+)`)
+// parseSnippets extracts imported snippets and the arguments of
+// parameterised ones. Imports of snippets generated for other settings are
+// skipped, any other import is a custom snippet.
+func (p *ParserService) parseSnippets(content string) ([]string, map[string][]string) {
+	re := regexp.MustCompile(`import\s+(\S+)([^\n]*)`)
 	matches := re.FindAllStringSubmatch(content, -1)
 
 	var snippets []string
+	var args map[string][]string
 	for _, match := range matches {
 		if len(match) > 1 {
 			snippet := match[1]
-			if contains(p.knownSnippets, snippet) {
+			if contains(p.knownSnippets, snippet) || !isGeneratedSnippet(snippet) && !contains(snippets, snippet) {
 				snippets = append(snippets, snippet)
+				if values := importArgs(match[2]); len(values) > 0 {
+					if args == nil {
+						args = make(map[string][]string)
+					}
+					args[snippet] = values
+				}
 			}
 		}
 	}
 
 	if len(snippets) == 0 {
-		return []string{"cloudflare_dns"}
+		return []string{"cloudflare_dns"}, nil
+	}
+	return snippets, args
+}
+
+// importArgs splits the arguments of an import, unquoting quoted ones
+func importArgs(value string) []string {
+	var args []string
+	for _, token := range regexp.MustCompile(`"(?:[^"\\]|\\.)*"|\S+`).FindAllString(value, -1) {
+		if strings.HasPrefix(token, "#") {
+			break
+		}
+		if unquoted, err := strconv.Unquote(token); err == nil {
+			token = unquoted
+		}
+		args = append(args, token)
 	}
-	return snippets
+	return args
+}
+
+// isGeneratedSnippet returns true for snippets CPM imports for other settings,
+// like wildcard certificates and client certificate revocation. Security
+// header profiles are reserved names too, but sites select them as snippets.
+func isGeneratedSnippet(name string) bool {
+	if strings.HasPrefix(name, models.SecurityHeadersProfileSnippet("")) {
+		return false
+	}
+	return models.IsReservedSnippetName(name) || strings.ContainsAny(name, "/.*")
+}
+
+// parseRouteChecks removes the access checks the generator repeats inside the
+// route handle blocks; the maintenance route snippet is parsed with the
+// maintenance mode
+func (p *ParserService) parseRouteChecks(content string) string {
+	checkRe := regexp.MustCompile(`(?m)^[ \t]*(import[ \t]+(` + models.InternalOnlyRouteSnippet + `|` + models.AccessListRouteSnippet + `|` +
+		models.ClientCARevokedRouteSnippet(`\S+`) + `)|error[ \t]+@mtls_denied[ \t]+403)[ \t]*(\n|$)`)
+	return checkRe.ReplaceAllString(content, "")
+}
+
+// parseAccessLists extracts the imported access lists and removes their
+// imports, the check and the site_acl variable of wildcard sites
+func (p *ParserService) parseAccessLists(content string) ([]string, string) {
+	var ids []string
+	var rest []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 2 && fields[0] == "import" && fields[1] == models.AccessListSnippet:
+			continue
+		case len(fields) == 2 && fields[0] == "import" && strings.HasPrefix(fields[1], models.AccessListSnippetName("")):
+			ids = append(ids, strings.TrimPrefix(fields[1], models.AccessListSnippetName("")))
+			continue
+		case len(fields) == 4 && fields[0] == "vars" && fields[2] == models.AccessListSiteVar:
+			continue
+		}
+		rest = append(rest, line)
+	}
+	return ids, strings.Join(rest, "\n")
 }
 
 // parseReverseProxy extracts reverse proxy settings
 func (p *ParserService) parseReverseProxy(content string, site *models.Site) {
-	re := regexp.MustCompile(`reverse_proxy\s+(https?://)?([^:\s{]+):(\d+)`)
+	// IPv6 addresses are written in brackets
+	re := regexp.MustCompile(`reverse_proxy\s+(https?://)?(\[[0-9A-Fa-f:.]+\]|[^:\s{]+):(\d+)`)
 	match := re.FindStringSubmatch(content)
 
 	if len(match) > 3 {
 		if match[1] != "" {
 			site.IsHTTPSBackend = strings.Contains(strings.ToLower(match[1]), "https")
 		}
-		site.TargetIP = match[2]
+		site.TargetIP = strings.Trim(match[2], "[]")
 		site.TargetPort = match[3]
 	}
 }
@@ -219,7 +324,7 @@ func (p *ParserService) parseLoadBalancing(content string, site *models.Site) {
 
 	if len(match) > 1 {
 		backendStr := strings.TrimSpace(match[1])
-		backendRe := regexp.MustCompile(`(https?://[^:\s]+:\d+)`)
+		backendRe := regexp.MustCompile(`(https?://(\[[0-9A-Fa-f:.]+\]|[^:\s]+):\d+)`)
 		backends := backendRe.FindAllString(backendStr, -1)
 
 		if len(backends) > 1 {
@@ -243,7 +348,7 @@ func (p *ParserService) parseBasicAuth(content string) (bool, []string) {
 		return false, nil
 	}
 
-	re := regexp.MustCompile(`basic_auth\s*\{([^}]+)\}`)
+	re := regexp.MustCompile(`basic_auth(?:\s+` + models.BasicAuthMatcher + `)?\s*\{([^}]+)\}`)
 	match := re.FindStringSubmatch(content)
 
 	if len(match) < 2 {
@@ -266,8 +371,583 @@ func (p *ParserService) parseBasicAuth(content string) (bool, []string) {
 	return false, nil
 }
 
-// parseExtraConfig extracts non-standard configuration
-func (p *ParserService) parseExtraConfig(content string) string {
+// parseIssuer extracts the ACME settings from the generated tls block
+func (p *ParserService) parseIssuer(content string, site *models.Site) {
+	if match := regexp.MustCompile(`(?m)^\s*tls\s+([^\s{]+@[^\s{]+)`).FindStringSubmatch(content); len(match) > 1 {
+		site.Email = match[1]
+	}
+	if site.TLSMode == models.IssuerACME {
+		if match := regexp.MustCompile(`(?m)^\s*ca\s+(\S+)`).FindStringSubmatch(content); len(match) > 1 {
+			site.Directory = match[1]
+		}
+	}
+	if match := regexp.MustCompile(`(?m)^\s*eab\s+(\S+)\s+(\S+)`).FindStringSubmatch(content); len(match) > 2 {
+		site.EABKeyID = match[1]
+		site.EABMACKey = match[2]
+	}
+}
+
+// parseForwardAuth extracts the forward_auth directive, its bypass matcher and
+// the provider endpoints proxied next to it. It returns the settings and the
+// remaining content.
+func (p *ParserService) parseForwardAuth(content string) (*models.ForwardAuth, string) {
+	lines := strings.Split(content, "\n")
+
+	var auth *models.ForwardAuth
+	var excluded []string
+	var rest []string
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		switch {
+		case len(fields) >= 2 && fields[0] == "import" && fields[1] == models.ForwardAuthSnippet && auth == nil:
+			auth = &models.ForwardAuth{Provider: models.ForwardAuthDefault}
+			continue
+		case len(fields) >= 3 && fields[0] == "import" && fields[1] == models.ForwardAuthBypassSnippet && auth == nil:
+			auth = &models.ForwardAuth{Provider: models.ForwardAuthDefault, Bypass: fields[2:]}
+			continue
+		case len(fields) > 3 && fields[0] == models.ForwardAuthMatcher && fields[1] == "not" && fields[2] == "path":
+			excluded = fields[3:]
+			continue
+		case len(fields) == 2 && fields[0] == models.ForwardAuthMatcher && fields[1] == "{":
+			end := blockEnd(lines, i)
+			for _, line := range lines[i+1 : end] {
+				if inner := strings.Fields(line); len(inner) > 2 && inner[0] == "not" && inner[1] == "path" {
+					excluded = inner[2:]
+				}
+			}
+			i = end
+			continue
+		case len(fields) >= 3 && fields[0] == "forward_auth" && fields[len(fields)-1] == "{" && auth == nil:
+			args := fields[1 : len(fields)-1]
+			if len(args) == 2 && args[0] == models.ForwardAuthMatcher {
+				args = args[1:]
+			}
+			if len(args) != 1 {
+				break
+			}
+			auth = &models.ForwardAuth{Upstream: args[0]}
+			end := blockEnd(lines, i)
+			for _, line := range lines[i+1 : end] {
+				inner := strings.Fields(line)
+				switch {
+				case len(inner) == 2 && inner[0] == "uri":
+					auth.URI = inner[1]
+				case len(inner) > 1 && inner[0] == "copy_headers":
+					auth.CopyHeaders = inner[1:]
+				}
+			}
+			auth.Provider = models.ForwardAuthProvider(auth.URI)
+			i = end
+			continue
+		}
+		rest = append(rest, lines[i])
+	}
+
+	if auth == nil {
+		return nil, content
+	}
+	if auth.Provider == models.ForwardAuthDefault {
+		return auth, strings.Join(rest, "\n")
+	}
+
+	// Provider endpoints are rendered from the preset, everything else is a bypass path
+	proxyPaths := auth.Preset().ProxyPaths
+	for _, path := range excluded {
+		if !contains(proxyPaths, path) {
+			auth.Bypass = append(auth.Bypass, path)
+		}
+	}
+
+	lines, rest = rest, nil
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		if len(fields) == 3 && fields[0] == "handle" && contains(proxyPaths, fields[1]) && fields[2] == "{" {
+			end := blockEnd(lines, i)
+			if body := strings.Fields(strings.Join(lines[i+1:end], " ")); len(body) == 2 && body[0] == "reverse_proxy" && body[1] == auth.Upstream {
+				i = end
+				continue
+			}
+		}
+		rest = append(rest, lines[i])
+	}
+
+	return auth, strings.Join(rest, "\n")
+}
+
+// parseCORS extracts the CORS matchers, headers and preflight responses. It
+// returns the policy and the remaining content.
+func (p *ParserService) parseCORS(content string) (*models.CORS, string) {
+	lines := strings.Split(content, "\n")
+
+	var cors *models.CORS
+	var headers []string
+	var rest []string
+	for i := 0; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		switch {
+		case len(fields) == 4 && fields[0] == models.CORSOriginMatcher && fields[1] == "header" && fields[2] == "Origin" && fields[3] == "*":
+			cors = &models.CORS{Origins: []string{"*"}}
+			continue
+		case len(fields) == 4 && fields[0] == models.CORSOriginMatcher && fields[1] == "header_regexp" && fields[2] == "Origin":
+			cors = &models.CORS{Origins: models.CORSOrigins(fields[3])}
+			continue
+		case len(fields) == 3 && fields[0] == "respond" && fields[1] == models.CORSPreflightMatcher && fields[2] == "204":
+			continue
+		case len(fields) == 4 && fields[0] == "vars" && fields[1] == models.CORSPreflightMatcher && fields[2] == models.CORSPreflightVar:
+			continue
+		case len(fields) == 2 && fields[0] == models.CORSPreflightMatcher && fields[1] == "{":
+			i = blockEnd(lines, i)
+			continue
+		case len(fields) == 3 && fields[0] == "header" && (fields[1] == models.CORSOriginMatcher || fields[1] == models.CORSPreflightMatcher) && fields[2] == "{":
+			end := blockEnd(lines, i)
+			headers = append(headers, lines[i+1:end]...)
+			i = end
+			continue
+		}
+		rest = append(rest, lines[i])
+	}
+
+	if cors == nil {
+		return nil, content
+	}
+
+	for _, line := range headers {
+		name, value, _ := strings.Cut(strings.TrimSpace(line), " ")
+		if unquoted, err := strconv.Unquote(strings.TrimSpace(value)); err == nil {
+			value = unquoted
+		}
+		switch name {
+		case "Access-Control-Allow-Credentials":
+			cors.AllowCredentials = value == "true"
+		case "Access-Control-Allow-Methods":
+			cors.Methods = strings.Split(value, ", ")
+		case "Access-Control-Allow-Headers":
+			cors.Headers = strings.Split(value, ", ")
+		case "Access-Control-Max-Age":
+			cors.MaxAge, _ = strconv.Atoi(value)
+		}
+	}
+
+	return cors, strings.Join(rest, "\n")
+}
+
+// parseMaintenance detects the maintenance snippet and removes its imports.
+// It returns whether the site is in maintenance and the remaining content.
+func (p *ParserService) parseMaintenance(content string) (bool, string) {
+	importRe := regexp.MustCompile(`(?m)^[ \t]*import[ \t]+(` + models.MaintenanceSnippet + `|` + models.MaintenanceRouteSnippet + `)[ \t]*(\n|$)`)
+	if !importRe.MatchString(content) {
+		return false, content
+	}
+	return true, importRe.ReplaceAllString(content, "")
+}
+
+// parseSiteType reads the site type from the # @type: comment, or detects it in
+// blocks without a reverse proxy, and extracts the type's directives. It returns
+// the remaining content.
+func (p *ParserService) parseSiteType(content string, site *models.Site) string {
+	if match := regexp.MustCompile(`#\s*@type:\s*(\w+)`).FindStringSubmatch(content); len(match) > 1 {
+		site.Type = match[1]
+	} else if !regexp.MustCompile(`(?m)^\s*reverse_proxy\s`).MatchString(content) {
+		switch {
+		case regexp.MustCompile(`(?m)^\s*redir\s+[^@\s]`).MatchString(content):
+			site.Type = models.SiteTypeRedirect
+		case regexp.MustCompile(`(?m)^\s*file_server\b`).MatchString(content):
+			site.Type = models.SiteTypeStatic
+		case regexp.MustCompile(`(?m)^\s*respond\b`).MatchString(content):
+			site.Type = models.SiteTypeRespond
+		}
+	}
+	if site.IsProxy() {
+		return content
+	}
+
+	quotedRe := regexp.MustCompile(`^respond\s+"((?:[^"\\]|\\.)*)"(?:\s+(\d{3}))?$`)
+	statusRe := regexp.MustCompile(`^respond(?:\s+(\d{3}))?$`)
+	heredocRe := regexp.MustCompile(`^respond\s+<<(\w+)$`)
+	contentTypeRe := regexp.MustCompile(`^header\s+Content-Type\s+"([^"]*)"$`)
+
+	lines := strings.Split(content, "\n")
+	var rest []string
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		fields := strings.Fields(trimmed)
+
+		switch site.Type {
+		case models.SiteTypeRedirect:
+			if len(fields) >= 2 && len(fields) <= 3 && fields[0] == "redir" && !strings.HasPrefix(fields[1], "@") && site.Redirect == nil {
+				site.Redirect = parseRedirect(fields, site.PrimaryDomain())
+				continue
+			}
+
+		case models.SiteTypeStatic:
+			if len(fields) == 3 && fields[0] == "root" && fields[1] == "*" {
+				if site.Static == nil {
+					site.Static = &models.StaticSettings{}
+				}
+				site.Static.Root = fields[2]
+				continue
+			}
+			if len(fields) > 0 && fields[0] == "file_server" {
+				if site.Static == nil {
+					site.Static = &models.StaticSettings{}
+				}
+				site.Static.Browse = contains(fields, "browse")
+				if strings.HasSuffix(trimmed, "{") {
+					end := blockEnd(lines, i)
+					for _, line := range lines[i+1 : end] {
+						inner := strings.Fields(line)
+						switch {
+						case len(inner) == 1 && inner[0] == "browse":
+							site.Static.Browse = true
+						case len(inner) > 1 && inner[0] == "index":
+							site.Static.Index = inner[1:]
+						}
+					}
+					i = end
+				}
+				continue
+			}
+
+		case models.SiteTypeRespond:
+			if site.Respond == nil {
+				site.Respond = &models.RespondSettings{}
+			}
+			if match := contentTypeRe.FindStringSubmatch(trimmed); match != nil {
+				site.Respond.ContentType = match[1]
+				continue
+			}
+			if match := quotedRe.FindStringSubmatch(trimmed); match != nil {
+				site.Respond.Body = strings.ReplaceAll(match[1], `\"`, `"`)
+				site.Respond.Status, _ = strconv.Atoi(match[2])
+				continue
+			}
+			if match := statusRe.FindStringSubmatch(trimmed); match != nil {
+				site.Respond.Status, _ = strconv.Atoi(match[1])
+				continue
+			}
+			if match := heredocRe.FindStringSubmatch(trimmed); match != nil {
+				// The closing marker's indentation is stripped from every line
+				var body []string
+				end := i + 1
+				for ; end < len(lines); end++ {
+					closing := strings.Fields(lines[end])
+					if len(closing) > 0 && closing[0] == match[1] {
+						break
+					}
+				}
+				if end < len(lines) {
+					indent := lines[end][:len(lines[end])-len(strings.TrimLeft(lines[end], " \t"))]
+					for _, line := range lines[i+1 : end] {
+						body = append(body, strings.TrimPrefix(line, indent))
+					}
+					if closing := strings.Fields(lines[end]); len(closing) > 1 {
+						site.Respond.Status, _ = strconv.Atoi(closing[1])
+					}
+					site.Respond.Body = strings.Join(body, "\n")
+					i = end
+					continue
+				}
+			}
+		}
+
+		rest = append(rest, lines[i])
+	}
+
+	return strings.Join(rest, "\n")
+}
+
+// parseRedirect reads a redir directive: redir <target> [code]
+func parseRedirect(fields []string, domain string) *models.RedirectSettings {
+	redirect := &models.RedirectSettings{}
+	if len(fields) > 2 {
+		redirect.Code = fields[2]
+	}
+
+	target, preservePath := strings.CutSuffix(fields[1], "{uri}")
+	redirect.PreservePath = preservePath
+	for _, canonical := range []string{"apex", "www"} {
+		if host := models.CanonicalHost(domain, canonical); host != "" && target == "https://"+host {
+			redirect.Canonical = canonical
+			return redirect
+		}
+	}
+	redirect.To = target
+
+	return redirect
+}
+
+// parseRoutes extracts the handle/handle_path blocks of path routes and unwraps
+// the catch-all handle block holding the default backend. It returns the
+// routes and the remaining content.
+func (p *ParserService) parseRoutes(content string) ([]models.Route, string) {
+	lines := strings.Split(content, "\n")
+	routeRe := regexp.MustCompile(`^(handle_path|handle)\s+(/\S*)\s*\{$`)
+	siteHeaders := catchAllProxyHeaders(lines)
+
+	var routes []models.Route
+	var rest []string
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		match := routeRe.FindStringSubmatch(trimmed)
+		if match == nil && trimmed != "handle {" {
+			rest = append(rest, lines[i])
+			continue
+		}
+
+		end := blockEnd(lines, i)
+		body := lines[i+1 : end]
+		if match == nil {
+			rest = append(rest, body...)
+		} else if route, ok := parseRoute(match[2], match[1] == "handle_path", trimProxyHeaders(body, siteHeaders)); ok {
+			routes = append(routes, route)
+		} else {
+			// Not a generated route, keep it as extra config
+			rest = append(rest, lines[i:end+1]...)
+		}
+		i = end
+	}
+
+	if len(routes) == 0 {
+		return nil, content
+	}
+	return routes, strings.Join(rest, "\n")
+}
+
+// catchAllProxyHeaders returns the header_up and header_down lines of the
+// default backend in the catch-all handle block, without the WebSocket set.
+// They are the site's header rules, which every route proxy repeats.
+func catchAllProxyHeaders(lines []string) []string {
+	webSocketLines := []string{"header_up Host {host}", "header_up X-Real-IP {remote_host}", "header_up X-Forwarded-For {remote_host}", "header_up X-Forwarded-Proto {scheme}"}
+	for i := range lines {
+		if strings.TrimSpace(lines[i]) != "handle {" {
+			continue
+		}
+		body := lines[i+1 : blockEnd(lines, i)]
+		webSocket := contains(normalizedLines(body), "header_up X-Real-IP {remote_host}")
+
+		var headers []string
+		for _, line := range normalizedLines(body) {
+			directive, _, _ := strings.Cut(line, " ")
+			if (directive == "header_up" || directive == "header_down") && !(webSocket && contains(webSocketLines, line)) {
+				headers = append(headers, line)
+			}
+		}
+		return headers
+	}
+	return nil
+}
+
+// trimProxyHeaders removes the site's header lines from the end of a route's
+// reverse_proxy block
+func trimProxyHeaders(body, siteHeaders []string) []string {
+	if len(siteHeaders) == 0 {
+		return body
+	}
+	end := len(body) - 1
+	for end >= 0 && strings.TrimSpace(body[end]) == "" {
+		end--
+	}
+	start := end - len(siteHeaders)
+	if start < 0 || strings.TrimSpace(body[end]) != "}" {
+		return body
+	}
+	for i, line := range normalizedLines(body[start:end]) {
+		if line != siteHeaders[i] {
+			return body
+		}
+	}
+	return append(append([]string{}, body[:start]...), body[end:]...)
+}
+
+// normalizedLines returns the lines with whitespace collapsed
+func normalizedLines(lines []string) []string {
+	normalized := make([]string, len(lines))
+	for i, line := range lines {
+		normalized[i] = strings.Join(strings.Fields(line), " ")
+	}
+	return normalized
+}
+
+// parseRoute reads a generated route block; blocks with other directives aren't routes
+func parseRoute(path string, stripPrefix bool, body []string) (models.Route, bool) {
+	route := models.Route{Path: path, StripPrefix: stripPrefix}
+	for _, line := range body {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == "}" {
+			continue
+		}
+		switch fields[0] {
+		case "import":
+			route.Snippets = append(route.Snippets, fields[1:]...)
+		case "reverse_proxy":
+			for _, backend := range fields[1:] {
+				if backend != "{" {
+					route.Backends = append(route.Backends, backend)
+				}
+			}
+		case "lb_policy":
+			route.LBPolicy = strings.Join(fields[1:], " ")
+		case "header_up":
+			route.Headers = append(route.Headers, strings.Join(fields[1:], " "))
+		default:
+			return route, false
+		}
+	}
+	return route, len(route.Backends) > 0
+}
+
+// parseHeaderRules extracts the header directives of the site and the
+// header_up/header_down lines of the reverse proxy, except the WebSocket set.
+// Headers with matchers, deferred or default values stay extra config. It
+// returns the rules and the remaining content.
+func (p *ParserService) parseHeaderRules(content string) ([]models.HeaderRule, string) {
+	lines := strings.Split(content, "\n")
+	webSocket := regexp.MustCompile(`(?m)^\s*header_up\s+X-Real-IP\s+\{remote_host\}\s*$`).MatchString(content)
+	webSocketLines := []string{"header_up Host {host}", "header_up X-Real-IP {remote_host}", "header_up X-Forwarded-For {remote_host}", "header_up X-Forwarded-Proto {scheme}"}
+	targets := map[string]string{"header_up": models.HeaderTargetUpstream, "header_down": models.HeaderTargetDownstream}
+
+	var rules []models.HeaderRule
+	var rest []string
+	depth := 0
+	for i := 0; i < len(lines); i++ {
+		trimmed := strings.TrimSpace(lines[i])
+		fields := strings.Fields(trimmed)
+		switch {
+		case depth != 1 || len(fields) == 0:
+		case trimmed == "header {":
+			end := blockEnd(lines, i)
+			var block []models.HeaderRule
+			for _, line := range lines[i+1 : end] {
+				rule, ok := parseHeaderRule(models.HeaderTargetResponse, headerArgs(line))
+				if !ok {
+					block = nil
+					break
+				}
+				block = append(block, rule)
+			}
+			if block != nil {
+				rules = append(rules, block...)
+				i = end
+				continue
+			}
+		case fields[0] == "header":
+			if rule, ok := parseHeaderRule(models.HeaderTargetResponse, headerArgs(trimmed)[1:]); ok {
+				rules = append(rules, rule)
+				continue
+			}
+		case fields[0] == "reverse_proxy" && fields[len(fields)-1] == "{":
+			end := blockEnd(lines, i)
+			rest = append(rest, lines[i])
+			for _, line := range lines[i+1 : end] {
+				inner := strings.Join(strings.Fields(line), " ")
+				directive, _, _ := strings.Cut(inner, " ")
+				target := targets[directive]
+				if target != "" && !(webSocket && contains(webSocketLines, inner)) {
+					if rule, ok := parseHeaderRule(target, headerArgs(line)[1:]); ok {
+						rules = append(rules, rule)
+						continue
+					}
+				}
+				rest = append(rest, line)
+			}
+			rest = append(rest, lines[end])
+			i = end
+			continue
+		}
+		depth += strings.Count(trimmed, "{") - strings.Count(trimmed, "}")
+		rest = append(rest, lines[i])
+	}
+
+	if len(rules) == 0 {
+		return nil, content
+	}
+	return rules, strings.Join(rest, "\n")
+}
+
+// parseHeaderRule reads the arguments of a header directive; default values
+// (?), deferred (>) and empty values aren't editor rules
+func parseHeaderRule(target string, args []string) (models.HeaderRule, bool) {
+	if len(args) == 0 {
+		return models.HeaderRule{}, false
+	}
+	rule := models.HeaderRule{Target: target, Operation: models.HeaderSet, Name: args[0]}
+	switch {
+	case len(args) == 1 && strings.HasPrefix(args[0], "-"):
+		rule.Operation, rule.Name = models.HeaderDelete, args[0][1:]
+	case len(args) == 2 && strings.HasPrefix(args[0], "+"):
+		rule.Operation, rule.Name, rule.Value = models.HeaderAdd, args[0][1:], args[1]
+	case len(args) == 2:
+		rule.Value = args[1]
+	case len(args) == 3:
+		rule.Operation, rule.Value, rule.Replace = models.HeaderReplace, args[1], args[2]
+	default:
+		return rule, false
+	}
+	return rule, rule.Validate() == nil
+}
+
+// headerArgs splits the arguments of a header directive. Only escaped quotes
+// are unescaped, backslashes of regular expressions are kept.
+func headerArgs(value string) []string {
+	var args []string
+	for _, token := range regexp.MustCompile(`"(?:[^"\\]|\\.)*"|\S+`).FindAllString(value, -1) {
+		if len(token) >= 2 && strings.HasPrefix(token, `"`) && strings.HasSuffix(token, `"`) {
+			token = strings.ReplaceAll(token[1:len(token)-1], `\"`, `"`)
+		}
+		args = append(args, token)
+	}
+	return args
+}
+
+// blockEnd returns the index of the line closing the block opened on line start
+func blockEnd(lines []string, start int) int {
+	depth := 0
+	for i := start; i < len(lines); i++ {
+		depth += strings.Count(lines[i], "{") - strings.Count(lines[i], "}")
+		if depth <= 0 {
+			return i
+		}
+	}
+	return len(lines) - 1
+}
+
+// parseClientAuth extracts the client_auth block and the allow-list matcher
+func (p *ParserService) parseClientAuth(content string) *models.ClientAuth {
+	block := regexp.MustCompile(`client_auth\s*\{([^}]*)\}`).FindStringSubmatch(content)
+	if len(block) < 2 {
+		return nil
+	}
+
+	auth := &models.ClientAuth{}
+	if match := regexp.MustCompile(`mode\s+(\S+)`).FindStringSubmatch(block[1]); len(match) > 1 {
+		auth.Mode = match[1]
+	}
+	if match := regexp.MustCompile(`trust_pool\s+file\s+\S*/` + models.ClientCAsDir + `/([^/\s]+)/ca\.pem`).FindStringSubmatch(block[1]); len(match) > 1 {
+		auth.CAID = match[1]
+	}
+
+	quotedRe := regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+	varsRe := regexp.MustCompile(`(?m)^\s*not\s+vars\s+\{http\.request\.tls\.client\.(subject|san\.emails\.0)\}\s+(.+)$`)
+	for _, match := range varsRe.FindAllStringSubmatch(content, -1) {
+		var values []string
+		for _, quoted := range quotedRe.FindAllString(match[2], -1) {
+			if v, err := strconv.Unquote(quoted); err == nil {
+				values = append(values, v)
+			}
+		}
+		if match[1] == "subject" {
+			auth.AllowedSubjects = values
+		} else {
+			auth.AllowedSANs = values
+		}
+	}
+
+	return auth
+}
+
+// parseExtraConfig extracts non-standard configuration.
+// With skipTLS the tls directive is treated as generated and left out.
+func (p *ParserService) parseExtraConfig(content string, skipTLS bool) string {
 	lines := strings.Split(content, "\n")
 	var extraLines []string
 	startReading := false
@@ -286,6 +966,7 @@ func (p *ParserService) parseExtraConfig(content string) string {
 		"dial_timeout",
 		"response_header_timeout",
 		"basic_auth",
+		"@mtls_denied",
 	}
 
 	for _, line := range lines {
@@ -310,17 +991,24 @@ func (p *ParserService) parseExtraConfig(content string) string {
 		if strings.Contains(trimmed, "{") {
 			braceDepth += strings.Count(trimmed, "{")
 			// Check if entering skip block
-			for _, pattern := range []string{"transport http", "reverse_proxy", "basic_auth"} {
+			for _, pattern := range []string{"transport http", "reverse_proxy", "basic_auth", "@mtls_denied"} {
 				if strings.Contains(trimmed, pattern) {
 					inSkipBlock = true
 					break
 				}
 			}
+			if skipTLS && isTLSDirective(trimmed) {
+				inSkipBlock = true
+			}
 		}
 
 		if strings.Contains(trimmed, "}") {
 			braceDepth -= strings.Count(trimmed, "}")
 			if braceDepth == 0 {
+				// Keep the closing brace of nested blocks we copied into extra config
+				if !inSkipBlock {
+					extraLines = append(extraLines, trimmed)
+				}
 				inSkipBlock = false
 				continue
 			}
@@ -339,7 +1027,11 @@ func (p *ParserService) parseExtraConfig(content string) string {
 			}
 		}
 
-		if !isStandard && trimmed != "" && trimmed != "}" {
+		if skipTLS && isTLSDirective(trimmed) {
+			isStandard = true
+		}
+
+		if !isStandard && trimmed != "" {
 			extraLines = append(extraLines, trimmed)
 		}
 	}
@@ -347,6 +1039,11 @@ func (p *ParserService) parseExtraConfig(content string) string {
 	return strings.Join(extraLines, "\n")
 }
 
+// isTLSDirective returns true for a line starting the tls directive
+func isTLSDirective(line string) bool {
+	return line == "tls" || strings.HasPrefix(line, "tls ") || strings.HasPrefix(line, "tls{")
+}
+
 // CleanDomains normalizes domain list
 func CleanDomains(domainsStr string) []string {
 	var domains []string
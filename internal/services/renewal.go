@@ -0,0 +1,414 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+	"golang.org/x/net/publicsuffix"
+)
+
+// renewalPollInterval is how often a running renewal looks for the new certificate
+const renewalPollInterval = 5 * time.Second
+
+// maxRenewalJobs is the number of finished renewals kept for the UI
+const maxRenewalJobs = 20
+
+// RenewalService renews ACME certificates without losing the current ones
+type RenewalService struct {
+	certService  *CertificateService
+	caddyService *CaddyService
+	dataDir      string
+	timeout      time.Duration
+	stop         <-chan struct{} // Closed on shutdown; running renewals restore the previous certificate
+	mu           sync.Mutex
+	jobs         []*models.RenewalJob
+}
+
+// NewRenewalService creates a new renewal service
+func NewRenewalService(certService *CertificateService, caddyService *CaddyService, dataDir string, timeout time.Duration) *RenewalService {
+	return &RenewalService{
+		certService:  certService,
+		caddyService: caddyService,
+		dataDir:      dataDir,
+		timeout:      timeout,
+	}
+}
+
+// SetStop sets the channel closed on shutdown. Renewals running at that point
+// restore the previous certificate, and no new ones are started.
+func (r *RenewalService) SetStop(stop <-chan struct{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stop = stop
+}
+
+// stopped returns true once the stop channel is closed
+func (r *RenewalService) stopped() bool {
+	select {
+	case <-r.stopChan():
+		return true
+	default:
+		return false
+	}
+}
+
+// stopChan returns the stop channel, nil (never closed) until SetStop is called
+func (r *RenewalService) stopChan() <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stop
+}
+
+// Budget returns the remaining rate-limit budget for renewing a certificate.
+// Only issuances CPM can see are counted: stored certificates and past renewals.
+func (r *RenewalService) Budget(cert *models.Certificate) *models.RenewalBudget {
+	budget := &models.RenewalBudget{RegisteredDomain: registeredDomain(cert.Domain)}
+
+	issuerKey := certificateIssuerKey(cert)
+	limit, ok := models.RateLimitFor(issuerKey)
+	if !ok {
+		return budget
+	}
+	budget.Limited = true
+	budget.Limit = limit.PerDomain
+	budget.DuplicateLimit = limit.Duplicates
+
+	names := strings.Join(sortedNames(cert.DNSNames), ",")
+	since := time.Now().Add(-models.RateLimitWindow)
+	for _, issuance := range r.issuances() {
+		if issuance.IssuerKey != issuerKey || issuance.RegisteredDomain != budget.RegisteredDomain || !issuance.IssuedAt.After(since) {
+			continue
+		}
+		budget.Issued++
+		if strings.Join(sortedNames(issuance.Names), ",") == names {
+			budget.Duplicates++
+		}
+		if reset := issuance.IssuedAt.Add(models.RateLimitWindow); budget.ResetAt.IsZero() || reset.Before(budget.ResetAt) {
+			budget.ResetAt = reset
+		}
+	}
+
+	return budget
+}
+
+// Start begins renewing the ACME certificate of a domain in the background
+func (r *RenewalService) Start(domain string) (*models.RenewalJob, error) {
+	if r.stopped() {
+		return nil, fmt.Errorf("CPM is shutting down")
+	}
+
+	cert, err := r.managedCertificate(domain)
+	if err != nil {
+		return nil, err
+	}
+
+	if budget := r.Budget(cert); !budget.Allows() {
+		return nil, fmt.Errorf("rate limit budget for %s is used up (%d/%d certificates, %d/%d duplicates in 7 days), try again after %s",
+			budget.RegisteredDomain, budget.Issued, budget.Limit, budget.Duplicates, budget.DuplicateLimit,
+			budget.ResetAt.Format("2006-01-02 15:04"))
+	}
+
+	r.mu.Lock()
+	for _, job := range r.jobs {
+		if job.Domain == domain && job.State == models.RenewalRunning {
+			r.mu.Unlock()
+			return nil, fmt.Errorf("renewal of %s is already running", domain)
+		}
+	}
+	job := &models.RenewalJob{
+		Domain:      domain,
+		Fingerprint: cert.Fingerprint,
+		State:       models.RenewalRunning,
+		StartedAt:   time.Now(),
+	}
+	r.jobs = append([]*models.RenewalJob{job}, r.jobs...)
+	if len(r.jobs) > maxRenewalJobs {
+		r.jobs = r.jobs[:maxRenewalJobs]
+	}
+	r.mu.Unlock()
+
+	go r.run(job, cert, r.stopChan())
+
+	return r.snapshot(job), nil
+}
+
+// Jobs returns the recent renewals, newest first
+func (r *RenewalService) Jobs() []*models.RenewalJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	jobs := make([]*models.RenewalJob, 0, len(r.jobs))
+	for _, job := range r.jobs {
+		jobs = append(jobs, r.copyJob(job))
+	}
+	return jobs
+}
+
+// run backs up the certificate files, removes them and reloads Caddy so it
+// obtains a new certificate. The previous files are restored if none appears
+// in time or stop is closed first.
+func (r *RenewalService) run(job *models.RenewalJob, cert *models.Certificate, stop <-chan struct{}) {
+	base := strings.TrimSuffix(cert.FilePath, ".crt")
+	backupDir := filepath.Join(r.dataDir, "cpm-renewal-backups",
+		fmt.Sprintf("%s-%d", filepath.Base(base), job.StartedAt.Unix()))
+
+	if err := os.MkdirAll(backupDir, 0700); err != nil {
+		r.finish(job, models.RenewalFailed, "Failed to create backup directory: %v", err)
+		return
+	}
+
+	// Copy first, so the live files stay untouched until the backup is complete
+	var backedUp []string
+	for _, ext := range []string{".crt", ".key", ".json"} {
+		if _, err := os.Stat(base + ext); err != nil {
+			continue
+		}
+		if err := copyFile(base+ext, filepath.Join(backupDir, filepath.Base(base)+ext)); err != nil {
+			os.RemoveAll(backupDir)
+			r.finish(job, models.RenewalFailed, "Failed to back up %s: %v", filepath.Base(base)+ext, err)
+			return
+		}
+		backedUp = append(backedUp, ext)
+	}
+	r.logf(job, "Backed up %s to %s", strings.Join(backedUp, ", "), backupDir)
+
+	// Caddy keeps using a stored certificate until it is close to expiry, so
+	// it only orders a new one once the live files are gone
+	for _, ext := range backedUp {
+		if err := os.Remove(base + ext); err != nil && !os.IsNotExist(err) {
+			r.restore(job, base, backupDir, backedUp)
+			r.finish(job, models.RenewalFailed, "Failed to remove %s: %v", filepath.Base(base)+ext, err)
+			return
+		}
+	}
+
+	if result := r.caddyService.ForceReload(); !result.Success {
+		r.logf(job, "Reload failed: %s", result.Error)
+		r.restore(job, base, backupDir, backedUp)
+		r.finish(job, models.RenewalFailed, "Previous certificate restored")
+		return
+	}
+	r.logf(job, "Caddy reloaded, waiting up to %s for a new certificate", r.timeout)
+
+	deadline := time.After(r.timeout)
+	ticker := time.NewTicker(renewalPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			r.restore(job, base, backupDir, backedUp)
+			r.finish(job, models.RenewalRestored, "CPM is shutting down, previous certificate restored")
+			return
+		case <-deadline:
+			r.logf(job, "No new certificate after %s", r.timeout)
+			r.restore(job, base, backupDir, backedUp)
+			if result := r.caddyService.ForceReload(); !result.Success {
+				r.logf(job, "Reload after restore failed: %s", result.Error)
+			}
+			r.finish(job, models.RenewalRestored, "Previous certificate restored, check the Caddy logs for the ACME error")
+			return
+		case <-ticker.C:
+		}
+
+		renewed, err := r.managedCertificate(job.Domain)
+		if err != nil || !renewed.NotBefore.After(cert.NotBefore) {
+			continue
+		}
+
+		r.recordIssuance(renewed)
+		if err := os.RemoveAll(backupDir); err != nil {
+			r.logf(job, "Failed to remove backup: %v", err)
+		}
+		r.finish(job, models.RenewalRenewed, "New certificate obtained, valid until %s", renewed.NotAfter.Format("2006-01-02"))
+		return
+	}
+}
+
+// restore moves the backed up files back into Caddy's storage
+func (r *RenewalService) restore(job *models.RenewalJob, base, backupDir string, moved []string) {
+	for _, ext := range moved {
+		if err := moveFile(filepath.Join(backupDir, filepath.Base(base)+ext), base+ext); err != nil {
+			r.logf(job, "Failed to restore %s: %v (backup kept in %s)", filepath.Base(base)+ext, err, backupDir)
+			return
+		}
+	}
+	os.RemoveAll(backupDir)
+}
+
+// managedCertificate returns the certificate Caddy manages for a domain
+func (r *RenewalService) managedCertificate(domain string) (*models.Certificate, error) {
+	certs, err := r.certService.GetAllCertificates()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, cert := range certs {
+		if cert.Domain == domain && cert.CustomID == "" {
+			return cert, nil
+		}
+	}
+
+	return nil, fmt.Errorf("certificate not found for domain: %s", domain)
+}
+
+// issuances returns the issuances recorded by CPM merged with the stored certificates
+func (r *RenewalService) issuances() []models.Issuance {
+	issuances := r.loadHistory()
+
+	seen := make(map[string]bool)
+	for _, issuance := range issuances {
+		seen[issuance.Fingerprint] = true
+	}
+
+	certs, _ := r.certService.GetAllCertificates()
+	for _, cert := range certs {
+		if cert.CustomID != "" || seen[cert.Fingerprint] {
+			continue
+		}
+		issuances = append(issuances, newIssuance(cert))
+	}
+
+	return issuances
+}
+
+// recordIssuance adds a certificate to the issuance history, dropping entries outside the window
+func (r *RenewalService) recordIssuance(cert *models.Certificate) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	since := time.Now().Add(-models.RateLimitWindow)
+	issuances := []models.Issuance{newIssuance(cert)}
+	for _, issuance := range r.loadHistory() {
+		if issuance.IssuedAt.After(since) && issuance.Fingerprint != cert.Fingerprint {
+			issuances = append(issuances, issuance)
+		}
+	}
+
+	data, err := json.MarshalIndent(issuances, "", "  ")
+	if err != nil {
+		log.Printf("Error encoding issuance history: %v", err)
+		return
+	}
+	if err := os.WriteFile(r.historyPath(), data, 0644); err != nil {
+		log.Printf("Error writing issuance history: %v", err)
+	}
+}
+
+// loadHistory reads the issuance history file
+func (r *RenewalService) loadHistory() []models.Issuance {
+	var issuances []models.Issuance
+
+	data, err := os.ReadFile(r.historyPath())
+	if err != nil {
+		return issuances
+	}
+	if err := json.Unmarshal(data, &issuances); err != nil {
+		log.Printf("Error reading issuance history: %v", err)
+	}
+	return issuances
+}
+
+func (r *RenewalService) historyPath() string {
+	return filepath.Join(r.dataDir, "cpm-issuances.json")
+}
+
+// logf reports renewal progress to the log and the job
+func (r *RenewalService) logf(job *models.RenewalJob, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	log.Printf("[renew %s] %s", job.Domain, msg)
+
+	r.mu.Lock()
+	job.Log = append(job.Log, time.Now().Format("15:04:05")+" "+msg)
+	r.mu.Unlock()
+}
+
+// finish logs the final message and sets the job state
+func (r *RenewalService) finish(job *models.RenewalJob, state, format string, args ...interface{}) {
+	r.logf(job, format, args...)
+
+	r.mu.Lock()
+	now := time.Now()
+	job.State = state
+	job.FinishedAt = &now
+	r.mu.Unlock()
+}
+
+func (r *RenewalService) snapshot(job *models.RenewalJob) *models.RenewalJob {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.copyJob(job)
+}
+
+// copyJob copies a job so it can be read while the renewal runs; the caller holds the lock
+func (r *RenewalService) copyJob(job *models.RenewalJob) *models.RenewalJob {
+	c := *job
+	c.Log = append([]string(nil), job.Log...)
+	return &c
+}
+
+func newIssuance(cert *models.Certificate) models.Issuance {
+	return models.Issuance{
+		Names:            cert.DNSNames,
+		RegisteredDomain: registeredDomain(cert.Domain),
+		IssuerKey:        certificateIssuerKey(cert),
+		Fingerprint:      cert.Fingerprint,
+		IssuedAt:         cert.NotBefore,
+	}
+}
+
+// certificateIssuerKey returns the issuer directory of a certificate in Caddy's storage
+func certificateIssuerKey(cert *models.Certificate) string {
+	// Layout: caddy/certificates/<issuer key>/<name>/<name>.crt
+	return filepath.Base(filepath.Dir(filepath.Dir(cert.FilePath)))
+}
+
+// registeredDomain returns the domain rate limits are counted against (e.g. example.co.uk)
+func registeredDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimPrefix(domain, "*."))
+	if registered, err := publicsuffix.EffectiveTLDPlusOne(domain); err == nil {
+		return registered
+	}
+	return domain
+}
+
+func sortedNames(names []string) []string {
+	sorted := make([]string, len(names))
+	for i, name := range names {
+		sorted[i] = strings.ToLower(name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// moveFile renames a file, falling back to copy and remove across filesystems
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies a file with its permissions
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, info.Mode().Perm())
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestRegisteredDomain(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{domain: "app.example.com", want: "example.com"},
+		{domain: "*.example.com", want: "example.com"},
+		{domain: "a.b.example.co.uk", want: "example.co.uk"},
+		{domain: "App.Example.COM", want: "example.com"},
+		{domain: "localhost", want: "localhost"},
+	}
+
+	for _, tt := range tests {
+		if got := registeredDomain(tt.domain); got != tt.want {
+			t.Errorf("registeredDomain(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestRenewalBudget(t *testing.T) {
+	dataDir := t.TempDir()
+	issuerKey := models.IssuerKeyFor(models.LetsEncryptDirectory)
+	now := time.Now()
+
+	issuance := func(names []string, issuerKey string, age time.Duration) models.Issuance {
+		return models.Issuance{
+			Names:            names,
+			RegisteredDomain: registeredDomain(names[0]),
+			IssuerKey:        issuerKey,
+			Fingerprint:      names[0] + age.String(),
+			IssuedAt:         now.Add(-age),
+		}
+	}
+	history := []models.Issuance{
+		issuance([]string{"app.example.com"}, issuerKey, 24*time.Hour),
+		issuance([]string{"APP.example.com"}, issuerKey, 48*time.Hour),
+		issuance([]string{"api.example.com", "www.example.com"}, issuerKey, 72*time.Hour),
+		issuance([]string{"app.example.com"}, issuerKey, 8*24*time.Hour),
+		issuance([]string{"app.example.com"}, "acme.example.net-directory", time.Hour),
+		issuance([]string{"app.example.org"}, issuerKey, time.Hour),
+	}
+	data, err := json.Marshal(history)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dataDir, "cpm-issuances.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	renewal := NewRenewalService(NewCertificateService(dataDir), nil, dataDir, time.Minute)
+	cert := &models.Certificate{
+		Domain:   "app.example.com",
+		DNSNames: []string{"app.example.com"},
+		FilePath: filepath.Join(dataDir, "caddy", "certificates", issuerKey, "app.example.com", "app.example.com.crt"),
+	}
+
+	budget := renewal.Budget(cert)
+	if !budget.Limited || budget.Limit != 50 || budget.DuplicateLimit != 5 {
+		t.Fatalf("budget limits = %+v", budget)
+	}
+	if budget.RegisteredDomain != "example.com" || budget.Issued != 3 || budget.Duplicates != 2 {
+		t.Errorf("budget = %+v, want 3 issued and 2 duplicates for example.com", budget)
+	}
+	if want := history[2].IssuedAt.Add(models.RateLimitWindow); !budget.ResetAt.Equal(want) {
+		t.Errorf("reset at %s, want %s", budget.ResetAt, want)
+	}
+	if !budget.Allows() {
+		t.Error("budget within limits does not allow renewal")
+	}
+
+	budget.Duplicates = budget.DuplicateLimit
+	if budget.Allows() {
+		t.Error("budget with exhausted duplicates allows renewal")
+	}
+
+	cert.FilePath = filepath.Join(dataDir, "caddy", "certificates", "acme.example.net-directory", "app.example.com", "app.example.com.crt")
+	if budget := renewal.Budget(cert); budget.Limited || !budget.Allows() {
+		t.Errorf("unknown issuer budget = %+v, want unlimited", budget)
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// routeBlocks returns the route and catch-all handle blocks of a site file
+func routeBlocks(t *testing.T, content string) []string {
+	t.Helper()
+	var blocks []string
+	lines := strings.Split(content, "\n")
+	for i := 0; i < len(lines); i++ {
+		if !strings.HasPrefix(lines[i], "    handle /") && !strings.HasPrefix(lines[i], "    handle_path /") &&
+			lines[i] != "    handle {" {
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && lines[end] != "    }" {
+			end++
+		}
+		blocks = append(blocks, strings.Join(lines[i:end+1], "\n"))
+		i = end
+	}
+	return blocks
+}
+
+// assertChecksBeforeProxy fails unless every block contains the checks, in
+// order, before its reverse_proxy
+func assertChecksBeforeProxy(t *testing.T, blocks []string, checks []string) {
+	t.Helper()
+	for _, block := range blocks {
+		last := -1
+		for _, check := range checks {
+			idx := strings.Index(block, "        "+check+"\n")
+			if idx < 0 || idx < last {
+				t.Errorf("block misses %q or has it out of order:\n%s", check, block)
+				continue
+			}
+			last = idx
+		}
+		if proxy := strings.Index(block, "reverse_proxy"); proxy < last {
+			t.Errorf("checks follow reverse_proxy:\n%s", block)
+		}
+	}
+}
+
+func TestRouteBlocksRepeatSiteChecks(t *testing.T) {
+	routes := []models.Route{
+		{Path: "/api/*", Backends: []string{"10.0.0.6:9000"}},
+		{Path: "/static/*", StripPrefix: true, Backends: []string{"10.0.0.7:80"}},
+	}
+	site := &models.Site{
+		Filename:    "app.example.com.caddy",
+		Domains:     []string{"app.example.com"},
+		TargetIP:    "10.0.0.5",
+		TargetPort:  "8080",
+		IsInternal:  true,
+		Snippets:    []string{"internal_only"},
+		Maintenance: true,
+		Routes:      routes,
+		ClientAuth: &models.ClientAuth{
+			CAID:        "0123456789abcdef",
+			AllowedSANs: []string{"alice@example.com"},
+		},
+	}
+
+	content := site.ToCaddyfile()
+	blocks := routeBlocks(t, content)
+	if len(blocks) != len(routes)+1 {
+		t.Fatalf("found %d route blocks, want %d:\n%s", len(blocks), len(routes)+1, content)
+	}
+	assertChecksBeforeProxy(t, blocks, []string{
+		"import " + models.MaintenanceRouteSnippet,
+		"import " + models.InternalOnlyRouteSnippet,
+		"import " + models.ClientCARevokedRouteSnippet("0123456789abcdef"),
+		"error @mtls_denied 403",
+	})
+
+	parsed := NewParserService().Parse(content, site.Filename)
+	if !reflect.DeepEqual(parsed.Routes, routes) {
+		t.Errorf("parsed routes = %+v, want %+v", parsed.Routes, routes)
+	}
+	if !parsed.IsInternal || !parsed.Maintenance {
+		t.Errorf("parsed internal = %v, maintenance = %v", parsed.IsInternal, parsed.Maintenance)
+	}
+	if parsed.ClientAuth == nil || parsed.ClientAuth.CAID != "0123456789abcdef" {
+		t.Errorf("parsed client auth = %+v", parsed.ClientAuth)
+	}
+	if parsed.ExtraConfig != "" {
+		t.Errorf("route checks leaked into extra config: %q", parsed.ExtraConfig)
+	}
+}
+
+func TestRouteBlocksWithoutChecks(t *testing.T) {
+	site := &models.Site{
+		Domains:    []string{"app.example.com"},
+		TargetIP:   "10.0.0.5",
+		TargetPort: "8080",
+		Routes:     []models.Route{{Path: "/api/*", Backends: []string{"10.0.0.6:9000"}}},
+	}
+
+	content := site.ToCaddyfile()
+	for _, name := range []string{models.InternalOnlyRouteSnippet, "mtls_", models.MaintenanceRouteSnippet} {
+		if strings.Contains(content, name) {
+			t.Errorf("unrestricted site references %s:\n%s", name, content)
+		}
+	}
+}
+
+func TestClientCARevokedRouteSnippet(t *testing.T) {
+	ca := &models.ClientCA{ID: "0123456789abcdef"}
+	want := []string{"(mtls_revoked_0123456789abcdef) {", "}", "(mtls_revoked_0123456789abcdef_route) {", "}"}
+	if got := ca.RevokedSnippet(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RevokedSnippet() = %q, want %q", got, want)
+	}
+	if !models.IsReservedSnippetName(models.ClientCARevokedRouteSnippet(ca.ID)) ||
+		!models.IsReservedSnippetName(models.InternalOnlyRouteSnippet) {
+		t.Error("route check snippets are not reserved")
+	}
+}
+
+func TestRouteBlocksRepeatAccessListCheck(t *testing.T) {
+	routes := []models.Route{{Path: "/api/*", Backends: []string{"10.0.0.6:9000"}}}
+	tests := []struct {
+		name    string
+		tlsMode string
+	}{
+		{name: "standard"},
+		{name: "wildcard", tlsMode: "wildcard:example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &models.Site{
+				Filename:    "app.example.com.caddy",
+				Domains:     []string{"app.example.com"},
+				TargetIP:    "10.0.0.5",
+				TargetPort:  "8080",
+				TLSMode:     tt.tlsMode,
+				AccessLists: []string{"lan", "vpn"},
+				Routes:      routes,
+			}
+
+			content := site.ToCaddyfile()
+			blocks := routeBlocks(t, content)
+			if len(blocks) != len(routes)+1 {
+				t.Fatalf("found %d route blocks, want %d:\n%s", len(blocks), len(routes)+1, content)
+			}
+			assertChecksBeforeProxy(t, blocks, []string{"import " + models.AccessListRouteSnippet})
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.AccessLists, site.AccessLists) {
+				t.Errorf("parsed access lists = %q, want %q", parsed.AccessLists, site.AccessLists)
+			}
+			if !reflect.DeepEqual(parsed.Routes, routes) {
+				t.Errorf("parsed routes = %+v, want %+v", parsed.Routes, routes)
+			}
+			if parsed.ExtraConfig != "" {
+				t.Errorf("route checks leaked into extra config: %q", parsed.ExtraConfig)
+			}
+		})
+	}
+}
+
+func TestAccessListRouteNameReserved(t *testing.T) {
+	list := models.AccessList{ID: "route", Name: "Route", Allow: []string{"10.0.0.0/8"}}
+	if err := list.Validate(); err == nil {
+		t.Error("access list colliding with the acl_route snippet accepted")
+	}
+}
+
+func TestRouteProxiesApplySiteHeaderRules(t *testing.T) {
+	routes := []models.Route{
+		{Path: "/api/*", Backends: []string{"10.0.0.6:9000"}, Headers: []string{"X-Service api"}},
+		{Path: "/static/*", StripPrefix: true, Backends: []string{"10.0.0.7:80"}},
+	}
+	rules := []models.HeaderRule{
+		{Target: models.HeaderTargetResponse, Operation: models.HeaderSet, Name: "X-Served-By", Value: "cpm"},
+		{Target: models.HeaderTargetUpstream, Operation: models.HeaderAdd, Name: "X-Tenant", Value: "acme"},
+		{Target: models.HeaderTargetDownstream, Operation: models.HeaderDelete, Name: "X-Powered-By"},
+	}
+	tests := []struct {
+		name    string
+		tlsMode string
+	}{
+		{name: "standard"},
+		{name: "wildcard", tlsMode: "wildcard:example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &models.Site{
+				Filename:        "app.example.com.caddy",
+				Domains:         []string{"app.example.com"},
+				TargetIP:        "10.0.0.5",
+				TargetPort:      "8080",
+				TLSMode:         tt.tlsMode,
+				EnableWebSocket: true,
+				HeaderRules:     rules,
+				Routes:          routes,
+			}
+
+			content := site.ToCaddyfile()
+			blocks := routeBlocks(t, content)
+			if len(blocks) != len(routes)+1 {
+				t.Fatalf("found %d route blocks, want %d:\n%s", len(blocks), len(routes)+1, content)
+			}
+			for _, block := range blocks {
+				for _, line := range []string{`header_up +X-Tenant "acme"`, "header_down -X-Powered-By"} {
+					if strings.Count(block, line) != 1 {
+						t.Errorf("block misses %q:\n%s", line, block)
+					}
+				}
+				if strings.Contains(block, "X-Served-By") {
+					t.Errorf("response header rule inside a route proxy:\n%s", block)
+				}
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.Routes, routes) {
+				t.Errorf("parsed routes = %+v, want %+v", parsed.Routes, routes)
+			}
+			if !reflect.DeepEqual(parsed.HeaderRules, rules) {
+				t.Errorf("parsed header rules = %+v, want %+v", parsed.HeaderRules, rules)
+			}
+			if !parsed.EnableWebSocket {
+				t.Error("parsed WebSocket = false")
+			}
+			if parsed.ExtraConfig != "" {
+				t.Errorf("route headers leaked into extra config: %q", parsed.ExtraConfig)
+			}
+		})
+	}
+}
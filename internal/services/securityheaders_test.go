@@ -0,0 +1,171 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestNormalizeCSPSources(t *testing.T) {
+	tests := []struct {
+		name    string
+		sources []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "keywords are quoted", sources: []string{"self", "'unsafe-inline'"}, want: []string{"'self'", "'unsafe-inline'"}},
+		{name: "hashes and nonces are quoted", sources: []string{"sha256-abc+/=", "nonce-r4nd0m"}, want: []string{"'sha256-abc+/='", "'nonce-r4nd0m'"}},
+		{name: "hosts and schemes", sources: []string{"https://cdn.example.com", "*.example.com:443", "data:", "blob:"}, want: []string{"https://cdn.example.com", "*.example.com:443", "data:", "blob:"}},
+		{name: "duplicates", sources: []string{"self", "'self'"}, want: []string{"'self'"}},
+		{name: "unknown keyword", sources: []string{"'unsafe-everything'"}, wantErr: true},
+		{name: "invalid source", sources: []string{"https://cdn.example.com; script-src *"}, wantErr: true},
+		{name: "none with other sources", sources: []string{"none", "self"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := models.NormalizeCSPSources(tt.sources)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeCSPSources(%q) error = %v, wantErr %v", tt.sources, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeCSPSources(%q) = %q, want %q", tt.sources, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers models.SecurityHeadersConfig
+		wantErr bool
+	}{
+		{name: "disabled CSP is not checked", headers: models.SecurityHeadersConfig{CSP: models.ContentSecurityPolicy{Directives: []models.CSPDirective{{Name: "sandbox"}}}}},
+		{name: "unknown directive", headers: models.SecurityHeadersConfig{CSP: models.ContentSecurityPolicy{Enabled: true, Directives: []models.CSPDirective{{Name: "sandbox", Sources: []string{"self"}}}}}, wantErr: true},
+		{name: "directive without sources", headers: models.SecurityHeadersConfig{CSP: models.ContentSecurityPolicy{Enabled: true, Directives: []models.CSPDirective{{Name: "img-src"}}}}, wantErr: true},
+		{name: "empty policy", headers: models.SecurityHeadersConfig{CSP: models.ContentSecurityPolicy{Enabled: true}}, wantErr: true},
+		{name: "invalid report URI", headers: models.SecurityHeadersConfig{CSP: models.ContentSecurityPolicy{Enabled: true, UpgradeInsecureRequests: true, ReportURI: "/csp; img-src *"}}, wantErr: true},
+		{name: "preload without includeSubDomains", headers: models.SecurityHeadersConfig{HSTSMaxAge: 31536000, HSTSPreload: true}, wantErr: true},
+		{name: "unknown permission", headers: models.SecurityHeadersConfig{PermissionsPolicy: map[string]string{"vibrate": models.PermissionNone}}, wantErr: true},
+		{name: "invalid allowlist", headers: models.SecurityHeadersConfig{PermissionsPolicy: map[string]string{"camera": "everyone"}}, wantErr: true},
+		{name: "invalid cross-origin policy", headers: models.SecurityHeadersConfig{CrossOriginOpenerPolicy: "same-site"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.headers.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSecurityHeadersSnippetLines(t *testing.T) {
+	headers := models.SecurityHeadersConfig{
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		XContentTypeOptions:   true,
+		XFrameOptions:         "DENY",
+		CSP: models.ContentSecurityPolicy{
+			Enabled: true,
+			Directives: []models.CSPDirective{
+				{Name: "default-src", Sources: []string{"'self'"}},
+				{Name: "img-src", Sources: []string{"'self'", "data:"}},
+			},
+			UpgradeInsecureRequests: true,
+			ReportURI:               "/csp-report",
+		},
+		PermissionsPolicy:       map[string]string{"geolocation": models.PermissionSelf, "camera": models.PermissionNone, "fullscreen": models.PermissionAll},
+		CrossOriginOpenerPolicy: "same-origin",
+		HideServer:              true,
+	}
+	if err := headers.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	want := []string{
+		"(security_headers) {",
+		"    header {",
+		`        Strict-Transport-Security "max-age=31536000; includeSubDomains; preload"`,
+		`        X-Content-Type-Options "nosniff"`,
+		`        X-Frame-Options "DENY"`,
+		`        Content-Security-Policy "default-src 'self'; img-src 'self' data:; upgrade-insecure-requests; report-uri /csp-report"`,
+		`        Permissions-Policy "camera=(), geolocation=(self), fullscreen=*"`,
+		`        Cross-Origin-Opener-Policy "same-origin"`,
+		"        -Server",
+		"    }",
+		"}",
+	}
+	if got := headers.SnippetLines(models.SecurityHeadersSnippet); !reflect.DeepEqual(got, want) {
+		t.Errorf("SnippetLines() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	headers.CSP.ReportOnly = true
+	if got := strings.Join(headers.SnippetLines(models.SecurityHeadersSnippet), "\n"); !strings.Contains(got, `        Content-Security-Policy-Report-Only "default-src 'self';`) {
+		t.Errorf("report-only policy is not sent as Content-Security-Policy-Report-Only:\n%s", got)
+	}
+}
+
+func TestSecurityHeadersProfileRoundTrip(t *testing.T) {
+	profile := models.SecurityHeadersProfile{
+		ID:   models.AccessListID("Embeddable"),
+		Name: "Embeddable",
+		Headers: models.SecurityHeadersConfig{
+			XContentTypeOptions: true,
+			CSP: models.ContentSecurityPolicy{
+				Enabled:    true,
+				Directives: []models.CSPDirective{{Name: "frame-ancestors", Sources: []string{"https://intranet.example.com"}}},
+			},
+		},
+	}
+	if err := profile.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	cfg := &config.Config{ConfigDir: t.TempDir()}
+	if err := NewSnippetsService(cfg).GenerateSnippetsFile(&models.SnippetConfig{SecurityHeaderProfiles: []models.SecurityHeadersProfile{profile}}); err != nil {
+		t.Fatalf("GenerateSnippetsFile: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.ConfigDir, "snippets.caddy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Embeddable\n(" + profile.Snippet() + ") {\n    header {\n        X-Content-Type-Options \"nosniff\"\n        Content-Security-Policy \"frame-ancestors https://intranet.example.com\"\n    }\n}\n"
+	if !strings.Contains(string(data), want) {
+		t.Errorf("snippets.caddy misses %q:\n%s", want, data)
+	}
+
+	for _, tlsMode := range []string{"", "wildcard:example.com"} {
+		t.Run("tls "+tlsMode, func(t *testing.T) {
+			site := &models.Site{
+				Filename:   "app.example.com.caddy",
+				Domains:    []string{"app.example.com"},
+				TargetIP:   "10.0.0.5",
+				TargetPort: "8080",
+				TLSMode:    tlsMode,
+				Snippets:   []string{profile.Snippet(), "compression"},
+			}
+
+			content := site.ToCaddyfile()
+			if !strings.Contains(content, "    import "+profile.Snippet()+"\n") {
+				t.Errorf("site file does not import the profile:\n%s", content)
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.Snippets, site.Snippets) {
+				t.Errorf("parsed snippets = %q, want %q", parsed.Snippets, site.Snippets)
+			}
+			if parsed.ExtraConfig != "" {
+				t.Errorf("profile import leaked into extra config %q", parsed.ExtraConfig)
+			}
+		})
+	}
+}
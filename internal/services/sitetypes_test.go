@@ -0,0 +1,154 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestSiteTypesRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		site    models.Site
+		want    []string // Generated handler lines
+		tlsMode string
+	}{
+		{
+			name: "redirect",
+			site: models.Site{
+				Domains:  []string{"old.example.com"},
+				Type:     models.SiteTypeRedirect,
+				Redirect: &models.RedirectSettings{To: "https://new.example.com/landing", Code: "permanent", PreservePath: true},
+			},
+			want: []string{"    redir https://new.example.com/landing{uri} permanent"},
+		},
+		{
+			name: "canonical redirect",
+			site: models.Site{
+				Domains:  []string{"www.example.com"},
+				Type:     models.SiteTypeRedirect,
+				Redirect: &models.RedirectSettings{Canonical: "apex", PreservePath: true},
+			},
+			want: []string{"    redir https://example.com{uri}"},
+		},
+		{
+			name: "static",
+			site: models.Site{
+				Domains: []string{"files.example.com"},
+				Type:    models.SiteTypeStatic,
+				Static:  &models.StaticSettings{Root: "/data/www/files"},
+			},
+			want: []string{"    root * /data/www/files", "    file_server"},
+		},
+		{
+			name: "static with index and browse",
+			site: models.Site{
+				Domains: []string{"docs.example.com"},
+				Type:    models.SiteTypeStatic,
+				Static:  &models.StaticSettings{Root: "/data/www/docs", Browse: true, Index: []string{"index.htm", "README.txt"}},
+			},
+			want: []string{"    root * /data/www/docs", "    file_server {", "        browse", "        index index.htm README.txt", "    }"},
+		},
+		{
+			name: "respond",
+			site: models.Site{
+				Domains: []string{"status.example.com"},
+				Type:    models.SiteTypeRespond,
+				Respond: &models.RespondSettings{Status: 503, Body: `Down for "maintenance"`, ContentType: "text/plain"},
+			},
+			want: []string{`    header Content-Type "text/plain"`, `    respond "Down for \"maintenance\"" 503`},
+		},
+		{
+			name: "respond with heredoc",
+			site: models.Site{
+				Domains: []string{"robots.example.com"},
+				Type:    models.SiteTypeRespond,
+				Respond: &models.RespondSettings{Body: "User-agent: *\n  Disallow: /"},
+			},
+			want: []string{"    respond <<BODY", "        User-agent: *", "          Disallow: /", "        BODY"},
+		},
+		{
+			name: "respond status only",
+			site: models.Site{
+				Domains: []string{"empty.example.com"},
+				Type:    models.SiteTypeRespond,
+				Respond: &models.RespondSettings{Status: 204},
+			},
+			want: []string{"    respond 204"},
+		},
+		{
+			name: "wildcard static",
+			site: models.Site{
+				Domains: []string{"files.example.com"},
+				Type:    models.SiteTypeStatic,
+				Static:  &models.StaticSettings{Root: "/usr/share/caddy", Browse: true},
+			},
+			tlsMode: "wildcard:example.com",
+			want:    []string{"    root * /usr/share/caddy", "    file_server browse"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := tt.site
+			site.Filename = site.PrimaryDomain() + ".caddy"
+			site.TLSMode = tt.tlsMode
+			if err := site.ValidateType(); err != nil {
+				t.Fatalf("ValidateType: %v", err)
+			}
+
+			content := site.ToCaddyfile()
+			if !strings.Contains(content, strings.Join(tt.want, "\n")+"\n}") {
+				t.Errorf("site file misses the handler lines %q:\n%s", tt.want, content)
+			}
+			if strings.Contains(content, "reverse_proxy") {
+				t.Errorf("%s site proxies:\n%s", site.Type, content)
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if parsed.Type != site.Type {
+				t.Errorf("parsed type = %q, want %q", parsed.Type, site.Type)
+			}
+			if !reflect.DeepEqual(parsed.Redirect, site.Redirect) || !reflect.DeepEqual(parsed.Static, site.Static) ||
+				!reflect.DeepEqual(parsed.Respond, site.Respond) {
+				t.Errorf("parsed settings = %+v %+v %+v, want %+v %+v %+v",
+					parsed.Redirect, parsed.Static, parsed.Respond, site.Redirect, site.Static, site.Respond)
+			}
+			if parsed.ExtraConfig != "" {
+				t.Errorf("handler lines leaked into extra config: %q", parsed.ExtraConfig)
+			}
+		})
+	}
+}
+
+func TestSiteTypeValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		site models.Site
+	}{
+		{name: "unknown type", site: models.Site{Type: "ftp"}},
+		{name: "redirect without settings", site: models.Site{Type: models.SiteTypeRedirect}},
+		{name: "redirect with spaces", site: models.Site{Type: models.SiteTypeRedirect, Redirect: &models.RedirectSettings{To: "https://example.com/a b"}}},
+		{name: "redirect to another scheme", site: models.Site{Type: models.SiteTypeRedirect, Redirect: &models.RedirectSettings{To: "ftp://example.com"}}},
+		{name: "unknown redirect code", site: models.Site{Type: models.SiteTypeRedirect, Redirect: &models.RedirectSettings{To: "example.com", Code: "303"}}},
+		{name: "canonical redirect to itself", site: models.Site{Domains: []string{"example.com"}, Type: models.SiteTypeRedirect, Redirect: &models.RedirectSettings{Canonical: "apex"}}},
+		{name: "canonical redirect to two hosts", site: models.Site{Domains: []string{"www.a.example", "www.b.example"}, Type: models.SiteTypeRedirect, Redirect: &models.RedirectSettings{Canonical: "apex"}}},
+		{name: "static outside the allowed roots", site: models.Site{Type: models.SiteTypeStatic, Static: &models.StaticSettings{Root: "/data/caddy"}}},
+		{name: "static root escaping", site: models.Site{Type: models.SiteTypeStatic, Static: &models.StaticSettings{Root: "/data/www/../caddy"}}},
+		{name: "static relative root", site: models.Site{Type: models.SiteTypeStatic, Static: &models.StaticSettings{Root: "www"}}},
+		{name: "static index with path", site: models.Site{Type: models.SiteTypeStatic, Static: &models.StaticSettings{Root: "/data/www", Index: []string{"a/index.html"}}}},
+		{name: "respond invalid status", site: models.Site{Type: models.SiteTypeRespond, Respond: &models.RespondSettings{Status: 99}}},
+		{name: "respond heredoc marker", site: models.Site{Type: models.SiteTypeRespond, Respond: &models.RespondSettings{Body: "a\nBODY\nb"}}},
+		{name: "respond unbalanced braces", site: models.Site{Type: models.SiteTypeRespond, Respond: &models.RespondSettings{Body: "{"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.site.ValidateType(); err == nil {
+				t.Error("invalid site type settings accepted")
+			}
+		})
+	}
+}
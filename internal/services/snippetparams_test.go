@@ -0,0 +1,211 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestSnippetParamCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		param   models.SnippetParam
+		value   string
+		wantErr bool
+	}{
+		{name: "int", param: models.SnippetParam{Name: "requests", Type: models.SnippetParamInt}, value: "100"},
+		{name: "negative int", param: models.SnippetParam{Name: "requests", Type: models.SnippetParamInt}, value: "-1", wantErr: true},
+		{name: "int with unit", param: models.SnippetParam{Name: "requests", Type: models.SnippetParamInt}, value: "10s", wantErr: true},
+		{name: "duration", param: models.SnippetParam{Name: "window", Type: models.SnippetParamDuration}, value: "1m30s"},
+		{name: "duration without unit", param: models.SnippetParam{Name: "window", Type: models.SnippetParamDuration}, value: "60", wantErr: true},
+		{name: "string with spaces", param: models.SnippetParam{Name: "label", Type: models.SnippetParamString}, value: "Internal API"},
+		{name: "empty string", param: models.SnippetParam{Name: "label", Type: models.SnippetParamString}, value: "", wantErr: true},
+		{name: "multiline string", param: models.SnippetParam{Name: "label", Type: models.SnippetParamString}, value: "a\nb", wantErr: true},
+		{name: "unknown type", param: models.SnippetParam{Name: "label", Type: "bool"}, value: "true", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.param.Check(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Check(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParamSnippetValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		params  []models.SnippetParam
+		body    string
+		wantErr bool
+	}{
+		{
+			name:   "all args declared",
+			params: []models.SnippetParam{{Name: "status", Type: models.SnippetParamInt, Default: "503"}, {Name: "message", Type: models.SnippetParamString, Default: "Be right back"}},
+			body:   "respond {args[1]} {args[0]}",
+		},
+		{
+			name:    "undeclared arg",
+			params:  []models.SnippetParam{{Name: "status", Type: models.SnippetParamInt, Default: "503"}},
+			body:    "respond {args[1]} {args[0]}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid default",
+			params:  []models.SnippetParam{{Name: "status", Type: models.SnippetParamInt, Default: "soon"}},
+			body:    "respond {args[0]}",
+			wantErr: true,
+		},
+		{
+			name:    "invalid parameter name",
+			params:  []models.SnippetParam{{Name: "Status", Type: models.SnippetParamInt, Default: "503"}},
+			body:    "respond {args[0]}",
+			wantErr: true,
+		},
+		{
+			name:    "duplicate parameter",
+			params:  []models.SnippetParam{{Name: "status", Type: models.SnippetParamInt, Default: "503"}, {Name: "status", Type: models.SnippetParamInt, Default: "502"}},
+			body:    "respond {args[0]}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet := models.CustomSnippet{Name: "unavailable", Body: tt.body, Params: tt.params}
+			err := snippet.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParamSnippetsFile(t *testing.T) {
+	cfg := &config.Config{ConfigDir: t.TempDir()}
+	snippets := &models.SnippetConfig{
+		RateLimit: models.RateLimitConfig{Enabled: true, Requests: 100, WindowSecs: 60},
+		Custom: []models.CustomSnippet{{
+			Name:        "unavailable",
+			Description: "Temporarily unavailable",
+			Body:        "respond {args[1]} {args[0]}",
+			Params: []models.SnippetParam{
+				{Name: "status", Type: models.SnippetParamInt, Default: "503"},
+				{Name: "message", Type: models.SnippetParamString, Default: "Be right back"},
+			},
+		}},
+	}
+	if err := NewSnippetsService(cfg).GenerateSnippetsFile(snippets); err != nil {
+		t.Fatalf("GenerateSnippetsFile: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.ConfigDir, "snippets.caddy"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := string(data)
+	for _, want := range []string{
+		"(rate_limit_args) {\n    rate_limit {remote.ip} {args[0]} {args[1]}\n}\n(rate_limit) {\n    import rate_limit_args {args[:]} 100 60s\n}\n",
+		"# Temporarily unavailable\n(unavailable_args) {\n    respond {args[1]} {args[0]}\n}\n(unavailable) {\n    import unavailable_args {args[:]} 503 \"Be right back\"\n}\n",
+	} {
+		if !strings.Contains(content, want) {
+			t.Errorf("snippets.caddy misses %q:\n%s", want, content)
+		}
+	}
+}
+
+func TestCheckSnippetArgs(t *testing.T) {
+	snippets := &models.SnippetConfig{
+		RateLimit: models.RateLimitConfig{Enabled: true, Requests: 100, WindowSecs: 60},
+		Custom: []models.CustomSnippet{
+			{Name: "gzip", Body: "encode gzip"},
+			{Name: "unavailable", Body: "respond {args[0]}", Params: []models.SnippetParam{{Name: "status", Type: models.SnippetParamInt, Default: "503"}}},
+		},
+	}
+	tests := []struct {
+		name    string
+		snippet string
+		args    []string
+		wantErr bool
+	}{
+		{name: "defaults", snippet: "rate_limit"},
+		{name: "all arguments", snippet: "rate_limit", args: []string{"10", "1m"}},
+		{name: "too few arguments", snippet: "rate_limit", args: []string{"10"}, wantErr: true},
+		{name: "invalid argument", snippet: "rate_limit", args: []string{"10", "soon"}, wantErr: true},
+		{name: "custom snippet", snippet: "unavailable", args: []string{"502"}},
+		{name: "snippet without parameters", snippet: "gzip", args: []string{"9"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := snippets.CheckSnippetArgs(tt.snippet, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckSnippetArgs(%s, %q) error = %v, wantErr %v", tt.snippet, tt.args, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSnippetArgsRoundTrip(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    map[string][]string
+		want    []string // Generated imports
+		tlsMode string
+	}{
+		{
+			name: "defaults",
+			want: []string{"    import rate_limit\n", "    import unavailable\n"},
+		},
+		{
+			name: "arguments",
+			args: map[string][]string{"rate_limit": {"10", "1m"}, "unavailable": {"502", "Back at 10:00"}},
+			want: []string{"    import rate_limit 10 1m\n", "    import unavailable 502 \"Back at 10:00\"\n"},
+		},
+		{
+			name:    "wildcard site",
+			args:    map[string][]string{"rate_limit": {"10", "1m"}},
+			tlsMode: "wildcard:example.com",
+			want:    []string{"    import rate_limit 10 1m\n", "    import unavailable\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := &models.Site{
+				Filename:    "app.example.com.caddy",
+				Domains:     []string{"app.example.com"},
+				TargetIP:    "10.0.0.5",
+				TargetPort:  "8080",
+				TLSMode:     tt.tlsMode,
+				Snippets:    []string{"rate_limit", "unavailable"},
+				SnippetArgs: tt.args,
+			}
+
+			content := site.ToCaddyfile()
+			for _, want := range tt.want {
+				if !strings.Contains(content, want) {
+					t.Errorf("site file misses %q:\n%s", want, content)
+				}
+			}
+
+			parsed := NewParserService().Parse(content, site.Filename)
+			if !reflect.DeepEqual(parsed.Snippets, site.Snippets) {
+				t.Errorf("parsed snippets = %q, want %q", parsed.Snippets, site.Snippets)
+			}
+			if !reflect.DeepEqual(parsed.SnippetArgs, site.SnippetArgs) {
+				t.Errorf("parsed snippet args = %q, want %q", parsed.SnippetArgs, site.SnippetArgs)
+			}
+			if parsed.ExtraConfig != "" {
+				t.Errorf("snippet arguments leaked into extra config %q", parsed.ExtraConfig)
+			}
+		})
+	}
+}
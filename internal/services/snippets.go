@@ -17,6 +17,7 @@ type SnippetsService struct {
 	config          *config.Config
 	configPath      string
 	wildcardService *WildcardService
+	mtlsService     *MTLSService
 }
 
 // NewSnippetsService creates a new snippets service
@@ -32,6 +33,11 @@ func (s *SnippetsService) SetWildcardService(ws *WildcardService) {
 	s.wildcardService = ws
 }
 
+// SetMTLSService sets the client CA store whose revocation snippets are generated
+func (s *SnippetsService) SetMTLSService(ms *MTLSService) {
+	s.mtlsService = ms
+}
+
 // GetConfig returns the current snippet configuration
 func (s *SnippetsService) GetConfig() (*models.SnippetConfig, error) {
 	if _, err := os.Stat(s.configPath); os.IsNotExist(err) {
@@ -110,6 +116,16 @@ func (s *SnippetsService) GetConfig() (*models.SnippetConfig, error) {
 		cfg.BasicAuth.Users = make(map[string]string)
 	}
 
+	// Configs saved before access lists existed get a LAN list with the internal networks
+	if cfg.AccessLists == nil {
+		cfg.AccessLists = models.DefaultAccessLists(cfg.InternalOnly.AllowedNetworks)
+	}
+
+	// Configs saved before forward auth existed start with the Authelia preset
+	if cfg.ForwardAuth.Provider == "" {
+		cfg.ForwardAuth = models.DefaultSnippetConfig().ForwardAuth
+	}
+
 	return &cfg, nil
 }
 
@@ -140,13 +156,14 @@ func (s *SnippetsService) GenerateSnippetsFile(cfg *models.SnippetConfig) error
 	// Cloudflare DNS
 	if cfg.CloudflareDNS.Enabled {
 		lines = append(lines, "# --- CLOUDFLARE DNS CHALLENGE ---")
+		lines = append(lines, "("+models.CloudflareDNSChallengeSnippet+") {")
+		if dns := cfg.CloudflareDNS.DNSLine(); dns != "" {
+			lines = append(lines, "    "+dns)
+		}
+		lines = append(lines, "}")
 		lines = append(lines, "(cloudflare_dns) {")
 		lines = append(lines, "    tls {")
-		if cfg.CloudflareDNS.UseEnv {
-			lines = append(lines, "        dns cloudflare {env.CF_API_TOKEN}")
-		} else if cfg.CloudflareDNS.APIToken != "" {
-			lines = append(lines, fmt.Sprintf("        dns cloudflare %s", cfg.CloudflareDNS.APIToken))
-		}
+		lines = append(lines, "        import "+models.CloudflareDNSChallengeSnippet)
 		lines = append(lines, "    }")
 		lines = append(lines, "}")
 		lines = append(lines, "")
@@ -171,42 +188,40 @@ func (s *SnippetsService) GenerateSnippetsFile(cfg *models.SnippetConfig) error
 		lines = append(lines, "        }")
 		lines = append(lines, "    }")
 		lines = append(lines, "}")
+		lines = append(lines, "("+models.InternalOnlyRouteSnippet+") {")
+		lines = append(lines, "    handle @denied {")
+		lines = append(lines, "        error 403")
+		lines = append(lines, "    }")
+		lines = append(lines, "}")
+		lines = append(lines, "")
+	}
+
+	// Access lists
+	if len(cfg.AccessLists) > 0 {
+		lines = append(lines, "# --- ACCESS LISTS ---")
+		for _, list := range cfg.AccessLists {
+			lines = append(lines, list.SnippetLines()...)
+			lines = append(lines, "")
+		}
+		lines = append(lines, models.AccessListCheckLines()...)
 		lines = append(lines, "")
 	}
 
 	// Security Headers
 	if cfg.SecurityHeaders.Enabled {
 		lines = append(lines, "# --- SECURITY HEADERS ---")
-		lines = append(lines, "(security_headers) {")
-		lines = append(lines, "    header {")
-
-		if cfg.SecurityHeaders.HSTSMaxAge > 0 {
-			hsts := fmt.Sprintf("max-age=%d", cfg.SecurityHeaders.HSTSMaxAge)
-			if cfg.SecurityHeaders.HSTSIncludeSubdomains {
-				hsts += "; includeSubDomains"
-			}
-			lines = append(lines, fmt.Sprintf(`        Strict-Transport-Security "%s"`, hsts))
-		}
-
-		if cfg.SecurityHeaders.XContentTypeOptions {
-			lines = append(lines, `        X-Content-Type-Options "nosniff"`)
-		}
-
-		if cfg.SecurityHeaders.XFrameOptions != "" {
-			lines = append(lines, fmt.Sprintf(`        X-Frame-Options "%s"`, cfg.SecurityHeaders.XFrameOptions))
-		}
-
-		if cfg.SecurityHeaders.ReferrerPolicy != "" {
-			lines = append(lines, fmt.Sprintf(`        Referrer-Policy "%s"`, cfg.SecurityHeaders.ReferrerPolicy))
-		}
+		lines = append(lines, cfg.SecurityHeaders.SnippetLines(models.SecurityHeadersSnippet)...)
+		lines = append(lines, "")
+	}
 
-		if cfg.SecurityHeaders.HideServer {
-			lines = append(lines, "        -Server")
+	// Security header profiles
+	if len(cfg.SecurityHeaderProfiles) > 0 {
+		lines = append(lines, "# --- SECURITY HEADER PROFILES ---")
+		for _, profile := range cfg.SecurityHeaderProfiles {
+			lines = append(lines, "# "+profile.Name)
+			lines = append(lines, profile.Headers.SnippetLines(profile.Snippet())...)
+			lines = append(lines, "")
 		}
-
-		lines = append(lines, "    }")
-		lines = append(lines, "}")
-		lines = append(lines, "")
 	}
 
 	// Compression
@@ -230,9 +245,7 @@ func (s *SnippetsService) GenerateSnippetsFile(cfg *models.SnippetConfig) error
 	// Rate Limit
 	if cfg.RateLimit.Enabled {
 		lines = append(lines, "# --- RATE LIMITING ---")
-		lines = append(lines, "(rate_limit) {")
-		lines = append(lines, fmt.Sprintf("    rate_limit {remote.ip} %d %ds", cfg.RateLimit.Requests, cfg.RateLimit.WindowSecs))
-		lines = append(lines, "}")
+		lines = append(lines, models.ParamSnippetLines("rate_limit", cfg.RateLimit.Params(), []string{"rate_limit {remote.ip} {args[0]} {args[1]}"})...)
 		lines = append(lines, "")
 	}
 
@@ -240,7 +253,8 @@ func (s *SnippetsService) GenerateSnippetsFile(cfg *models.SnippetConfig) error
 	if cfg.BasicAuth.Enabled && len(cfg.BasicAuth.Users) > 0 {
 		lines = append(lines, "# --- BASIC AUTHENTICATION ---")
 		lines = append(lines, "(basic_auth) {")
-		lines = append(lines, "    basic_auth {")
+		lines = append(lines, "    "+models.BasicAuthMatcher+" "+models.CORSPreflightExemption)
+		lines = append(lines, "    basic_auth "+models.BasicAuthMatcher+" {")
 		for username, hash := range cfg.BasicAuth.Users {
 			lines = append(lines, fmt.Sprintf("        %s %s", username, hash))
 		}
@@ -249,6 +263,27 @@ func (s *SnippetsService) GenerateSnippetsFile(cfg *models.SnippetConfig) error
 		lines = append(lines, "")
 	}
 
+	// Forward Auth
+	if cfg.ForwardAuth.Enabled {
+		lines = append(lines, "# --- FORWARD AUTH ---")
+		lines = append(lines, cfg.ForwardAuth.SnippetLines()...)
+		lines = append(lines, "")
+	}
+
+	// Maintenance mode, always generated as the maintenance windows import it
+	lines = append(lines, "# --- MAINTENANCE MODE ---")
+	lines = append(lines, cfg.Maintenance.SnippetLines()...)
+	lines = append(lines, "")
+
+	// Custom snippets
+	if len(cfg.Custom) > 0 {
+		lines = append(lines, "# --- CUSTOM SNIPPETS ---")
+		for _, snippet := range cfg.Custom {
+			lines = append(lines, snippet.SnippetLines()...)
+			lines = append(lines, "")
+		}
+	}
+
 	// Wildcard TLS Snippets
 	if s.wildcardService != nil {
 		wildcardConfig, err := s.wildcardService.GenerateCaddyConfig()
@@ -267,13 +302,22 @@ func (s *SnippetsService) GenerateSnippetsFile(cfg *models.SnippetConfig) error
 		}
 	}
 
+	// Client certificate revocation
+	if s.mtlsService != nil {
+		if revoked := s.mtlsService.RevokedSnippets(); len(revoked) > 0 {
+			lines = append(lines, "# --- CLIENT CERTIFICATE REVOCATION ---")
+			lines = append(lines, revoked...)
+			lines = append(lines, "")
+		}
+	}
+
 	content := strings.Join(lines, "\n")
 	snippetsPath := filepath.Join(s.config.ConfigDir, "snippets.caddy")
 
 	return os.WriteFile(snippetsPath, []byte(content), 0644)
 }
 
-// GetAvailableSnippets returns list of enabled snippets
+// GetAvailableSnippets returns list of enabled snippets, custom snippets last
 func (s *SnippetsService) GetAvailableSnippets() ([]string, error) {
 	cfg, err := s.GetConfig()
 	if err != nil {
@@ -291,6 +335,9 @@ func (s *SnippetsService) GetAvailableSnippets() ([]string, error) {
 	if cfg.SecurityHeaders.Enabled {
 		available = append(available, "security_headers")
 	}
+	for _, profile := range cfg.SecurityHeaderProfiles {
+		available = append(available, profile.Snippet())
+	}
 	if cfg.Compression.Enabled {
 		available = append(available, "compression")
 	}
@@ -300,6 +347,9 @@ func (s *SnippetsService) GetAvailableSnippets() ([]string, error) {
 	if cfg.BasicAuth.Enabled {
 		available = append(available, "basic_auth")
 	}
+	for _, snippet := range cfg.Custom {
+		available = append(available, snippet.Name)
+	}
 
 	return available, nil
 }
@@ -337,46 +387,3 @@ func (s *SnippetsService) RemoveBasicAuthUser(username string) error {
 
 	return s.SaveConfig(cfg)
 }
-
-// ValidateIPorCIDR validates IP or CIDR format
-func ValidateIPorCIDR(value string) bool {
-	// Simple validation - check if it looks like IP or CIDR
-	value = strings.TrimSpace(value)
-	if value == "" {
-		return false
-	}
-
-	// CIDR format: x.x.x.x/y
-	if strings.Contains(value, "/") {
-		parts := strings.Split(value, "/")
-		if len(parts) != 2 {
-			return false
-		}
-		// Check mask is number
-		for _, c := range parts[1] {
-			if c < '0' || c > '9' {
-				return false
-			}
-		}
-	}
-
-	// Check IP part has valid format
-	ipPart := strings.Split(value, "/")[0]
-	octets := strings.Split(ipPart, ".")
-	if len(octets) != 4 {
-		return false
-	}
-
-	for _, octet := range octets {
-		if octet == "" {
-			return false
-		}
-		for _, c := range octet {
-			if c < '0' || c > '9' {
-				return false
-			}
-		}
-	}
-
-	return true
-}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestCloudflareDNSChallengeSnippet(t *testing.T) {
+	tests := []struct {
+		name   string
+		config models.CloudflareDNSConfig
+		want   string
+	}{
+		{
+			name:   "env",
+			config: models.CloudflareDNSConfig{Enabled: true, UseEnv: true},
+			want:   "(cloudflare_dns_challenge) {\n    dns cloudflare {env.CF_API_TOKEN}\n}\n",
+		},
+		{
+			name:   "token",
+			config: models.CloudflareDNSConfig{Enabled: true, APIToken: "literal-token"},
+			want:   "(cloudflare_dns_challenge) {\n    dns cloudflare literal-token\n}\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{ConfigDir: t.TempDir()}
+			if err := NewSnippetsService(cfg).GenerateSnippetsFile(&models.SnippetConfig{CloudflareDNS: tt.config}); err != nil {
+				t.Fatalf("GenerateSnippetsFile: %v", err)
+			}
+			data, err := os.ReadFile(filepath.Join(cfg.ConfigDir, "snippets.caddy"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			content := string(data)
+			if !strings.Contains(content, tt.want) {
+				t.Errorf("snippets.caddy misses the challenge snippet %q:\n%s", tt.want, content)
+			}
+			if !strings.Contains(content, "(cloudflare_dns) {\n    tls {\n        import cloudflare_dns_challenge\n    }\n}") {
+				t.Errorf("cloudflare_dns does not import the challenge snippet:\n%s", content)
+			}
+		})
+	}
+}
+
+func TestSiteTLSImportsCloudflareDNSChallenge(t *testing.T) {
+	site := &models.Site{
+		Domains:    []string{"app.example.com"},
+		TargetIP:   "10.0.0.5",
+		TargetPort: "8080",
+		TLSMode:    models.IssuerLetsEncrypt,
+		Snippets:   []string{"cloudflare_dns"},
+	}
+
+	content := site.ToCaddyfile()
+	if strings.Contains(content, "CF_API_TOKEN") {
+		t.Errorf("site hard-codes the Cloudflare token variable:\n%s", content)
+	}
+	if strings.Contains(content, "import cloudflare_dns\n") {
+		t.Errorf("site imports the cloudflare_dns snippet next to its own tls block:\n%s", content)
+	}
+	if !strings.Contains(content, "        import cloudflare_dns_challenge\n") {
+		t.Errorf("tls block does not import the challenge snippet:\n%s", content)
+	}
+
+	parsed := NewParserService().Parse(content, "app.example.com")
+	if !contains(parsed.Snippets, "cloudflare_dns") || contains(parsed.Snippets, models.CloudflareDNSChallengeSnippet) {
+		t.Errorf("parsed snippets = %q, want cloudflare_dns only", parsed.Snippets)
+	}
+}
@@ -0,0 +1,539 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// StateService reconciles the proxy configuration with a declarative YAML state file
+type StateService struct {
+	config          *config.Config
+	caddyService    *CaddyService
+	wildcardService *WildcardService
+	snippetsService *SnippetsService
+	certService     *CertificateService
+
+	mu       sync.Mutex
+	status   models.StateSyncStatus
+	lastHash [32]byte
+}
+
+// NewStateService creates a new state service
+func NewStateService(cfg *config.Config, cs *CaddyService, ws *WildcardService, ss *SnippetsService, certs *CertificateService) *StateService {
+	return &StateService{
+		config:          cfg,
+		caddyService:    cs,
+		wildcardService: ws,
+		snippetsService: ss,
+		certService:     certs,
+		status: models.StateSyncStatus{
+			Enabled: cfg.StateFile != "",
+			Path:    cfg.StateFile,
+		},
+	}
+}
+
+// ParseState parses and validates a YAML state file
+func (s *StateService) ParseState(data []byte) (*models.DesiredState, error) {
+	var state models.DesiredState
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid state file: %w", err)
+	}
+
+	if state.Version != models.StateVersion {
+		return nil, fmt.Errorf("unsupported state version: %d", state.Version)
+	}
+	if len(state.Sites) == 0 {
+		// Guard against wiping every site with an empty or truncated file
+		return nil, fmt.Errorf("state file lists no sites")
+	}
+
+	if state.Snippets != nil {
+		if err := state.Snippets.Validate(); err != nil {
+			return nil, fmt.Errorf("snippets: %w", err)
+		}
+	}
+
+	wildcards := make(map[string]bool)
+	if state.WildcardDomains != nil {
+		for _, wd := range state.WildcardDomains {
+			if wd.Domain == "" {
+				return nil, fmt.Errorf("wildcard domain without a domain")
+			}
+			if wildcards[wd.Domain] {
+				return nil, fmt.Errorf("duplicate wildcard domain %s", wd.Domain)
+			}
+			if err := ValidateWildcardDomain(wd); err != nil {
+				return nil, fmt.Errorf("wildcard domain %s: %w", wd.Domain, err)
+			}
+			wildcards[wd.Domain] = true
+		}
+	} else if domains, err := s.wildcardService.GetDomains(); err == nil {
+		for _, wd := range domains {
+			wildcards[wd.Domain] = true
+		}
+	}
+
+	filenames := make(map[string]bool)
+	domains := make(map[string]string)
+	var errs []string
+	for i := range state.Sites {
+		site := &state.Sites[i]
+		if site.Filename == "" {
+			site.Filename = sanitizeFilename(site.PrimaryDomain())
+		}
+		if site.TLSMode == "" {
+			site.TLSMode = "auto"
+		}
+		if site.Snippets == nil {
+			site.Snippets = []string{}
+		}
+		site.IsInternal = site.IsInternal || contains(site.Snippets, "internal_only")
+
+		if err := validateRule(site, wildcards, s.certService); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", site.PrimaryDomain(), err))
+			continue
+		}
+		if filenames[site.Filename] {
+			errs = append(errs, fmt.Sprintf("%s: duplicate filename %s", site.PrimaryDomain(), site.Filename))
+			continue
+		}
+		filenames[site.Filename] = true
+		for _, d := range site.Domains {
+			key := strings.ToLower(d)
+			if owner, ok := domains[key]; ok {
+				errs = append(errs, fmt.Sprintf("%s: domain %s is already used by %s", site.PrimaryDomain(), d, owner))
+			}
+			domains[key] = site.Filename
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid sites: %s", strings.Join(errs, "; "))
+	}
+
+	return &state, nil
+}
+
+// ExportState returns the current configuration as a YAML state file
+func (s *StateService) ExportState() ([]byte, error) {
+	sites, err := s.caddyService.GetAllSites()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sites: %w", err)
+	}
+	wildcards, err := s.wildcardService.GetDomains()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load wildcard domains: %w", err)
+	}
+	snippets, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snippet config: %w", err)
+	}
+
+	state := models.DesiredState{
+		Version:         models.StateVersion,
+		WildcardDomains: wildcards,
+		Snippets:        snippets,
+	}
+	for _, site := range sites {
+		state.Sites = append(state.Sites, *site)
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(state); err != nil {
+		return nil, fmt.Errorf("failed to encode state: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// Plan compares the desired state with the current configuration
+func (s *StateService) Plan(desired *models.DesiredState) (*models.StatePlan, error) {
+	plan := &models.StatePlan{Actions: []models.StateAction{}}
+
+	// Snippets
+	if desired.Snippets != nil {
+		current, err := s.snippetsService.GetConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load snippet config: %w", err)
+		}
+		if !sameJSON(current, desired.Snippets) {
+			plan.Actions = append(plan.Actions, models.StateAction{
+				Kind: "snippets", Name: "snippets", Action: "update",
+				Before: toYAML(current), After: toYAML(desired.Snippets),
+			})
+		}
+	}
+
+	// Wildcard domains
+	if desired.WildcardDomains != nil {
+		current, err := s.wildcardService.GetDomains()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load wildcard domains: %w", err)
+		}
+		currentByDomain := make(map[string]models.WildcardDomain)
+		for _, wd := range current {
+			currentByDomain[wd.Domain] = wd
+		}
+		wanted := make(map[string]bool)
+		for _, wd := range desired.WildcardDomains {
+			wanted[wd.Domain] = true
+			old, exists := currentByDomain[wd.Domain]
+			switch {
+			case !exists:
+				plan.Actions = append(plan.Actions, models.StateAction{Kind: "wildcard", Name: wd.Domain, Action: "create", After: toYAML(wd)})
+			case !sameJSON(old, wd):
+				plan.Actions = append(plan.Actions, models.StateAction{Kind: "wildcard", Name: wd.Domain, Action: "update", Before: toYAML(old), After: toYAML(wd)})
+			}
+		}
+		for _, wd := range current {
+			if !wanted[wd.Domain] {
+				plan.Actions = append(plan.Actions, models.StateAction{Kind: "wildcard", Name: wd.Domain, Action: "delete", Before: toYAML(wd)})
+			}
+		}
+	}
+
+	// Sites
+	current, err := s.caddyService.GetAllSites()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sites: %w", err)
+	}
+	currentByName := make(map[string]*models.Site)
+	for _, site := range current {
+		currentByName[site.Filename] = site
+	}
+	// Maintenance mode isn't part of the state; sites keep the active windows
+	snippets := desired.Snippets
+	if snippets == nil {
+		if snippets, err = s.snippetsService.GetConfig(); err != nil {
+			return nil, fmt.Errorf("failed to load snippet config: %w", err)
+		}
+	}
+	now := time.Now()
+
+	wanted := make(map[string]bool)
+	for i := range desired.Sites {
+		site := &desired.Sites[i]
+		wanted[site.Filename] = true
+		site.Maintenance = snippets.Maintenance.InMaintenance(site, now)
+
+		content := site.ToCaddyfile()
+		old, exists := currentByName[site.Filename]
+		switch {
+		case !exists:
+			plan.Actions = append(plan.Actions, models.StateAction{Kind: "site", Name: site.Filename, Action: "create", After: content})
+		case strings.TrimSpace(old.RawContent) != strings.TrimSpace(content) ||
+			filepath.Dir(old.Filepath) != s.caddyService.SiteDirectory(site):
+			plan.Actions = append(plan.Actions, models.StateAction{Kind: "site", Name: site.Filename, Action: "update", Before: old.RawContent, After: content})
+		}
+	}
+	for _, site := range current {
+		if !wanted[site.Filename] {
+			plan.Actions = append(plan.Actions, models.StateAction{Kind: "site", Name: site.Filename, Action: "delete", Before: site.RawContent})
+		}
+	}
+
+	for _, action := range plan.Actions {
+		switch action.Action {
+		case "create":
+			plan.Create++
+		case "update":
+			plan.Update++
+		case "delete":
+			plan.Delete++
+		}
+	}
+
+	return plan, nil
+}
+
+// Apply brings the configuration to the desired state. All touched files are
+// snapshotted first and restored if writing or the Caddy reload fails.
+func (s *StateService) Apply(desired *models.DesiredState) (*models.StatePlan, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	plan, err := s.Plan(desired)
+	if err != nil {
+		return nil, err
+	}
+	if !plan.HasChanges() {
+		return plan, nil
+	}
+
+	snapshot, err := s.snapshot(desired)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.execute(desired, plan); err != nil {
+		s.rollback(snapshot)
+		return plan, err
+	}
+
+	if result := s.caddyService.ReloadWithValidation(); !result.Success {
+		s.rollback(snapshot)
+		if rollback := s.caddyService.ReloadWithValidation(); !rollback.Success {
+			log.Printf("State rollback reload failed: %s", rollback.Error)
+		}
+		return plan, fmt.Errorf("reload failed, changes rolled back: %s", result.Error)
+	}
+
+	return plan, nil
+}
+
+// execute performs the plan's actions
+func (s *StateService) execute(desired *models.DesiredState, plan *models.StatePlan) error {
+	configChanged := false
+	sites := make(map[string]*models.Site)
+	for i := range desired.Sites {
+		sites[desired.Sites[i].Filename] = &desired.Sites[i]
+	}
+
+	for _, action := range plan.Actions {
+		switch action.Kind {
+		case "snippets":
+			if err := s.snippetsService.SaveConfig(desired.Snippets); err != nil {
+				return fmt.Errorf("failed to save snippet config: %w", err)
+			}
+			configChanged = true
+
+		case "wildcard":
+			// Wildcard domains are written once as a whole below
+			configChanged = true
+
+		case "site":
+			if action.Action == "delete" {
+				if err := s.caddyService.DeleteSite(action.Name); err != nil {
+					return fmt.Errorf("failed to delete %s: %w", action.Name, err)
+				}
+				continue
+			}
+
+			site := sites[action.Name]
+			if action.Action == "update" {
+				if old, err := s.caddyService.GetSite(action.Name); err == nil {
+					site.Filepath = old.Filepath
+				}
+				if err := s.caddyService.UpdateSite(site); err != nil {
+					return fmt.Errorf("failed to update %s: %w", action.Name, err)
+				}
+				continue
+			}
+			if err := s.caddyService.CreateSite(site); err != nil {
+				return fmt.Errorf("failed to create %s: %w", action.Name, err)
+			}
+		}
+	}
+
+	if !configChanged {
+		return nil
+	}
+
+	if desired.WildcardDomains != nil {
+		if err := s.wildcardService.SaveConfig(&models.WildcardConfig{Domains: desired.WildcardDomains}); err != nil {
+			return fmt.Errorf("failed to save wildcard domains: %w", err)
+		}
+	}
+
+	cfg, err := s.snippetsService.GetConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load snippet config: %w", err)
+	}
+	if err := s.snippetsService.GenerateSnippetsFile(cfg); err != nil {
+		return fmt.Errorf("failed to regenerate snippets: %w", err)
+	}
+	if s.caddyService.GetCaddyfileManager() != nil {
+		if err := s.caddyService.RegenerateCaddyfile(); err != nil {
+			return fmt.Errorf("failed to regenerate Caddyfile: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// stateSnapshot holds file contents from before an apply; nil means the file didn't exist
+type stateSnapshot map[string][]byte
+
+// snapshot records every file an apply may touch
+func (s *StateService) snapshot(desired *models.DesiredState) (stateSnapshot, error) {
+	paths := []string{
+		filepath.Join(s.config.ConfigDir, "Caddyfile"),
+		filepath.Join(s.config.ConfigDir, "snippets.caddy"),
+		filepath.Join(s.config.ConfigDir, ".snippets_config.json"),
+		filepath.Join(s.config.ConfigDir, "wildcard.json"),
+	}
+
+	current, err := s.caddyService.GetAllSites()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sites: %w", err)
+	}
+	for _, site := range current {
+		paths = append(paths, site.Filepath)
+	}
+	for i := range desired.Sites {
+		site := &desired.Sites[i]
+		paths = append(paths, filepath.Join(s.caddyService.SiteDirectory(site), site.Filename+".caddy"))
+	}
+
+	return snapshotFiles(paths)
+}
+
+// snapshotFiles records the contents of the given files
+func snapshotFiles(paths []string) (stateSnapshot, error) {
+	snap := make(stateSnapshot)
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			snap[path] = content
+		case os.IsNotExist(err):
+			snap[path] = nil
+		default:
+			return nil, fmt.Errorf("failed to snapshot %s: %w", path, err)
+		}
+	}
+
+	return snap, nil
+}
+
+// rollback restores the files recorded in a snapshot
+func (s *StateService) rollback(snap stateSnapshot) {
+	snap.restore()
+}
+
+// restore writes the recorded contents back and removes the files that didn't exist
+func (snap stateSnapshot) restore() {
+	for path, content := range snap {
+		if content == nil {
+			os.Remove(path)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Printf("Rollback failed for %s: %v", path, err)
+			continue
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			log.Printf("Rollback failed for %s: %v", path, err)
+		}
+	}
+}
+
+// Status returns the status of the watched state file
+func (s *StateService) Status() models.StateSyncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Watch polls the configured state file and applies it whenever it changes.
+// It returns when stop is closed.
+func (s *StateService) Watch(stop <-chan struct{}) {
+	if s.config.StateFile == "" {
+		return
+	}
+
+	interval := time.Duration(s.config.StateWatchInterval) * time.Second
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	log.Printf("Watching state file %s every %s", s.config.StateFile, interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.syncStateFile()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.syncStateFile()
+		}
+	}
+}
+
+// syncStateFile applies the state file if its content changed since the last sync
+func (s *StateService) syncStateFile() {
+	data, err := os.ReadFile(s.config.StateFile)
+	hash := sha256.Sum256(data)
+
+	s.mu.Lock()
+	s.status.LastCheck = time.Now()
+	unchanged := err == nil && hash == s.lastHash
+	s.mu.Unlock()
+
+	if err != nil {
+		s.setSyncError(fmt.Errorf("failed to read state file: %w", err))
+		return
+	}
+	if unchanged {
+		return
+	}
+
+	// Failed versions aren't retried until the file changes again
+	s.mu.Lock()
+	s.lastHash = hash
+	s.mu.Unlock()
+
+	desired, err := s.ParseState(data)
+	if err != nil {
+		s.setSyncError(err)
+		return
+	}
+
+	plan, err := s.Apply(desired)
+	if err != nil {
+		s.setSyncError(err)
+		return
+	}
+
+	s.mu.Lock()
+	s.status.Error = ""
+	if plan.HasChanges() {
+		s.status.LastApply = time.Now()
+		s.status.Applied = len(plan.Actions)
+		log.Printf("State file applied: %d created, %d updated, %d deleted", plan.Create, plan.Update, plan.Delete)
+	}
+	s.mu.Unlock()
+}
+
+// setSyncError records a failed sync
+func (s *StateService) setSyncError(err error) {
+	log.Printf("State sync failed: %v", err)
+	s.mu.Lock()
+	s.status.Error = err.Error()
+	s.mu.Unlock()
+}
+
+// sameJSON compares two values by their JSON encoding
+func sameJSON(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(ja, jb)
+}
+
+// toYAML renders a value for display in a plan
+func toYAML(v interface{}) string {
+	out, err := yaml.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
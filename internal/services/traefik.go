@@ -0,0 +1,1008 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/TomasZmek/cpm/internal/config"
+	"github.com/TomasZmek/cpm/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Traefik conversion formats
+const (
+	TraefikFormatYAML   = "yaml"
+	TraefikFormatTOML   = "toml"
+	TraefikFormatLabels = "labels"
+)
+
+// Names of the shared middlewares generated from CPM snippets
+const (
+	traefikInternalOnly    = "cpm-internal-only"
+	traefikSecurityHeaders = "cpm-security-headers"
+	traefikCompression     = "cpm-compression"
+	traefikRateLimit       = "cpm-rate-limit"
+	traefikBasicAuth       = "cpm-basic-auth"
+)
+
+// TraefikExportOptions controls how routers are generated
+type TraefikExportOptions struct {
+	EntryPoint   string // e.g. "websecure"
+	CertResolver string // e.g. "letsencrypt"
+}
+
+// TraefikConverter converts between CPM sites and Traefik router/service definitions
+type TraefikConverter struct {
+	config          *config.Config
+	snippetsService *SnippetsService
+	wildcardService *WildcardService
+}
+
+// NewTraefikConverter creates a new Traefik converter
+func NewTraefikConverter(cfg *config.Config, ss *SnippetsService, ws *WildcardService) *TraefikConverter {
+	return &TraefikConverter{
+		config:          cfg,
+		snippetsService: ss,
+		wildcardService: ws,
+	}
+}
+
+// Export converts sites into the requested format
+func (t *TraefikConverter) Export(sites []*models.Site, format string, opts TraefikExportOptions) (string, *models.TraefikMappingReport, error) {
+	if format == TraefikFormatLabels {
+		out, report := t.ToLabels(sites, opts)
+		return out, report, nil
+	}
+
+	dynamic, report := t.ToDynamicConfig(sites, opts)
+
+	switch format {
+	case TraefikFormatTOML:
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(dynamic); err != nil {
+			return "", report, fmt.Errorf("failed to encode TOML: %w", err)
+		}
+		return buf.String(), report, nil
+	case TraefikFormatYAML, "":
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(dynamic); err != nil {
+			return "", report, fmt.Errorf("failed to encode YAML: %w", err)
+		}
+		return buf.String(), report, nil
+	default:
+		return "", report, fmt.Errorf("unknown format: %s", format)
+	}
+}
+
+// ToDynamicConfig converts sites into a Traefik file-provider dynamic configuration
+func (t *TraefikConverter) ToDynamicConfig(sites []*models.Site, opts TraefikExportOptions) (*models.TraefikDynamicConfig, *models.TraefikMappingReport) {
+	opts = defaultTraefikOptions(opts)
+	report := &models.TraefikMappingReport{}
+	snippetCfg := t.snippetConfig()
+
+	httpCfg := &models.TraefikHTTPConfig{
+		Routers:           map[string]*models.TraefikRouter{},
+		Services:          map[string]*models.TraefikService{},
+		Middlewares:       map[string]*models.TraefikMiddleware{},
+		ServersTransports: map[string]*models.TraefikServersTransport{},
+	}
+
+	for _, site := range sites {
+		name := traefikName(site)
+		domain := site.PrimaryDomain()
+		if site.Disabled {
+			report.Add(domain, "disabled", "Disabled rules aren't exported")
+			continue
+		}
+		if !site.IsProxy() {
+			report.Add(domain, "type", "Only reverse proxy rules are exported, the "+site.Type+" rule is skipped")
+			continue
+		}
+
+		router := &models.TraefikRouter{
+			Rule:        traefikHostRule(site.Domains),
+			EntryPoints: []string{opts.EntryPoint},
+			Service:     name,
+			TLS:         traefikRouterTLS(site, opts),
+		}
+
+		for _, mw := range t.siteMiddlewares(site, snippetCfg, report) {
+			if _, ok := httpCfg.Middlewares[mw]; !ok {
+				httpCfg.Middlewares[mw] = t.sharedMiddleware(mw, snippetCfg)
+			}
+			router.Middlewares = append(router.Middlewares, mw)
+		}
+
+		if site.BasicAuthEnabled && len(site.BasicAuthUsers) > 0 {
+			authName := name + "-auth"
+			httpCfg.Middlewares[authName] = &models.TraefikMiddleware{
+				BasicAuth: &models.TraefikBasicAuth{Users: traefikUsers(site.BasicAuthUsers)},
+			}
+			router.Middlewares = append(router.Middlewares, authName)
+		}
+
+		lb := &models.TraefikLoadBalancer{}
+		for _, backend := range site.AllBackends() {
+			lb.Servers = append(lb.Servers, models.TraefikServer{URL: backend})
+		}
+		if site.HealthCheckPath != "" {
+			lb.HealthCheck = &models.TraefikHealthCheck{Path: site.HealthCheckPath, Interval: "30s"}
+		}
+		t.mapLBPolicy(site, lb, report)
+
+		if site.IsHTTPSBackend || site.TimeoutSeconds > 0 {
+			transport := &models.TraefikServersTransport{InsecureSkipVerify: site.IsHTTPSBackend}
+			if site.TimeoutSeconds > 0 {
+				timeout := fmt.Sprintf("%ds", site.TimeoutSeconds)
+				transport.ForwardingTimeouts = &models.TraefikForwardingTimeouts{
+					DialTimeout:           timeout,
+					ResponseHeaderTimeout: timeout,
+				}
+			}
+			httpCfg.ServersTransports[name] = transport
+			lb.ServersTransport = name
+		}
+
+		reportUnsupported(site, opts, report)
+
+		httpCfg.Routers[name] = router
+		httpCfg.Services[name] = &models.TraefikService{LoadBalancer: lb}
+	}
+
+	if len(httpCfg.ServersTransports) == 0 {
+		httpCfg.ServersTransports = nil
+	}
+
+	return &models.TraefikDynamicConfig{HTTP: httpCfg}, report
+}
+
+// ToLabels converts sites into docker-compose labels, one block per site
+func (t *TraefikConverter) ToLabels(sites []*models.Site, opts TraefikExportOptions) (string, *models.TraefikMappingReport) {
+	opts = defaultTraefikOptions(opts)
+	report := &models.TraefikMappingReport{}
+	snippetCfg := t.snippetConfig()
+
+	var blocks []string
+	shared := map[string]bool{}
+
+	for _, site := range sites {
+		name := traefikName(site)
+		domain := site.PrimaryDomain()
+		if site.Disabled {
+			report.Add(domain, "disabled", "Disabled rules aren't exported")
+			continue
+		}
+		if !site.IsProxy() {
+			report.Add(domain, "type", "Only reverse proxy rules are exported, the "+site.Type+" rule is skipped")
+			continue
+		}
+		router := "traefik.http.routers." + name
+		service := "traefik.http.services." + name
+
+		labels := []string{
+			"traefik.enable=true",
+			fmt.Sprintf("%s.rule=%s", router, traefikHostRule(site.Domains)),
+			fmt.Sprintf("%s.entrypoints=%s", router, opts.EntryPoint),
+			fmt.Sprintf("%s.service=%s", router, name),
+			fmt.Sprintf("%s.tls=true", router),
+			fmt.Sprintf("%s.tls.certresolver=%s", router, opts.CertResolver),
+		}
+		if site.IsWildcard() {
+			labels = append(labels,
+				fmt.Sprintf("%s.tls.domains[0].main=%s", router, site.WildcardDomain()),
+				fmt.Sprintf("%s.tls.domains[0].sans=*.%s", router, site.WildcardDomain()),
+			)
+		}
+
+		var middlewares []string
+		for _, mw := range t.siteMiddlewares(site, snippetCfg, report) {
+			shared[mw] = true
+			middlewares = append(middlewares, mw+"@docker")
+		}
+		if site.BasicAuthEnabled && len(site.BasicAuthUsers) > 0 {
+			authName := name + "-auth"
+			labels = append(labels, fmt.Sprintf("traefik.http.middlewares.%s.basicauth.users=%s",
+				authName, escapeComposeValue(strings.Join(traefikUsers(site.BasicAuthUsers), ","))))
+			middlewares = append(middlewares, authName)
+		}
+		if len(middlewares) > 0 {
+			labels = append(labels, fmt.Sprintf("%s.middlewares=%s", router, strings.Join(middlewares, ",")))
+		}
+
+		// Labels describe the container they are attached to, so only the port is kept
+		labels = append(labels, fmt.Sprintf("%s.loadbalancer.server.port=%s", service, site.TargetPort))
+		if site.IsHTTPSBackend {
+			labels = append(labels, fmt.Sprintf("%s.loadbalancer.server.scheme=https", service))
+		}
+		if site.TargetIP != "" {
+			report.Add(domain, "target_ip", "Labels use the container address, target IP "+site.TargetIP+" is dropped")
+		}
+		if len(site.AdditionalBackends) > 0 {
+			report.Add(domain, "additional_backends", "Labels describe a single container, additional backends are dropped")
+		}
+		if site.HealthCheckPath != "" {
+			labels = append(labels,
+				fmt.Sprintf("%s.loadbalancer.healthcheck.path=%s", service, site.HealthCheckPath),
+				fmt.Sprintf("%s.loadbalancer.healthcheck.interval=30s", service),
+			)
+		}
+
+		lb := &models.TraefikLoadBalancer{}
+		t.mapLBPolicy(site, lb, report)
+		if lb.Sticky != nil {
+			labels = append(labels, fmt.Sprintf("%s.loadbalancer.sticky.cookie=true", service))
+		}
+
+		if site.IsHTTPSBackend || site.TimeoutSeconds > 0 {
+			report.Add(domain, "transport", "Servers transports (skip TLS verify, timeouts) can't be defined with labels, define one in the file provider and reference it with loadbalancer.serverstransport")
+		}
+		reportUnsupported(site, opts, report)
+
+		blocks = append(blocks, formatComposeLabels("# "+domain, labels))
+	}
+
+	if len(shared) > 0 {
+		var names []string
+		for mw := range shared {
+			names = append(names, mw)
+		}
+		sort.Strings(names)
+
+		var labels []string
+		for _, mw := range names {
+			labels = append(labels, t.sharedMiddlewareLabels(mw, snippetCfg)...)
+		}
+		blocks = append([]string{formatComposeLabels("# Shared middlewares - add to the Traefik container", labels)}, blocks...)
+	}
+
+	return strings.Join(blocks, "\n"), report
+}
+
+// reportUnsupported records the site settings neither export format can express
+func reportUnsupported(site *models.Site, opts TraefikExportOptions, report *models.TraefikMappingReport) {
+	domain := site.PrimaryDomain()
+	if strings.TrimSpace(site.ExtraConfig) != "" {
+		report.Add(domain, "extra_config", "Raw Caddy directives can't be converted")
+	}
+	if site.HasIssuer() {
+		report.Add(domain, "tls_mode", "Certificate issuer "+site.TLSMode+" is configured on the Traefik certificate resolver, "+opts.CertResolver+" is used")
+	}
+	if site.HasClientAuth() {
+		report.Add(domain, "client_auth", "Client certificate authentication needs a Traefik TLS option with clientAuth, it isn't exported")
+	}
+	if site.OnDemand {
+		report.Add(domain, "on_demand", "Traefik has no on-demand certificates, the router gets a certificate for its domains")
+	}
+	if site.Maintenance {
+		report.Add(domain, "maintenance", "The rule is in maintenance mode, the exported router forwards to the backend anyway")
+	}
+	if len(site.Routes) > 0 {
+		report.Add(domain, "routes", "Path routes aren't exported, only the default backend is")
+	}
+	if site.ForwardAuth != nil {
+		report.Add(domain, "forward_auth", "Forward auth isn't exported, define a Traefik forwardAuth middleware for "+site.ForwardAuth.Provider)
+	}
+	if site.CORS != nil {
+		report.Add(domain, "cors", "CORS isn't exported, set accessControlAllowOriginList on a Traefik headers middleware")
+	}
+	if len(site.HeaderRules) > 0 {
+		report.Add(domain, "header_rules", "Header rules aren't exported, use a Traefik headers middleware")
+	}
+}
+
+// Import converts Traefik definitions into sites. For labels, targetHost is used as the
+// backend address because labels only carry the container port.
+func (t *TraefikConverter) Import(data, format, targetHost string) ([]*models.Site, *models.TraefikMappingReport, error) {
+	if format == "" || format == "auto" {
+		format = DetectTraefikFormat(data)
+	}
+
+	var dynamic models.TraefikDynamicConfig
+	switch format {
+	case TraefikFormatLabels:
+		dynamic = ParseTraefikLabels(data, targetHost)
+	case TraefikFormatTOML:
+		if _, err := toml.Decode(data, &dynamic); err != nil {
+			return nil, nil, fmt.Errorf("invalid TOML: %w", err)
+		}
+	case TraefikFormatYAML:
+		if err := yaml.Unmarshal([]byte(data), &dynamic); err != nil {
+			return nil, nil, fmt.Errorf("invalid YAML: %w", err)
+		}
+	default:
+		return nil, nil, fmt.Errorf("unknown format: %s", format)
+	}
+
+	if dynamic.HTTP == nil || len(dynamic.HTTP.Routers) == 0 {
+		return nil, nil, fmt.Errorf("no HTTP routers found")
+	}
+
+	return t.FromDynamicConfig(&dynamic)
+}
+
+// FromDynamicConfig converts Traefik routers into sites
+func (t *TraefikConverter) FromDynamicConfig(dynamic *models.TraefikDynamicConfig) ([]*models.Site, *models.TraefikMappingReport, error) {
+	report := &models.TraefikMappingReport{}
+	snippetCfg := t.snippetConfig()
+
+	wildcards := map[string]bool{}
+	if t.wildcardService != nil {
+		if domains, err := t.wildcardService.GetDomains(); err == nil {
+			for _, wd := range domains {
+				wildcards[wd.Domain] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range dynamic.HTTP.Routers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sites []*models.Site
+	for _, name := range names {
+		router := dynamic.HTTP.Routers[name]
+
+		hosts, leftover := parseTraefikRule(router.Rule)
+		if len(hosts) == 0 {
+			report.Add(name, "rule", "No Host() matcher in rule "+router.Rule+", router skipped")
+			continue
+		}
+		if leftover != "" {
+			report.Add(hosts[0], "rule", "Only Host() matchers are converted, ignored: "+leftover)
+		}
+
+		site := &models.Site{
+			Filename: sanitizeFilename(hosts[0]),
+			Domains:  hosts,
+			TLSMode:  "auto",
+			Snippets: []string{},
+			Tags:     []string{},
+		}
+
+		serviceName := stripProvider(router.Service)
+		if serviceName == "" && len(dynamic.HTTP.Services) == 1 {
+			for n := range dynamic.HTTP.Services {
+				serviceName = n
+			}
+		}
+		service := dynamic.HTTP.Services[serviceName]
+		if service == nil || service.LoadBalancer == nil || len(service.LoadBalancer.Servers) == 0 {
+			report.Add(hosts[0], "service", "Service "+router.Service+" has no load balancer servers, router skipped")
+			continue
+		}
+		if err := t.mapServers(site, service.LoadBalancer, dynamic.HTTP.ServersTransports, report); err != nil {
+			report.Add(hosts[0], "service", err.Error()+", router skipped")
+			continue
+		}
+
+		for _, mwRef := range router.Middlewares {
+			mwName := stripProvider(mwRef)
+			mw := dynamic.HTTP.Middlewares[mwName]
+			if mw == nil {
+				report.Add(hosts[0], "middlewares", "Middleware "+mwRef+" is not defined in the input, ignored")
+				continue
+			}
+			t.mapMiddleware(site, mwName, mw, snippetCfg, report)
+		}
+
+		if router.TLS != nil {
+			for _, d := range router.TLS.Domains {
+				for _, candidate := range append([]string{d.Main}, d.SANs...) {
+					base := strings.TrimPrefix(candidate, "*.")
+					if strings.HasPrefix(candidate, "*.") && strings.HasSuffix(hosts[0], "."+base) {
+						if wildcards[base] {
+							site.TLSMode = "wildcard:" + base
+						} else {
+							report.Add(hosts[0], "tls", "Wildcard certificate *."+base+" is not configured in CPM, using automatic TLS")
+						}
+					}
+				}
+			}
+		} else if !contains(router.EntryPoints, "websecure") && len(router.EntryPoints) > 0 {
+			report.Add(hosts[0], "tls", "Router has no TLS, Caddy will serve it over HTTPS automatically")
+		}
+
+		if router.Priority != 0 {
+			report.Add(hosts[0], "priority", "Router priority has no Caddy equivalent")
+		}
+
+		site.IsInternal = contains(site.Snippets, "internal_only")
+		sites = append(sites, site)
+	}
+
+	return sites, report, nil
+}
+
+// mapServers fills backend settings from a Traefik load balancer
+func (t *TraefikConverter) mapServers(site *models.Site, lb *models.TraefikLoadBalancer, transports map[string]*models.TraefikServersTransport, report *models.TraefikMappingReport) error {
+	for i, server := range lb.Servers {
+		u, err := url.Parse(server.URL)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid server URL %s", server.URL)
+		}
+		if i == 0 {
+			site.IsHTTPSBackend = u.Scheme == "https"
+			site.TargetIP = u.Hostname()
+			site.TargetPort = u.Port()
+			if site.TargetPort == "" {
+				site.TargetPort = "80"
+				if site.IsHTTPSBackend {
+					site.TargetPort = "443"
+				}
+			}
+			if u.Path != "" && u.Path != "/" {
+				report.Add(site.PrimaryDomain(), "service", "Server URL path "+u.Path+" is dropped")
+			}
+			continue
+		}
+		site.AdditionalBackends = append(site.AdditionalBackends, server.URL)
+	}
+
+	if lb.HealthCheck != nil {
+		site.HealthCheckPath = lb.HealthCheck.Path
+		if lb.HealthCheck.Interval != "" && lb.HealthCheck.Interval != "30s" {
+			report.Add(site.PrimaryDomain(), "health_check", "Health check interval "+lb.HealthCheck.Interval+" is replaced by 30s")
+		}
+	}
+
+	if lb.Sticky != nil {
+		site.LBPolicy = "cookie"
+	} else if len(site.AdditionalBackends) > 0 {
+		site.LBPolicy = "round_robin"
+	}
+
+	if lb.ServersTransport != "" {
+		transport := transports[stripProvider(lb.ServersTransport)]
+		if transport == nil {
+			report.Add(site.PrimaryDomain(), "transport", "Servers transport "+lb.ServersTransport+" is not defined in the input, ignored")
+			return nil
+		}
+		if transport.InsecureSkipVerify && !site.IsHTTPSBackend {
+			report.Add(site.PrimaryDomain(), "transport", "insecureSkipVerify only applies to HTTPS backends, ignored")
+		}
+		if transport.ForwardingTimeouts != nil {
+			timeout := transport.ForwardingTimeouts.DialTimeout
+			if timeout == "" {
+				timeout = transport.ForwardingTimeouts.ResponseHeaderTimeout
+			}
+			if d, err := time.ParseDuration(timeout); err == nil {
+				site.TimeoutSeconds = int(d.Seconds())
+			} else if timeout != "" {
+				report.Add(site.PrimaryDomain(), "transport", "Unsupported timeout "+timeout)
+			}
+		}
+	}
+
+	return nil
+}
+
+// mapMiddleware maps a Traefik middleware onto snippets or site fields
+func (t *TraefikConverter) mapMiddleware(site *models.Site, name string, mw *models.TraefikMiddleware, snippetCfg *models.SnippetConfig, report *models.TraefikMappingReport) {
+	domain := site.PrimaryDomain()
+	addSnippet := func(snippet string) {
+		if !contains(site.Snippets, snippet) {
+			site.Snippets = append(site.Snippets, snippet)
+		}
+	}
+
+	allowList := mw.IPAllowList
+	if allowList == nil {
+		allowList = mw.IPWhiteList
+	}
+
+	switch {
+	case allowList != nil:
+		addSnippet("internal_only")
+		if !sameStringSet(allowList.SourceRange, snippetCfg.InternalOnly.AllowedNetworks) {
+			report.Add(domain, "middlewares", fmt.Sprintf("%s allows %s, internal_only uses the CPM network list instead",
+				name, strings.Join(allowList.SourceRange, ", ")))
+		}
+	case mw.Compress != nil:
+		addSnippet("compression")
+	case mw.Headers != nil:
+		addSnippet("security_headers")
+		for header, value := range mw.Headers.CustomResponseHeaders {
+			// An empty Server header is how hide_server is exported
+			if header == "Server" && value == "" {
+				continue
+			}
+			report.Add(domain, "middlewares", name+": custom response header "+header+" is not converted")
+		}
+	case mw.RateLimit != nil:
+		addSnippet("rate_limit")
+		if mw.RateLimit.Average != snippetCfg.RateLimit.Requests {
+			report.Add(domain, "middlewares", fmt.Sprintf("%s: rate limit %d/%s is replaced by the global rate_limit snippet",
+				name, mw.RateLimit.Average, mw.RateLimit.Period))
+		}
+	case mw.BasicAuth != nil:
+		site.BasicAuthEnabled = true
+		bcryptOnly := true
+		for _, user := range mw.BasicAuth.Users {
+			parts := strings.SplitN(user, ":", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				report.Add(domain, "middlewares", fmt.Sprintf("%s: user entry %q has no password hash, skipped", name, parts[0]))
+				continue
+			}
+			site.BasicAuthUsers = append(site.BasicAuthUsers, parts[0]+" "+parts[1])
+			if !strings.HasPrefix(parts[1], "$2") {
+				bcryptOnly = false
+			}
+		}
+		if !bcryptOnly {
+			report.Add(domain, "middlewares", name+": only bcrypt password hashes work with Caddy")
+		}
+	default:
+		report.Add(domain, "middlewares", "Middleware "+name+" has no CPM equivalent, ignored")
+	}
+}
+
+// mapLBPolicy maps the CPM load balancing policy onto a Traefik load balancer
+func (t *TraefikConverter) mapLBPolicy(site *models.Site, lb *models.TraefikLoadBalancer, report *models.TraefikMappingReport) {
+	if len(site.AllBackends()) < 2 {
+		return
+	}
+	switch site.LBPolicy {
+	case "", "round_robin", "weighted_round_robin":
+		// Traefik default
+	case "cookie":
+		lb.Sticky = &models.TraefikSticky{Cookie: &models.TraefikStickyCookie{}}
+	default:
+		report.Add(site.PrimaryDomain(), "lb_policy", "Load balancing policy "+site.LBPolicy+" has no Traefik equivalent, round robin is used")
+	}
+}
+
+// siteMiddlewares returns the shared middlewares a site needs
+func (t *TraefikConverter) siteMiddlewares(site *models.Site, snippetCfg *models.SnippetConfig, report *models.TraefikMappingReport) []string {
+	var middlewares []string
+	domain := site.PrimaryDomain()
+
+	// Wildcard sites are restricted at the wildcard block level, unless they have access lists
+	internal := site.IsInternal || contains(site.Snippets, "internal_only") ||
+		(site.IsWildcard() && len(site.AccessLists) == 0 && snippetCfg.InternalOnly.Enabled && len(snippetCfg.InternalOnly.AllowedNetworks) > 0)
+	if internal {
+		middlewares = append(middlewares, traefikInternalOnly)
+	}
+	if len(site.AccessLists) > 0 {
+		report.Add(domain, "access_lists", "Access lists aren't exported, define a Traefik ipAllowList middleware for "+strings.Join(site.AccessLists, ", "))
+	}
+
+	for _, snippet := range site.Snippets {
+		switch snippet {
+		case "", "internal_only":
+		case "security_headers":
+			middlewares = append(middlewares, traefikSecurityHeaders)
+		case "compression":
+			middlewares = append(middlewares, traefikCompression)
+		case "rate_limit":
+			middlewares = append(middlewares, traefikRateLimit)
+			if len(site.SnippetArgs[snippet]) > 0 {
+				report.Add(domain, "snippets", "rate_limit "+strings.Join(site.SnippetArgs[snippet], " ")+": per-site limits aren't exported, the global limit is used")
+			}
+		case "basic_auth":
+			middlewares = append(middlewares, traefikBasicAuth)
+		case "cloudflare_dns":
+			report.Add(domain, "snippets", "cloudflare_dns: configure the DNS challenge on the Traefik certificate resolver")
+		default:
+			report.Add(domain, "snippets", "Snippet "+snippet+" has no Traefik equivalent")
+		}
+	}
+
+	return middlewares
+}
+
+// sharedMiddleware builds the middleware definition for a CPM snippet
+func (t *TraefikConverter) sharedMiddleware(name string, cfg *models.SnippetConfig) *models.TraefikMiddleware {
+	switch name {
+	case traefikInternalOnly:
+		return &models.TraefikMiddleware{IPAllowList: &models.TraefikIPAllowList{SourceRange: cfg.InternalOnly.AllowedNetworks}}
+	case traefikSecurityHeaders:
+		h := cfg.SecurityHeaders
+		headers := &models.TraefikHeaders{
+			STSSeconds:           h.HSTSMaxAge,
+			STSIncludeSubdomains: h.HSTSIncludeSubdomains,
+			STSPreload:           h.HSTSPreload,
+			ContentTypeNosniff:   h.XContentTypeOptions,
+			ReferrerPolicy:       h.ReferrerPolicy,
+			PermissionsPolicy:    h.PermissionsPolicyValue(),
+		}
+		if h.CSP.Enabled && h.CSP.ReportOnly {
+			headers.ContentSecurityPolicyReportOnly = h.CSP.Value()
+		} else if h.CSP.Enabled {
+			headers.ContentSecurityPolicy = h.CSP.Value()
+		}
+		if h.XFrameOptions == "DENY" {
+			headers.FrameDeny = true
+		} else if h.XFrameOptions != "" {
+			headers.CustomFrameOptionsValue = h.XFrameOptions
+		}
+		custom := make(map[string]string)
+		if h.HideServer {
+			custom["Server"] = ""
+		}
+		for name, value := range map[string]string{
+			"Cross-Origin-Opener-Policy":   h.CrossOriginOpenerPolicy,
+			"Cross-Origin-Embedder-Policy": h.CrossOriginEmbedderPolicy,
+			"Cross-Origin-Resource-Policy": h.CrossOriginResourcePolicy,
+		} {
+			if value != "" {
+				custom[name] = value
+			}
+		}
+		if len(custom) > 0 {
+			headers.CustomResponseHeaders = custom
+		}
+		return &models.TraefikMiddleware{Headers: headers}
+	case traefikCompression:
+		return &models.TraefikMiddleware{Compress: &struct{}{}}
+	case traefikRateLimit:
+		return &models.TraefikMiddleware{RateLimit: &models.TraefikRateLimit{
+			Average: cfg.RateLimit.Requests,
+			Period:  fmt.Sprintf("%ds", cfg.RateLimit.WindowSecs),
+		}}
+	case traefikBasicAuth:
+		var users []string
+		for username, hash := range cfg.BasicAuth.Users {
+			users = append(users, username+":"+hash)
+		}
+		sort.Strings(users)
+		return &models.TraefikMiddleware{BasicAuth: &models.TraefikBasicAuth{Users: users}}
+	}
+	return &models.TraefikMiddleware{}
+}
+
+// sharedMiddlewareLabels returns the label form of a shared middleware
+func (t *TraefikConverter) sharedMiddlewareLabels(name string, cfg *models.SnippetConfig) []string {
+	mw := t.sharedMiddleware(name, cfg)
+	prefix := "traefik.http.middlewares." + name
+
+	switch {
+	case mw.IPAllowList != nil:
+		return []string{fmt.Sprintf("%s.ipallowlist.sourcerange=%s", prefix, strings.Join(mw.IPAllowList.SourceRange, ","))}
+	case mw.Headers != nil:
+		var labels []string
+		if mw.Headers.STSSeconds > 0 {
+			labels = append(labels, fmt.Sprintf("%s.headers.stsseconds=%d", prefix, mw.Headers.STSSeconds))
+		}
+		if mw.Headers.STSIncludeSubdomains {
+			labels = append(labels, prefix+".headers.stsincludesubdomains=true")
+		}
+		if mw.Headers.ContentTypeNosniff {
+			labels = append(labels, prefix+".headers.contenttypenosniff=true")
+		}
+		if mw.Headers.FrameDeny {
+			labels = append(labels, prefix+".headers.framedeny=true")
+		}
+		if mw.Headers.CustomFrameOptionsValue != "" {
+			labels = append(labels, prefix+".headers.customframeoptionsvalue="+mw.Headers.CustomFrameOptionsValue)
+		}
+		if mw.Headers.ReferrerPolicy != "" {
+			labels = append(labels, prefix+".headers.referrerpolicy="+mw.Headers.ReferrerPolicy)
+		}
+		if _, ok := mw.Headers.CustomResponseHeaders["Server"]; ok {
+			labels = append(labels, prefix+".headers.customresponseheaders.Server=")
+		}
+		return labels
+	case mw.Compress != nil:
+		return []string{prefix + ".compress=true"}
+	case mw.RateLimit != nil:
+		return []string{
+			fmt.Sprintf("%s.ratelimit.average=%d", prefix, mw.RateLimit.Average),
+			fmt.Sprintf("%s.ratelimit.period=%s", prefix, mw.RateLimit.Period),
+		}
+	case mw.BasicAuth != nil:
+		return []string{fmt.Sprintf("%s.basicauth.users=%s", prefix, escapeComposeValue(strings.Join(mw.BasicAuth.Users, ",")))}
+	}
+	return nil
+}
+
+// snippetConfig returns the snippet configuration or defaults
+func (t *TraefikConverter) snippetConfig() *models.SnippetConfig {
+	if t.snippetsService != nil {
+		if cfg, err := t.snippetsService.GetConfig(); err == nil {
+			return cfg
+		}
+	}
+	return models.DefaultSnippetConfig()
+}
+
+// DetectTraefikFormat guesses the format of Traefik input
+func DetectTraefikFormat(data string) string {
+	if strings.Contains(data, "traefik.http.") {
+		return TraefikFormatLabels
+	}
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return TraefikFormatTOML
+		}
+		break
+	}
+	return TraefikFormatYAML
+}
+
+// ParseTraefikLabels builds a dynamic configuration from docker-compose style labels.
+// Accepts "- key=value", "key=value" and "key: value" lines.
+func ParseTraefikLabels(data, targetHost string) models.TraefikDynamicConfig {
+	httpCfg := &models.TraefikHTTPConfig{
+		Routers:     map[string]*models.TraefikRouter{},
+		Services:    map[string]*models.TraefikService{},
+		Middlewares: map[string]*models.TraefikMiddleware{},
+	}
+
+	ports := map[string]string{}
+	schemes := map[string]string{}
+
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "-"))
+		line = strings.Trim(line, `"'`)
+		if !strings.HasPrefix(strings.ToLower(line), "traefik.http.") {
+			continue
+		}
+
+		var key, value string
+		if idx := strings.Index(line, "="); idx != -1 {
+			key, value = line[:idx], line[idx+1:]
+		} else if idx := strings.Index(line, ":"); idx != -1 {
+			key, value = line[:idx], line[idx+1:]
+		} else {
+			continue
+		}
+		value = strings.ReplaceAll(strings.Trim(strings.TrimSpace(value), `"'`), "$$", "$")
+
+		parts := strings.Split(strings.TrimSpace(key), ".")
+		if len(parts) < 5 {
+			continue
+		}
+		kind, name := strings.ToLower(parts[2]), parts[3]
+		attr := strings.ToLower(strings.Join(parts[4:], "."))
+
+		switch kind {
+		case "routers":
+			r := httpCfg.Routers[name]
+			if r == nil {
+				r = &models.TraefikRouter{}
+				httpCfg.Routers[name] = r
+			}
+			switch {
+			case attr == "rule":
+				r.Rule = value
+			case attr == "entrypoints":
+				r.EntryPoints = splitList(value)
+			case attr == "service":
+				r.Service = value
+			case attr == "middlewares":
+				r.Middlewares = splitList(value)
+			case attr == "priority":
+				r.Priority, _ = strconv.Atoi(value)
+			case attr == "tls" || strings.HasPrefix(attr, "tls."):
+				if r.TLS == nil {
+					r.TLS = &models.TraefikRouterTLS{}
+				}
+				switch {
+				case attr == "tls.certresolver":
+					r.TLS.CertResolver = value
+				case strings.HasSuffix(attr, ".main"):
+					r.TLS.Domains = append(r.TLS.Domains, models.TraefikTLSDomain{Main: value})
+				case strings.HasSuffix(attr, ".sans"):
+					r.TLS.Domains = append(r.TLS.Domains, models.TraefikTLSDomain{SANs: splitList(value)})
+				}
+			}
+
+		case "services":
+			s := httpCfg.Services[name]
+			if s == nil {
+				s = &models.TraefikService{LoadBalancer: &models.TraefikLoadBalancer{}}
+				httpCfg.Services[name] = s
+			}
+			switch attr {
+			case "loadbalancer.server.port":
+				ports[name] = value
+			case "loadbalancer.server.scheme":
+				schemes[name] = value
+			case "loadbalancer.server.url":
+				s.LoadBalancer.Servers = append(s.LoadBalancer.Servers, models.TraefikServer{URL: value})
+			case "loadbalancer.healthcheck.path":
+				if s.LoadBalancer.HealthCheck == nil {
+					s.LoadBalancer.HealthCheck = &models.TraefikHealthCheck{}
+				}
+				s.LoadBalancer.HealthCheck.Path = value
+			case "loadbalancer.healthcheck.interval":
+				if s.LoadBalancer.HealthCheck == nil {
+					s.LoadBalancer.HealthCheck = &models.TraefikHealthCheck{}
+				}
+				s.LoadBalancer.HealthCheck.Interval = value
+			case "loadbalancer.sticky.cookie", "loadbalancer.sticky.cookie.name":
+				s.LoadBalancer.Sticky = &models.TraefikSticky{Cookie: &models.TraefikStickyCookie{}}
+			case "loadbalancer.serverstransport":
+				s.LoadBalancer.ServersTransport = value
+			}
+
+		case "middlewares":
+			m := httpCfg.Middlewares[name]
+			if m == nil {
+				m = &models.TraefikMiddleware{}
+				httpCfg.Middlewares[name] = m
+			}
+			switch {
+			case attr == "basicauth.users":
+				m.BasicAuth = &models.TraefikBasicAuth{Users: splitList(value)}
+			case attr == "ipallowlist.sourcerange" || attr == "ipwhitelist.sourcerange":
+				m.IPAllowList = &models.TraefikIPAllowList{SourceRange: splitList(value)}
+			case attr == "compress" || strings.HasPrefix(attr, "compress."):
+				m.Compress = &struct{}{}
+			case strings.HasPrefix(attr, "headers."):
+				if m.Headers == nil {
+					m.Headers = &models.TraefikHeaders{}
+				}
+				switch attr {
+				case "headers.stsseconds":
+					m.Headers.STSSeconds, _ = strconv.Atoi(value)
+				case "headers.stsincludesubdomains":
+					m.Headers.STSIncludeSubdomains = value == "true"
+				case "headers.contenttypenosniff":
+					m.Headers.ContentTypeNosniff = value == "true"
+				case "headers.framedeny":
+					m.Headers.FrameDeny = value == "true"
+				case "headers.referrerpolicy":
+					m.Headers.ReferrerPolicy = value
+				}
+			case strings.HasPrefix(attr, "ratelimit."):
+				if m.RateLimit == nil {
+					m.RateLimit = &models.TraefikRateLimit{}
+				}
+				switch attr {
+				case "ratelimit.average":
+					m.RateLimit.Average, _ = strconv.Atoi(value)
+				case "ratelimit.period":
+					m.RateLimit.Period = value
+				case "ratelimit.burst":
+					m.RateLimit.Burst, _ = strconv.Atoi(value)
+				}
+			}
+		}
+	}
+
+	for name, port := range ports {
+		scheme := schemes[name]
+		if scheme == "" {
+			scheme = "http"
+		}
+		lb := httpCfg.Services[name].LoadBalancer
+		lb.Servers = append(lb.Servers, models.TraefikServer{URL: fmt.Sprintf("%s://%s:%s", scheme, targetHost, port)})
+	}
+
+	return models.TraefikDynamicConfig{HTTP: httpCfg}
+}
+
+// traefikName returns a router/service name for a site
+func traefikName(site *models.Site) string {
+	return strings.ReplaceAll(site.MatcherName(), "_", "-")
+}
+
+// traefikHostRule builds a Host() rule for the given domains
+func traefikHostRule(domains []string) string {
+	var parts []string
+	for _, d := range domains {
+		parts = append(parts, fmt.Sprintf("Host(`%s`)", d))
+	}
+	return strings.Join(parts, " || ")
+}
+
+// traefikRouterTLS returns router TLS settings for a site
+func traefikRouterTLS(site *models.Site, opts TraefikExportOptions) *models.TraefikRouterTLS {
+	tls := &models.TraefikRouterTLS{CertResolver: opts.CertResolver}
+	if site.IsWildcard() {
+		tls.Domains = []models.TraefikTLSDomain{{
+			Main: site.WildcardDomain(),
+			SANs: []string{"*." + site.WildcardDomain()},
+		}}
+	}
+	return tls
+}
+
+// traefikUsers converts Caddy "user hash" entries to htpasswd "user:hash"
+func traefikUsers(users []string) []string {
+	var result []string
+	for _, user := range users {
+		if fields := strings.Fields(user); len(fields) == 2 {
+			result = append(result, fields[0]+":"+fields[1])
+		}
+	}
+	return result
+}
+
+var traefikHostRe = regexp.MustCompile("Host\\(([^)]*)\\)")
+var traefikBacktickRe = regexp.MustCompile("`([^`]+)`")
+
+// parseTraefikRule extracts Host() domains and returns what else the rule contains
+func parseTraefikRule(rule string) ([]string, string) {
+	var hosts []string
+	for _, match := range traefikHostRe.FindAllStringSubmatch(rule, -1) {
+		for _, host := range traefikBacktickRe.FindAllStringSubmatch(match[1], -1) {
+			hosts = append(hosts, host[1])
+		}
+	}
+
+	leftover := traefikHostRe.ReplaceAllString(rule, "")
+	leftover = strings.NewReplacer("||", "", "(", "", ")", "").Replace(leftover)
+	if strings.TrimSpace(leftover) != "" {
+		return hosts, strings.TrimSpace(rule)
+	}
+	return hosts, ""
+}
+
+// stripProvider removes the @provider suffix from a Traefik reference
+func stripProvider(name string) string {
+	if idx := strings.Index(name, "@"); idx != -1 {
+		return name[:idx]
+	}
+	return name
+}
+
+// escapeComposeValue escapes $ for docker-compose interpolation
+func escapeComposeValue(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// formatComposeLabels formats labels as a docker-compose labels list
+func formatComposeLabels(comment string, labels []string) string {
+	var lines []string
+	lines = append(lines, comment, "labels:")
+	for _, label := range labels {
+		lines = append(lines, fmt.Sprintf("  - %q", label))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// splitList splits a comma-separated list
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// sameStringSet reports whether two slices contain the same items
+func sameStringSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	set := make(map[string]bool)
+	for _, item := range a {
+		set[item] = true
+	}
+	for _, item := range b {
+		if !set[item] {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultTraefikOptions fills in missing export options
+func defaultTraefikOptions(opts TraefikExportOptions) TraefikExportOptions {
+	if opts.EntryPoint == "" {
+		opts.EntryPoint = "websecure"
+	}
+	if opts.CertResolver == "" {
+		opts.CertResolver = "letsencrypt"
+	}
+	return opts
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+func TestTraefikImportBasicAuthWithoutHash(t *testing.T) {
+	data := `http:
+  routers:
+    app:
+      rule: Host(` + "`app.example.com`" + `)
+      service: app
+      middlewares: [auth]
+  services:
+    app:
+      loadBalancer:
+        servers:
+          - url: http://10.0.0.5:8080
+  middlewares:
+    auth:
+      basicAuth:
+        users:
+          - "admin:"
+          - "alice:$2y$05$abcdefghijklmnopqrstuv"
+          - "bob"
+`
+	sites, report, err := NewTraefikConverter(nil, nil, nil).Import(data, TraefikFormatYAML, "")
+	if err != nil {
+		t.Fatalf("Import: %v", err)
+	}
+	if len(sites) != 1 {
+		t.Fatalf("got %d sites, want 1", len(sites))
+	}
+
+	users := sites[0].BasicAuthUsers
+	if len(users) != 1 || users[0] != "alice $2y$05$abcdefghijklmnopqrstuv" {
+		t.Errorf("BasicAuthUsers = %q, want only alice", users)
+	}
+
+	skipped := 0
+	for _, issue := range report.Issues {
+		if strings.Contains(issue.Message, "has no password hash") {
+			skipped++
+		}
+		if strings.Contains(issue.Message, "only bcrypt") {
+			t.Errorf("unexpected bcrypt issue: %s", issue.Message)
+		}
+	}
+	if skipped != 2 {
+		t.Errorf("reported %d skipped users, want 2", skipped)
+	}
+}
+
+func TestTraefikExportReportsUnsupportedSettings(t *testing.T) {
+	site := &models.Site{
+		Domains:     []string{"app.example.com"},
+		TargetIP:    "10.0.0.5",
+		TargetPort:  "8080",
+		OnDemand:    true,
+		Maintenance: true,
+		ExtraConfig: "encode gzip",
+		Routes:      []models.Route{{Path: "/api/*", Backends: []string{"10.0.0.6:9000"}}},
+		CORS:        &models.CORS{Origins: []string{"https://example.com"}},
+	}
+	want := []string{"extra_config", "on_demand", "maintenance", "routes", "cors"}
+
+	converter := NewTraefikConverter(nil, nil, nil)
+	_, dynamicReport := converter.ToDynamicConfig([]*models.Site{site}, TraefikExportOptions{})
+	_, labelsReport := converter.ToLabels([]*models.Site{site}, TraefikExportOptions{})
+
+	for name, report := range map[string]*models.TraefikMappingReport{"dynamic config": dynamicReport, "labels": labelsReport} {
+		var fields []string
+		for _, issue := range report.Issues {
+			if issue.Field != "target_ip" {
+				fields = append(fields, issue.Field)
+			}
+		}
+		if !reflect.DeepEqual(fields, want) {
+			t.Errorf("%s report fields = %q, want %q", name, fields, want)
+		}
+	}
+}
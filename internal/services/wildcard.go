@@ -2,14 +2,20 @@ package services
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/TomasZmek/cpm/internal/models"
 )
 
+// envVarName matches environment variable names usable in {env.NAME}
+var envVarName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // WildcardService manages wildcard SSL certificate configurations
 type WildcardService struct {
 	configPath string
@@ -156,34 +162,117 @@ func (s *WildcardService) GenerateCaddyConfig() (string, error) {
 	result += "# Import this in your site configs with: import wildcard-tls-<domain>\n\n"
 
 	for _, domain := range config.Domains {
-		var tokenSource string
-		if domain.UseEnv {
-			tokenSource = "{env.CF_API_TOKEN}"
-		} else {
-			tokenSource = domain.APIToken
-		}
-
 		// Generate snippet for wildcard TLS
-		snippetName := "wildcard-tls-" + strings.ReplaceAll(domain.Domain, ".", "-")
-		
+		snippetName := GetSnippetName(domain.Domain)
+
 		result += "# Wildcard TLS snippet for *." + domain.Domain + "\n"
 		result += "(" + snippetName + ") {\n"
-		result += "    tls {\n"
-		
-		switch domain.Provider {
-		case "cloudflare":
-			result += "        dns cloudflare " + tokenSource + "\n"
-		default:
-			result += "        dns cloudflare " + tokenSource + "\n"
+
+		var directive []string
+		if domain.Issuer != models.IssuerInternal {
+			var err error
+			directive, err = DNSDirective(domain)
+			if err != nil {
+				log.Printf("Error generating DNS challenge for %s: %v", domain.Domain, err)
+				directive = []string{"# " + err.Error()}
+			}
 		}
-		
-		result += "    }\n"
+		for _, line := range domain.ACMESettings.TLSBlock(domain.Issuer, directive) {
+			result += "    " + line + "\n"
+		}
+
 		result += "}\n\n"
 	}
 
 	return result, nil
 }
 
+// ValidateWildcardDomain checks the issuer, the provider and its required fields
+func ValidateWildcardDomain(domain models.WildcardDomain) error {
+	if err := domain.ACMESettings.Validate(domain.Issuer); err != nil {
+		return err
+	}
+	if domain.Issuer == models.IssuerInternal {
+		// The internal CA doesn't need a DNS challenge
+		return nil
+	}
+
+	provider := models.GetDNSProviderByID(domain.ProviderID())
+	if provider == nil {
+		return fmt.Errorf("unknown DNS provider: %s", domain.Provider)
+	}
+
+	for _, field := range provider.Fields {
+		if field.Secret && domain.UseEnv {
+			name := domain.EnvVar(field)
+			if name == "" && field.Required {
+				return fmt.Errorf("%s: environment variable name is required", field.Label)
+			}
+			if name != "" && !envVarName.MatchString(name) {
+				return fmt.Errorf("%s: invalid environment variable name %q", field.Label, name)
+			}
+			continue
+		}
+		value := domain.Credential(field.Key)
+		if value == "" && field.Required {
+			return fmt.Errorf("%s is required for %s", field.Label, provider.Name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("%s must be a single line", field.Label)
+		}
+	}
+
+	return nil
+}
+
+// DNSDirective returns the "dns <provider> ..." lines for a wildcard domain
+func DNSDirective(domain models.WildcardDomain) ([]string, error) {
+	if err := ValidateWildcardDomain(domain); err != nil {
+		return nil, err
+	}
+	provider := models.GetDNSProviderByID(domain.ProviderID())
+	if provider == nil {
+		return nil, fmt.Errorf("unknown DNS provider: %s", domain.Provider)
+	}
+
+	type setting struct{ key, value string }
+	var settings []setting
+	for _, field := range provider.Fields {
+		value := ""
+		if field.Secret && domain.UseEnv {
+			// Optional secrets are only read from the environment when a variable is named
+			if field.Required || domain.EnvVars[field.Key] != "" {
+				value = "{env." + domain.EnvVar(field) + "}"
+			}
+		} else if v := domain.Credential(field.Key); v != "" {
+			value = caddyArg(v)
+		}
+		if value != "" {
+			settings = append(settings, setting{field.Key, value})
+		}
+	}
+
+	// Single credential providers accept it as the only argument
+	if provider.Inline && len(settings) == 1 && settings[0].key == provider.Fields[0].Key {
+		return []string{"dns " + provider.ID + " " + settings[0].value}, nil
+	}
+
+	lines := []string{"dns " + provider.ID + " {"}
+	for _, s := range settings {
+		lines = append(lines, "    "+s.key+" "+s.value)
+	}
+	lines = append(lines, "}")
+	return lines, nil
+}
+
+// caddyArg quotes a Caddyfile argument if it contains whitespace or quotes
+func caddyArg(value string) string {
+	if strings.ContainsAny(value, " \t\"'{}") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
 // GetSnippetName returns the snippet name for a domain
 func GetSnippetName(domain string) string {
 	return "wildcard-tls-" + strings.ReplaceAll(domain, ".", "-")
@@ -0,0 +1,275 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/TomasZmek/cpm/internal/models"
+)
+
+// dnsProviderTests has one entry per registered DNS provider with literal
+// credentials and the dns lines expected with and without the environment
+var dnsProviderTests = []struct {
+	provider    string
+	credentials map[string]string
+	literal     []string
+	env         []string
+}{
+	{
+		provider:    "cloudflare",
+		credentials: map[string]string{"api_token": "cf-token"},
+		literal:     []string{"dns cloudflare cf-token"},
+		env:         []string{"dns cloudflare {env.CF_API_TOKEN}"},
+	},
+	{
+		provider: "route53",
+		credentials: map[string]string{
+			"access_key_id":     "AKID",
+			"secret_access_key": "SECRET",
+			"region":            "eu-central-1",
+		},
+		literal: []string{
+			"dns route53 {",
+			"    access_key_id AKID",
+			"    secret_access_key SECRET",
+			"    region eu-central-1",
+			"}",
+		},
+		env: []string{
+			"dns route53 {",
+			"    access_key_id {env.AWS_ACCESS_KEY_ID}",
+			"    secret_access_key {env.AWS_SECRET_ACCESS_KEY}",
+			"    region eu-central-1",
+			"}",
+		},
+	},
+	{
+		provider:    "digitalocean",
+		credentials: map[string]string{"auth_token": "do-token"},
+		literal:     []string{"dns digitalocean do-token"},
+		env:         []string{"dns digitalocean {env.DO_AUTH_TOKEN}"},
+	},
+	{
+		provider:    "hetzner",
+		credentials: map[string]string{"api_token": "hz-token"},
+		literal:     []string{"dns hetzner hz-token"},
+		env:         []string{"dns hetzner {env.HETZNER_API_TOKEN}"},
+	},
+	{
+		provider:    "porkbun",
+		credentials: map[string]string{"api_key": "pk1", "api_secret_key": "sk1"},
+		literal:     []string{"dns porkbun {", "    api_key pk1", "    api_secret_key sk1", "}"},
+		env: []string{
+			"dns porkbun {",
+			"    api_key {env.PORKBUN_API_KEY}",
+			"    api_secret_key {env.PORKBUN_API_SECRET_KEY}",
+			"}",
+		},
+	},
+	{
+		provider:    "duckdns",
+		credentials: map[string]string{"api_token": "duck-token"},
+		literal:     []string{"dns duckdns duck-token"},
+		env:         []string{"dns duckdns {env.DUCKDNS_API_TOKEN}"},
+	},
+	{
+		provider:    "desec",
+		credentials: map[string]string{"token": "desec-token"},
+		literal:     []string{"dns desec {", "    token desec-token", "}"},
+		env:         []string{"dns desec {", "    token {env.DESEC_TOKEN}", "}"},
+	},
+	{
+		provider: "rfc2136",
+		credentials: map[string]string{
+			"key_name": "caddy.",
+			"key_alg":  "hmac-sha256",
+			"key":      "c2VjcmV0",
+			"server":   "ns1.example.com:53",
+		},
+		literal: []string{
+			"dns rfc2136 {",
+			"    key_name caddy.",
+			"    key_alg hmac-sha256",
+			"    key c2VjcmV0",
+			"    server ns1.example.com:53",
+			"}",
+		},
+		env: []string{
+			"dns rfc2136 {",
+			"    key_name caddy.",
+			"    key_alg hmac-sha256",
+			"    key {env.RFC2136_KEY}",
+			"    server ns1.example.com:53",
+			"}",
+		},
+	},
+}
+
+func TestDNSProvidersCovered(t *testing.T) {
+	tested := map[string]bool{}
+	for _, tt := range dnsProviderTests {
+		tested[tt.provider] = true
+	}
+	for _, p := range models.GetDNSProviders() {
+		if !tested[p.ID] {
+			t.Errorf("DNS provider %s has no test case", p.ID)
+		}
+	}
+}
+
+func TestDNSDirective(t *testing.T) {
+	for _, tt := range dnsProviderTests {
+		t.Run(tt.provider, func(t *testing.T) {
+			provider := models.GetDNSProviderByID(tt.provider)
+			if provider == nil {
+				t.Fatalf("provider %s is not registered", tt.provider)
+			}
+
+			literal := models.WildcardDomain{Domain: "example.com", Provider: tt.provider, Credentials: tt.credentials}
+			lines, err := DNSDirective(literal)
+			if err != nil {
+				t.Fatalf("literal: %v", err)
+			}
+			if !reflect.DeepEqual(lines, tt.literal) {
+				t.Errorf("literal lines = %q, want %q", lines, tt.literal)
+			}
+
+			// Secrets come from the environment, other fields stay literal
+			env := literal
+			env.UseEnv = true
+			env.Credentials = map[string]string{}
+			for _, field := range provider.Fields {
+				if !field.Secret {
+					env.Credentials[field.Key] = tt.credentials[field.Key]
+				}
+			}
+			lines, err = DNSDirective(env)
+			if err != nil {
+				t.Fatalf("env: %v", err)
+			}
+			if !reflect.DeepEqual(lines, tt.env) {
+				t.Errorf("env lines = %q, want %q", lines, tt.env)
+			}
+		})
+	}
+}
+
+func TestValidateWildcardDomainRejectsCredentials(t *testing.T) {
+	for _, tt := range dnsProviderTests {
+		provider := models.GetDNSProviderByID(tt.provider)
+		for _, field := range provider.Fields {
+			if field.Required {
+				t.Run(tt.provider+"/missing "+field.Key, func(t *testing.T) {
+					domain := models.WildcardDomain{Domain: "example.com", Provider: tt.provider, Credentials: map[string]string{}}
+					for k, v := range tt.credentials {
+						if k != field.Key {
+							domain.Credentials[k] = v
+						}
+					}
+					if err := ValidateWildcardDomain(domain); err == nil {
+						t.Error("missing credential accepted")
+					}
+				})
+			}
+
+			if _, ok := tt.credentials[field.Key]; ok {
+				t.Run(tt.provider+"/multiline "+field.Key, func(t *testing.T) {
+					domain := models.WildcardDomain{Domain: "example.com", Provider: tt.provider, Credentials: map[string]string{}}
+					for k, v := range tt.credentials {
+						domain.Credentials[k] = v
+					}
+					domain.Credentials[field.Key] += "\n}"
+					if err := ValidateWildcardDomain(domain); err == nil {
+						t.Error("multiline credential accepted")
+					}
+				})
+			}
+
+			if field.Secret {
+				t.Run(tt.provider+"/invalid env "+field.Key, func(t *testing.T) {
+					domain := models.WildcardDomain{
+						Domain:      "example.com",
+						Provider:    tt.provider,
+						UseEnv:      true,
+						Credentials: tt.credentials,
+						EnvVars:     map[string]string{field.Key: "BAD NAME}"},
+					}
+					if err := ValidateWildcardDomain(domain); err == nil {
+						t.Error("invalid environment variable name accepted")
+					}
+				})
+			}
+		}
+	}
+}
+
+func TestDNSDirectiveCredentialOutput(t *testing.T) {
+	tests := []struct {
+		name   string
+		domain models.WildcardDomain
+		want   []string
+	}{
+		{
+			name:   "custom env var",
+			domain: models.WildcardDomain{Provider: "cloudflare", UseEnv: true, EnvVars: map[string]string{"api_token": "EXAMPLE_CF_TOKEN"}},
+			want:   []string{"dns cloudflare {env.EXAMPLE_CF_TOKEN}"},
+		},
+		{
+			name: "optional secret from env",
+			domain: models.WildcardDomain{Provider: "cloudflare", UseEnv: true, EnvVars: map[string]string{
+				"zone_token": "CF_ZONE_TOKEN",
+			}},
+			want: []string{"dns cloudflare {", "    api_token {env.CF_API_TOKEN}", "    zone_token {env.CF_ZONE_TOKEN}", "}"},
+		},
+		{
+			name:   "legacy api token",
+			domain: models.WildcardDomain{APIToken: "legacy-token"},
+			want:   []string{"dns cloudflare legacy-token"},
+		},
+		{
+			name:   "quoted literal",
+			domain: models.WildcardDomain{Provider: "hetzner", Credentials: map[string]string{"api_token": "tok en"}},
+			want:   []string{`dns hetzner "tok en"`},
+		},
+		{
+			name:   "literal placeholder text is quoted",
+			domain: models.WildcardDomain{Provider: "desec", Credentials: map[string]string{"token": "{env.X}"}},
+			want:   []string{"dns desec {", `    token "{env.X}"`, "}"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.domain.Domain = "example.com"
+			lines, err := DNSDirective(tt.domain)
+			if err != nil {
+				t.Fatalf("DNSDirective: %v", err)
+			}
+			if !reflect.DeepEqual(lines, tt.want) {
+				t.Errorf("lines = %q, want %q", lines, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateWildcardDomainProvider(t *testing.T) {
+	tests := []struct {
+		name    string
+		domain  models.WildcardDomain
+		wantErr bool
+	}{
+		{name: "unknown provider", domain: models.WildcardDomain{Provider: "nope", Credentials: map[string]string{"api_token": "x"}}, wantErr: true},
+		{name: "internal issuer needs no provider", domain: models.WildcardDomain{Provider: "nope", Issuer: models.IssuerInternal}},
+		{name: "cloudflare default", domain: models.WildcardDomain{Credentials: map[string]string{"api_token": "x"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.domain.Domain = "example.com"
+			err := ValidateWildcardDomain(tt.domain)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateWildcardDomain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}